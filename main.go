@@ -16,12 +16,16 @@ import (
 	"github.com/Cepave/grafana/pkg/login"
 	"github.com/Cepave/grafana/pkg/metrics"
 	"github.com/Cepave/grafana/pkg/plugins"
+	"github.com/Cepave/grafana/pkg/services/cleanup"
 	"github.com/Cepave/grafana/pkg/services/eventpublisher"
+	"github.com/Cepave/grafana/pkg/services/eventstream"
 	"github.com/Cepave/grafana/pkg/services/notifications"
+	"github.com/Cepave/grafana/pkg/services/provisioning"
 	"github.com/Cepave/grafana/pkg/services/search"
 	"github.com/Cepave/grafana/pkg/services/sqlstore"
 	"github.com/Cepave/grafana/pkg/setting"
 	"github.com/Cepave/grafana/pkg/social"
+	"golang.org/x/net/context"
 )
 
 var version = "master"
@@ -33,6 +37,8 @@ var homePath = flag.String("homepath", "", "path to grafana install/home path, d
 var pidFile = flag.String("pidfile", "", "path to pid file")
 var exitChan = make(chan int)
 
+const shutdownTimeout = 30 * time.Second
+
 func init() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 }
@@ -55,12 +61,19 @@ func main() {
 	login.Init()
 	social.NewOAuthService()
 	eventpublisher.Init()
+	eventstream.Init()
 	plugins.Init()
 
 	if err := notifications.Init(); err != nil {
 		log.Fatal(3, "Notification service failed to initialize", err)
 	}
 
+	if err := provisioning.Init(); err != nil {
+		log.Fatal(3, "Provisioning failed: %v", err)
+	}
+
+	cleanup.Init()
+
 	if setting.ReportingEnabled {
 		go metrics.StartUsageReportLoop()
 	}
@@ -117,6 +130,11 @@ func listenToSystemSignels() {
 	select {
 	case sig := <-signalChan:
 		log.Info("Received signal %s. shutting down", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := cmd.Shutdown(ctx); err != nil {
+			log.Warn("Timed out waiting for in-flight requests: %v", err)
+		}
+		cancel()
 	case code = <-exitChan:
 		switch code {
 		case 0:
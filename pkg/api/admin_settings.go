@@ -2,8 +2,11 @@ package api
 
 import (
 	"strings"
+	"time"
 
+	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/setting"
 )
 
@@ -27,3 +30,41 @@ func AdminGetSettings(c *middleware.Context) {
 
 	c.JSON(200, settings)
 }
+
+// GET /api/admin/stats
+func AdminGetStats(c *middleware.Context) Response {
+	query := m.GetAdminStatsQuery{
+		ActiveSince: time.Now().Add(-setting.UserSessionIdleLifetime()),
+	}
+
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get admin stats", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// GET /api/admin/migrations
+func AdminGetMigrationStatus(c *middleware.Context) Response {
+	query := m.GetMigrationStatusQuery{}
+
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get migration status", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// POST /api/admin/integrity/check
+//
+// Reports rows pointing at deleted orgs, dashboards or users, ?fix=true
+// deletes them.
+func CheckIntegrity(c *middleware.Context) Response {
+	cmd := m.CheckIntegrityCommand{Fix: c.Query("fix") == "true"}
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to check integrity", err)
+	}
+
+	return Json(200, cmd.Result)
+}
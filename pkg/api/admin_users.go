@@ -6,6 +6,7 @@ import (
 	"github.com/Cepave/grafana/pkg/metrics"
 	"github.com/Cepave/grafana/pkg/middleware"
 	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
 	"github.com/Cepave/grafana/pkg/util"
 )
 
@@ -15,6 +16,8 @@ func AdminCreateUser(c *middleware.Context, form dtos.AdminCreateUserForm) {
 		Email:    form.Email,
 		Password: form.Password,
 		Name:     form.Name,
+		// the admin vouches for the address
+		EmailVerified: true,
 	}
 
 	if len(cmd.Login) == 0 {
@@ -31,6 +34,10 @@ func AdminCreateUser(c *middleware.Context, form dtos.AdminCreateUserForm) {
 	}
 
 	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrUserEmailTaken || err == m.ErrUserLoginTaken {
+			c.JsonApiErr(409, err.Error(), nil)
+			return
+		}
 		c.JsonApiErr(500, "failed to create user", err)
 		return
 	}
@@ -55,11 +62,10 @@ func AdminUpdateUserPassword(c *middleware.Context, form dtos.AdminUpdateUserPas
 		return
 	}
 
-	passwordHashed := util.EncodePassword(form.Password, userQuery.Result.Salt)
-
 	cmd := m.ChangeUserPasswordCommand{
-		UserId:      userId,
-		NewPassword: passwordHashed,
+		UserId:       userId,
+		NewPassword:  util.EncodePasswordWithCost(form.Password, userQuery.Result.Salt, setting.PasswordHashCost),
+		PasswordCost: setting.PasswordHashCost,
 	}
 
 	if err := bus.Dispatch(&cmd); err != nil {
@@ -79,6 +85,10 @@ func AdminUpdateUserPermissions(c *middleware.Context, form dtos.AdminUpdateUser
 	}
 
 	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrLastGrafanaAdmin {
+			c.JsonApiErr(400, "Cannot remove grafana admin from the last grafana admin", nil)
+			return
+		}
 		c.JsonApiErr(500, "Failed to update user permissions", err)
 		return
 	}
@@ -89,12 +99,69 @@ func AdminUpdateUserPermissions(c *middleware.Context, form dtos.AdminUpdateUser
 func AdminDeleteUser(c *middleware.Context) {
 	userId := c.ParamsInt64(":id")
 
+	// list what would be left behind without deleting anything
+	if c.Query("dryRun") == "true" {
+		query := m.GetUserOwnedResourcesQuery{UserId: userId}
+		if err := bus.Dispatch(&query); err != nil {
+			c.JsonApiErr(500, "Failed to get resources owned by user", err)
+			return
+		}
+
+		c.JSON(200, query.Result)
+		return
+	}
+
 	cmd := m.DeleteUserCommand{UserId: userId}
 
 	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrLastGrafanaAdmin {
+			c.JsonApiErr(400, "Cannot delete the last grafana admin", nil)
+			return
+		}
+		if err == m.ErrLastOrgAdmin {
+			c.JsonApiErr(400, "Cannot delete the last admin of an organization with other members", nil)
+			return
+		}
 		c.JsonApiErr(500, "Failed to delete user", err)
 		return
 	}
 
 	c.JsonOK("User deleted")
 }
+
+func AdminDisableUser(c *middleware.Context) {
+	userId := c.ParamsInt64(":id")
+
+	if userId == c.UserId {
+		c.JsonApiErr(400, "You cannot disable your own account", nil)
+		return
+	}
+
+	cmd := m.DisableUserCommand{UserId: userId}
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrUserNotFound {
+			c.JsonApiErr(404, "User not found", nil)
+			return
+		}
+		c.JsonApiErr(500, "Failed to disable user", err)
+		return
+	}
+
+	c.JsonOK("User disabled")
+}
+
+func AdminEnableUser(c *middleware.Context) {
+	cmd := m.EnableUserCommand{UserId: c.ParamsInt64(":id")}
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrUserNotFound {
+			c.JsonApiErr(404, "User not found", nil)
+			return
+		}
+		c.JsonApiErr(500, "Failed to enable user", err)
+		return
+	}
+
+	c.JsonOK("User enabled")
+}
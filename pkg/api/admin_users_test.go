@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Unknwon/macaron"
+	"github.com/macaron-contrib/binding"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestAdminDeleteUser(t *testing.T) {
+
+	Convey("When deleting a user", t, func() {
+		deleted := false
+		bus.AddHandler("test", func(cmd *m.DeleteUserCommand) error {
+			deleted = true
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetUserOwnedResourcesQuery) error {
+			query.Result = &m.UserOwnedResourcesDTO{
+				SoleAdminOrgs: []*m.OrgDTO{{Id: 3, Name: "ops"}},
+				Dashboards:    []*m.UserOwnedDashboardDTO{{Id: 7, OrgId: 3, Title: "overview"}},
+				ApiKeys:       []*m.UserOwnedApiKeyDTO{{Id: 9, OrgId: 3, Name: "ci"}},
+				Snapshots:     []*m.DashboardSnapshotDTO{},
+			}
+			return nil
+		})
+		defer bus.ClearBusHandlers()
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{IsGrafanaAdmin: true}, IsSignedIn: true})
+		})
+		mac.Delete("/api/admin/users/:id", AdminDeleteUser)
+
+		Convey("A dry run should list owned resources without deleting", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/api/admin/users/5?dryRun=true", nil)
+			mac.ServeHTTP(resp, req)
+
+			So(resp.Code, ShouldEqual, 200)
+			So(deleted, ShouldBeFalse)
+
+			var result m.UserOwnedResourcesDTO
+			So(json.Unmarshal(resp.Body.Bytes(), &result), ShouldBeNil)
+			So(result.SoleAdminOrgs[0].Name, ShouldEqual, "ops")
+			So(result.Dashboards[0].Title, ShouldEqual, "overview")
+			So(result.ApiKeys[0].Name, ShouldEqual, "ci")
+		})
+
+		Convey("Without a dry run the user should be deleted", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/api/admin/users/5", nil)
+			mac.ServeHTTP(resp, req)
+
+			So(resp.Code, ShouldEqual, 200)
+			So(deleted, ShouldBeTrue)
+		})
+	})
+}
+
+func TestAdminLastGrafanaAdmin(t *testing.T) {
+
+	Convey("When the last grafana admin would be removed", t, func() {
+		bus.AddHandler("test", func(cmd *m.DeleteUserCommand) error {
+			return m.ErrLastGrafanaAdmin
+		})
+		bus.AddHandler("test", func(cmd *m.UpdateUserPermissionsCommand) error {
+			return m.ErrLastGrafanaAdmin
+		})
+		defer bus.ClearBusHandlers()
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{IsGrafanaAdmin: true}, IsSignedIn: true})
+		})
+		mac.Delete("/api/admin/users/:id", AdminDeleteUser)
+		mac.Put("/api/admin/users/:id/permissions", binding.Bind(dtos.AdminUpdateUserPermissionsForm{}), AdminUpdateUserPermissions)
+
+		Convey("Deleting should be refused with 400", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/api/admin/users/1", nil)
+			mac.ServeHTTP(resp, req)
+
+			So(resp.Code, ShouldEqual, 400)
+		})
+
+		Convey("Downgrading should be refused with 400", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/api/admin/users/1/permissions", strings.NewReader(`{"IsGrafanaAdmin":false}`))
+			req.Header.Set("Content-Type", "application/json")
+			mac.ServeHTTP(resp, req)
+
+			So(resp.Code, ShouldEqual, 400)
+		})
+	})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/util"
+)
+
+const (
+	annotationsDefaultLimit = 100
+	annotationsMaxLimit     = 1000
+)
+
+// GET /api/annotations, from and to are epoch milliseconds
+func GetAnnotations(c *middleware.Context) Response {
+	from := c.QueryInt64("from")
+	to := c.QueryInt64("to")
+	if from < 0 || (to > 0 && to < from) {
+		return ApiError(400, "Invalid time range", nil)
+	}
+
+	limit := c.QueryInt("limit")
+	if limit <= 0 {
+		limit = annotationsDefaultLimit
+	}
+	if limit > annotationsMaxLimit {
+		limit = annotationsMaxLimit
+	}
+
+	query := m.GetAnnotationsQuery{
+		OrgId:       c.OrgId,
+		DashboardId: c.QueryInt64("dashboardId"),
+		From:        from,
+		To:          to,
+		Tags:        c.QueryStrings("tags"),
+		Limit:       limit,
+	}
+
+	if query.DashboardId != 0 {
+		if permission, err := dashboardPermission(c, query.DashboardId); err != nil {
+			return ApiError(500, "Failed to check dashboard permissions", err)
+		} else if permission < m.PERMISSION_VIEW {
+			return ApiError(403, m.ErrDashboardPermissionDenied.Error(), nil)
+		}
+	}
+
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get annotations", err)
+	}
+
+	annotations, err := filterAnnotationsByPermission(c, query.Result)
+	if err != nil {
+		return ApiError(500, "Failed to check dashboard permissions", err)
+	}
+
+	return Json(200, annotations)
+}
+
+// filterAnnotationsByPermission leaves out the annotations of dashboards the
+// user may not view, org wide ones are kept.
+func filterAnnotationsByPermission(c *middleware.Context, annotations []*m.AnnotationDTO) ([]*m.AnnotationDTO, error) {
+	query := m.GetDashboardPermissionsQuery{OrgId: c.OrgId, UserId: c.UserId, OrgRole: c.OrgRole}
+	for _, annotation := range annotations {
+		if annotation.DashboardId != 0 {
+			query.DashboardIds = append(query.DashboardIds, annotation.DashboardId)
+		}
+	}
+	if len(query.DashboardIds) == 0 {
+		return annotations, nil
+	}
+
+	if err := bus.Dispatch(&query); err != nil {
+		return nil, err
+	}
+
+	visible := make([]*m.AnnotationDTO, 0, len(annotations))
+	for _, annotation := range annotations {
+		if annotation.DashboardId == 0 || query.Result[annotation.DashboardId] >= m.PERMISSION_VIEW {
+			visible = append(visible, annotation)
+		}
+	}
+	return visible, nil
+}
+
+// POST /api/annotations
+func CreateAnnotation(c *middleware.Context, cmd m.CreateAnnotationCommand) Response {
+	cmd.OrgId = c.OrgId
+	cmd.UserId = c.UserId
+
+	if cmd.DashboardId != 0 {
+		if permission, err := dashboardPermission(c, cmd.DashboardId); err != nil {
+			return ApiError(500, "Failed to check dashboard permissions", err)
+		} else if permission < m.PERMISSION_EDIT {
+			return ApiError(403, m.ErrDashboardPermissionDenied.Error(), nil)
+		}
+	}
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to create annotation", err)
+	}
+
+	return Json(200, util.DynMap{"message": "Annotation added", "id": cmd.Result.Id})
+}
+
+// DELETE /api/annotations/:annotationId
+func DeleteAnnotation(c *middleware.Context) Response {
+	cmd := m.DeleteAnnotationCommand{Id: c.ParamsInt64(":annotationId"), OrgId: c.OrgId}
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to delete annotation", err)
+	}
+
+	return ApiSuccess("Annotation deleted")
+}
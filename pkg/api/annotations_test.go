@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAnnotationsAcl(t *testing.T) {
+
+	Convey("Given annotations on a dashboard an editor may only view", t, func() {
+		defer bus.ClearBusHandlers()
+
+		// dashboard 1 has no acl, 2 lets editors view it and 3 only admins
+		acls := map[int64][]*m.DashboardAcl{
+			2: {{DashboardId: 2, Role: m.ROLE_EDITOR, Permission: m.PERMISSION_VIEW}},
+			3: {{DashboardId: 3, Role: m.ROLE_ADMIN, Permission: m.PERMISSION_VIEW}},
+		}
+
+		bus.AddHandler("test", func(query *m.GetDashboardAclQuery) error {
+			query.Result = acls[query.DashboardId]
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDashboardPermissionsQuery) error {
+			query.Result = make(map[int64]m.PermissionType)
+			for _, id := range query.DashboardIds {
+				query.Result[id] = m.GetDashboardPermission(acls[id], query.UserId, nil, query.OrgRole)
+			}
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetAnnotationsQuery) error {
+			query.Result = []*m.AnnotationDTO{{Id: 1, DashboardId: 1}, {Id: 2, DashboardId: 2}, {Id: 3, DashboardId: 3}, {Id: 4}}
+			return nil
+		})
+
+		var created *m.CreateAnnotationCommand
+		bus.AddHandler("test", func(cmd *m.CreateAnnotationCommand) error {
+			created = cmd
+			cmd.Result = &m.Annotation{Id: 5}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, UserId: 5, OrgRole: m.ROLE_EDITOR}, IsSignedIn: true})
+		})
+		mac.Get("/api/annotations", wrap(GetAnnotations))
+
+		var body m.CreateAnnotationCommand
+		mac.Post("/api/annotations", wrap(func(c *middleware.Context) Response {
+			return CreateAnnotation(c, body)
+		}))
+
+		serve := func(method, url string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, url, strings.NewReader(""))
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should leave out the annotations of dashboards it may not view", func() {
+			resp := serve("GET", "/api/annotations")
+			So(resp.Code, ShouldEqual, 200)
+
+			var result []*m.AnnotationDTO
+			So(json.Unmarshal(resp.Body.Bytes(), &result), ShouldBeNil)
+
+			ids := []int64{}
+			for _, annotation := range result {
+				ids = append(ids, annotation.Id)
+			}
+			So(ids, ShouldResemble, []int64{1, 2, 4})
+		})
+
+		Convey("Should not list the annotations of a dashboard it may not view", func() {
+			So(serve("GET", "/api/annotations?dashboardId=3").Code, ShouldEqual, 403)
+		})
+
+		Convey("Should only annotate dashboards it may edit", func() {
+			body = m.CreateAnnotationCommand{DashboardId: 2, Time: 1, Text: "deploy"}
+			So(serve("POST", "/api/annotations").Code, ShouldEqual, 403)
+			So(created, ShouldBeNil)
+
+			body = m.CreateAnnotationCommand{DashboardId: 1, Time: 1, Text: "deploy"}
+			So(serve("POST", "/api/annotations").Code, ShouldEqual, 200)
+			So(created.DashboardId, ShouldEqual, 1)
+		})
+	})
+}
@@ -2,29 +2,79 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"github.com/toolkits/file"
-	"log"
+	l "log"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 
 	"github.com/Unknwon/macaron"
 	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/log"
 	"github.com/Cepave/grafana/pkg/middleware"
 	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/sqlstore"
+	"github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+	"github.com/Cepave/grafana/pkg/services/webhooks"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Cepave/grafana/pkg/util"
+	"github.com/lib/pq"
 	"github.com/macaron-contrib/binding"
 )
 
 var OpenFalconConfigFile = flag.String("configGlobal", "cfg.json", "configuration file")
 type DatabaseConfig struct {
-	Addr    string  `json:"addr"`
-	Idle    int     `json:"idle"`
-	Max     int     `json:"max"`
+	Type string `json:"type"`
+	Addr string `json:"addr"`
+	DSN  string `json:"dsn"`
+	Idle int    `json:"idle"`
+	Max  int    `json:"max"`
+}
+
+// ConnStr returns what is handed to the driver, a DSN wins over Addr.
+func (cfg *DatabaseConfig) ConnStr() string {
+	if cfg.DSN != "" {
+		return cfg.DSN
+	}
+	return cfg.Addr
+}
+
+type OpenFalconConfig struct {
+	Enabled   bool   `json:"enabled"`
+	QueryAddr string `json:"queryAddr"`
+	AlarmAddr string `json:"alarmAddr"`
+}
+
+type CorsConfig struct {
+	AllowedOrigins   []string `json:"allowedOrigins"`
+	AllowedMethods   []string `json:"allowedMethods"`
+	AllowedHeaders   []string `json:"allowedHeaders"`
+	AllowCredentials bool     `json:"allowCredentials"`
+}
+
+type WebhooksConfig struct {
+	OrgEvents string `json:"orgEvents"`
+	Secret    string `json:"secret"`
 }
 
 type GlobalConfig struct {
-	Db      *DatabaseConfig  `json:"db"`
-	Home    string           `json:"home"`
+	Db                   *DatabaseConfig   `json:"db"`
+	Replicas             []DatabaseConfig  `json:"replicas"`
+	Home                 string            `json:"home"`
+	OpenFalcon           *OpenFalconConfig `json:"openfalcon"`
+	Cors                 *CorsConfig       `json:"cors"`
+	AllowedSignupDomains []string          `json:"allowedSignupDomains"`
+	CookieSecure         bool              `json:"cookieSecure"`
+	CookieSameSite       string            `json:"cookieSameSite"`
+	CookieName           string            `json:"cookieName"`
+	CookieDomain         string            `json:"cookieDomain"`
+	TrustedProxies       []string          `json:"trustedProxies"`
+	RootPath             string            `json:"rootPath"`
+	Webhooks             *WebhooksConfig   `json:"webhooks"`
 }
 
 var (
@@ -45,22 +95,273 @@ var (
  */
 func parseConfig(cfg string) {
 	if !file.IsExist(cfg) {
-		log.Fatalln("config file:", cfg, "is not existent. maybe you need `mv cfg.example.json cfg.json`")
+		l.Fatalln("config file:", cfg, "is not existent. maybe you need `mv cfg.example.json cfg.json`")
 	}
+
+	configGlobal, err := loadConfig(cfg)
+	if err != nil {
+		l.Fatalln("parse config file:", cfg, "fail:", err)
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	configOpenFalcon = configGlobal
+}
+
+func loadConfig(cfg string) (*GlobalConfig, error) {
 	configContent, err := file.ToTrimString(cfg)
 	if err != nil {
-		log.Fatalln("read config file:", cfg, "fail:", err)
+		return nil, err
 	}
 
 	var configGlobal GlobalConfig
-	err = json.Unmarshal([]byte(configContent), &configGlobal)
-	if err != nil {
-		log.Fatalln("parse config file:", cfg, "fail:", err)
+	if err := json.Unmarshal([]byte(configContent), &configGlobal); err != nil {
+		return nil, err
+	}
+
+	if err := validateOpenFalconConfig(configGlobal.OpenFalcon); err != nil {
+		return nil, err
+	}
+
+	if _, ok := middleware.NormalizeSameSite(configGlobal.CookieSameSite); !ok {
+		return nil, fmt.Errorf("cookieSameSite must be one of Lax, Strict or None, got %q", configGlobal.CookieSameSite)
+	}
+
+	if strings.ContainsAny(configGlobal.CookieName, " \t\r\n;,=\"") {
+		return nil, fmt.Errorf("cookieName can't contain spaces, quotes or any of ;,=, got %q", configGlobal.CookieName)
+	}
+
+	if err := validateCorsConfig(configGlobal.Cors); err != nil {
+		return nil, err
+	}
+
+	if err := validateWebhooksConfig(configGlobal.Webhooks); err != nil {
+		return nil, err
+	}
+
+	if err := validateDatabaseConfig(configGlobal.Db, configGlobal.Replicas); err != nil {
+		return nil, err
+	}
+
+	if _, err := util.ParseCIDRs(configGlobal.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("trustedProxies is invalid: %v", err)
+	}
+
+	configGlobal.RootPath = normalizeRootPath(configGlobal.RootPath)
+
+	return &configGlobal, nil
+}
+
+func validateOpenFalconConfig(cfg *OpenFalconConfig) error {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	addrs := map[string]string{"queryAddr": cfg.QueryAddr, "alarmAddr": cfg.AlarmAddr}
+	for name, addr := range addrs {
+		u, err := url.Parse(addr)
+		if err != nil || !u.IsAbs() || u.Host == "" {
+			return fmt.Errorf("openfalcon.%s must be an absolute url, got %q", name, addr)
+		}
+	}
+
+	return nil
+}
+
+func validateCorsConfig(cfg *CorsConfig) error {
+	if cfg == nil || !cfg.AllowCredentials {
+		return nil
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			return fmt.Errorf("cors.allowCredentials can't be used with the * origin, list the allowed origins instead")
+		}
+	}
+
+	return nil
+}
+
+func validateWebhooksConfig(cfg *WebhooksConfig) error {
+	if cfg == nil || cfg.OrgEvents == "" {
+		return nil
+	}
+
+	u, err := url.Parse(cfg.OrgEvents)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("webhooks.orgEvents must be an absolute url, got %q", cfg.OrgEvents)
+	}
+
+	return nil
+}
+
+// validateDatabaseConfig checks the database types and DSNs, the open-falcon
+// database defaults to mysql and replicas to the type of the primary. The
+// DSN of a replica without a type can only be checked when connecting.
+func validateDatabaseConfig(db *DatabaseConfig, replicas []DatabaseConfig) error {
+	if db != nil {
+		if db.Type == "" {
+			db.Type = migrator.MYSQL
+		}
+		if !isDatabaseType(db.Type) {
+			return fmt.Errorf("db.type must be one of mysql, postgres or sqlite3, got %q", db.Type)
+		}
+		if err := validateDSN(db.Type, db.DSN); err != nil {
+			return fmt.Errorf("db.dsn is invalid: %v", err)
+		}
+	}
+
+	for i, replica := range replicas {
+		if replica.Type == "" {
+			continue
+		}
+		if !isDatabaseType(replica.Type) {
+			return fmt.Errorf("replicas[%d].type must be one of mysql, postgres or sqlite3, got %q", i, replica.Type)
+		}
+		if err := validateDSN(replica.Type, replica.DSN); err != nil {
+			return fmt.Errorf("replicas[%d].dsn is invalid: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateDSN checks the shape of a DSN, the drivers only parse them when
+// connecting.
+func validateDSN(dbType, dsn string) error {
+	if dsn == "" {
+		return nil
+	}
+
+	switch dbType {
+	case migrator.MYSQL:
+		// [user[:password]@][net[(addr)]]/dbname[?param=value]
+		slash := strings.LastIndex(dsn, "/")
+		if slash < 0 {
+			return errors.New("missing /dbname")
+		}
+		if query := strings.Index(dsn[slash:], "?"); query >= 0 {
+			if _, err := url.ParseQuery(dsn[slash+query+1:]); err != nil {
+				return err
+			}
+		}
+	case migrator.POSTGRES:
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+			_, err := pq.ParseURL(dsn)
+			return err
+		}
+		for _, field := range strings.Fields(dsn) {
+			if !strings.Contains(field, "=") {
+				return fmt.Errorf("%q is not a key=value setting", field)
+			}
+		}
+	case migrator.SQLITE:
+		if query := strings.Index(dsn, "?"); query >= 0 {
+			if _, err := url.ParseQuery(dsn[query+1:]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func isDatabaseType(dbType string) bool {
+	switch dbType {
+	case migrator.MYSQL, migrator.POSTGRES, migrator.SQLITE:
+		return true
+	}
+	return false
+}
+
+// normalizeRootPath turns "grafana/" into "/grafana", the root itself is "".
+func normalizeRootPath(root string) string {
+	root = strings.Trim(strings.TrimSpace(root), "/")
+	if root == "" {
+		return ""
+	}
+	return "/" + root
+}
+
+// homepageUrl returns the configured home, relative ones are served under
+// the root path.
+func homepageUrl(cfg *GlobalConfig) string {
+	if cfg.Home == "" {
+		return setting.AppPath("/")
+	}
+	if strings.HasPrefix(cfg.Home, "/") {
+		return setting.AppPath(cfg.Home)
+	}
+	return cfg.Home
+}
+
+func corsOptions() *middleware.CORSOptions {
+	cfg := GetGlobalConfig()
+	if cfg == nil || cfg.Cors == nil {
+		return &middleware.CORSOptions{}
+	}
+
+	return &middleware.CORSOptions{
+		AllowedOrigins:   cfg.Cors.AllowedOrigins,
+		AllowedMethods:   cfg.Cors.AllowedMethods,
+		AllowedHeaders:   cfg.Cors.AllowedHeaders,
+		AllowCredentials: cfg.Cors.AllowCredentials,
+	}
+}
+
+func cookieOptions() *middleware.CookieOptions {
+	cfg := GetGlobalConfig()
+	if cfg == nil {
+		return &middleware.CookieOptions{}
+	}
+
+	return &middleware.CookieOptions{
+		Secure:   cfg.CookieSecure,
+		SameSite: cfg.CookieSameSite,
+		Name:     cfg.CookieName,
+		Domain:   cfg.CookieDomain,
+	}
+}
+
+// initTrustedProxies replaces the default of trusting proxies on this host
+// when the config lists them.
+func initTrustedProxies(cfg *GlobalConfig) {
+	if cfg == nil || cfg.TrustedProxies == nil {
 		return
 	}
-	lock.Lock()
-	defer lock.Unlock()
-	configOpenFalcon = &configGlobal
+
+	// validated when the config was loaded
+	nets, _ := util.ParseCIDRs(cfg.TrustedProxies)
+	middleware.SetTrustedProxies(nets)
+}
+
+func initWebhooks(cfg *GlobalConfig) {
+	if cfg == nil || cfg.Webhooks == nil {
+		return
+	}
+
+	webhooks.Init(cfg.Webhooks.OrgEvents, cfg.Webhooks.Secret)
+}
+
+// initReadReplicas connects the configured read replicas, a replica that
+// can't be reached is skipped so reads fall back to the primary.
+func initReadReplicas(cfg *GlobalConfig) {
+	if cfg == nil {
+		return
+	}
+
+	for _, replica := range cfg.Replicas {
+		if err := sqlstore.AddReadReplica(replica.Type, replica.ConnStr(), replica.Idle, replica.Max); err != nil {
+			log.Error(3, "Failed to connect to read replica, reads use the primary: %v", err)
+		}
+	}
+}
+
+// GetGlobalConfig returns the config parsed from the global config file.
+func GetGlobalConfig() *GlobalConfig {
+	lock.RLock()
+	defer lock.RUnlock()
+	return configOpenFalcon
 }
 
 /**
@@ -75,8 +376,8 @@ func parseConfig(cfg string) {
  * @called by:       func Register(r *macaron.Macaron)
  */
 func GetHomepageUrl(w http.ResponseWriter) {
-	url := configOpenFalcon.Home
-	log.Println("url =", url)
+	url := homepageUrl(GetGlobalConfig())
+	l.Println("url =", url)
 	resp := []string {
 		url,
 	}
@@ -89,17 +390,34 @@ func GetHomepageUrl(w http.ResponseWriter) {
 	w.Write(bs)
 }
 
-// Register adds http routes
-func Register(r *macaron.Macaron) {
+// LoadConfig reads the global config file. It has to run before the static
+// files are mapped, they are served under its root path.
+func LoadConfig() {
 	flag.Parse()
 	parseConfig(*OpenFalconConfigFile)
+	setting.RootPath = GetGlobalConfig().RootPath
+}
+
+// Register adds http routes
+func Register(mac *macaron.Macaron) {
+	r := newRouteRegister(mac, setting.RootPath)
+	initReadReplicas(GetGlobalConfig())
+	initWebhooks(GetGlobalConfig())
+	middleware.SetCookieOptions(cookieOptions())
+	initTrustedProxies(GetGlobalConfig())
 
 	reqSignedIn := middleware.Auth(&middleware.AuthOptions{ReqSignedIn: true})
 	reqGrafanaAdmin := middleware.Auth(&middleware.AuthOptions{ReqSignedIn: true, ReqGrafanaAdmin: true})
-	reqEditorRole := middleware.RoleAuth(m.ROLE_EDITOR, m.ROLE_ADMIN)
-	regOrgAdmin := middleware.RoleAuth(m.ROLE_ADMIN)
+	reqEditorRole := middleware.RoleAuth(middleware.EditorRoles...)
+	regOrgAdmin := middleware.RoleAuth(middleware.OrgAdminRoles...)
 	quota := middleware.Quota
-	bind := binding.Bind
+	bind := func(obj interface{}) macaron.Handler {
+		return middleware.LimitBody(0, binding.Bind(obj))
+	}
+	bindDashboard := func(obj interface{}) macaron.Handler {
+		return middleware.LimitBody(setting.MaxDashboardBodyBytes, binding.Bind(obj))
+	}
+	cors := middleware.CORS(corsOptions())
 
 	// not logged in views
 	r.Get("/", reqSignedIn, Index)
@@ -110,6 +428,11 @@ func Register(r *macaron.Macaron) {
 	r.Get("/invite/:code", Index)
 	r.Get("/home", GetHomepageUrl)
 
+	// prometheus metrics, served here unless bound to a separate address
+	if setting.MetricsEndpointEnabled && setting.MetricsListenAddr == "" {
+		r.Get("/metrics", GetPrometheusMetrics)
+	}
+
 	// authed views
 	r.Get("/profile/", reqSignedIn, Index)
 	r.Get("/org/", reqSignedIn, Index)
@@ -146,8 +469,15 @@ func Register(r *macaron.Macaron) {
 	r.Post("/api/user/password/send-reset-email", bind(dtos.SendResetPasswordEmailForm{}), wrap(SendResetPasswordEmail))
 	r.Post("/api/user/password/reset", bind(dtos.ResetUserPasswordForm{}), wrap(ResetPassword))
 
+	// verify email
+	r.Get("/user/email/verify", Index)
+
+	r.Post("/api/user/email/send-verify", bind(dtos.SendVerifyEmailForm{}), wrap(SendVerifyEmail))
+	r.Post("/api/user/email/verify", bind(dtos.VerifyEmailForm{}), wrap(VerifyEmail))
+
 	// dashboard snapshots
-	r.Post("/api/snapshots/", bind(m.CreateDashboardSnapshotCommand{}), CreateDashboardSnapshot)
+	r.Post("/api/snapshots/", bindDashboard(m.CreateDashboardSnapshotCommand{}), CreateDashboardSnapshot)
+	r.Get("/api/snapshots", reqSignedIn, wrap(SearchDashboardSnapshots))
 	r.Get("/dashboard/snapshot/*", Index)
 
 	r.Get("/api/snapshots/:key", GetDashboardSnapshot)
@@ -155,6 +485,10 @@ func Register(r *macaron.Macaron) {
 
 	// api renew session based on remember cookie
 	r.Get("/api/login/ping", quota("session"), LoginApiPing)
+	r.Get("/api/csrf-token", reqSignedIn, wrap(GetCSRFToken))
+
+	// cors preflight
+	r.Options("/api/*", cors)
 
 	// authed api
 	r.Group("/api", func() {
@@ -162,13 +496,19 @@ func Register(r *macaron.Macaron) {
 		// user (signed in)
 		r.Group("/user", func() {
 			r.Get("/", wrap(GetSignedInUser))
+			r.Get("/whoami", wrap(GetWhoAmI))
 			r.Put("/", bind(m.UpdateUserCommand{}), wrap(UpdateSignedInUser))
 			r.Post("/using/:id", wrap(UserSetUsingOrg))
 			r.Get("/orgs", wrap(GetSignedInUserOrgList))
 			r.Post("/stars/dashboard/:id", wrap(StarDashboard))
 			r.Delete("/stars/dashboard/:id", wrap(UnstarDashboard))
 			r.Put("/password", bind(m.ChangeUserPasswordCommand{}), wrap(ChangeUserPassword))
+			r.Post("/logout-all", wrap(LogoutAll))
+			r.Get("/sessions", wrap(GetUserSessions))
+			r.Delete("/sessions/:id", wrap(RevokeUserSession))
 			r.Get("/quotas", wrap(GetUserQuotas))
+			r.Get("/preferences", wrap(GetUserPreferences))
+			r.Put("/preferences", bind(dtos.UpdatePrefsCmd{}), wrap(UpdateUserPreferences))
 		})
 
 		// users (admin permission required)
@@ -192,8 +532,13 @@ func Register(r *macaron.Macaron) {
 			r.Post("/users", quota("user"), bind(m.AddOrgUserCommand{}), wrap(AddOrgUserToCurrentOrg))
 			r.Get("/users", wrap(GetOrgUsersForCurrentOrg))
 			r.Patch("/users/:userId", bind(m.UpdateOrgUserCommand{}), wrap(UpdateOrgUserForCurrentOrg))
+			r.Get("/users/:userId/history", wrap(GetOrgUserRoleHistoryForCurrentOrg))
 			r.Delete("/users/:userId", wrap(RemoveOrgUserForCurrentOrg))
 
+			// org defaults for users without their own preferences
+			r.Get("/preferences", wrap(GetOrgPreferences))
+			r.Put("/preferences", bind(dtos.UpdatePrefsCmd{}), wrap(UpdateOrgPreferences))
+
 			// invites
 			r.Get("/invites", wrap(GetPendingOrgInvites))
 			r.Post("/invites", quota("user"), bind(dtos.AddInviteForm{}), wrap(AddOrgInvite))
@@ -212,13 +557,24 @@ func Register(r *macaron.Macaron) {
 			r.Put("/", bind(dtos.UpdateOrgForm{}), wrap(UpdateOrg))
 			r.Put("/address", bind(dtos.UpdateOrgAddressForm{}), wrap(UpdateOrgAddress))
 			r.Delete("/", wrap(DeleteOrgById))
+			r.Post("/users/:userId/move", bind(m.MoveOrgUserCommand{}), wrap(MoveOrgUser))
+			r.Get("/quotas", wrap(GetOrgQuotas))
+			r.Put("/quotas/:target", bind(m.UpdateOrgQuotaCmd{}), wrap(UpdateOrgQuota))
+			r.Get("/stats", wrap(GetOrgStats))
+			r.Get("/dashboards/export", ExportOrgDashboards)
+			r.Post("/dashboards/import-zip", middleware.LimitBody(setting.MaxDashboardImportBytes, wrap(ImportOrgDashboardsZip)))
+			r.Get("/feature-flags", wrap(GetOrgFeatureFlags))
+			r.Put("/feature-flags/:name", bind(m.SetOrgFeatureFlagCommand{}), wrap(SetOrgFeatureFlag))
+		}, reqGrafanaAdmin)
+
+		// org users, also open to the delegated admins of the org
+		r.Group("/orgs/:orgId", func() {
 			r.Get("/users", wrap(GetOrgUsers))
 			r.Post("/users", bind(m.AddOrgUserCommand{}), wrap(AddOrgUser))
 			r.Patch("/users/:userId", bind(m.UpdateOrgUserCommand{}), wrap(UpdateOrgUser))
+			r.Get("/users/:userId/history", wrap(GetOrgUserRoleHistory))
 			r.Delete("/users/:userId", wrap(RemoveOrgUser))
-			r.Get("/quotas", wrap(GetOrgQuotas))
-			r.Put("/quotas/:target", bind(m.UpdateOrgQuotaCmd{}), wrap(UpdateOrgQuota))
-		}, reqGrafanaAdmin)
+		}, middleware.RequireManagedOrg())
 
 		// auth api keys
 		r.Group("/auth/keys", func() {
@@ -227,47 +583,97 @@ func Register(r *macaron.Macaron) {
 			r.Delete("/:id", wrap(DeleteApiKey))
 		}, regOrgAdmin)
 
+		// teams of the current org
+		r.Group("/teams", func() {
+			r.Get("/", wrap(GetTeams))
+			r.Post("/", bind(m.CreateTeamCommand{}), wrap(CreateTeam))
+			r.Get("/:teamId", wrap(GetTeamById))
+			r.Put("/:teamId", bind(m.UpdateTeamCommand{}), wrap(UpdateTeam))
+			r.Delete("/:teamId", wrap(DeleteTeam))
+			r.Get("/:teamId/members", wrap(GetTeamMembers))
+			r.Post("/:teamId/members", bind(m.AddTeamMemberCommand{}), wrap(AddTeamMember))
+			r.Delete("/:teamId/members/:userId", wrap(RemoveTeamMember))
+		}, regOrgAdmin)
+
 		// Data sources
 		r.Group("/datasources", func() {
 			r.Get("/", GetDataSources)
 			r.Post("/", quota("data_source"), bind(m.AddDataSourceCommand{}), AddDataSource)
+			r.Post("/import", bind([]m.AddDataSourceCommand{}), ImportDataSources)
 			r.Put("/:id", bind(m.UpdateDataSourceCommand{}), UpdateDataSource)
 			r.Delete("/:id", DeleteDataSource)
+			r.Post("/:id/restore", RestoreDataSource)
+			r.Get("/default", GetDefaultDataSource)
 			r.Get("/:id", GetDataSourceById)
 			r.Get("/plugins", GetDataSourcePlugins)
 		}, regOrgAdmin)
 
 		r.Get("/frontend/settings/", GetFrontendSettings)
+		r.Get("/datasources/proxy/:id/annotations", reqSignedIn, wrap(GetOpenFalconAnnotations))
 		r.Any("/datasources/proxy/:id/*", reqSignedIn, ProxyDataSourceRequest)
 		r.Any("/datasources/proxy/:id", reqSignedIn, ProxyDataSourceRequest)
 
 		// Dashboard
 		r.Group("/dashboards", func() {
 			r.Combo("/db/:slug").Get(GetDashboard).Delete(DeleteDashboard)
-			r.Post("/db", reqEditorRole, bind(m.SaveDashboardCommand{}), PostDashboard)
+			r.Get("/db/:slug/export", wrap(ExportDashboard))
+			r.Get("/db/:slug/acl", regOrgAdmin, wrap(GetDashboardAcl))
+			r.Post("/db/:slug/acl", regOrgAdmin, bind(m.SetDashboardAclCommand{}), wrap(SetDashboardAcl))
+			r.Delete("/db/:slug/acl/:aclId", regOrgAdmin, wrap(RemoveDashboardAcl))
+			r.Post("/db", reqEditorRole, bindDashboard(m.SaveDashboardCommand{}), PostDashboard)
+			r.Post("/import", reqEditorRole, bindDashboard(dtos.ImportDashboardCommand{}), ImportDashboard)
 			r.Get("/file/:file", GetDashboardFromJsonFile)
 			r.Get("/home", GetHomeDashboard)
 			r.Get("/tags", GetDashboardTags)
+			r.Delete("/tags/:tag", reqEditorRole, wrap(DeleteDashboardsByTag))
+			r.Get("/deleted", reqEditorRole, wrap(GetDeletedDashboards))
+			r.Post("/id/:dashboardId/restore", reqEditorRole, wrap(RestoreDashboard))
+		})
+
+		// annotations
+		r.Group("/annotations", func() {
+			r.Get("/", wrap(GetAnnotations))
+			r.Post("/", reqEditorRole, bind(m.CreateAnnotationCommand{}), wrap(CreateAnnotation))
+			r.Delete("/:annotationId", reqEditorRole, wrap(DeleteAnnotation))
 		})
 
 		// Search
 		r.Get("/search/", Search)
 
+		// changes to the org and its dashboards as server-sent events
+		r.Get("/events/stream", StreamEvents)
+
 		// metrics
 		r.Get("/metrics/test", GetTestMetrics)
 
-	}, reqSignedIn)
+		// openfalcon
+		r.Get("/openfalcon/counters", wrap(GetOpenFalconCounters))
+
+	}, cors, reqSignedIn)
 
 	// admin api
 	r.Group("/api/admin", func() {
 		r.Get("/settings", AdminGetSettings)
+		r.Get("/stats", wrap(AdminGetStats))
+		r.Get("/migrations", wrap(AdminGetMigrationStatus))
+		r.Post("/integrity/check", wrap(CheckIntegrity))
+		r.Get("/maintenance", wrap(GetMaintenance))
+		r.Put("/maintenance", bind(m.SetMaintenanceCommand{}), wrap(SetMaintenance))
 		r.Post("/users", bind(dtos.AdminCreateUserForm{}), AdminCreateUser)
 		r.Put("/users/:id/password", bind(dtos.AdminUpdateUserPasswordForm{}), AdminUpdateUserPassword)
 		r.Put("/users/:id/permissions", bind(dtos.AdminUpdateUserPermissionsForm{}), AdminUpdateUserPermissions)
 		r.Delete("/users/:id", AdminDeleteUser)
+		r.Post("/users/:id/disable", AdminDisableUser)
+		r.Post("/users/:id/enable", AdminEnableUser)
+		r.Get("/users/:id/managed-orgs", wrap(GetManagedOrgs))
+		r.Post("/users/:id/managed-orgs", bind(m.AddManagedOrgCommand{}), wrap(AddManagedOrg))
+		r.Delete("/users/:id/managed-orgs/:orgId", wrap(RemoveManagedOrg))
 		r.Get("/users/:id/quotas", wrap(GetUserQuotas))
 		r.Put("/users/:id/quotas/:target", bind(m.UpdateUserQuotaCmd{}), wrap(UpdateUserQuota))
-	}, reqGrafanaAdmin)
+	}, cors, reqGrafanaAdmin)
+
+	// health check, also served during maintenance
+	r.Get("/healthz", Healthz)
 
 	// rendering
 	r.Get("/render/*", reqSignedIn, RenderToPng)
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"strings"
+
 	"github.com/Cepave/grafana/pkg/api/dtos"
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/components/apikeygen"
 	"github.com/Cepave/grafana/pkg/middleware"
 	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/util"
 )
 
 func GetApiKeys(c *middleware.Context) Response {
@@ -18,9 +21,12 @@ func GetApiKeys(c *middleware.Context) Response {
 	result := make([]*m.ApiKeyDTO, len(query.Result))
 	for i, t := range query.Result {
 		result[i] = &m.ApiKeyDTO{
-			Id:   t.Id,
-			Name: t.Name,
-			Role: t.Role,
+			Id:       t.Id,
+			Name:     t.Name,
+			Role:     t.Role,
+			ReadOnly: t.ReadOnly,
+
+			AllowedIps: t.AllowedIpList(),
 		}
 	}
 
@@ -45,6 +51,17 @@ func AddApiKey(c *middleware.Context, cmd m.AddApiKeyCommand) Response {
 		return ApiError(400, "Invalid role specified", nil)
 	}
 
+	allowedIps := make([]string, 0, len(cmd.AllowedIps))
+	for _, ip := range cmd.AllowedIps {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			allowedIps = append(allowedIps, ip)
+		}
+	}
+	if _, err := util.ParseCIDRs(allowedIps); err != nil {
+		return ApiError(400, "Invalid allowed ips", err)
+	}
+	cmd.AllowedIps = allowedIps
+
 	cmd.OrgId = c.OrgId
 
 	newKeyInfo := apikeygen.New(cmd.OrgId, cmd.Name)
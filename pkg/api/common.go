@@ -8,6 +8,7 @@ import (
 	"github.com/Cepave/grafana/pkg/log"
 	"github.com/Cepave/grafana/pkg/metrics"
 	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/setting"
 )
 
@@ -16,14 +17,60 @@ var (
 	ServerError = ApiError(500, "Server error", nil)
 )
 
+// modelErrorStatus maps errors from the models package to a status code.
+// Handlers returning ApiError(500, ...) with one of these get the mapped
+// status and the error's own message instead.
+var modelErrorStatus = map[error]int{
+	m.ErrOrgNotFound:               404,
+	m.ErrOrgUserNotFound:           404,
+	m.ErrUserNotFound:              404,
+	m.ErrTempUserNotFound:          404,
+	m.ErrDashboardNotFound:         404,
+	m.ErrDashboardSnapshotNotFound: 404,
+	m.ErrDataSourceNotFound:        404,
+	m.ErrFeatureFlagNotFound:       404,
+	m.ErrDashboardAclNotFound:      404,
+	m.ErrTeamNotFound:              404,
+	m.ErrTeamMemberNotFound:        404,
+	m.ErrAnnotationNotFound:        404,
+
+	m.ErrOrgNameTaken:                409,
+	m.ErrOrgUserAlreadyAdded:         409,
+	m.ErrDashboardWithSameNameExists: 409,
+	m.ErrDashboardVersionMismatch:    409,
+	m.ErrDashboardProvisioned:        409,
+	m.ErrDashboardFolderNotEmpty:     409,
+	m.ErrDataSourceNameExists:        409,
+	m.ErrUserEmailTaken:              409,
+	m.ErrUserLoginTaken:              409,
+	m.ErrManagedOrgAlreadyAdded:      409,
+	m.ErrTeamNameTaken:               409,
+	m.ErrTeamMemberAlreadyAdded:      409,
+
+	m.ErrCommandValidationFailed: 400,
+	m.ErrInvalidRoleType:         400,
+	m.ErrDashboardAclInvalid:     400,
+	m.ErrDashboardFolderNotFound: 400,
+	m.ErrDashboardFolderCycle:    400,
+	m.ErrLastOrgAdmin:            400,
+	m.ErrLastGrafanaAdmin:        400,
+	m.ErrInvalidQuotaTarget:      400,
+	m.ErrInvalidEmailCode:        400,
+	m.ErrEmailDomainNotAllowed:   400,
+
+	m.ErrDashboardPermissionDenied: 403,
+}
+
 type Response interface {
 	WriteTo(out http.ResponseWriter)
 }
 
 type NormalResponse struct {
-	status int
-	body   []byte
-	header http.Header
+	status     int
+	body       []byte
+	header     http.Header
+	errMessage string
+	err        error
 }
 
 func wrap(action interface{}) macaron.Handler {
@@ -37,6 +84,14 @@ func wrap(action interface{}) macaron.Handler {
 			res = ServerError
 		}
 
+		if r, ok := res.(*NormalResponse); ok && r.err != nil {
+			if status, known := modelErrorStatus[r.err]; known && r.status == 500 {
+				res = ApiError(status, r.err.Error(), nil)
+			} else {
+				log.ErrorFields(4, log.Fields{"request_id": c.RequestId()}, "%s: %v", r.errMessage, r.err)
+			}
+		}
+
 		res.WriteTo(c.Resp)
 	}
 }
@@ -79,7 +134,6 @@ func ApiError(status int, message string, err error) *NormalResponse {
 	resp := make(map[string]interface{})
 
 	if err != nil {
-		log.Error(4, "%s: %v", message, err)
 		if setting.Env != setting.PROD {
 			resp["error"] = err.Error()
 		}
@@ -98,7 +152,10 @@ func ApiError(status int, message string, err error) *NormalResponse {
 		resp["message"] = message
 	}
 
-	return Json(status, resp)
+	result := Json(status, resp)
+	result.errMessage = message
+	result.err = err
+	return result
 }
 
 func Respond(status int, body interface{}) *NormalResponse {
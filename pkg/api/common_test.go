@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Unknwon/macaron"
+)
+
+func TestWrapModelErrors(t *testing.T) {
+
+	Convey("Given handlers failing with model errors", t, func() {
+		env := setting.Env
+		setting.Env = setting.PROD
+		defer func() { setting.Env = env }()
+
+		respond := func(res Response) (int, map[string]interface{}) {
+			mac := macaron.New()
+			mac.Use(func(c *macaron.Context) {
+				c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{}})
+			})
+			mac.Get("/", wrap(func() Response { return res }))
+
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			mac.ServeHTTP(resp, req)
+
+			body := map[string]interface{}{}
+			json.Unmarshal(resp.Body.Bytes(), &body)
+			return resp.Code, body
+		}
+
+		Convey("Should map each known error to its status", func() {
+			expected := map[error]int{
+				m.ErrOrgNotFound:                 404,
+				m.ErrOrgUserNotFound:             404,
+				m.ErrUserNotFound:                404,
+				m.ErrTempUserNotFound:            404,
+				m.ErrDashboardNotFound:           404,
+				m.ErrDashboardSnapshotNotFound:   404,
+				m.ErrDataSourceNotFound:          404,
+				m.ErrOrgNameTaken:                409,
+				m.ErrOrgUserAlreadyAdded:         409,
+				m.ErrDashboardWithSameNameExists: 409,
+				m.ErrDashboardVersionMismatch:    409,
+				m.ErrDataSourceNameExists:        409,
+				m.ErrCommandValidationFailed:     400,
+				m.ErrInvalidRoleType:             400,
+				m.ErrLastOrgAdmin:                400,
+				m.ErrInvalidQuotaTarget:          400,
+				m.ErrInvalidEmailCode:            400,
+				m.ErrEmailDomainNotAllowed:       400,
+			}
+
+			for err, status := range expected {
+				code, body := respond(ApiError(500, "Failed", err))
+				So(code, ShouldEqual, status)
+				So(body["message"], ShouldEqual, err.Error())
+			}
+		})
+
+		Convey("Should keep statuses chosen by the handler", func() {
+			code, _ := respond(ApiError(403, "Access denied", m.ErrOrgNotFound))
+			So(code, ShouldEqual, 403)
+		})
+
+		Convey("Should answer other errors with a generic 500", func() {
+			code, body := respond(ApiError(500, "", errors.New("dial tcp 10.0.0.1:3306: connection refused")))
+			So(code, ShouldEqual, 500)
+			So(body["message"], ShouldEqual, "Internal Server Error")
+			So(body["error"], ShouldBeNil)
+		})
+	})
+}
@@ -41,6 +41,16 @@ func GetDashboard(c *middleware.Context) {
 		return
 	}
 
+	permission, err := dashboardPermission(c, query.Result.Id)
+	if err != nil {
+		c.JsonApiErr(500, "Failed to check dashboard permissions", err)
+		return
+	}
+	if permission < m.PERMISSION_VIEW {
+		c.JsonApiErr(403, m.ErrDashboardPermissionDenied.Error(), nil)
+		return
+	}
+
 	isStarred, err := isDasboardStarredByUser(c, query.Result.Id)
 	if err != nil {
 		c.JsonApiErr(500, "Error while checking if dashboard was starred by user", err)
@@ -51,12 +61,15 @@ func GetDashboard(c *middleware.Context) {
 	dto := dtos.DashboardFullWithMeta{
 		Dashboard: dash.Data,
 		Meta: dtos.DashboardMeta{
-			IsStarred: isStarred,
-			Slug:      slug,
-			Type:      m.DashTypeDB,
-			CanStar:   c.IsSignedIn,
-			CanSave:   c.OrgRole == m.ROLE_ADMIN || c.OrgRole == m.ROLE_EDITOR,
-			CanEdit:   canEditDashboard(c.OrgRole),
+			IsStarred:     isStarred,
+			IsProvisioned: dash.Provisioned,
+			IsFolder:      dash.IsFolder,
+			FolderId:      dash.ParentId,
+			Slug:          slug,
+			Type:          m.DashTypeDB,
+			CanStar:       c.IsSignedIn,
+			CanSave:       !dash.Provisioned && permission >= m.PERMISSION_EDIT,
+			CanEdit:       canEditDashboard(c.OrgRole),
 		},
 	}
 
@@ -71,9 +84,35 @@ func DeleteDashboard(c *middleware.Context) {
 		c.JsonApiErr(404, "Dashboard not found", nil)
 		return
 	}
+	if query.Result.Provisioned {
+		c.JsonApiErr(409, m.ErrDashboardProvisioned.Error(), nil)
+		return
+	}
 
-	cmd := m.DeleteDashboardCommand{Slug: slug, OrgId: c.OrgId}
+	if permission, err := dashboardPermission(c, query.Result.Id); err != nil {
+		c.JsonApiErr(500, "Failed to check dashboard permissions", err)
+		return
+	} else if permission < m.PERMISSION_EDIT {
+		c.JsonApiErr(403, m.ErrDashboardPermissionDenied.Error(), nil)
+		return
+	}
+
+	cmd := m.DeleteDashboardCommand{
+		Slug:    slug,
+		OrgId:   c.OrgId,
+		Cascade: c.Query("cascade") == "true",
+		UserId:  c.UserId,
+		OrgRole: c.OrgRole,
+	}
 	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrDashboardFolderNotEmpty || err == m.ErrDashboardProvisioned {
+			c.JsonApiErr(409, err.Error(), nil)
+			return
+		}
+		if err == m.ErrDashboardPermissionDenied {
+			c.JsonApiErr(403, err.Error(), nil)
+			return
+		}
 		c.JsonApiErr(500, "Failed to delete dashboard", err)
 		return
 	}
@@ -83,10 +122,85 @@ func DeleteDashboard(c *middleware.Context) {
 	c.JSON(200, resp)
 }
 
+// GET /api/dashboards/deleted
+func GetDeletedDashboards(c *middleware.Context) Response {
+	query := m.GetDeletedDashboardsQuery{OrgId: c.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get deleted dashboards", err)
+	}
+
+	permissions := m.GetDashboardPermissionsQuery{OrgId: c.OrgId, UserId: c.UserId, OrgRole: c.OrgRole}
+	for _, dash := range query.Result {
+		permissions.DashboardIds = append(permissions.DashboardIds, dash.Id)
+	}
+	if err := bus.Dispatch(&permissions); err != nil {
+		return ApiError(500, "Failed to check dashboard permissions", err)
+	}
+
+	visible := make([]*m.DeletedDashboardDTO, 0, len(query.Result))
+	for _, dash := range query.Result {
+		if permissions.Result[dash.Id] >= m.PERMISSION_VIEW {
+			visible = append(visible, dash)
+		}
+	}
+
+	return Json(200, visible)
+}
+
+// POST /api/dashboards/id/:dashboardId/restore
+func RestoreDashboard(c *middleware.Context) Response {
+	cmd := m.RestoreDashboardCommand{
+		Id:      c.ParamsInt64(":dashboardId"),
+		OrgId:   c.OrgId,
+		UserId:  c.UserId,
+		OrgRole: c.OrgRole,
+	}
+
+	if permission, err := dashboardPermission(c, cmd.Id); err != nil {
+		return ApiError(500, "Failed to check dashboard permissions", err)
+	} else if permission < m.PERMISSION_EDIT {
+		return ApiError(403, m.ErrDashboardPermissionDenied.Error(), nil)
+	}
+
+	if err := bus.Dispatch(&cmd); err == m.ErrDashboardNotFound {
+		return ApiError(404, "Deleted dashboard not found", nil)
+	} else if err == m.ErrDashboardPermissionDenied {
+		return ApiError(403, err.Error(), nil)
+	} else if err != nil {
+		return ApiError(500, "Failed to restore dashboard", err)
+	}
+
+	return ApiSuccess("Dashboard restored")
+}
+
+// DELETE /api/dashboards/tags/:tag
+func DeleteDashboardsByTag(c *middleware.Context) Response {
+	cmd := m.DeleteDashboardsByTagCommand{
+		OrgId:   c.OrgId,
+		Tag:     c.Params(":tag"),
+		UserId:  c.UserId,
+		OrgRole: c.OrgRole,
+	}
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to delete dashboards", err)
+	}
+
+	return Json(200, util.DynMap{"message": "Dashboards deleted", "deleted": cmd.Result})
+}
+
 func PostDashboard(c *middleware.Context, cmd m.SaveDashboardCommand) {
 	cmd.OrgId = c.OrgId
 
 	dash := cmd.GetDashboardModel()
+
+	if canSave, err := canSaveDashboard(c, dash, cmd.Overwrite); err != nil {
+		c.JsonApiErr(500, "Failed to check dashboard permissions", err)
+		return
+	} else if !canSave {
+		c.JsonApiErr(403, m.ErrDashboardPermissionDenied.Error(), nil)
+		return
+	}
+
 	if dash.Id == 0 {
 		limitReached, err := middleware.QuotaReached(c, "dashboard")
 		if err != nil {
@@ -109,6 +223,14 @@ func PostDashboard(c *middleware.Context, cmd m.SaveDashboardCommand) {
 			c.JSON(412, util.DynMap{"status": "version-mismatch", "message": err.Error()})
 			return
 		}
+		if err == m.ErrDashboardProvisioned {
+			c.JSON(409, util.DynMap{"status": "provisioned", "message": err.Error()})
+			return
+		}
+		if err == m.ErrDashboardFolderNotFound || err == m.ErrDashboardFolderCycle {
+			c.JSON(400, util.DynMap{"status": "invalid-folder", "message": err.Error()})
+			return
+		}
 		if err == m.ErrDashboardNotFound {
 			c.JSON(404, util.DynMap{"status": "not-found", "message": err.Error()})
 			return
@@ -122,11 +244,79 @@ func PostDashboard(c *middleware.Context, cmd m.SaveDashboardCommand) {
 	c.JSON(200, util.DynMap{"status": "success", "slug": cmd.Result.Slug, "version": cmd.Result.Version})
 }
 
+// canSaveDashboard checks edit permission on the dashboards a save replaces:
+// the one with its id and, with overwrite, the one with its title. Creating
+// a dashboard only needs an editor role.
+func canSaveDashboard(c *middleware.Context, dash *m.Dashboard, overwrite bool) (bool, error) {
+	var replaced []int64
+
+	if dash.Id != 0 {
+		query := m.GetDashboardQuery{Id: dash.Id, OrgId: c.OrgId}
+		if err := bus.Dispatch(&query); err == nil {
+			replaced = append(replaced, query.Result.Id)
+		} else if err != m.ErrDashboardNotFound {
+			return false, err
+		}
+	}
+
+	if overwrite {
+		query := m.GetDashboardQuery{Slug: dash.Slug, OrgId: c.OrgId}
+		if err := bus.Dispatch(&query); err == nil && query.Result.Id != dash.Id {
+			replaced = append(replaced, query.Result.Id)
+		} else if err != nil && err != m.ErrDashboardNotFound {
+			return false, err
+		}
+	}
+
+	if len(replaced) == 0 {
+		return m.RolePermission(c.OrgRole) >= m.PERMISSION_EDIT, nil
+	}
+
+	for _, id := range replaced {
+		permission, err := dashboardPermission(c, id)
+		if err != nil {
+			return false, err
+		}
+		if permission < m.PERMISSION_EDIT {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 func canEditDashboard(role m.RoleType) bool {
 	return role == m.ROLE_ADMIN || role == m.ROLE_EDITOR || role == m.ROLE_READ_ONLY_EDITOR
 }
 
 func GetHomeDashboard(c *middleware.Context) {
+	prefsQuery := m.GetPreferencesWithDefaultsQuery{OrgId: c.OrgId, UserId: c.UserId}
+	if err := bus.Dispatch(&prefsQuery); err != nil {
+		c.JsonApiErr(500, "Failed to get preferences", err)
+		return
+	}
+
+	if prefsQuery.Result.HomeDashboardId != 0 {
+		query := m.GetDashboardQuery{Id: prefsQuery.Result.HomeDashboardId, OrgId: c.OrgId}
+		err := bus.Dispatch(&query)
+		if err == nil {
+			dash := dtos.DashboardFullWithMeta{Dashboard: query.Result.Data}
+			dash.Meta.IsHome = true
+			dash.Meta.Slug = query.Result.Slug
+			dash.Meta.Type = m.DashTypeDB
+			dash.Meta.IsProvisioned = query.Result.Provisioned
+			dash.Meta.CanSave = !query.Result.Provisioned && (c.OrgRole == m.ROLE_ADMIN || c.OrgRole == m.ROLE_EDITOR)
+			dash.Meta.CanEdit = canEditDashboard(c.OrgRole)
+			dash.Meta.CanStar = c.IsSignedIn
+			c.JSON(200, &dash)
+			return
+		}
+		// a deleted home dashboard falls back to the global one
+		if err != m.ErrDashboardNotFound {
+			c.JsonApiErr(500, "Failed to load home dashboard", err)
+			return
+		}
+	}
+
 	filePath := path.Join(setting.StaticRootPath, "dashboards/home.json")
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// dashboardPermission resolves what the current user may do with the
+// dashboard, dashboards without an acl fall back to the org role. The teams
+// of the user are only looked up for acls with team items.
+func dashboardPermission(c *middleware.Context, dashboardId int64) (m.PermissionType, error) {
+	query := m.GetDashboardAclQuery{OrgId: c.OrgId, DashboardId: dashboardId}
+	if err := bus.Dispatch(&query); err != nil {
+		return m.PERMISSION_NONE, err
+	}
+
+	var teamIds []int64
+	if m.HasTeamItems(query.Result) {
+		teams := m.GetUserTeamsQuery{OrgId: c.OrgId, UserId: c.UserId}
+		if err := bus.Dispatch(&teams); err != nil {
+			return m.PERMISSION_NONE, err
+		}
+		for _, team := range teams.Result {
+			teamIds = append(teamIds, team.Id)
+		}
+	}
+
+	return m.GetDashboardPermission(query.Result, c.UserId, teamIds, c.OrgRole), nil
+}
+
+func getDashboardBySlug(c *middleware.Context) (*m.Dashboard, Response) {
+	query := m.GetDashboardQuery{Slug: strings.ToLower(c.Params(":slug")), OrgId: c.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		return nil, ApiError(404, "Dashboard not found", nil)
+	}
+	return query.Result, nil
+}
+
+// GET /api/dashboards/db/:slug/acl
+func GetDashboardAcl(c *middleware.Context) Response {
+	dash, rsp := getDashboardBySlug(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	query := m.GetDashboardAclQuery{OrgId: c.OrgId, DashboardId: dash.Id}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get dashboard permissions", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// POST /api/dashboards/db/:slug/acl
+func SetDashboardAcl(c *middleware.Context, cmd m.SetDashboardAclCommand) Response {
+	dash, rsp := getDashboardBySlug(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	cmd.OrgId = c.OrgId
+	cmd.DashboardId = dash.Id
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to set dashboard permission", err)
+	}
+
+	return Json(200, cmd.Result)
+}
+
+// DELETE /api/dashboards/db/:slug/acl/:aclId
+func RemoveDashboardAcl(c *middleware.Context) Response {
+	dash, rsp := getDashboardBySlug(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	cmd := m.RemoveDashboardAclCommand{OrgId: c.OrgId, DashboardId: dash.Id, AclId: c.ParamsInt64(":aclId")}
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to remove dashboard permission", err)
+	}
+
+	return ApiSuccess("Dashboard permission removed")
+}
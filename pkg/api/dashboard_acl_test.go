@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDashboardAcl(t *testing.T) {
+
+	Convey("Given a viewer granted edit on one dashboard", t, func() {
+		defer bus.ClearBusHandlers()
+
+		// "granted" has an acl letting user 5 edit, "locked" one that only
+		// lets editors view and "open" has none
+		dashboards := map[string]int64{"granted": 1, "locked": 2, "open": 3}
+		acls := map[int64][]*m.DashboardAcl{
+			1: {{DashboardId: 1, UserId: 5, Permission: m.PERMISSION_EDIT}},
+			2: {{DashboardId: 2, Role: m.ROLE_EDITOR, Permission: m.PERMISSION_VIEW}},
+		}
+
+		bus.AddHandler("test", func(query *m.GetDashboardQuery) error {
+			for slug, id := range dashboards {
+				if query.Slug == slug || query.Id == id {
+					query.Result = m.NewDashboard(slug)
+					query.Result.Id = id
+					return nil
+				}
+			}
+			return m.ErrDashboardNotFound
+		})
+		bus.AddHandler("test", func(query *m.GetDashboardAclQuery) error {
+			query.Result = acls[query.DashboardId]
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.IsStarredByUserQuery) error {
+			return nil
+		})
+
+		var saved *m.SaveDashboardCommand
+		bus.AddHandler("test", func(cmd *m.SaveDashboardCommand) error {
+			saved = cmd
+			cmd.Result = cmd.GetDashboardModel()
+			return nil
+		})
+		bus.AddHandler("test", func(cmd *m.DeleteDashboardCommand) error {
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDashboardPermissionsQuery) error {
+			query.Result = make(map[int64]m.PermissionType)
+			for _, id := range query.DashboardIds {
+				query.Result[id] = m.GetDashboardPermission(acls[id], query.UserId, nil, query.OrgRole)
+			}
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDeletedDashboardsQuery) error {
+			for slug, id := range dashboards {
+				query.Result = append(query.Result, &m.DeletedDashboardDTO{Id: id, Slug: slug})
+			}
+			return nil
+		})
+
+		var restored *m.RestoreDashboardCommand
+		bus.AddHandler("test", func(cmd *m.RestoreDashboardCommand) error {
+			restored = cmd
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, UserId: 5, OrgRole: m.ROLE_VIEWER}, IsSignedIn: true})
+		})
+		mac.Get("/api/dashboards/db/:slug", GetDashboard)
+		mac.Delete("/api/dashboards/db/:slug", DeleteDashboard)
+		mac.Get("/api/dashboards/deleted", wrap(GetDeletedDashboards))
+		mac.Post("/api/dashboards/id/:dashboardId/restore", wrap(RestoreDashboard))
+
+		var body m.SaveDashboardCommand
+		mac.Post("/api/dashboards/db", func(c *middleware.Context) {
+			PostDashboard(c, body)
+		})
+
+		serve := func(method, url string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, url, nil)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		save := func(dashboard map[string]interface{}) *httptest.ResponseRecorder {
+			saved = nil
+			body = m.SaveDashboardCommand{Dashboard: dashboard}
+			return serve("POST", "/api/dashboards/db")
+		}
+
+		Convey("Should be able to save the granted dashboard", func() {
+			resp := save(map[string]interface{}{"id": float64(1), "title": "granted"})
+
+			So(resp.Code, ShouldEqual, 200)
+			So(saved, ShouldNotBeNil)
+		})
+
+		Convey("Should be offered to save the granted dashboard", func() {
+			resp := serve("GET", "/api/dashboards/db/granted")
+			So(resp.Code, ShouldEqual, 200)
+
+			var dto dtos.DashboardFullWithMeta
+			So(json.Unmarshal(resp.Body.Bytes(), &dto), ShouldBeNil)
+			So(dto.Meta.CanSave, ShouldBeTrue)
+		})
+
+		Convey("Should be able to delete the granted dashboard", func() {
+			So(serve("DELETE", "/api/dashboards/db/granted").Code, ShouldEqual, 200)
+		})
+
+		Convey("Should only view dashboards without acl", func() {
+			resp := serve("GET", "/api/dashboards/db/open")
+			So(resp.Code, ShouldEqual, 200)
+
+			var dto dtos.DashboardFullWithMeta
+			So(json.Unmarshal(resp.Body.Bytes(), &dto), ShouldBeNil)
+			So(dto.Meta.CanSave, ShouldBeFalse)
+
+			So(save(map[string]interface{}{"id": float64(3), "title": "open"}).Code, ShouldEqual, 403)
+			So(saved, ShouldBeNil)
+			So(serve("DELETE", "/api/dashboards/db/open").Code, ShouldEqual, 403)
+		})
+
+		Convey("Should not see dashboards whose acl leaves it out", func() {
+			So(serve("GET", "/api/dashboards/db/locked").Code, ShouldEqual, 403)
+		})
+
+		Convey("Should not overwrite the locked dashboard by title", func() {
+			body = m.SaveDashboardCommand{Dashboard: map[string]interface{}{"title": "locked"}, Overwrite: true}
+			So(serve("POST", "/api/dashboards/db").Code, ShouldEqual, 403)
+		})
+
+		Convey("Should not overwrite the locked dashboard by renaming the granted one", func() {
+			body = m.SaveDashboardCommand{Dashboard: map[string]interface{}{"id": float64(1), "title": "locked"}, Overwrite: true}
+			saved = nil
+			So(serve("POST", "/api/dashboards/db").Code, ShouldEqual, 403)
+			So(saved, ShouldBeNil)
+		})
+
+		Convey("Should only list the deleted dashboards it may view", func() {
+			resp := serve("GET", "/api/dashboards/deleted")
+			So(resp.Code, ShouldEqual, 200)
+
+			var result []*m.DeletedDashboardDTO
+			So(json.Unmarshal(resp.Body.Bytes(), &result), ShouldBeNil)
+
+			slugs := map[string]bool{}
+			for _, dash := range result {
+				slugs[dash.Slug] = true
+			}
+			So(slugs, ShouldResemble, map[string]bool{"granted": true, "open": true})
+		})
+
+		Convey("Should only restore the dashboards it may edit", func() {
+			So(serve("POST", "/api/dashboards/id/3/restore").Code, ShouldEqual, 403)
+			So(restored, ShouldBeNil)
+
+			So(serve("POST", "/api/dashboards/id/1/restore").Code, ShouldEqual, 200)
+			So(restored.Id, ShouldEqual, 1)
+			So(restored.UserId, ShouldEqual, 5)
+		})
+
+		Convey("Should not create dashboards", func() {
+			So(save(map[string]interface{}{"title": "new one"}).Code, ShouldEqual, 403)
+			So(saved, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/plugins"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+var invalidInputNameChars = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+type dashboardExportInput struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	PluginId    string `json:"pluginId"`
+	PluginName  string `json:"pluginName"`
+}
+
+type dashboardExportRequire struct {
+	Type    string `json:"type"`
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type dashboardExporter struct {
+	orgId    int64
+	inputs   map[string]*dashboardExportInput
+	requires map[string]*dashboardExportRequire
+}
+
+func dataSourceInputName(dsName string) string {
+	return "DS_" + strings.Trim(invalidInputNameChars.ReplaceAllString(strings.ToUpper(dsName), "_"), "_")
+}
+
+func dataSourcePluginName(dsType string) string {
+	if plugin, ok := plugins.DataSources[dsType].(map[string]interface{}); ok {
+		if name, ok := plugin["name"].(string); ok {
+			return name
+		}
+	}
+	return dsType
+}
+
+// inputFor returns the placeholder for a data source name, or an empty
+// string if the org has no data source by that name.
+func (e *dashboardExporter) inputFor(dsName string) (string, error) {
+	inputName := dataSourceInputName(dsName)
+	if _, exists := e.inputs[inputName]; exists {
+		return "${" + inputName + "}", nil
+	}
+
+	query := m.GetDataSourceByNameQuery{Name: dsName, OrgId: e.orgId}
+	if err := bus.Dispatch(&query); err == m.ErrDataSourceNotFound {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	pluginName := dataSourcePluginName(query.Result.Type)
+	e.inputs[inputName] = &dashboardExportInput{
+		Name:       inputName,
+		Label:      dsName,
+		Type:       "datasource",
+		PluginId:   query.Result.Type,
+		PluginName: pluginName,
+	}
+	e.requires[query.Result.Type] = &dashboardExportRequire{
+		Type:    "datasource",
+		Id:      query.Result.Type,
+		Name:    pluginName,
+		Version: "1.0.0",
+	}
+
+	return "${" + inputName + "}", nil
+}
+
+// replaceDataSources swaps every datasource name referenced in the dashboard
+// json for an input placeholder.
+func (e *dashboardExporter) replaceDataSources(node interface{}) error {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if name, ok := child.(string); ok && key == "datasource" {
+				if isDataSourceInput(name) {
+					continue
+				}
+				input, err := e.inputFor(name)
+				if err != nil {
+					return err
+				}
+				if input != "" {
+					value[key] = input
+				}
+				continue
+			}
+			if err := e.replaceDataSources(child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			if err := e.replaceDataSources(child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func ExportDashboard(c *middleware.Context) Response {
+	query := m.GetDashboardQuery{Slug: strings.ToLower(c.Params(":slug")), OrgId: c.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(404, "Dashboard not found", nil)
+	}
+
+	if permission, err := dashboardPermission(c, query.Result.Id); err != nil {
+		return ApiError(500, "Failed to check dashboard permissions", err)
+	} else if permission < m.PERMISSION_VIEW {
+		return ApiError(403, m.ErrDashboardPermissionDenied.Error(), nil)
+	}
+
+	// work on a copy so the stored dashboard is left untouched
+	var dashboard map[string]interface{}
+	data, err := json.Marshal(query.Result.Data)
+	if err != nil {
+		return ApiError(500, "Failed to export dashboard", err)
+	}
+	if err := json.Unmarshal(data, &dashboard); err != nil {
+		return ApiError(500, "Failed to export dashboard", err)
+	}
+
+	exporter := &dashboardExporter{
+		orgId:    c.OrgId,
+		inputs:   make(map[string]*dashboardExportInput),
+		requires: make(map[string]*dashboardExportRequire),
+	}
+	if err := exporter.replaceDataSources(dashboard); err != nil {
+		return ApiError(500, "Failed to export dashboard", err)
+	}
+
+	inputNames := make([]string, 0, len(exporter.inputs))
+	for name := range exporter.inputs {
+		inputNames = append(inputNames, name)
+	}
+	sort.Strings(inputNames)
+
+	inputs := make([]*dashboardExportInput, 0, len(inputNames))
+	for _, name := range inputNames {
+		inputs = append(inputs, exporter.inputs[name])
+	}
+
+	requireIds := make([]string, 0, len(exporter.requires))
+	for id := range exporter.requires {
+		requireIds = append(requireIds, id)
+	}
+	sort.Strings(requireIds)
+
+	requires := []*dashboardExportRequire{{Type: "grafana", Id: "grafana", Name: "Grafana", Version: setting.BuildVersion}}
+	for _, id := range requireIds {
+		requires = append(requires, exporter.requires[id])
+	}
+
+	dashboard["id"] = nil
+	dashboard["__inputs"] = inputs
+	dashboard["__requires"] = requires
+
+	return Json(200, dashboard)
+}
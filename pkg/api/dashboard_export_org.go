@@ -0,0 +1,110 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/log"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+const orgExportManifestName = "manifest.json"
+
+type orgExportManifest struct {
+	OrgId      int64                     `json:"orgId"`
+	Exported   time.Time                 `json:"exported"`
+	Dashboards []*orgExportManifestEntry `json:"dashboards"`
+}
+
+type orgExportManifestEntry struct {
+	Id       int64  `json:"id"`
+	Title    string `json:"title"`
+	File     string `json:"file"`
+	IsFolder bool   `json:"isFolder,omitempty"`
+	FolderId int64  `json:"folderId,omitempty"`
+}
+
+// GET /api/orgs/:orgId/dashboards/export
+//
+// Streams a zip with one json file per dashboard, named by slug, and a
+// manifest of them. Dashboards are loaded one at a time so the archive is
+// never held in memory.
+func ExportOrgDashboards(c *middleware.Context) {
+	orgId := c.ParamsInt64(":orgId")
+
+	if err := bus.Dispatch(&m.GetOrgByIdQuery{Id: orgId}); err == m.ErrOrgNotFound {
+		c.JsonApiErr(404, "Organization not found", nil)
+		return
+	} else if err != nil {
+		c.JsonApiErr(500, "Failed to get organization", err)
+		return
+	}
+
+	refs := m.GetDashboardRefsQuery{OrgId: orgId}
+	if err := bus.Dispatch(&refs); err != nil {
+		c.JsonApiErr(500, "Failed to get dashboards", err)
+		return
+	}
+
+	c.Resp.Header().Set("Content-Type", "application/zip")
+	c.Resp.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="org-%d-dashboards.zip"`, orgId))
+	c.Resp.WriteHeader(200)
+
+	// the status is sent, failures can only cut the archive short
+	if err := writeOrgExport(zip.NewWriter(c.Resp), orgId, refs.Result); err != nil {
+		log.Error(3, "Failed to export dashboards of org %d: %v", orgId, err)
+	}
+}
+
+func writeOrgExport(archive *zip.Writer, orgId int64, refs []*m.DashboardRef) error {
+	manifest := orgExportManifest{
+		OrgId:      orgId,
+		Exported:   time.Now(),
+		Dashboards: make([]*orgExportManifestEntry, 0, len(refs)),
+	}
+
+	for _, ref := range refs {
+		query := m.GetDashboardQuery{Id: ref.Id, OrgId: orgId}
+		if err := bus.Dispatch(&query); err == m.ErrDashboardNotFound {
+			// deleted since it was listed
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		entry := &orgExportManifestEntry{
+			Id:       ref.Id,
+			Title:    ref.Title,
+			File:     ref.Slug + ".json",
+			IsFolder: ref.IsFolder,
+			FolderId: ref.ParentId,
+		}
+		if err := writeZipJson(archive, entry.File, query.Result.Data); err != nil {
+			return err
+		}
+		manifest.Dashboards = append(manifest.Dashboards, entry)
+	}
+
+	if err := writeZipJson(archive, orgExportManifestName, manifest); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeZipJson(archive *zip.Writer, name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	return err
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExportOrgDashboards(t *testing.T) {
+
+	Convey("Given an org with dashboards", t, func() {
+		defer bus.ClearBusHandlers()
+
+		dashboards := map[int64]*m.Dashboard{}
+		for id, title := range map[int64]string{1: "cpu", 2: "disk usage", 3: "memory"} {
+			dash := m.NewDashboard(title)
+			dash.Id = id
+			dash.Data["rows"] = []interface{}{}
+			dashboards[id] = dash
+		}
+
+		bus.AddHandler("test", func(query *m.GetOrgByIdQuery) error {
+			if query.Id != 1 {
+				return m.ErrOrgNotFound
+			}
+			query.Result = &m.Org{Id: 1}
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDashboardRefsQuery) error {
+			query.Result = []*m.DashboardRef{}
+			for _, id := range []int64{1, 2, 3, 4} {
+				query.Result = append(query.Result, &m.DashboardRef{Id: id, Slug: fmt.Sprintf("dash-%d", id)})
+			}
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDashboardQuery) error {
+			// 4 was deleted after the listing
+			dash, ok := dashboards[query.Id]
+			if !ok {
+				return m.ErrDashboardNotFound
+			}
+			query.Result = dash
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{IsGrafanaAdmin: true}, IsSignedIn: true})
+		})
+		mac.Get("/api/orgs/:orgId/dashboards/export", ExportOrgDashboards)
+
+		serve := func(url string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", url, nil)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should stream a zip of them", func() {
+			resp := serve("/api/orgs/1/dashboards/export")
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Header().Get("Content-Type"), ShouldEqual, "application/zip")
+
+			body := resp.Body.Bytes()
+			archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+			So(err, ShouldBeNil)
+
+			files := map[string][]byte{}
+			for _, file := range archive.File {
+				reader, err := file.Open()
+				So(err, ShouldBeNil)
+				data, err := ioutil.ReadAll(reader)
+				So(err, ShouldBeNil)
+				reader.Close()
+				files[file.Name] = data
+			}
+
+			So(len(files), ShouldEqual, 4)
+
+			var dash map[string]interface{}
+			So(json.Unmarshal(files["dash-2.json"], &dash), ShouldBeNil)
+			So(dash["title"], ShouldEqual, "disk usage")
+
+			var manifest orgExportManifest
+			So(json.Unmarshal(files[orgExportManifestName], &manifest), ShouldBeNil)
+			So(manifest.OrgId, ShouldEqual, 1)
+			So(len(manifest.Dashboards), ShouldEqual, 3)
+			So(manifest.Dashboards[1].File, ShouldEqual, "dash-2.json")
+		})
+
+		Convey("Should return 404 for an unknown org", func() {
+			So(serve("/api/orgs/2/dashboards/export").Code, ShouldEqual, 404)
+		})
+	})
+}
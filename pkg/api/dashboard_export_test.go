@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDashboardExport(t *testing.T) {
+
+	Convey("Given a saved dashboard", t, func() {
+		defer bus.ClearBusHandlers()
+
+		var stored map[string]interface{}
+		json.Unmarshal([]byte(`{
+			"id": 5,
+			"title": "Hosts",
+			"rows": [{"panels": [
+				{"datasource": "Falcon Prod"},
+				{"datasource": "Falcon Prod"},
+				{"datasource": "unknown"}
+			]}]
+		}`), &stored)
+
+		bus.AddHandler("test", func(query *m.GetDashboardQuery) error {
+			query.Result = &m.Dashboard{Id: 5, Slug: "hosts", Data: stored}
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDashboardAclQuery) error {
+			return nil
+		})
+
+		bus.AddHandler("test", func(query *m.GetDataSourceByNameQuery) error {
+			if query.Name != "Falcon Prod" {
+				return m.ErrDataSourceNotFound
+			}
+			query.Result = m.DataSource{Name: query.Name, Type: m.DS_OPENFALCON}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, OrgRole: m.ROLE_VIEWER}})
+		})
+		mac.Get("/api/dashboards/db/:slug/export", wrap(ExportDashboard))
+
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/dashboards/db/hosts/export", nil)
+		mac.ServeHTTP(resp, req)
+		So(resp.Code, ShouldEqual, 200)
+
+		var exported map[string]interface{}
+		So(json.Unmarshal(resp.Body.Bytes(), &exported), ShouldBeNil)
+
+		Convey("Should not contain the dashboard id", func() {
+			_, isNumber := exported["id"].(float64)
+			So(isNumber, ShouldBeFalse)
+			So(exported["id"], ShouldBeNil)
+		})
+
+		Convey("Should contain one input per data source", func() {
+			inputs := exported["__inputs"].([]interface{})
+			So(len(inputs), ShouldEqual, 1)
+
+			input := inputs[0].(map[string]interface{})
+			So(input["name"], ShouldEqual, "DS_FALCON_PROD")
+			So(input["label"], ShouldEqual, "Falcon Prod")
+			So(input["pluginId"], ShouldEqual, m.DS_OPENFALCON)
+			So(exported["__requires"], ShouldNotBeNil)
+		})
+
+		Convey("Should replace data source references with inputs", func() {
+			panels := exported["rows"].([]interface{})[0].(map[string]interface{})["panels"].([]interface{})
+			So(panels[0].(map[string]interface{})["datasource"], ShouldEqual, "${DS_FALCON_PROD}")
+			So(panels[1].(map[string]interface{})["datasource"], ShouldEqual, "${DS_FALCON_PROD}")
+			So(panels[2].(map[string]interface{})["datasource"], ShouldEqual, "unknown")
+		})
+
+		Convey("Should leave the stored dashboard untouched", func() {
+			So(stored["id"], ShouldEqual, 5)
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// isDataSourceInput reports whether name is an input placeholder like
+// ${DS_GRAPHITE} as used by dashboards exported for grafana.net.
+func isDataSourceInput(name string) bool {
+	return strings.HasPrefix(name, "${") && strings.HasSuffix(name, "}")
+}
+
+// remapDataSources walks the dashboard json and replaces every datasource
+// reference found in mapping. Placeholders without a mapping are an error.
+func remapDataSources(node interface{}, mapping map[string]string) error {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if name, ok := child.(string); ok && key == "datasource" {
+				if target, exists := mapping[name]; exists {
+					value[key] = target
+				} else if isDataSourceInput(name) {
+					return fmt.Errorf("No data source mapping for %s", name)
+				}
+				continue
+			}
+			if err := remapDataSources(child, mapping); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			if err := remapDataSources(child, mapping); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func ImportDashboard(c *middleware.Context, cmd dtos.ImportDashboardCommand) {
+	if title, ok := cmd.Dashboard["title"].(string); !ok || title == "" {
+		c.JsonApiErr(400, "Dashboard title is required", nil)
+		return
+	}
+
+	for source, target := range cmd.DataSources {
+		query := m.GetDataSourceByNameQuery{Name: target, OrgId: c.OrgId}
+		err := bus.Dispatch(&query)
+		if err == m.ErrDataSourceNotFound {
+			c.JsonApiErr(400, fmt.Sprintf("Data source %s mapped from %s not found", target, source), nil)
+			return
+		}
+		if err != nil {
+			c.JsonApiErr(500, "Failed to query datasources", err)
+			return
+		}
+	}
+
+	if err := remapDataSources(cmd.Dashboard, cmd.DataSources); err != nil {
+		c.JsonApiErr(400, err.Error(), nil)
+		return
+	}
+
+	// imported dashboards are always new to this org
+	delete(cmd.Dashboard, "__inputs")
+	delete(cmd.Dashboard, "__requires")
+	cmd.Dashboard["id"] = nil
+
+	PostDashboard(c, m.SaveDashboardCommand{Dashboard: cmd.Dashboard, Overwrite: cmd.Overwrite})
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+var (
+	errImportNotJson      = errors.New("Not a json file")
+	errImportFileTooLarge = errors.New("File too large")
+	errImportNoTitle      = errors.New("Dashboard has no title")
+)
+
+const (
+	orgImportImported = "imported"
+	orgImportError    = "error"
+	// valid, but left out as the import failed as a whole
+	orgImportSkipped = "skipped"
+)
+
+type orgImportResult struct {
+	Imported int                    `json:"imported"`
+	Files    []*orgImportFileResult `json:"files"`
+}
+
+type orgImportFileResult struct {
+	File        string `json:"file"`
+	Status      string `json:"status"`
+	Message     string `json:"message,omitempty"`
+	DashboardId int64  `json:"dashboardId,omitempty"`
+}
+
+type orgImportFile struct {
+	result    *orgImportFileResult
+	dashboard map[string]interface{}
+}
+
+// POST /api/orgs/:orgId/dashboards/import-zip
+//
+// Imports a zip made by the org export, saving its dashboards over the ones
+// with the same title. Nothing is saved unless every file can be.
+func ImportOrgDashboardsZip(c *middleware.Context) Response {
+	orgId := c.ParamsInt64(":orgId")
+	if err := bus.Dispatch(&m.GetOrgByIdQuery{Id: orgId}); err != nil {
+		return ApiError(500, "Failed to get organization", err)
+	}
+
+	body, err := ioutil.ReadAll(c.Req.Request.Body)
+	if err != nil {
+		return ApiError(400, "Failed to read request body", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return ApiError(400, "Request body is not a zip", err)
+	}
+
+	result := orgImportResult{Files: make([]*orgImportFileResult, 0, len(archive.File))}
+	files := make([]*orgImportFile, 0, len(archive.File))
+	var manifest orgExportManifest
+	valid := true
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		fileResult := &orgImportFileResult{File: file.Name, Status: orgImportSkipped}
+		var err error
+		if file.Name == orgExportManifestName {
+			err = readZipJson(file, &manifest)
+		} else {
+			var dashboard map[string]interface{}
+			if err = readZipJson(file, &dashboard); err == nil {
+				if title, ok := dashboard["title"].(string); !ok || title == "" {
+					err = errImportNoTitle
+				}
+			}
+			if err == nil {
+				// ids and versions belong to the org the zip was exported from
+				delete(dashboard, "id")
+				delete(dashboard, "version")
+				files = append(files, &orgImportFile{result: fileResult, dashboard: dashboard})
+				result.Files = append(result.Files, fileResult)
+				continue
+			}
+		}
+
+		if err != nil {
+			fileResult.Status = orgImportError
+			fileResult.Message = err.Error()
+			result.Files = append(result.Files, fileResult)
+			valid = false
+		}
+	}
+
+	if !valid {
+		return Json(400, result)
+	}
+
+	cmd := m.ImportDashboardsCommand{OrgId: orgId}
+	order := orgImportOrder(files, &manifest, &cmd)
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		status, known := modelErrorStatus[err]
+		if !known || cmd.FailedIndex < 0 {
+			return ApiError(500, "Failed to import dashboards", err)
+		}
+		failed := files[order[cmd.FailedIndex]].result
+		failed.Status = orgImportError
+		failed.Message = err.Error()
+		return Json(status, result)
+	}
+
+	for i, dash := range cmd.Result {
+		fileResult := files[order[i]].result
+		fileResult.Status = orgImportImported
+		fileResult.DashboardId = dash.Id
+	}
+	result.Imported = len(cmd.Result)
+
+	return Json(200, result)
+}
+
+// orgImportOrder adds the files to the command with folders ahead of their
+// content, as linked by the manifest, and returns the file of each item.
+// Dashboards whose folder isn't in the zip go to the root.
+func orgImportOrder(files []*orgImportFile, manifest *orgExportManifest, cmd *m.ImportDashboardsCommand) []int {
+	entries := make(map[string]*orgExportManifestEntry, len(manifest.Dashboards))
+	for _, entry := range manifest.Dashboards {
+		entries[entry.File] = entry
+	}
+
+	folders := make(map[int64]int)
+	for i, file := range files {
+		if entry := entries[file.result.File]; entry != nil && entry.IsFolder {
+			folders[entry.Id] = i
+		}
+	}
+
+	order := make([]int, 0, len(files))
+	placed := make(map[int]int, len(files))
+	add := func(i int, folderIndex int) {
+		entry := entries[files[i].result.File]
+		cmd.Items = append(cmd.Items, &m.ImportDashboardItem{
+			Dashboard:   files[i].dashboard,
+			IsFolder:    entry != nil && entry.IsFolder,
+			FolderIndex: folderIndex,
+		})
+		placed[i] = len(order)
+		order = append(order, i)
+	}
+
+	for len(order) < len(files) {
+		progress := false
+		for i, file := range files {
+			if _, done := placed[i]; done {
+				continue
+			}
+
+			folderIndex := -1
+			if entry := entries[file.result.File]; entry != nil && entry.FolderId != 0 {
+				if folder, inZip := folders[entry.FolderId]; inZip && folder != i {
+					item, done := placed[folder]
+					if !done {
+						continue
+					}
+					folderIndex = item
+				}
+			}
+
+			add(i, folderIndex)
+			progress = true
+		}
+
+		// folders holding each other, what is left goes to the root
+		if !progress {
+			for i := range files {
+				if _, done := placed[i]; !done {
+					add(i, -1)
+				}
+			}
+		}
+	}
+
+	return order
+}
+
+func readZipJson(file *zip.File, value interface{}) error {
+	if !strings.HasSuffix(strings.ToLower(file.Name), ".json") {
+		return errImportNotJson
+	}
+
+	max := setting.MaxDashboardBodyBytes
+	if file.UncompressedSize64 > uint64(max) {
+		return errImportFileTooLarge
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// the size in the header can't be trusted
+	data, err := ioutil.ReadAll(io.LimitReader(reader, max+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > max {
+		return errImportFileTooLarge
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("Invalid json: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestImportOrgDashboardsZip(t *testing.T) {
+
+	Convey("Given an org to import into", t, func() {
+		defer bus.ClearBusHandlers()
+
+		setting.MaxDashboardBodyBytes = 1024
+
+		bus.AddHandler("test", func(query *m.GetOrgByIdQuery) error {
+			query.Result = &m.Org{Id: query.Id}
+			return nil
+		})
+
+		var imported *m.ImportDashboardsCommand
+		bus.AddHandler("test", func(cmd *m.ImportDashboardsCommand) error {
+			imported = cmd
+			for i, item := range cmd.Items {
+				dash := m.NewDashboardFromJson(item.Dashboard)
+				dash.Id = int64(100 + i)
+				cmd.Result = append(cmd.Result, dash)
+			}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{IsGrafanaAdmin: true}, IsSignedIn: true})
+		})
+		mac.Post("/api/orgs/:orgId/dashboards/import-zip", wrap(ImportOrgDashboardsZip))
+
+		importZip := func(files map[string]string) (*httptest.ResponseRecorder, orgImportResult) {
+			var buf bytes.Buffer
+			archive := zip.NewWriter(&buf)
+			for name, content := range files {
+				file, err := archive.Create(name)
+				So(err, ShouldBeNil)
+				file.Write([]byte(content))
+			}
+			So(archive.Close(), ShouldBeNil)
+
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/orgs/1/dashboards/import-zip", &buf)
+			mac.ServeHTTP(resp, req)
+
+			var result orgImportResult
+			json.Unmarshal(resp.Body.Bytes(), &result)
+			return resp, result
+		}
+
+		Convey("Should import a clean export with its folders", func() {
+			resp, result := importZip(map[string]string{
+				"disk.json": `{"id": 7, "version": 3, "title": "Disk"}`,
+				"ops.json":  `{"id": 2, "title": "Ops"}`,
+				"cpu.json":  `{"id": 8, "title": "Cpu"}`,
+				orgExportManifestName: `{"orgId": 1, "dashboards": [
+					{"id": 7, "file": "disk.json", "folderId": 2},
+					{"id": 2, "file": "ops.json", "isFolder": true},
+					{"id": 8, "file": "cpu.json"}
+				]}`,
+			})
+
+			So(resp.Code, ShouldEqual, 200)
+			So(result.Imported, ShouldEqual, 3)
+			So(len(result.Files), ShouldEqual, 3)
+			for _, file := range result.Files {
+				So(file.Status, ShouldEqual, orgImportImported)
+				So(file.DashboardId, ShouldBeGreaterThan, 0)
+			}
+
+			So(len(imported.Items), ShouldEqual, 3)
+			folderIndex := -1
+			for i, item := range imported.Items {
+				So(item.Dashboard["id"], ShouldBeNil)
+				if item.IsFolder {
+					folderIndex = i
+				}
+			}
+			So(folderIndex, ShouldBeGreaterThanOrEqualTo, 0)
+			for _, item := range imported.Items {
+				if item.Dashboard["title"] == "Disk" {
+					So(item.FolderIndex, ShouldEqual, folderIndex)
+				} else {
+					So(item.FolderIndex, ShouldEqual, -1)
+				}
+			}
+		})
+
+		Convey("Should import nothing when a file is malformed", func() {
+			resp, result := importZip(map[string]string{
+				"good.json":   `{"title": "Good"}`,
+				"broken.json": `{"title": `,
+				"notes.txt":   `hello`,
+			})
+
+			So(resp.Code, ShouldEqual, 400)
+			So(imported, ShouldBeNil)
+
+			statuses := map[string]string{}
+			for _, file := range result.Files {
+				statuses[file.File] = file.Status
+			}
+			So(statuses["good.json"], ShouldEqual, orgImportSkipped)
+			So(statuses["broken.json"], ShouldEqual, orgImportError)
+			So(statuses["notes.txt"], ShouldEqual, orgImportError)
+		})
+
+		Convey("Should reject files over the size limit", func() {
+			big := make([]byte, 2048)
+			for i := range big {
+				big[i] = ' '
+			}
+			resp, result := importZip(map[string]string{"big.json": `{"title": "Big"}` + string(big)})
+
+			So(resp.Code, ShouldEqual, 400)
+			So(result.Files[0].Message, ShouldEqual, errImportFileTooLarge.Error())
+		})
+
+		Convey("Should reject a body that isn't a zip", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/orgs/1/dashboards/import-zip", bytes.NewBufferString("{}"))
+			mac.ServeHTTP(resp, req)
+			So(resp.Code, ShouldEqual, 400)
+		})
+	})
+}
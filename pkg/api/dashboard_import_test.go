@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
+	"github.com/macaron-contrib/binding"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDashboardImport(t *testing.T) {
+
+	Convey("Given a dashboard import api", t, func() {
+		defer bus.ClearBusHandlers()
+
+		bus.AddHandler("test", func(query *m.GetDataSourceByNameQuery) error {
+			if query.Name != "local graphite" {
+				return m.ErrDataSourceNotFound
+			}
+			query.Result = m.DataSource{Id: 1, OrgId: query.OrgId, Name: query.Name}
+			return nil
+		})
+
+		bus.AddHandler("test", func(query *m.GetDashboardQuery) error {
+			return m.ErrDashboardNotFound
+		})
+
+		var saved *m.SaveDashboardCommand
+		bus.AddHandler("test", func(cmd *m.SaveDashboardCommand) error {
+			saved = cmd
+			cmd.Result = cmd.GetDashboardModel()
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, OrgRole: m.ROLE_EDITOR}})
+		})
+		mac.Post("/api/dashboards/import", binding.Bind(dtos.ImportDashboardCommand{}), ImportDashboard)
+
+		dashboardJson := `{
+			"__inputs": [{"name": "DS_GRAPHITE", "type": "datasource", "pluginId": "graphite"}],
+			"id": 12,
+			"title": "Imported",
+			"rows": [{"panels": [
+				{"datasource": "${DS_GRAPHITE}", "targets": [{"target": "a.b"}]},
+				{"datasource": "already local"}
+			]}]
+		}`
+
+		post := func(mapping map[string]string) *httptest.ResponseRecorder {
+			var dashboard map[string]interface{}
+			json.Unmarshal([]byte(dashboardJson), &dashboard)
+			body, _ := json.Marshal(dtos.ImportDashboardCommand{Dashboard: dashboard, DataSources: mapping})
+
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/dashboards/import", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should remap data sources and save", func() {
+			resp := post(map[string]string{"${DS_GRAPHITE}": "local graphite"})
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Body.String(), ShouldContainSubstring, `"slug":"imported"`)
+
+			panels := saved.Dashboard["rows"].([]interface{})[0].(map[string]interface{})["panels"].([]interface{})
+			So(panels[0].(map[string]interface{})["datasource"], ShouldEqual, "local graphite")
+			So(panels[1].(map[string]interface{})["datasource"], ShouldEqual, "already local")
+			So(saved.Dashboard["id"], ShouldBeNil)
+			So(saved.Dashboard["__inputs"], ShouldBeNil)
+			So(saved.OrgId, ShouldEqual, 1)
+		})
+
+		Convey("Should reject mappings to unknown data sources", func() {
+			resp := post(map[string]string{"${DS_GRAPHITE}": "missing"})
+			So(resp.Code, ShouldEqual, 400)
+			So(saved, ShouldBeNil)
+		})
+
+		Convey("Should reject placeholders without a mapping", func() {
+			resp := post(nil)
+			So(resp.Code, ShouldEqual, 400)
+			So(resp.Body.String(), ShouldContainSubstring, "${DS_GRAPHITE}")
+			So(saved, ShouldBeNil)
+		})
+	})
+}
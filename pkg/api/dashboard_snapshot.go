@@ -14,6 +14,11 @@ import (
 
 func CreateDashboardSnapshot(c *middleware.Context, cmd m.CreateDashboardSnapshotCommand) {
 	if cmd.External {
+		if !setting.ExternalSnapshotEnabled {
+			c.JsonApiErr(403, "External dashboard snapshots are disabled", nil)
+			return
+		}
+
 		// external snapshot ref requires key and delete key
 		if cmd.Key == "" || cmd.DeleteKey == "" {
 			c.JsonApiErr(400, "Missing key and delete key for external snapshot", nil)
@@ -31,19 +36,52 @@ func CreateDashboardSnapshot(c *middleware.Context, cmd m.CreateDashboardSnapsho
 		metrics.M_Api_Dashboard_Snapshot_Create.Inc(1)
 	}
 
+	if cmd.Name == "" {
+		cmd.Name, _ = cmd.Dashboard["title"].(string)
+	}
+
+	cmd.IdempotencyKey = c.Req.Header.Get("Idempotency-Key")
+
 	if err := bus.Dispatch(&cmd); err != nil {
 		c.JsonApiErr(500, "Failed to create snaphost", err)
 		return
 	}
 
+	// a retried request gets the snapshot from the first attempt
+	snapshot := cmd.Result
 	c.JSON(200, util.DynMap{
-		"key":       cmd.Key,
-		"deleteKey": cmd.DeleteKey,
-		"url":       setting.ToAbsUrl("dashboard/snapshot/" + cmd.Key),
-		"deleteUrl": setting.ToAbsUrl("api/snapshots-delete/" + cmd.DeleteKey),
+		"key":       snapshot.Key,
+		"deleteKey": snapshot.DeleteKey,
+		"url":       setting.ToAbsUrl("dashboard/snapshot/" + snapshot.Key),
+		"deleteUrl": setting.ToAbsUrl("api/snapshots-delete/" + snapshot.DeleteKey),
 	})
 }
 
+// GET /api/snapshots
+func SearchDashboardSnapshots(c *middleware.Context) Response {
+	limit := c.QueryInt("limit")
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	page := c.QueryInt("page")
+	if page < 1 {
+		page = 1
+	}
+
+	query := m.GetDashboardSnapshotsQuery{OrgId: c.OrgId, UserId: c.UserId, Page: page - 1, Limit: limit}
+
+	// org admins see the snapshots of everyone in the org
+	if c.OrgRole == m.ROLE_ADMIN {
+		query.UserId = 0
+	}
+
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to list dashboard snapshots", err)
+	}
+
+	return Json(200, query.Result)
+}
+
 func GetDashboardSnapshot(c *middleware.Context) {
 
 	key := c.Params(":key")
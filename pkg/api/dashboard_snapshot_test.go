@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Unknwon/macaron"
+	"github.com/macaron-contrib/binding"
+)
+
+func snapshotScenario() func(form m.CreateDashboardSnapshotCommand, idempotencyKey string) (int, map[string]interface{}) {
+	mac := macaron.New()
+	mac.Use(macaron.Renderer())
+	mac.Use(func(c *macaron.Context) {
+		c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, UserId: 1}})
+	})
+	mac.Post("/api/snapshots/", binding.Bind(m.CreateDashboardSnapshotCommand{}), CreateDashboardSnapshot)
+
+	return func(form m.CreateDashboardSnapshotCommand, idempotencyKey string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(form)
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/snapshots/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+		mac.ServeHTTP(resp, req)
+
+		result := map[string]interface{}{}
+		json.Unmarshal(resp.Body.Bytes(), &result)
+		return resp.Code, result
+	}
+}
+
+func TestCreateDashboardSnapshot(t *testing.T) {
+
+	Convey("Given a snapshot store keyed by idempotency key", t, func() {
+		defer bus.ClearBusHandlers()
+
+		created := map[string]*m.DashboardSnapshot{}
+		bus.AddHandler("test", func(cmd *m.CreateDashboardSnapshotCommand) error {
+			if existing, ok := created[cmd.IdempotencyKey]; ok && cmd.IdempotencyKey != "" {
+				cmd.Result = existing
+				return nil
+			}
+			cmd.Result = &m.DashboardSnapshot{Key: cmd.Key, DeleteKey: cmd.DeleteKey}
+			created[cmd.IdempotencyKey] = cmd.Result
+			return nil
+		})
+
+		create := snapshotScenario()
+		form := m.CreateDashboardSnapshotCommand{Dashboard: map[string]interface{}{"title": "snap"}}
+
+		Convey("Should create a snapshot on the first request", func() {
+			code, first := create(form, "abc")
+			So(code, ShouldEqual, 200)
+			So(first["key"], ShouldNotBeEmpty)
+
+			Convey("And return the same snapshot on a retry", func() {
+				code, retry := create(form, "abc")
+				So(code, ShouldEqual, 200)
+				So(retry["key"], ShouldEqual, first["key"])
+				So(retry["url"], ShouldEqual, first["url"])
+			})
+		})
+	})
+}
+
+func TestCreateExternalDashboardSnapshot(t *testing.T) {
+
+	Convey("Given an external snapshot request", t, func() {
+		defer bus.ClearBusHandlers()
+
+		var stored *m.CreateDashboardSnapshotCommand
+		bus.AddHandler("test", func(cmd *m.CreateDashboardSnapshotCommand) error {
+			stored = cmd
+			cmd.Result = &m.DashboardSnapshot{Key: cmd.Key, DeleteKey: cmd.DeleteKey}
+			return nil
+		})
+
+		create := snapshotScenario()
+		form := m.CreateDashboardSnapshotCommand{
+			Dashboard: map[string]interface{}{"title": "snap"},
+			External:  true,
+			Key:       "external-key",
+			DeleteKey: "external-delete-key",
+		}
+
+		Convey("Should store it when external snapshots are enabled", func() {
+			setting.ExternalSnapshotEnabled = true
+
+			code, result := create(form, "")
+			So(code, ShouldEqual, 200)
+			So(result["key"], ShouldEqual, "external-key")
+			So(stored, ShouldNotBeNil)
+		})
+
+		Convey("Should reject it when external snapshots are disabled", func() {
+			setting.ExternalSnapshotEnabled = false
+
+			code, _ := create(form, "")
+			So(code, ShouldEqual, 403)
+			So(stored, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDashboardGzip(t *testing.T) {
+
+	Convey("Given a gzip enabled dashboard api", t, func() {
+		defer bus.ClearBusHandlers()
+
+		var title string
+		bus.AddHandler("test", func(query *m.GetDashboardQuery) error {
+			query.Result = m.NewDashboard(title)
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDashboardAclQuery) error {
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(middleware.Gziper())
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, OrgRole: m.ROLE_VIEWER}})
+		})
+		mac.Get("/api/dashboards/db/:slug", GetDashboard)
+
+		get := func() *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/dashboards/db/test", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Large dashboards should be gzipped", func() {
+			title = strings.Repeat("large ", 1000)
+			resp := get()
+
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Header().Get("Content-Encoding"), ShouldEqual, "gzip")
+
+			reader, err := gzip.NewReader(resp.Body)
+			So(err, ShouldBeNil)
+
+			var dto map[string]interface{}
+			So(json.NewDecoder(reader).Decode(&dto), ShouldBeNil)
+			So(dto["dashboard"].(map[string]interface{})["title"], ShouldEqual, title)
+		})
+
+		Convey("Small dashboards should not be gzipped", func() {
+			title = "small"
+			resp := get()
+
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Header().Get("Content-Encoding"), ShouldEqual, "")
+
+			var dto map[string]interface{}
+			So(json.Unmarshal(resp.Body.Bytes(), &dto), ShouldBeNil)
+			So(dto["dashboard"].(map[string]interface{})["title"], ShouldEqual, "small")
+		})
+	})
+}
+
+func TestHomeDashboard(t *testing.T) {
+
+	Convey("Given a home dashboard api", t, func() {
+		defer bus.ClearBusHandlers()
+		setting.StaticRootPath = "../../public"
+
+		var homeDashboardId int64
+		bus.AddHandler("test", func(query *m.GetPreferencesWithDefaultsQuery) error {
+			query.Result = &m.Preferences{OrgId: query.OrgId, UserId: query.UserId, HomeDashboardId: homeDashboardId}
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDashboardQuery) error {
+			if query.Id != 10 || query.OrgId != 1 {
+				return m.ErrDashboardNotFound
+			}
+			query.Result = m.NewDashboard("Preferred")
+			query.Result.Id = 10
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, UserId: 1}})
+		})
+		mac.Get("/api/dashboards/home", GetHomeDashboard)
+
+		get := func() dtos.DashboardFullWithMeta {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/dashboards/home", nil)
+			mac.ServeHTTP(resp, req)
+			So(resp.Code, ShouldEqual, 200)
+
+			var dash dtos.DashboardFullWithMeta
+			So(json.Unmarshal(resp.Body.Bytes(), &dash), ShouldBeNil)
+			return dash
+		}
+
+		Convey("Should return the preferred home dashboard", func() {
+			homeDashboardId = 10
+			dash := get()
+			So(dash.Meta.IsHome, ShouldBeTrue)
+			So(dash.Meta.Slug, ShouldEqual, "preferred")
+			So(dash.Dashboard["title"], ShouldEqual, "Preferred")
+		})
+
+		Convey("Should fall back to the global home dashboard", func() {
+			homeDashboardId = 0
+			dash := get()
+			So(dash.Meta.IsHome, ShouldBeTrue)
+			So(dash.Meta.Slug, ShouldEqual, "")
+			So(dash.Dashboard["title"], ShouldNotEqual, "Preferred")
+		})
+
+		Convey("Should fall back when the preferred dashboard is gone", func() {
+			homeDashboardId = 11
+			dash := get()
+			So(dash.Dashboard["title"], ShouldNotEqual, "Preferred")
+		})
+	})
+}
+
+func TestProvisionedDashboard(t *testing.T) {
+
+	Convey("Given a provisioned dashboard", t, func() {
+		defer bus.ClearBusHandlers()
+
+		bus.AddHandler("test", func(query *m.GetDashboardQuery) error {
+			query.Result = m.NewDashboard("provisioned")
+			query.Result.Provisioned = true
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.IsStarredByUserQuery) error {
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDashboardAclQuery) error {
+			return nil
+		})
+		bus.AddHandler("test", func(cmd *m.SaveDashboardCommand) error {
+			return m.ErrDashboardProvisioned
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, OrgRole: m.ROLE_ADMIN}, IsSignedIn: true})
+		})
+		mac.Get("/api/dashboards/db/:slug", GetDashboard)
+		mac.Delete("/api/dashboards/db/:slug", DeleteDashboard)
+		mac.Post("/api/dashboards/db", func(c *middleware.Context) {
+			PostDashboard(c, m.SaveDashboardCommand{Dashboard: map[string]interface{}{"id": float64(5), "title": "provisioned"}})
+		})
+
+		serve := func(method, url string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, url, nil)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should not offer to save it", func() {
+			resp := serve("GET", "/api/dashboards/db/provisioned")
+			So(resp.Code, ShouldEqual, 200)
+
+			var dto dtos.DashboardFullWithMeta
+			So(json.Unmarshal(resp.Body.Bytes(), &dto), ShouldBeNil)
+			So(dto.Meta.IsProvisioned, ShouldBeTrue)
+			So(dto.Meta.CanSave, ShouldBeFalse)
+		})
+
+		Convey("Should reject edits with 409", func() {
+			resp := serve("POST", "/api/dashboards/db")
+			So(resp.Code, ShouldEqual, 409)
+
+			var body map[string]interface{}
+			So(json.Unmarshal(resp.Body.Bytes(), &body), ShouldBeNil)
+			So(body["status"], ShouldEqual, "provisioned")
+		})
+
+		Convey("Should reject deleting it with 409", func() {
+			resp := serve("DELETE", "/api/dashboards/db/provisioned")
+			So(resp.Code, ShouldEqual, 409)
+		})
+	})
+}
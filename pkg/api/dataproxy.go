@@ -62,7 +62,7 @@ func NewReverseProxy(ds *m.DataSource, proxyPath string, targetUrl *url.URL) *ht
 				req.Header.Del("Authorization")
 				req.Header.Add("Authorization", util.GetBasicAuthHeader(ds.User, ds.Password))
 			}
-		} else if ds.Type == "openfalcon" {
+		} else if ds.Type == m.DS_OPENFALCON {
 			reqQueryVals.Add("target", ds.Url)
 			req.URL.RawQuery = reqQueryVals.Encode()
 
@@ -88,36 +88,36 @@ func NewReverseProxy(ds *m.DataSource, proxyPath string, targetUrl *url.URL) *ht
 	return &httputil.ReverseProxy{Director: director}
 }
 
-var dsMap map[int64]*m.DataSource = make(map[int64]*m.DataSource)
-
 func getDatasource(id int64, orgId int64) (*m.DataSource, error) {
-	// ds, exists := dsMap[id]
-	// if exists && ds.OrgId == orgId {
-	// 	return ds, nil
-	// }
-
 	query := m.GetDataSourceByIdQuery{Id: id, OrgId: orgId}
 	if err := bus.Dispatch(&query); err != nil {
 		return nil, err
 	}
 
-	dsMap[id] = &query.Result
 	return &query.Result, nil
 }
 
 func ProxyDataSourceRequest(c *middleware.Context) {
 	ds, err := getDatasource(c.ParamsInt64(":id"), c.OrgId)
+	if err == m.ErrDataSourceNotFound {
+		c.JsonApiErr(404, "Data source not found", nil)
+		return
+	}
 	if err != nil {
 		c.JsonApiErr(500, "Unable to load datasource meta data", err)
 		return
 	}
 
+	// never proxy to a data source outside of the signed in org
+	if ds.OrgId != c.OrgId {
+		c.JsonApiErr(403, "Access denied to data source", nil)
+		return
+	}
+
 	targetUrl, _ := url.Parse(ds.Url)
-	if len(setting.DataProxyWhiteList) > 0 {
-		if _, exists := setting.DataProxyWhiteList[targetUrl.Host]; !exists {
-			c.JsonApiErr(403, "Data proxy hostname and ip are not included in whitelist", nil)
-			return
-		}
+	if !dataProxyHostAllowed(targetUrl) {
+		c.JsonApiErr(403, "Data proxy hostname and ip are not included in whitelist", nil)
+		return
 	}
 
 	if ds.Type == m.DS_CLOUDWATCH {
@@ -129,3 +129,12 @@ func ProxyDataSourceRequest(c *middleware.Context) {
 		proxy.ServeHTTP(c.RW(), c.Req.Request)
 	}
 }
+
+// dataProxyHostAllowed reports whether the server may send requests to the
+// host of targetUrl, any host is allowed without a whitelist.
+func dataProxyHostAllowed(targetUrl *url.URL) bool {
+	if len(setting.DataProxyWhiteList) == 0 {
+		return true
+	}
+	return targetUrl != nil && setting.DataProxyWhiteList[targetUrl.Host]
+}
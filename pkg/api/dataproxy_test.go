@@ -1,13 +1,18 @@
 package api
 
 import (
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
 	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
 )
 
 func TestDataSourceProxy(t *testing.T) {
@@ -57,4 +62,62 @@ func TestDataSourceProxy(t *testing.T) {
 
 	})
 
+	Convey("When proxying requests for a signed in user", t, func() {
+		defer bus.ClearBusHandlers()
+
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte("proxied"))
+		}))
+		defer backend.Close()
+
+		datasources := map[int64]m.DataSource{
+			1: {Id: 1, OrgId: 1, Url: backend.URL, Type: m.DS_GRAPHITE},
+			2: {Id: 2, OrgId: 2, Url: backend.URL, Type: m.DS_GRAPHITE},
+		}
+
+		bus.AddHandler("test", func(query *m.GetDataSourceByIdQuery) error {
+			ds, exists := datasources[query.Id]
+			if !exists {
+				return m.ErrDataSourceNotFound
+			}
+			query.Result = ds
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1}})
+		})
+		mac.Any("/api/datasources/proxy/:id/*", ProxyDataSourceRequest)
+
+		// the reverse proxy needs a real connection to close notify on
+		server := httptest.NewServer(mac)
+		defer server.Close()
+
+		get := func(path string) (int, string) {
+			resp, err := http.Get(server.URL + path)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+			return resp.StatusCode, string(body)
+		}
+
+		Convey("Should proxy to a data source in the same org", func() {
+			code, body := get("/api/datasources/proxy/1/render")
+			So(code, ShouldEqual, 200)
+			So(body, ShouldEqual, "proxied")
+		})
+
+		Convey("Should reject a data source in another org", func() {
+			code, body := get("/api/datasources/proxy/2/render")
+			So(code, ShouldEqual, 403)
+			So(body, ShouldNotContainSubstring, "proxied")
+		})
+
+		Convey("Should return 404 for unknown data sources", func() {
+			code, _ := get("/api/datasources/proxy/3/render")
+			So(code, ShouldEqual, 404)
+		})
+	})
 }
@@ -1,16 +1,56 @@
 package api
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
 	"github.com/Cepave/grafana/pkg/api/dtos"
 	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/log"
 	"github.com/Cepave/grafana/pkg/middleware"
 	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/plugins"
 	"github.com/Cepave/grafana/pkg/util"
 )
 
+// dataSourceSecretMask replaces stored secrets in api responses. Sending it
+// back on update keeps the stored value unchanged.
+const dataSourceSecretMask = "********"
+
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return dataSourceSecretMask
+}
+
+// hasMaskedSecret tells whether a new data source was sent with the mask as
+// a secret, there is no stored value it could stand for.
+func hasMaskedSecret(cmd *m.AddDataSourceCommand) bool {
+	return cmd.Password == dataSourceSecretMask || cmd.BasicAuthPassword == dataSourceSecretMask
+}
+
 func GetDataSources(c *middleware.Context) {
-	query := m.GetDataSourcesQuery{OrgId: c.OrgId}
+	query := m.GetDataSourcesQuery{OrgId: c.OrgId, Type: c.Query("type"), Query: c.Query("q")}
+
+	if query.Type != "" {
+		if _, ok := plugins.DataSources[query.Type]; !ok {
+			c.JsonApiErr(400, "Unknown datasource type", nil)
+			return
+		}
+	}
+
+	lastModified := m.GetDataSourcesLastModifiedQuery{OrgId: c.OrgId}
+	if err := bus.Dispatch(&lastModified); err != nil {
+		c.JsonApiErr(500, "Failed to query datasources", err)
+		return
+	}
+
+	if notModified(c, lastModified.Result) {
+		return
+	}
 
 	if err := bus.Dispatch(&query); err != nil {
 		c.JsonApiErr(500, "Failed to query datasources", err)
@@ -26,7 +66,7 @@ func GetDataSources(c *middleware.Context) {
 			Url:       ds.Url,
 			Type:      ds.Type,
 			Access:    ds.Access,
-			Password:  ds.Password,
+			Password:  maskSecret(ds.Password),
 			Database:  ds.Database,
 			User:      ds.User,
 			BasicAuth: ds.BasicAuth,
@@ -37,35 +77,61 @@ func GetDataSources(c *middleware.Context) {
 	c.JSON(200, result)
 }
 
+// notModified sets Last-Modified and answers with a 304 when the client's
+// If-Modified-Since is not older than lastModified. Http dates only have
+// second precision, so lastModified is truncated to whole seconds.
+func notModified(c *middleware.Context, lastModified time.Time) bool {
+	if lastModified.IsZero() {
+		return false
+	}
+
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	c.Resp.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	// make browsers revalidate instead of guessing how long the list stays fresh
+	c.Resp.Header().Set("Cache-Control", "no-cache")
+
+	since, err := http.ParseTime(c.Req.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.After(since) {
+		return false
+	}
+
+	c.Resp.WriteHeader(http.StatusNotModified)
+	return true
+}
+
 func GetDataSourceById(c *middleware.Context) {
 	query := m.GetDataSourceByIdQuery{
 		Id:    c.ParamsInt64(":id"),
 		OrgId: c.OrgId,
 	}
 
-	if err := bus.Dispatch(&query); err != nil {
+	err := bus.Dispatch(&query)
+	if err == m.ErrDataSourceNotFound {
+		c.JsonApiErr(404, "Data source not found", nil)
+		return
+	}
+	if err != nil {
 		c.JsonApiErr(500, "Failed to query datasources", err)
 		return
 	}
 
-	ds := query.Result
+	c.JSON(200, convertModelToDtos(query.Result))
+}
 
-	c.JSON(200, &dtos.DataSource{
-		Id:                ds.Id,
-		OrgId:             ds.OrgId,
-		Name:              ds.Name,
-		Url:               ds.Url,
-		Type:              ds.Type,
-		Access:            ds.Access,
-		Password:          ds.Password,
-		Database:          ds.Database,
-		User:              ds.User,
-		BasicAuth:         ds.BasicAuth,
-		BasicAuthUser:     ds.BasicAuthUser,
-		BasicAuthPassword: ds.BasicAuthPassword,
-		IsDefault:         ds.IsDefault,
-		JsonData:          ds.JsonData,
-	})
+func GetDefaultDataSource(c *middleware.Context) {
+	query := m.GetDefaultDataSourceQuery{OrgId: c.OrgId}
+
+	err := bus.Dispatch(&query)
+	if err == m.ErrDataSourceNotFound {
+		c.JsonApiErr(404, "No default data source", nil)
+		return
+	}
+	if err != nil {
+		c.JsonApiErr(500, "Failed to query datasources", err)
+		return
+	}
+
+	c.JSON(200, convertModelToDtos(query.Result))
 }
 
 func DeleteDataSource(c *middleware.Context) {
@@ -87,9 +153,82 @@ func DeleteDataSource(c *middleware.Context) {
 	c.JsonOK("Data source deleted")
 }
 
+func RestoreDataSource(c *middleware.Context) {
+	cmd := &m.RestoreDataSourceCommand{Id: c.ParamsInt64(":id"), OrgId: c.OrgId}
+
+	err := bus.Dispatch(cmd)
+	if err == m.ErrDataSourceNotFound {
+		c.JsonApiErr(404, "Deleted data source not found", nil)
+		return
+	}
+	if err != nil {
+		c.JsonApiErr(500, "Failed to restore datasource", err)
+		return
+	}
+
+	c.JsonOK("Data source restored")
+}
+
+var dataSourceTestClient = &http.Client{
+	Timeout:   5 * time.Second,
+	Transport: dataProxyTransport,
+}
+
+// testDataSourceConnection does a lightweight check that the data source url
+// can be reached. Any http response counts for data sources without a ping api.
+func testDataSourceConnection(dsType string, dsUrl string) error {
+	if dsType == m.DS_OPENFALCON {
+		return pingOpenFalcon(dsUrl)
+	}
+
+	resp, err := dataSourceTestClient.Get(dsUrl)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("Data source responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// checkDataSourceConnection runs the connection test before saving and
+// answers the request when it fails. Only hosts the data proxy may reach are
+// tested, and the reason of a failure stays in the server log so the test
+// can't be used to probe the network.
+func checkDataSourceConnection(c *middleware.Context, dsType string, dsUrl string) bool {
+	targetUrl, err := url.Parse(dsUrl)
+	if err != nil {
+		c.JsonApiErr(400, "Invalid data source url", nil)
+		return false
+	}
+	if !dataProxyHostAllowed(targetUrl) {
+		c.JsonApiErr(403, "Data proxy hostname and ip are not included in whitelist", nil)
+		return false
+	}
+
+	if err := testDataSourceConnection(dsType, dsUrl); err != nil {
+		log.Info("Data source connection test of %s failed: %v", dsUrl, err)
+		c.JsonApiErr(400, "Data source connection test failed", nil)
+		return false
+	}
+	return true
+}
+
 func AddDataSource(c *middleware.Context, cmd m.AddDataSourceCommand) {
 	cmd.OrgId = c.OrgId
 
+	if hasMaskedSecret(&cmd) {
+		c.JsonApiErr(400, "Secrets can't be "+dataSourceSecretMask+" for a new data source", nil)
+		return
+	}
+
+	if cmd.TestBeforeSave && !checkDataSourceConnection(c, cmd.Type, cmd.Url) {
+		return
+	}
+
 	if err := bus.Dispatch(&cmd); err != nil {
 		c.JsonApiErr(500, "Failed to add datasource", err)
 		return
@@ -98,10 +237,71 @@ func AddDataSource(c *middleware.Context, cmd m.AddDataSourceCommand) {
 	c.JSON(200, util.DynMap{"message": "Datasource added", "id": cmd.Result.Id})
 }
 
+func ImportDataSources(c *middleware.Context, dataSources []m.AddDataSourceCommand) {
+	for i := range dataSources {
+		if hasMaskedSecret(&dataSources[i]) {
+			c.JsonApiErr(400, fmt.Sprintf("Secrets of data source %q can't be %s", dataSources[i].Name, dataSourceSecretMask), nil)
+			return
+		}
+	}
+
+	limitReached, err := middleware.QuotaReachedBy(c, "data_source", int64(len(dataSources)))
+	if err != nil {
+		c.JsonApiErr(500, "failed to get quota", err)
+		return
+	}
+	if limitReached {
+		c.JsonApiErr(403, "data_source Quota reached", nil)
+		return
+	}
+
+	cmd := m.ImportDataSourcesCommand{
+		OrgId:            c.OrgId,
+		DataSources:      dataSources,
+		AbortOnDuplicate: c.Query("abortOnDuplicate") == "true",
+	}
+
+	err = bus.Dispatch(&cmd)
+	if err == m.ErrDataSourceNameExists {
+		c.JsonApiErr(409, "Import aborted, "+err.Error(), nil)
+		return
+	}
+	if err != nil {
+		c.JsonApiErr(500, "Failed to import datasources", err)
+		return
+	}
+
+	c.JSON(200, cmd.Result)
+}
+
 func UpdateDataSource(c *middleware.Context, cmd m.UpdateDataSourceCommand) {
 	cmd.OrgId = c.OrgId
 	cmd.Id = c.ParamsInt64(":id")
 
+	if cmd.TestBeforeSave && !checkDataSourceConnection(c, cmd.Type, cmd.Url) {
+		return
+	}
+
+	if cmd.Password == dataSourceSecretMask || cmd.BasicAuthPassword == dataSourceSecretMask {
+		query := m.GetDataSourceByIdQuery{Id: cmd.Id, OrgId: cmd.OrgId}
+		err := bus.Dispatch(&query)
+		if err == m.ErrDataSourceNotFound {
+			c.JsonApiErr(404, "Data source not found", nil)
+			return
+		}
+		if err != nil {
+			c.JsonApiErr(500, "Failed to query datasources", err)
+			return
+		}
+
+		if cmd.Password == dataSourceSecretMask {
+			cmd.Password = query.Result.Password
+		}
+		if cmd.BasicAuthPassword == dataSourceSecretMask {
+			cmd.BasicAuthPassword = query.Result.BasicAuthPassword
+		}
+	}
+
 	err := bus.Dispatch(&cmd)
 	if err != nil {
 		c.JsonApiErr(500, "Failed to update datasource", err)
@@ -111,14 +311,66 @@ func UpdateDataSource(c *middleware.Context, cmd m.UpdateDataSourceCommand) {
 	c.JsonOK("Datasource updated")
 }
 
+type dataSourcePluginMeta struct {
+	DefaultPort           int
+	OpenFalconQuery       bool
+	OpenFalconAnnotations bool
+}
+
+// dataSourcePluginsMeta adds what the data source editor needs to know about a
+// plugin type on top of its plugin.json.
+var dataSourcePluginsMeta = map[string]dataSourcePluginMeta{
+	m.DS_GRAPHITE:    {DefaultPort: 8080},
+	m.DS_INFLUXDB:    {DefaultPort: 8086},
+	m.DS_INFLUXDB_08: {DefaultPort: 8086},
+	m.DS_ES:          {DefaultPort: 9200},
+	m.DS_OPENTSDB:    {DefaultPort: 4242},
+	m.DS_KAIROSDB:    {DefaultPort: 8080},
+	m.DS_PROMETHEUS:  {DefaultPort: 9090},
+	m.DS_OPENFALCON:  {DefaultPort: 9966, OpenFalconQuery: true, OpenFalconAnnotations: true},
+}
+
 func GetDataSourcePlugins(c *middleware.Context) {
 	dsList := make(map[string]interface{})
 
 	for key, value := range plugins.DataSources {
-		if value.(map[string]interface{})["builtIn"] == nil {
-			dsList[key] = value
+		pluginJson := value.(map[string]interface{})
+		if pluginJson["builtIn"] != nil {
+			continue
+		}
+
+		plugin := make(map[string]interface{}, len(pluginJson)+4)
+		for k, v := range pluginJson {
+			plugin[k] = v
 		}
+
+		meta := dataSourcePluginsMeta[key]
+		plugin["type"] = key
+		plugin["defaultPort"] = meta.DefaultPort
+		plugin["openFalconQuery"] = meta.OpenFalconQuery
+		plugin["openFalconAnnotations"] = meta.OpenFalconAnnotations
+
+		dsList[key] = plugin
 	}
 
 	c.JSON(200, dsList)
 }
+
+func convertModelToDtos(ds m.DataSource) *dtos.DataSource {
+	return &dtos.DataSource{
+		Id:                ds.Id,
+		OrgId:             ds.OrgId,
+		Name:              ds.Name,
+		Url:               ds.Url,
+		Type:              ds.Type,
+		Access:            ds.Access,
+		Password:          maskSecret(ds.Password),
+		Database:          ds.Database,
+		User:              ds.User,
+		BasicAuth:         ds.BasicAuth,
+		BasicAuthUser:     ds.BasicAuthUser,
+		BasicAuthPassword: maskSecret(ds.BasicAuthPassword),
+		IsDefault:         ds.IsDefault,
+		JsonData:          ds.JsonData,
+	}
+}
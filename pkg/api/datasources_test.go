@@ -0,0 +1,467 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/plugins"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Unknwon/macaron"
+	"github.com/macaron-contrib/binding"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDataSourceTestBeforeSave(t *testing.T) {
+
+	Convey("Given a data source api", t, func() {
+		defer bus.ClearBusHandlers()
+
+		saved := false
+		bus.AddHandler("test", func(cmd *m.AddDataSourceCommand) error {
+			saved = true
+			cmd.Result = &m.DataSource{Id: 1}
+			return nil
+		})
+
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/health" {
+				w.Write([]byte("ok"))
+				return
+			}
+			http.NotFound(w, req)
+		}))
+		defer backend.Close()
+
+		unreachable := httptest.NewServer(http.NotFoundHandler())
+		unreachable.Close()
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1}})
+		})
+		mac.Post("/api/datasources", binding.Bind(m.AddDataSourceCommand{}), AddDataSource)
+
+		post := func(cmd m.AddDataSourceCommand) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(cmd)
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/datasources", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		cmd := m.AddDataSourceCommand{Name: "falcon", Type: m.DS_OPENFALCON, Access: m.DS_ACCESS_PROXY}
+
+		Convey("Should save when the connection test succeeds", func() {
+			cmd.Url = backend.URL
+			cmd.TestBeforeSave = true
+			resp := post(cmd)
+
+			So(resp.Code, ShouldEqual, 200)
+			So(saved, ShouldBeTrue)
+		})
+
+		Convey("Should not save when the backend is unreachable", func() {
+			cmd.Url = unreachable.URL
+			cmd.TestBeforeSave = true
+			resp := post(cmd)
+
+			So(resp.Code, ShouldEqual, 400)
+			So(resp.Body.String(), ShouldContainSubstring, "connection test failed")
+			So(resp.Body.String(), ShouldNotContainSubstring, "refused")
+			So(saved, ShouldBeFalse)
+		})
+
+		Convey("Should not test a host outside of the data proxy whitelist", func() {
+			defer func(old map[string]bool) { setting.DataProxyWhiteList = old }(setting.DataProxyWhiteList)
+			setting.DataProxyWhiteList = map[string]bool{"falcon.example.com:9966": true}
+
+			tested := false
+			probed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				tested = true
+			}))
+			defer probed.Close()
+
+			cmd.Url = probed.URL
+			cmd.TestBeforeSave = true
+			resp := post(cmd)
+
+			So(resp.Code, ShouldEqual, 403)
+			So(tested, ShouldBeFalse)
+			So(saved, ShouldBeFalse)
+		})
+
+		Convey("Should reject the secret mask as a password", func() {
+			cmd.Password = dataSourceSecretMask
+			resp := post(cmd)
+
+			So(resp.Code, ShouldEqual, 400)
+			So(saved, ShouldBeFalse)
+		})
+
+		Convey("Should save without testing by default", func() {
+			cmd.Url = unreachable.URL
+			resp := post(cmd)
+
+			So(resp.Code, ShouldEqual, 200)
+			So(saved, ShouldBeTrue)
+		})
+	})
+}
+
+func TestGetDataSourceById(t *testing.T) {
+
+	Convey("Given a data source api", t, func() {
+		defer bus.ClearBusHandlers()
+
+		var dbErr error
+		bus.AddHandler("test", func(query *m.GetDataSourceByIdQuery) error {
+			if dbErr != nil {
+				return dbErr
+			}
+			if query.Id != 1 || query.OrgId != 1 {
+				return m.ErrDataSourceNotFound
+			}
+			query.Result = m.DataSource{Id: 1, OrgId: 1, Name: "graphite"}
+			return nil
+		})
+
+		var orgId int64 = 1
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: orgId}})
+		})
+		mac.Get("/api/datasources/:id", GetDataSourceById)
+
+		get := func(url string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", url, nil)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should return the data source", func() {
+			resp := get("/api/datasources/1")
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Body.String(), ShouldContainSubstring, "graphite")
+		})
+
+		Convey("Should return 404 for an unknown id", func() {
+			So(get("/api/datasources/2").Code, ShouldEqual, 404)
+		})
+
+		Convey("Should return 404 for a data source in another org", func() {
+			orgId = 2
+			So(get("/api/datasources/1").Code, ShouldEqual, 404)
+		})
+
+		Convey("Should return 500 when the query fails", func() {
+			dbErr = errors.New("database is locked")
+			So(get("/api/datasources/1").Code, ShouldEqual, 500)
+		})
+	})
+}
+
+func TestDataSourceSecrets(t *testing.T) {
+
+	Convey("Given a data source with secrets", t, func() {
+		defer bus.ClearBusHandlers()
+
+		stored := m.DataSource{Id: 1, OrgId: 1, Name: "influx", Password: "db-secret", BasicAuthPassword: "auth-secret"}
+		bus.AddHandler("test", func(query *m.GetDataSourcesQuery) error {
+			query.Result = []*m.DataSource{&stored}
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDataSourcesLastModifiedQuery) error {
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDataSourceByIdQuery) error {
+			query.Result = stored
+			return nil
+		})
+
+		var updated *m.UpdateDataSourceCommand
+		bus.AddHandler("test", func(cmd *m.UpdateDataSourceCommand) error {
+			updated = cmd
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1}})
+		})
+		mac.Get("/api/datasources", GetDataSources)
+		mac.Get("/api/datasources/:id", GetDataSourceById)
+		mac.Put("/api/datasources/:id", binding.Bind(m.UpdateDataSourceCommand{}), UpdateDataSource)
+
+		request := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+			data, _ := json.Marshal(body)
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, url, bytes.NewReader(data))
+			req.Header.Set("Content-Type", "application/json")
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should never serialize secrets", func() {
+			for _, url := range []string{"/api/datasources", "/api/datasources/1"} {
+				resp := request("GET", url, nil)
+				So(resp.Code, ShouldEqual, 200)
+				So(resp.Body.String(), ShouldNotContainSubstring, "db-secret")
+				So(resp.Body.String(), ShouldNotContainSubstring, "auth-secret")
+				So(resp.Body.String(), ShouldContainSubstring, dataSourceSecretMask)
+			}
+		})
+
+		cmd := m.UpdateDataSourceCommand{Name: "influx", Type: m.DS_INFLUXDB, Access: m.DS_ACCESS_PROXY}
+
+		Convey("Should keep stored secrets when updating with the mask", func() {
+			cmd.Password = dataSourceSecretMask
+			cmd.BasicAuthPassword = dataSourceSecretMask
+			resp := request("PUT", "/api/datasources/1", cmd)
+
+			So(resp.Code, ShouldEqual, 200)
+			So(updated.Password, ShouldEqual, "db-secret")
+			So(updated.BasicAuthPassword, ShouldEqual, "auth-secret")
+		})
+
+		Convey("Should store new secrets", func() {
+			cmd.Password = "new-secret"
+			resp := request("PUT", "/api/datasources/1", cmd)
+
+			So(resp.Code, ShouldEqual, 200)
+			So(updated.Password, ShouldEqual, "new-secret")
+			So(updated.BasicAuthPassword, ShouldEqual, "")
+		})
+	})
+}
+
+func TestImportDataSources(t *testing.T) {
+
+	Convey("Given a data source import api", t, func() {
+		defer bus.ClearBusHandlers()
+
+		var imported *m.ImportDataSourcesCommand
+		bus.AddHandler("test", func(cmd *m.ImportDataSourcesCommand) error {
+			imported = cmd
+			if cmd.AbortOnDuplicate {
+				return m.ErrDataSourceNameExists
+			}
+			for _, ds := range cmd.DataSources {
+				cmd.Result = append(cmd.Result, &m.ImportDataSourceResult{Name: ds.Name, Id: 1})
+			}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1}})
+		})
+		mac.Post("/api/datasources/import", binding.Bind([]m.AddDataSourceCommand{}), ImportDataSources)
+
+		dataSources := `[{"name":"graphite","type":"graphite","access":"proxy"},{"name":"falcon","type":"openfalcon","access":"proxy"}]`
+
+		post := func(url string, body string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", url, bytes.NewReader([]byte(body)))
+			req.Header.Set("Content-Type", "application/json")
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should import all data sources into the current org", func() {
+			resp := post("/api/datasources/import", dataSources)
+
+			So(resp.Code, ShouldEqual, 200)
+			So(imported.OrgId, ShouldEqual, 1)
+			So(len(imported.DataSources), ShouldEqual, 2)
+			So(imported.DataSources[1].Type, ShouldEqual, m.DS_OPENFALCON)
+
+			var result []m.ImportDataSourceResult
+			So(json.Unmarshal(resp.Body.Bytes(), &result), ShouldBeNil)
+			So(len(result), ShouldEqual, 2)
+		})
+
+		Convey("Should return 409 when the batch is aborted on a duplicate", func() {
+			resp := post("/api/datasources/import?abortOnDuplicate=true", dataSources)
+			So(resp.Code, ShouldEqual, 409)
+		})
+
+		Convey("Should reject the secret mask as a password", func() {
+			resp := post("/api/datasources/import", `[{"name":"graphite","type":"graphite","access":"proxy","basicAuthPassword":"********"}]`)
+
+			So(resp.Code, ShouldEqual, 400)
+			So(imported, ShouldBeNil)
+		})
+	})
+}
+
+func TestDataSourcePlugins(t *testing.T) {
+
+	Convey("When listing data source plugins", t, func() {
+		plugins.DataSources = map[string]interface{}{
+			"openfalcon": map[string]interface{}{"name": "Open-Falcon", "type": "openfalcon", "annotations": true},
+			"graphite":   map[string]interface{}{"name": "Graphite", "type": "graphite"},
+			"grafana":    map[string]interface{}{"name": "Grafana", "type": "grafana", "builtIn": true},
+		}
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1}})
+		})
+		mac.Get("/api/datasources/plugins", GetDataSourcePlugins)
+
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/datasources/plugins", nil)
+		mac.ServeHTTP(resp, req)
+
+		var result map[string]map[string]interface{}
+		So(json.Unmarshal(resp.Body.Bytes(), &result), ShouldBeNil)
+
+		Convey("Should include the openfalcon plugin metadata", func() {
+			falcon := result["openfalcon"]
+			So(falcon["name"], ShouldEqual, "Open-Falcon")
+			So(falcon["type"], ShouldEqual, "openfalcon")
+			So(falcon["defaultPort"], ShouldEqual, 9966)
+			So(falcon["openFalconQuery"], ShouldEqual, true)
+			So(falcon["openFalconAnnotations"], ShouldEqual, true)
+		})
+
+		Convey("Should not flag other plugins as openfalcon capable", func() {
+			So(result["graphite"]["defaultPort"], ShouldEqual, 8080)
+			So(result["graphite"]["openFalconQuery"], ShouldEqual, false)
+		})
+
+		Convey("Should skip built in plugins", func() {
+			_, exists := result["grafana"]
+			So(exists, ShouldBeFalse)
+		})
+	})
+}
+
+func TestGetDataSourcesIfModifiedSince(t *testing.T) {
+
+	Convey("Given datasources last changed at noon", t, func() {
+		defer bus.ClearBusHandlers()
+
+		noon := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+		lastModified := noon.Add(500 * time.Millisecond)
+		listed := false
+
+		bus.AddHandler("test", func(query *m.GetDataSourcesLastModifiedQuery) error {
+			query.Result = lastModified
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDataSourcesQuery) error {
+			listed = true
+			query.Result = []*m.DataSource{{Id: 1, OrgId: 1, Name: "influx"}}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1}})
+		})
+		mac.Get("/api/datasources", GetDataSources)
+
+		get := func(ifModifiedSince string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/datasources", nil)
+			if ifModifiedSince != "" {
+				req.Header.Set("If-Modified-Since", ifModifiedSince)
+			}
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should send Last-Modified with the list", func() {
+			resp := get("")
+
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Header().Get("Last-Modified"), ShouldEqual, "Fri, 01 Jan 2016 12:00:00 GMT")
+			So(resp.Header().Get("Cache-Control"), ShouldEqual, "no-cache")
+			So(resp.Body.String(), ShouldContainSubstring, "influx")
+		})
+
+		Convey("Should answer 304 when nothing changed since", func() {
+			resp := get(noon.Format(http.TimeFormat))
+
+			So(resp.Code, ShouldEqual, 304)
+			So(resp.Body.Len(), ShouldEqual, 0)
+			So(listed, ShouldBeFalse)
+		})
+
+		Convey("Should send the list when a datasource was updated since", func() {
+			lastModified = noon.Add(time.Minute)
+			resp := get(noon.Format(http.TimeFormat))
+
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Header().Get("Last-Modified"), ShouldEqual, "Fri, 01 Jan 2016 12:01:00 GMT")
+			So(listed, ShouldBeTrue)
+		})
+
+		Convey("Should ignore an invalid If-Modified-Since", func() {
+			So(get("yesterday").Code, ShouldEqual, 200)
+		})
+	})
+}
+
+func TestGetDataSourcesFilters(t *testing.T) {
+
+	Convey("Given a data source api", t, func() {
+		defer bus.ClearBusHandlers()
+
+		plugins.DataSources = map[string]interface{}{
+			"graphite": map[string]interface{}{"name": "Graphite", "type": "graphite"},
+		}
+
+		var sent *m.GetDataSourcesQuery
+		bus.AddHandler("test", func(query *m.GetDataSourcesLastModifiedQuery) error {
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetDataSourcesQuery) error {
+			sent = query
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1}})
+		})
+		mac.Get("/api/datasources", GetDataSources)
+
+		get := func(url string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", url, nil)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should pass the filters to the query", func() {
+			So(get("/api/datasources?type=graphite&q=prod").Code, ShouldEqual, 200)
+			So(sent.OrgId, ShouldEqual, 1)
+			So(sent.Type, ShouldEqual, "graphite")
+			So(sent.Query, ShouldEqual, "prod")
+		})
+
+		Convey("Should reject unknown types", func() {
+			So(get("/api/datasources?type=nosuchdb").Code, ShouldEqual, 400)
+			So(sent, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,7 @@
+package dtos
+
+type ImportDashboardCommand struct {
+	Dashboard   map[string]interface{} `json:"dashboard" binding:"Required"`
+	DataSources map[string]string      `json:"datasources"`
+	Overwrite   bool                   `json:"overwrite"`
+}
@@ -1,9 +1,6 @@
 package dtos
 
 import (
-	"crypto/md5"
-	"fmt"
-	"strings"
 	"time"
 
 	m "github.com/Cepave/grafana/pkg/models"
@@ -30,16 +27,19 @@ type CurrentUser struct {
 }
 
 type DashboardMeta struct {
-	IsStarred  bool      `json:"isStarred,omitempty"`
-	IsHome     bool      `json:"isHome,omitempty"`
-	IsSnapshot bool      `json:"isSnapshot,omitempty"`
-	Type       string    `json:"type,omitempty"`
-	CanSave    bool      `json:"canSave"`
-	CanEdit    bool      `json:"canEdit"`
-	CanStar    bool      `json:"canStar"`
-	Slug       string    `json:"slug"`
-	Expires    time.Time `json:"expires"`
-	Created    time.Time `json:"created"`
+	IsStarred     bool      `json:"isStarred,omitempty"`
+	IsHome        bool      `json:"isHome,omitempty"`
+	IsSnapshot    bool      `json:"isSnapshot,omitempty"`
+	IsProvisioned bool      `json:"isProvisioned,omitempty"`
+	IsFolder      bool      `json:"isFolder,omitempty"`
+	FolderId      int64     `json:"folderId"`
+	Type          string    `json:"type,omitempty"`
+	CanSave       bool      `json:"canSave"`
+	CanEdit       bool      `json:"canEdit"`
+	CanStar       bool      `json:"canStar"`
+	Slug          string    `json:"slug"`
+	Expires       time.Time `json:"expires"`
+	Created       time.Time `json:"created"`
 }
 
 type DashboardFullWithMeta struct {
@@ -64,6 +64,13 @@ type DataSource struct {
 	JsonData          map[string]interface{} `json:"jsonData"`
 }
 
+type Annotation struct {
+	Time  int64    `json:"time"`
+	Title string   `json:"title"`
+	Text  string   `json:"text"`
+	Tags  []string `json:"tags"`
+}
+
 type MetricQueryResultDto struct {
 	Data []MetricQueryResultDataDto `json:"data"`
 }
@@ -77,12 +84,11 @@ type UserStars struct {
 	DashboardIds map[string]bool `json:"dashboardIds"`
 }
 
-func GetGravatarUrl(text string) string {
-	if text == "" {
-		return ""
-	}
-
-	hasher := md5.New()
-	hasher.Write([]byte(strings.ToLower(text)))
-	return fmt.Sprintf("https://secure.gravatar.com/avatar/%x?s=90&default=mm", hasher.Sum(nil))
+// PagedResult wraps one page of a search together with what is
+// needed to request the rest of it.
+type PagedResult struct {
+	Items      interface{} `json:"items"`
+	Page       int         `json:"page"`
+	PerPage    int         `json:"perPage"`
+	TotalCount int64       `json:"totalCount"`
 }
@@ -0,0 +1,13 @@
+package dtos
+
+type Prefs struct {
+	Theme           string `json:"theme"`
+	Timezone        string `json:"timezone"`
+	HomeDashboardId int64  `json:"homeDashboardId"`
+}
+
+type UpdatePrefsCmd struct {
+	Theme           string `json:"theme"`
+	Timezone        string `json:"timezone"`
+	HomeDashboardId int64  `json:"homeDashboardId"`
+}
@@ -1,5 +1,10 @@
 package dtos
 
+import (
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
 type SignUpForm struct {
 	Email string `json:"email" binding:"Required"`
 }
@@ -45,8 +50,25 @@ type SendResetPasswordEmailForm struct {
 	UserOrEmail string `json:"userOrEmail" binding:"Required"`
 }
 
+type SendVerifyEmailForm struct {
+	UserOrEmail string `json:"userOrEmail" binding:"Required"`
+}
+
+type VerifyEmailForm struct {
+	Code string `json:"code" binding:"Required"`
+}
+
 type ResetUserPasswordForm struct {
 	Code            string `json:"code"`
 	NewPassword     string `json:"newPassword"`
 	ConfirmPassword string `json:"confirmPassword"`
 }
+
+type WhoAmI struct {
+	Login          string                 `json:"login"`
+	Email          string                 `json:"email"`
+	OrgId          int64                  `json:"orgId"`
+	OrgRole        m.RoleType             `json:"orgRole"`
+	IsGrafanaAdmin bool                   `json:"isGrafanaAdmin"`
+	Permissions    middleware.Permissions `json:"permissions"`
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// POST /api/user/email/send-verify
+func SendVerifyEmail(c *middleware.Context, form dtos.SendVerifyEmailForm) Response {
+	userQuery := m.GetUserByLoginQuery{LoginOrEmail: form.UserOrEmail}
+
+	if err := bus.Dispatch(&userQuery); err != nil {
+		return ApiError(404, "User does not exist", err)
+	}
+
+	if userQuery.Result.EmailVerified {
+		return ApiSuccess("Email already verified")
+	}
+
+	emailCmd := m.SendVerifyEmailCommand{User: userQuery.Result}
+	if err := bus.Dispatch(&emailCmd); err != nil {
+		return ApiError(500, "Failed to send email", err)
+	}
+
+	return ApiSuccess("Email sent")
+}
+
+// POST /api/user/email/verify
+func VerifyEmail(c *middleware.Context, form dtos.VerifyEmailForm) Response {
+	query := m.ValidateVerifyEmailCodeQuery{Code: form.Code}
+
+	if err := bus.Dispatch(&query); err != nil {
+		if err == m.ErrInvalidEmailCode {
+			return ApiError(400, "Invalid or expired email verification code", nil)
+		}
+		return ApiError(500, "Unknown error validating email code", err)
+	}
+
+	cmd := m.VerifyUserEmailCommand{UserId: query.Result.Id}
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to verify email", err)
+	}
+
+	return ApiSuccess("Email verified")
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/events"
+	"github.com/Cepave/grafana/pkg/log"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/eventstream"
+)
+
+// Comment lines sent while idle so proxies don't drop the connection.
+var eventStreamKeepAlive = 30 * time.Second
+
+// canViewStreamEvent hides saves of dashboards whose acl doesn't let the
+// user view them.
+func canViewStreamEvent(c *middleware.Context, evt *events.OnTheWireEvent) bool {
+	saved, ok := evt.Payload.(*events.DashboardSaved)
+	if !ok {
+		return true
+	}
+
+	permission, err := dashboardPermission(c, saved.Id)
+	if err != nil {
+		log.Error(3, "Failed to check dashboard permissions for the event stream: %v", err)
+		return false
+	}
+	return permission >= m.PERMISSION_VIEW
+}
+
+// GET /api/events/stream
+func StreamEvents(c *middleware.Context) {
+	flusher, ok := c.Resp.(http.Flusher)
+	if !ok {
+		c.JsonApiErr(500, "Streaming is not supported", nil)
+		return
+	}
+
+	reqCtx, cancel := c.RequestContext()
+	defer cancel()
+
+	stream, unsubscribe := eventstream.Subscribe(c.OrgId)
+	defer unsubscribe()
+
+	header := c.Resp.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	c.Resp.WriteHeader(200)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-reqCtx.Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(c.Resp, ": keep-alive\n\n")
+		case evt := <-stream:
+			if !canViewStreamEvent(c, evt) {
+				continue
+			}
+			data, err := json.Marshal(evt)
+			if err != nil {
+				log.Error(3, "Failed to marshal %s for the event stream: %v", evt.EventType, err)
+				continue
+			}
+			fmt.Fprintf(c.Resp, "event: %s\ndata: %s\n\n", evt.EventType, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/events"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/eventstream"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStreamEvents(t *testing.T) {
+
+	Convey("Given a client listening to the event stream", t, func() {
+		bus.ClearBusHandlers()
+		eventstream.Init()
+		defer bus.ClearBusHandlers()
+
+		// dashboard 9 only lets editors view it
+		bus.AddHandler("test", func(query *m.GetDashboardAclQuery) error {
+			query.Result = nil
+			if query.DashboardId == 9 {
+				query.Result = []*m.DashboardAcl{{DashboardId: 9, Role: m.ROLE_EDITOR, Permission: m.PERMISSION_VIEW}}
+			}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, OrgRole: m.ROLE_VIEWER}, IsSignedIn: true})
+		})
+		mac.Get("/api/events/stream", StreamEvents)
+
+		server := httptest.NewServer(mac)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/api/events/stream")
+		So(err, ShouldBeNil)
+		defer resp.Body.Close()
+
+		So(resp.StatusCode, ShouldEqual, 200)
+		So(resp.Header.Get("Content-Type"), ShouldEqual, "text/event-stream")
+
+		Convey("Should receive dashboards saved in its org it may view only", func() {
+			So(bus.Publish(&events.DashboardSaved{Id: 7, OrgId: 2, Slug: "other-org"}), ShouldBeNil)
+			So(bus.Publish(&events.DashboardSaved{Id: 9, OrgId: 1, Slug: "restricted"}), ShouldBeNil)
+			So(bus.Publish(&events.DashboardSaved{Id: 8, OrgId: 1, Slug: "prod", Version: 3}), ShouldBeNil)
+
+			reader := bufio.NewReader(resp.Body)
+			line, err := reader.ReadString('\n')
+			So(err, ShouldBeNil)
+			So(line, ShouldEqual, "event: DashboardSaved\n")
+
+			line, err = reader.ReadString('\n')
+			So(err, ShouldBeNil)
+			So(line, ShouldStartWith, "data: ")
+
+			var evt struct {
+				EventType string                `json:"event_type"`
+				Payload   events.DashboardSaved `json:"payload"`
+			}
+			So(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt), ShouldBeNil)
+			So(evt.EventType, ShouldEqual, "DashboardSaved")
+			So(evt.Payload.Id, ShouldEqual, 8)
+			So(evt.Payload.Slug, ShouldEqual, "prod")
+			So(evt.Payload.Version, ShouldEqual, 3)
+		})
+	})
+}
@@ -14,16 +14,18 @@ import (
 
 func getFrontendSettingsMap(c *middleware.Context) (map[string]interface{}, error) {
 	orgDataSources := make([]*m.DataSource, 0)
+	featureFlags := make(map[string]bool)
 
 	if c.OrgId != 0 {
 		query := m.GetDataSourcesQuery{OrgId: c.OrgId}
-		err := bus.Dispatch(&query)
+		flagsQuery := m.GetOrgFeatureFlagsQuery{OrgId: c.OrgId}
 
-		if err != nil {
+		if err := bus.DispatchBatch([]bus.Msg{&query, &flagsQuery}); err != nil {
 			return nil, err
 		}
 
 		orgDataSources = query.Result
+		featureFlags = flagsQuery.Result
 	}
 
 	datasources := make(map[string]interface{})
@@ -33,7 +35,7 @@ func getFrontendSettingsMap(c *middleware.Context) (map[string]interface{}, erro
 		url := ds.Url
 
 		if ds.Access == m.DS_ACCESS_PROXY {
-			url = setting.AppSubUrl + "/api/datasources/proxy/" + strconv.FormatInt(ds.Id, 10)
+			url = setting.AppPath("/api/datasources/proxy/" + strconv.FormatInt(ds.Id, 10))
 		}
 
 		var dsMap = map[string]interface{}{
@@ -107,10 +109,12 @@ func getFrontendSettingsMap(c *middleware.Context) (map[string]interface{}, erro
 	}
 
 	jsonObj := map[string]interface{}{
-		"defaultDatasource": defaultDatasource,
-		"datasources":       datasources,
-		"appSubUrl":         setting.AppSubUrl,
-		"allowOrgCreate":    (setting.AllowUserOrgCreate && c.IsSignedIn) || c.IsGrafanaAdmin,
+		"defaultDatasource":       defaultDatasource,
+		"datasources":             datasources,
+		"appSubUrl":               setting.AppPath(""),
+		"allowOrgCreate":          (setting.AllowUserOrgCreate && c.IsSignedIn) || c.IsGrafanaAdmin,
+		"externalSnapshotEnabled": setting.ExternalSnapshotEnabled,
+		"featureFlags":            featureFlags,
 		"buildInfo": map[string]interface{}{
 			"version":    setting.BuildVersion,
 			"commit":     setting.BuildCommit,
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFrontendSettingsFeatureFlags(t *testing.T) {
+
+	Convey("Given an org with a feature flag turned on", t, func() {
+		defer bus.ClearBusHandlers()
+
+		bus.AddHandler("test", func(query *m.GetDataSourcesQuery) error {
+			query.Result = []*m.DataSource{}
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetOrgFeatureFlagsQuery) error {
+			query.Result = map[string]bool{"new_editor": query.OrgId == 2}
+			return nil
+		})
+
+		featureFlags := func(orgId int64) map[string]interface{} {
+			mac := macaron.New()
+			mac.Use(macaron.Renderer())
+			mac.Use(func(c *macaron.Context) {
+				c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: orgId}, IsSignedIn: true})
+			})
+			mac.Get("/api/frontend/settings", GetFrontendSettings)
+
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/frontend/settings", nil)
+			mac.ServeHTTP(resp, req)
+			So(resp.Code, ShouldEqual, 200)
+
+			var settings map[string]interface{}
+			So(json.Unmarshal(resp.Body.Bytes(), &settings), ShouldBeNil)
+			return settings["featureFlags"].(map[string]interface{})
+		}
+
+		Convey("Should be on in the frontend settings of that org", func() {
+			So(featureFlags(2)["new_editor"], ShouldEqual, true)
+		})
+
+		Convey("Should be off for other orgs", func() {
+			So(featureFlags(1)["new_editor"], ShouldEqual, false)
+		})
+	})
+}
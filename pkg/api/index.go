@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/Cepave/grafana/pkg/api/dtos"
 	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/setting"
 )
 
@@ -22,14 +23,10 @@ func setIndexViewData(c *middleware.Context) error {
 		OrgId:          c.OrgId,
 		OrgName:        c.OrgName,
 		OrgRole:        c.OrgRole,
-		GravatarUrl:    dtos.GetGravatarUrl(c.Email),
+		GravatarUrl:    m.GetAvatarUrl(c.Email),
 		IsGrafanaAdmin: c.IsGrafanaAdmin,
 	}
 
-	if setting.DisableGravatar {
-		currentUser.GravatarUrl = setting.AppSubUrl + "/img/user_profile.png"
-	}
-
 	if len(currentUser.Name) == 0 {
 		currentUser.Name = currentUser.Login
 	}
@@ -42,7 +39,7 @@ func setIndexViewData(c *middleware.Context) error {
 	c.Data["User"] = currentUser
 	c.Data["Settings"] = settings
 	c.Data["AppUrl"] = setting.AppUrl
-	c.Data["AppSubUrl"] = setting.AppSubUrl
+	c.Data["AppSubUrl"] = setting.AppPath("")
 
 	if setting.GoogleAnalyticsId != "" {
 		c.Data["GoogleAnalyticsId"] = setting.GoogleAnalyticsId
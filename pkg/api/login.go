@@ -2,10 +2,12 @@ package api
 
 import (
 	"database/sql"
+	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	l "log"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Cepave/grafana/pkg/api/dtos"
@@ -15,6 +17,7 @@ import (
 	"github.com/Cepave/grafana/pkg/metrics"
 	"github.com/Cepave/grafana/pkg/middleware"
 	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
 	"github.com/Cepave/grafana/pkg/setting"
 	"github.com/Cepave/grafana/pkg/util"
 )
@@ -40,8 +43,8 @@ func GetOpenFalconSessionUsername(sig string) string {
 		return ""
 	}
 
-	str := configOpenFalcon.Db.Addr
-	db, err := sql.Open("mysql", str)
+	str := configOpenFalcon.Db.ConnStr()
+	db, err := sql.Open(configOpenFalcon.Db.Type, str)
 	db.SetMaxOpenConns(2000)
 	db.SetMaxIdleConns(1000)
 	defer db.Close()
@@ -50,7 +53,7 @@ func GetOpenFalconSessionUsername(sig string) string {
 		return ""
 	}
 	
-	stmtOut, err := db.Prepare("SELECT id, uid, expired FROM uic.session WHERE sig = ?")
+	stmtOut, err := db.Prepare(uicQuery(configOpenFalcon.Db.Type, "id, uid, expired", "session", "sig"))
 	if err != nil {
 		l.Println(err.Error())
 		return ""
@@ -81,7 +84,7 @@ func GetOpenFalconSessionUsername(sig string) string {
 		return ""
 	}
 
-	stmtOut, err = db.Prepare("SELECT name FROM uic.user WHERE id = ?")
+	stmtOut, err = db.Prepare(uicQuery(configOpenFalcon.Db.Type, "name", "user", "id"))
 	if err != nil {
 		l.Println(err.Error())
 		return ""
@@ -98,6 +101,19 @@ func GetOpenFalconSessionUsername(sig string) string {
 	return name
 }
 
+// uicQuery selects columns from the uic table matching column in the dialect
+// of the open-falcon database. The table is quoted as user and session are
+// reserved words in postgres, which also numbers its placeholders.
+func uicQuery(dbType, columns, table, column string) string {
+	placeholder := "?"
+	if dbType == migrator.POSTGRES {
+		placeholder = "$1"
+	}
+
+	return fmt.Sprintf("SELECT %s FROM uic.%s WHERE %s = %s",
+		columns, migrator.NewDialect(dbType).Quote(table), column, placeholder)
+}
+
 /**
  * @function name:	func LoginWithOpenFalconCookie(c *middleware.Context) bool
  * @description:	This function gets user logged in if "sig" cookie of Open-Falcon is valid.
@@ -137,7 +153,7 @@ func LoginWithOpenFalconCookie(c *middleware.Context) bool {
 func LoginView(c *middleware.Context) {
 	isLoggedIn := LoginWithOpenFalconCookie(c)
 	if isLoggedIn {
-		c.Redirect(setting.AppSubUrl + "/")
+		c.Redirect(setting.AppPath("/"))
 		return
 	}
 
@@ -162,41 +178,65 @@ func LoginView(c *middleware.Context) {
 		return
 	}
 
-	c.Redirect(setting.AppSubUrl + "/")
+	c.Redirect(setting.AppPath("/"))
 }
 
+// rememberTokenGracePeriod is how long a remember me token rotated away is
+// still accepted, requests sent at the same time all carry it.
+const rememberTokenGracePeriod = 30 * time.Second
+
 func tryLoginUsingRememberCookie(c *middleware.Context) bool {
 	// Check auto-login.
-	uname := c.GetCookie(setting.CookieUserName)
-	if len(uname) == 0 {
+	cookie := c.GetCookie(middleware.RememberCookieName())
+	if len(cookie) == 0 {
 		return false
 	}
 
 	isSucceed := false
 	defer func() {
 		if !isSucceed {
-			log.Trace("auto-login cookie cleared: %s", uname)
-			c.SetCookie(setting.CookieUserName, "", -1, setting.AppSubUrl+"/")
-			c.SetCookie(setting.CookieRememberName, "", -1, setting.AppSubUrl+"/")
+			log.Trace("auto-login cookie cleared")
+			c.SetAuthCookie(middleware.UserCookieName(), "", -1, setting.AppSubUrl+"/")
+			c.SetAuthCookie(middleware.RememberCookieName(), "", -1, setting.AppSubUrl+"/")
 			return
 		}
 	}()
 
-	userQuery := m.GetUserByLoginQuery{LoginOrEmail: uname}
-	if err := bus.Dispatch(&userQuery); err != nil {
+	parts := strings.SplitN(cookie, ":", 2)
+	if len(parts) != 2 {
 		return false
 	}
 
-	user := userQuery.Result
+	series, newToken := parts[0], newRememberToken()
+	cmd := m.RotateRememberTokenCommand{
+		Series:      series,
+		Token:       parts[1],
+		NewToken:    newToken,
+		MaxLifetime: setting.LoginMaxLifetime,
+		GracePeriod: rememberTokenGracePeriod,
+	}
+	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrRememberTokenReused {
+			log.Warn("Remember me token reused, series invalidated")
+		}
+		return false
+	}
 
-	// validate remember me cookie
-	if val, _ := c.GetSuperSecureCookie(
-		util.EncodeMd5(user.Rands+user.Password), setting.CookieRememberName); val != user.Login {
+	userQuery := m.GetUserByIdQuery{Id: cmd.Result.UserId}
+	if err := bus.Dispatch(&userQuery); err != nil || userQuery.Result.IsDisabled {
+		return false
+	}
+	if user := userQuery.Result; setting.RequireEmailVerification && !user.EmailVerified && !user.IsAdmin {
 		return false
 	}
 
 	isSucceed = true
-	loginUserWithUser(user, c)
+	if !cmd.Rotated {
+		// a concurrent request already rotated the token and hands out the
+		// new cookie, leave it alone
+		newToken = ""
+	}
+	setLoginCookies(userQuery.Result, c, series, newToken, cmd.Result.Created)
 	return true
 }
 
@@ -209,7 +249,19 @@ func LoginApiPing(c *middleware.Context) {
 	c.JsonOK("Logged in")
 }
 
+// GetCSRFToken hands the CSRF token to clients that can't read the cookie,
+// it has to be sent back in the X-XSRF-TOKEN header.
+func GetCSRFToken(c *middleware.Context) Response {
+	return Json(200, util.DynMap{"token": c.CSRFToken()})
+}
+
 func LoginPost(c *middleware.Context, cmd dtos.LoginCommand) Response {
+	if lockedFor := login.Attempts.LockedFor(cmd.User); lockedFor > 0 {
+		retryAfter := int((lockedFor + time.Second - 1) / time.Second)
+		return ApiError(429, "Too many failed login attempts, try again later", nil).
+			Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+
 	authQuery := login.LoginUserQuery{
 		Username: cmd.User,
 		Password: cmd.Password,
@@ -217,14 +269,24 @@ func LoginPost(c *middleware.Context, cmd dtos.LoginCommand) Response {
 
 	if err := bus.Dispatch(&authQuery); err != nil {
 		if err == login.ErrInvalidCredentials {
+			login.Attempts.Failed(cmd.User)
 			return ApiError(401, "Invalid username or password", err)
 		}
+		if err == m.ErrEmailNotVerified {
+			login.Attempts.Reset(cmd.User)
+			return ApiError(403, "Email address is not verified", err)
+		}
 
 		return ApiError(500, "Error while trying to authenticate user", err)
 	}
 
+	login.Attempts.Reset(cmd.User)
 	user := authQuery.User
 
+	if user.IsDisabled {
+		return ApiError(401, "User is disabled", m.ErrUserDisabled)
+	}
+
 	loginUserWithUser(user, c)
 
 	result := map[string]interface{}{
@@ -246,16 +308,102 @@ func loginUserWithUser(user *m.User, c *middleware.Context) {
 		log.Error(3, "User login with nil user")
 	}
 
+	series, token := newRememberToken(), newRememberToken()
+	cmd := m.CreateRememberTokenCommand{UserId: user.Id, Series: series, Token: token}
+	if err := bus.Dispatch(&cmd); err != nil {
+		log.Error(3, "Failed to store remember me token: %v", err)
+		series = ""
+	}
+
+	setLoginCookies(user, c, series, token, time.Now())
+}
+
+func newRememberToken() string {
+	return util.GetRandomString(32)
+}
+
+// setLoginCookies signs the user in and hands out the remember me cookie for
+// the given series, unless there is no series or token. loginTime is when the
+// user last entered their credentials, renewals keep the original one as
+// well as the user session of the series.
+func setLoginCookies(user *m.User, c *middleware.Context, series string, token string, loginTime time.Time) {
 	days := 86400 * setting.LogInRememberDays
-	c.SetCookie(setting.CookieUserName, user.Login, days, setting.AppSubUrl+"/")
-	c.SetSuperSecureCookie(util.EncodeMd5(user.Rands+user.Password), setting.CookieRememberName, user.Login, days, setting.AppSubUrl+"/")
+	c.SetAuthCookie(middleware.UserCookieName(), user.Login, days, setting.AppSubUrl+"/")
+	if series != "" && token != "" {
+		c.SetAuthCookie(middleware.RememberCookieName(), series+":"+token, days, setting.AppSubUrl+"/")
+	}
 
 	c.Session.Set(middleware.SESS_KEY_USERID, user.Id)
+	c.Session.Set(middleware.SESS_KEY_LOGIN_TIME, loginTime.Unix())
+
+	sessionCmd := m.CreateUserSessionCommand{
+		UserId:         user.Id,
+		Ip:             middleware.ClientIP(c.Req.Request).String(),
+		UserAgent:      c.Req.UserAgent(),
+		RememberSeries: series,
+	}
+	if err := bus.Dispatch(&sessionCmd); err != nil {
+		log.Error(3, "Failed to store user session: %v", err)
+		return
+	}
+	c.Session.Set(middleware.SESS_KEY_USER_SESSION, sessionCmd.Result.Id)
+}
+
+// rememberSeries is the series of the request's remember me cookie.
+func rememberSeries(c *middleware.Context) string {
+	return strings.SplitN(c.GetCookie(middleware.RememberCookieName()), ":", 2)[0]
+}
+
+func clearLoginCookies(c *middleware.Context) {
+	c.SetAuthCookie(middleware.UserCookieName(), "", -1, setting.AppSubUrl+"/")
+	c.SetAuthCookie(middleware.RememberCookieName(), "", -1, setting.AppSubUrl+"/")
+	if err := c.Session.Destory(c); err != nil {
+		log.Error(3, "Failed to destroy session: %v", err)
+	}
 }
 
 func Logout(c *middleware.Context) {
-	c.SetCookie(setting.CookieUserName, "", -1, setting.AppSubUrl+"/")
-	c.SetCookie(setting.CookieRememberName, "", -1, setting.AppSubUrl+"/")
-	c.Session.Destory(c)
-	c.Redirect(setting.AppSubUrl + "/login")
-}
\ No newline at end of file
+	if series := rememberSeries(c); series != "" {
+		if err := bus.Dispatch(&m.DeleteRememberTokenCommand{Series: series}); err != nil {
+			log.Error(3, "Failed to delete remember me token: %v", err)
+		}
+	}
+
+	if id, ok := c.Session.Get(middleware.SESS_KEY_USER_SESSION).(int64); ok {
+		if err := bus.Dispatch(&m.DeleteUserSessionCommand{Id: id, UserId: c.UserId}); err != nil && err != m.ErrUserSessionNotFound {
+			log.Error(3, "Failed to delete user session: %v", err)
+		}
+	}
+
+	clearLoginCookies(c)
+
+	if strings.Contains(c.Req.Header.Get("Accept"), "application/json") {
+		c.JsonOK("Logged out")
+		return
+	}
+
+	c.Redirect(setting.AppPath("/login"))
+}
+
+// POST /api/user/logout-all
+// Signs the user out on every device. With keepCurrent=true the session and
+// remember me cookie of this request stay valid.
+func LogoutAll(c *middleware.Context) Response {
+	keepCurrent := c.Query("keepCurrent") == "true"
+
+	cmd := m.DeleteUserSessionsCommand{UserId: c.UserId}
+	if keepCurrent {
+		cmd.KeepId, _ = c.Session.Get(middleware.SESS_KEY_USER_SESSION).(int64)
+		cmd.KeepSeries = rememberSeries(c)
+	}
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to sign out sessions", err)
+	}
+
+	if !keepCurrent {
+		clearLoginCookies(c)
+	}
+
+	return ApiSuccess("Signed out of all sessions")
+}
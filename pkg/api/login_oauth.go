@@ -1,9 +1,9 @@
 package api
 
 import (
-	"errors"
 	"fmt"
 	"net/url"
+	"sort"
 
 	"golang.org/x/oauth2"
 
@@ -14,18 +14,28 @@ import (
 	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/setting"
 	"github.com/Cepave/grafana/pkg/social"
+	"github.com/Cepave/grafana/pkg/util"
 )
 
-func OAuthLogin(ctx *middleware.Context) {
-	if setting.OAuthService == nil {
-		ctx.Handle(404, "login.OAuthLogin(oauth service not enabled)", nil)
-		return
+// enabledOAuthProviders lists the configured social logins by name.
+func enabledOAuthProviders() []string {
+	names := make([]string, 0, len(social.SocialMap))
+	for name := range social.SocialMap {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
+func OAuthLogin(ctx *middleware.Context) {
 	name := ctx.Params(":name")
 	connect, ok := social.SocialMap[name]
-	if !ok {
-		ctx.Handle(404, "login.OAuthLogin(social login not enabled)", errors.New(name))
+	if setting.OAuthService == nil || !ok {
+		log.Info("OAuth login attempt with unknown provider, %s", name)
+		ctx.JSON(404, util.DynMap{
+			"message":          fmt.Sprintf("OAuth provider %q is not enabled", name),
+			"enabledProviders": enabledOAuthProviders(),
+		})
 		return
 	}
 
@@ -47,9 +57,9 @@ func OAuthLogin(ctx *middleware.Context) {
 	userInfo, err := connect.UserInfo(token)
 	if err != nil {
 		if err == social.ErrMissingTeamMembership {
-			ctx.Redirect(setting.AppSubUrl + "/login?failedMsg=" + url.QueryEscape("Required Github team membership not fulfilled"))
+			ctx.Redirect(setting.AppPath("/login?failedMsg=") + url.QueryEscape("Required Github team membership not fulfilled"))
 		} else if err == social.ErrMissingOrganizationMembership {
-			ctx.Redirect(setting.AppSubUrl + "/login?failedMsg=" + url.QueryEscape("Required Github organization membership not fulfilled"))
+			ctx.Redirect(setting.AppPath("/login?failedMsg=") + url.QueryEscape("Required Github organization membership not fulfilled"))
 		} else {
 			ctx.Handle(500, fmt.Sprintf("login.OAuthLogin(get info from %s)", name), err)
 		}
@@ -61,7 +71,7 @@ func OAuthLogin(ctx *middleware.Context) {
 	// validate that the email is allowed to login to grafana
 	if !connect.IsEmailAllowed(userInfo.Email) {
 		log.Info("OAuth login attempt with unallowed email, %s", userInfo.Email)
-		ctx.Redirect(setting.AppSubUrl + "/login?failedMsg=" + url.QueryEscape("Required email domain not fulfilled"))
+		ctx.Redirect(setting.AppPath("/login?failedMsg=") + url.QueryEscape("Required email domain not fulfilled"))
 		return
 	}
 
@@ -71,7 +81,7 @@ func OAuthLogin(ctx *middleware.Context) {
 	// create account if missing
 	if err == m.ErrUserNotFound {
 		if !connect.IsSignupAllowed() {
-			ctx.Redirect(setting.AppSubUrl + "/login")
+			ctx.Redirect(setting.AppPath("/login"))
 			return
 		}
 		limitReached, err := middleware.QuotaReached(ctx, "user")
@@ -80,14 +90,15 @@ func OAuthLogin(ctx *middleware.Context) {
 			return
 		}
 		if limitReached {
-			ctx.Redirect(setting.AppSubUrl + "/login")
+			ctx.Redirect(setting.AppPath("/login"))
 			return
 		}
 		cmd := m.CreateUserCommand{
-			Login:   userInfo.Email,
-			Email:   userInfo.Email,
-			Name:    userInfo.Name,
-			Company: userInfo.Company,
+			Login:         userInfo.Email,
+			Email:         userInfo.Email,
+			Name:          userInfo.Name,
+			Company:       userInfo.Company,
+			EmailVerified: true,
 		}
 
 		if err = bus.Dispatch(&cmd); err != nil {
@@ -105,5 +116,5 @@ func OAuthLogin(ctx *middleware.Context) {
 
 	metrics.M_Api_Login_OAuth.Inc(1)
 
-	ctx.Redirect(setting.AppSubUrl + "/")
+	ctx.Redirect(setting.AppPath("/"))
 }
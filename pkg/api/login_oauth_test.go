@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Cepave/grafana/pkg/social"
+	"github.com/Unknwon/macaron"
+)
+
+func TestOAuthLogin(t *testing.T) {
+
+	Convey("Given github as the only social login", t, func() {
+		oauthService, socialMap := setting.OAuthService, social.SocialMap
+		defer func() {
+			setting.OAuthService, social.SocialMap = oauthService, socialMap
+		}()
+
+		setting.OAuthService = &setting.OAuther{GitHub: true}
+		social.SocialMap = map[string]social.SocialConnector{"github": &social.SocialGithub{}}
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{}})
+		})
+		mac.Get("/login/:name", OAuthLogin)
+
+		Convey("Should answer 404 with the enabled providers for an unknown one", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/login/gitlab", nil)
+			mac.ServeHTTP(resp, req)
+
+			So(resp.Code, ShouldEqual, 404)
+
+			var body struct {
+				Message          string
+				EnabledProviders []string
+			}
+			So(json.Unmarshal(resp.Body.Bytes(), &body), ShouldBeNil)
+			So(body.Message, ShouldContainSubstring, "gitlab")
+			So(body.EnabledProviders, ShouldResemble, []string{"github"})
+		})
+	})
+}
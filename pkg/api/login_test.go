@@ -0,0 +1,303 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/login"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Unknwon/macaron"
+	"github.com/macaron-contrib/binding"
+	"github.com/macaron-contrib/session"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoginThrottling(t *testing.T) {
+
+	Convey("Given login throttling after 3 failed attempts", t, func() {
+		defer bus.ClearBusHandlers()
+
+		setting.LoginMaxFailedAttempts = 3
+		setting.LoginLockoutSeconds = 60
+
+		login.Attempts = login.NewThrottle()
+
+		bus.AddHandler("test", func(query *login.LoginUserQuery) error {
+			if query.Username != "admin" || query.Password != "secret" {
+				return login.ErrInvalidCredentials
+			}
+			query.User = &m.User{Id: 1, Login: "admin"}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{}, Session: &middleware.SessionWrapper{}})
+		})
+		mac.Post("/login", binding.Bind(dtos.LoginCommand{}), wrap(LoginPost))
+
+		post := func(user, password string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(dtos.LoginCommand{User: user, Password: password})
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/login", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should lock the login name once the threshold is hit", func() {
+			for i := 0; i < 3; i++ {
+				So(post("admin", "wrong").Code, ShouldEqual, 401)
+			}
+
+			resp := post("admin", "secret")
+			So(resp.Code, ShouldEqual, 429)
+			So(resp.Header().Get("Retry-After"), ShouldEqual, "60")
+		})
+
+		Convey("Should answer the same for unknown login names", func() {
+			for i := 0; i < 3; i++ {
+				So(post("nobody", "wrong").Code, ShouldEqual, 401)
+			}
+
+			resp := post("nobody", "wrong")
+			So(resp.Code, ShouldEqual, 429)
+			So(resp.Header().Get("Retry-After"), ShouldEqual, "60")
+		})
+
+		Convey("Should reset the counter on a successful login", func() {
+			So(post("admin", "wrong").Code, ShouldEqual, 401)
+			So(post("admin", "wrong").Code, ShouldEqual, 401)
+			So(post("admin", "secret").Code, ShouldEqual, 200)
+			So(post("admin", "wrong").Code, ShouldEqual, 401)
+			So(post("admin", "wrong").Code, ShouldEqual, 401)
+			So(post("admin", "secret").Code, ShouldEqual, 200)
+		})
+	})
+}
+
+func TestLoginEmailVerification(t *testing.T) {
+
+	Convey("Given a user whose email is not verified", t, func() {
+		defer bus.ClearBusHandlers()
+
+		setting.LoginMaxFailedAttempts = 3
+		login.Attempts = login.NewThrottle()
+
+		bus.AddHandler("test", func(query *login.LoginUserQuery) error {
+			return m.ErrEmailNotVerified
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{}, Session: &middleware.SessionWrapper{}})
+		})
+		mac.Post("/login", binding.Bind(dtos.LoginCommand{}), wrap(LoginPost))
+
+		body, _ := json.Marshal(dtos.LoginCommand{User: "user", Password: "secret"})
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		mac.ServeHTTP(resp, req)
+
+		Convey("Should refuse the login with a specific error", func() {
+			So(resp.Code, ShouldEqual, 403)
+			So(resp.Body.String(), ShouldContainSubstring, "Email address is not verified")
+		})
+	})
+}
+
+func TestLoginApiPing(t *testing.T) {
+
+	Convey("Given a remember me cookie", t, func() {
+		defer bus.ClearBusHandlers()
+
+		setting.CookieUserName = "grafana_user"
+		setting.CookieRememberName = "grafana_remember"
+
+		var rotated *m.RotateRememberTokenCommand
+		bus.AddHandler("test", func(cmd *m.RotateRememberTokenCommand) error {
+			if cmd.Series == "expired" {
+				return m.ErrRememberTokenExpired
+			}
+			if cmd.Series != "series" {
+				return m.ErrRememberTokenNotFound
+			}
+			cmd.Result = &m.RememberToken{UserId: 1}
+			if cmd.Token == "previous" {
+				return nil
+			}
+			if cmd.Token != "current" {
+				return m.ErrRememberTokenReused
+			}
+			rotated = cmd
+			cmd.Rotated = true
+			return nil
+		})
+
+		bus.AddHandler("test", func(query *m.GetUserByIdQuery) error {
+			query.Result = &m.User{Id: query.Id, Login: "admin", EmailVerified: !setting.RequireEmailVerification}
+			return nil
+		})
+
+		var renewed *m.CreateUserSessionCommand
+		bus.AddHandler("test", func(cmd *m.CreateUserSessionCommand) error {
+			renewed = cmd
+			cmd.Result = &m.UserSession{Id: 7, UserId: cmd.UserId}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{}, Session: &middleware.SessionWrapper{}})
+		})
+		mac.Get("/api/login/ping", LoginApiPing)
+
+		ping := func(cookie string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/login/ping", nil)
+			req.AddCookie(&http.Cookie{Name: setting.CookieRememberName, Value: cookie})
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		rememberCookie := func(resp *httptest.ResponseRecorder) string {
+			for _, header := range resp.Header()["Set-Cookie"] {
+				if strings.HasPrefix(header, setting.CookieRememberName+"=") {
+					return strings.SplitN(strings.TrimPrefix(header, setting.CookieRememberName+"="), ";", 2)[0]
+				}
+			}
+			return ""
+		}
+
+		Convey("Should rotate the token on a successful ping", func() {
+			resp := ping("series:current")
+			So(resp.Code, ShouldEqual, 200)
+			So(rotated, ShouldNotBeNil)
+
+			cookie, _ := url.QueryUnescape(rememberCookie(resp))
+			So(cookie, ShouldEqual, "series:"+rotated.NewToken)
+			So(rotated.NewToken, ShouldNotEqual, "current")
+			So(rotated.MaxLifetime, ShouldEqual, setting.LoginMaxLifetime)
+			So(renewed.RememberSeries, ShouldEqual, "series")
+		})
+
+		Convey("Should keep the cookie when a concurrent request rotated the token", func() {
+			resp := ping("series:previous")
+			So(resp.Code, ShouldEqual, 200)
+			So(rememberCookie(resp), ShouldEqual, "")
+			So(renewed.RememberSeries, ShouldEqual, "series")
+		})
+
+		Convey("Should refuse to renew a login past its maximum lifetime", func() {
+			resp := ping("expired:current")
+			So(resp.Code, ShouldEqual, 401)
+			So(rememberCookie(resp), ShouldEqual, "")
+		})
+
+		Convey("Should refuse to renew the login of an unverified email", func() {
+			defer func(old bool) { setting.RequireEmailVerification = old }(setting.RequireEmailVerification)
+			setting.RequireEmailVerification = true
+
+			resp := ping("series:current")
+			So(resp.Code, ShouldEqual, 401)
+			So(rememberCookie(resp), ShouldEqual, "")
+		})
+
+		Convey("Should reject and clear a reused token", func() {
+			resp := ping("series:stale")
+			So(resp.Code, ShouldEqual, 401)
+			So(rememberCookie(resp), ShouldEqual, "")
+			So(resp.Header().Get("Set-Cookie"), ShouldContainSubstring, "Max-Age=0")
+		})
+	})
+}
+
+func TestLogout(t *testing.T) {
+
+	Convey("Given a signed in session", t, func() {
+		defer bus.ClearBusHandlers()
+
+		setting.CookieUserName = "grafana_user"
+		setting.CookieRememberName = "grafana_remember"
+
+		var deletedSeries string
+		bus.AddHandler("test", func(cmd *m.DeleteRememberTokenCommand) error {
+			deletedSeries = cmd.Series
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(middleware.GetContextHandler())
+		mac.Use(middleware.Sessioner(&session.Options{}))
+		mac.Get("/login", func(c *middleware.Context) {
+			c.Session.Set(middleware.SESS_KEY_USERID, int64(1))
+		})
+		mac.Get("/session", func(c *middleware.Context) {
+			c.JSON(200, c.Session.Get(middleware.SESS_KEY_USERID))
+		})
+		mac.Get("/logout", Logout)
+
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/login", nil)
+		mac.ServeHTTP(resp, req)
+		sessionCookie := strings.SplitN(resp.Header().Get("Set-Cookie"), ";", 2)[0]
+		So(sessionCookie, ShouldStartWith, "grafana_sess=")
+
+		get := func(path string, accept string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			req.Header.Set("Cookie", sessionCookie+"; grafana_remember=series:token")
+			req.Header.Set("Accept", accept)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		So(get("/session", "").Body.String(), ShouldEqual, "1")
+
+		Convey("Should destroy the session and the remember me series", func() {
+			resp := get("/logout", "application/json")
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Body.String(), ShouldContainSubstring, "Logged out")
+			So(deletedSeries, ShouldEqual, "series")
+
+			So(get("/session", "").Body.String(), ShouldEqual, "null")
+		})
+
+		Convey("Should redirect browsers to the login page", func() {
+			resp := get("/logout", "text/html")
+			So(resp.Code, ShouldEqual, 302)
+			So(resp.Header().Get("Location"), ShouldEqual, setting.AppSubUrl+"/login")
+		})
+	})
+}
+
+func TestOpenFalconSessionQuery(t *testing.T) {
+
+	Convey("When querying the open-falcon sessions", t, func() {
+		Convey("Should keep mysql placeholders", func() {
+			So(uicQuery("mysql", "id, uid, expired", "session", "sig"), ShouldEqual, "SELECT id, uid, expired FROM uic.`session` WHERE sig = ?")
+		})
+
+		Convey("Should number the placeholder for postgres", func() {
+			So(uicQuery("postgres", "name", "user", "id"), ShouldEqual, `SELECT name FROM uic."user" WHERE id = $1`)
+		})
+
+		Convey("Should quote the table for sqlite3", func() {
+			So(uicQuery("sqlite3", "name", "user", "id"), ShouldEqual, "SELECT name FROM uic.`user` WHERE id = ?")
+		})
+	})
+}
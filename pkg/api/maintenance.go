@@ -0,0 +1,36 @@
+package api
+
+import (
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// GET /api/admin/maintenance
+func GetMaintenance(c *middleware.Context) Response {
+	query := m.GetMaintenanceQuery{}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get maintenance mode", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// PUT /api/admin/maintenance
+func SetMaintenance(c *middleware.Context, cmd m.SetMaintenanceCommand) Response {
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to set maintenance mode", err)
+	}
+
+	if cmd.Enabled {
+		return ApiSuccess("Maintenance mode enabled")
+	}
+	return ApiSuccess("Maintenance mode disabled")
+}
+
+// GET /healthz
+func Healthz(c *middleware.Context) {
+	c.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.Resp.WriteHeader(200)
+	c.Resp.Write([]byte("ok\n"))
+}
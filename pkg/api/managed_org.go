@@ -0,0 +1,40 @@
+package api
+
+import (
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// GET /api/admin/users/:id/managed-orgs
+func GetManagedOrgs(c *middleware.Context) Response {
+	query := m.GetManagedOrgsQuery{UserId: c.ParamsInt64(":id")}
+
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get managed organizations", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// POST /api/admin/users/:id/managed-orgs
+func AddManagedOrg(c *middleware.Context, cmd m.AddManagedOrgCommand) Response {
+	cmd.UserId = c.ParamsInt64(":id")
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to add managed organization", err)
+	}
+
+	return ApiSuccess("Managed organization added")
+}
+
+// DELETE /api/admin/users/:id/managed-orgs/:orgId
+func RemoveManagedOrg(c *middleware.Context) Response {
+	cmd := m.RemoveManagedOrgCommand{UserId: c.ParamsInt64(":id"), OrgId: c.ParamsInt64(":orgId")}
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to remove managed organization", err)
+	}
+
+	return ApiSuccess("Managed organization removed")
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/metrics"
 	"github.com/Cepave/grafana/pkg/middleware"
 	"math/rand"
+	"net/http"
 	"strconv"
 )
 
@@ -34,3 +36,9 @@ func GetTestMetrics(c *middleware.Context) {
 
 	c.JSON(200, &result)
 }
+
+// GetPrometheusMetrics exposes the http request stats in prometheus text format.
+func GetPrometheusMetrics(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.RequestStats.WritePrometheus(w)
+}
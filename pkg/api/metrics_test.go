@@ -0,0 +1,115 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/metrics"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+
+	Convey("Given routes registered with request metrics", t, func() {
+		metrics.RequestStats = metrics.NewRequestStatsRegistry()
+
+		mac := macaron.New()
+		r := newRouteRegister(mac, "")
+		r.Get("/metrics", GetPrometheusMetrics)
+		r.Group("/api", func() {
+			r.Get("/dashboards/db/:slug", func(c *macaron.Context) string {
+				return c.Params(":slug")
+			})
+			r.Group("/admin", func() {
+				r.Get("/settings", func() string { return "settings" })
+			}, func(c *macaron.Context) {
+				c.Resp.WriteHeader(401)
+			})
+		})
+
+		request := func(method, url string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, url, nil)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		request("GET", "/api/dashboards/db/first")
+		request("GET", "/api/dashboards/db/second")
+		request("GET", "/api/admin/settings")
+
+		resp := request("GET", "/metrics")
+		body := resp.Body.String()
+
+		Convey("Should serve prometheus text format", func() {
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Header().Get("Content-Type"), ShouldStartWith, "text/plain")
+			So(body, ShouldContainSubstring, "# TYPE grafana_http_requests_total counter")
+			So(body, ShouldContainSubstring, "# TYPE grafana_http_request_duration_seconds histogram")
+		})
+
+		Convey("Should label requests by route template", func() {
+			So(body, ShouldContainSubstring, `grafana_http_requests_total{method="GET",route="/api/dashboards/db/:slug",status="2xx"} 2`)
+			So(body, ShouldContainSubstring, `grafana_http_request_duration_seconds_count{method="GET",route="/api/dashboards/db/:slug"} 2`)
+			So(body, ShouldNotContainSubstring, "first")
+		})
+
+		Convey("Should count requests rejected by group handlers", func() {
+			So(body, ShouldContainSubstring, `grafana_http_requests_total{method="GET",route="/api/admin/settings",status="4xx"} 1`)
+		})
+	})
+}
+
+func TestRouteRegisterRootPath(t *testing.T) {
+
+	Convey("Given routes registered under a root path", t, func() {
+		metrics.RequestStats = metrics.NewRequestStatsRegistry()
+
+		mac := macaron.New()
+		r := newRouteRegister(mac, normalizeRootPath("grafana/"))
+		r.Get("/home", func() string { return "home" })
+		r.Get("/metrics", GetPrometheusMetrics)
+		r.Group("/api", func() {
+			r.Get("/dashboards/db/:slug", func(c *macaron.Context) string {
+				return c.Params(":slug")
+			})
+		})
+
+		request := func(url string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", url, nil)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should resolve routes under the root path", func() {
+			So(request("/grafana/home").Body.String(), ShouldEqual, "home")
+			So(request("/grafana/api/dashboards/db/first").Body.String(), ShouldEqual, "first")
+		})
+
+		Convey("Should not resolve routes without the root path", func() {
+			So(request("/home").Code, ShouldEqual, 404)
+			So(request("/api/dashboards/db/first").Code, ShouldEqual, 404)
+		})
+
+		Convey("Should leave the root path out of metrics labels", func() {
+			request("/grafana/api/dashboards/db/first")
+			body := request("/grafana/metrics").Body.String()
+			So(body, ShouldContainSubstring, `route="/api/dashboards/db/:slug"`)
+		})
+	})
+
+	Convey("When computing the homepage url", t, func() {
+		So(homepageUrl(&GlobalConfig{}), ShouldEqual, "/")
+
+		defer func(old string) { setting.RootPath = old }(setting.RootPath)
+		setting.RootPath = "/grafana"
+
+		So(homepageUrl(&GlobalConfig{Home: "http://example.com/"}), ShouldEqual, "http://example.com/")
+		So(homepageUrl(&GlobalConfig{Home: "/dashboard/db/home"}), ShouldEqual, "/grafana/dashboard/db/home")
+		So(homepageUrl(&GlobalConfig{}), ShouldEqual, "/grafana/")
+	})
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/util"
+)
+
+const (
+	openFalconDefaultCounterLimit    = 500
+	openFalconMaxCounterLimit        = 5000
+	openFalconDefaultAnnotationLimit = 100
+	openFalconMaxAnnotationLimit     = 1000
+)
+
+var errOpenFalconNotConfigured = errors.New("No OpenFalcon data source configured")
+
+var openFalconClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: dataProxyTransport,
+}
+
+// openFalconEventCase is one alarm from the OpenFalcon alarm history api.
+type openFalconEventCase struct {
+	Id          string `json:"id"`
+	Endpoint    string `json:"endpoint"`
+	Metric      string `json:"metric"`
+	Func        string `json:"func"`
+	Cond        string `json:"cond"`
+	Note        string `json:"note"`
+	Priority    int    `json:"priority"`
+	Status      string `json:"status"`
+	Timestamp   string `json:"timestamp"`
+	CurrentStep int    `json:"current_step"`
+	MaxStep     int    `json:"max_step"`
+}
+
+type openFalconCountersResponse struct {
+	Msg  string          `json:"msg"`
+	Data [][]interface{} `json:"data"`
+}
+
+// getOpenFalconQueryAddr returns the base url of the OpenFalcon query api,
+// falling back to the org's OpenFalcon data source when none is configured.
+func getOpenFalconQueryAddr(orgId int64) (string, error) {
+	if cfg := GetGlobalConfig(); cfg != nil && cfg.OpenFalcon != nil && cfg.OpenFalcon.Enabled {
+		return cfg.OpenFalcon.QueryAddr, nil
+	}
+
+	query := m.GetDataSourcesQuery{OrgId: orgId}
+	if err := bus.Dispatch(&query); err != nil {
+		return "", err
+	}
+
+	for _, ds := range query.Result {
+		if ds.Type == m.DS_OPENFALCON && ds.Url != "" {
+			return ds.Url, nil
+		}
+	}
+
+	return "", errOpenFalconNotConfigured
+}
+
+// getOpenFalconAlarmAddr returns the base url of the OpenFalcon alarm api,
+// falling back to the url of the data source when none is configured.
+func getOpenFalconAlarmAddr(ds *m.DataSource) string {
+	if cfg := GetGlobalConfig(); cfg != nil && cfg.OpenFalcon != nil && cfg.OpenFalcon.Enabled {
+		return cfg.OpenFalcon.AlarmAddr
+	}
+	return ds.Url
+}
+
+// pingOpenFalcon checks that the OpenFalcon query api at queryAddr is reachable
+// and reports itself as healthy.
+func pingOpenFalcon(queryAddr string) error {
+	resp, err := openFalconClient.Get(util.JoinUrlFragments(queryAddr, "/health"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("OpenFalcon responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// queryOpenFalconCounters asks the OpenFalcon query api for the counters of an
+// endpoint and returns the names starting with prefix, at most limit of them.
+func queryOpenFalconCounters(queryAddr string, endpoint string, prefix string, limit int) ([]string, error) {
+	endpoints, _ := json.Marshal([]string{endpoint})
+
+	form := url.Values{}
+	form.Set("endpoints", string(endpoints))
+	form.Set("q", prefix)
+	form.Set("limit", "")
+
+	resp, err := openFalconClient.PostForm(util.JoinUrlFragments(queryAddr, "/api/counters"), form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("OpenFalcon responded with status %d", resp.StatusCode)
+	}
+
+	var body openFalconCountersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Msg != "" {
+		return nil, errors.New(body.Msg)
+	}
+
+	counters := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, row := range body.Data {
+		if len(row) == 0 {
+			continue
+		}
+		counter, ok := row[0].(string)
+		if !ok || seen[counter] || !strings.HasPrefix(counter, prefix) {
+			continue
+		}
+
+		seen[counter] = true
+		counters = append(counters, counter)
+		if len(counters) >= limit {
+			break
+		}
+	}
+
+	return counters, nil
+}
+
+func GetOpenFalconCounters(c *middleware.Context) Response {
+	endpoint := c.Query("endpoint")
+	if endpoint == "" {
+		return ApiError(400, "Missing endpoint", nil)
+	}
+
+	limit := c.QueryInt("limit")
+	if limit <= 0 {
+		limit = openFalconDefaultCounterLimit
+	}
+	if limit > openFalconMaxCounterLimit {
+		limit = openFalconMaxCounterLimit
+	}
+
+	queryAddr, err := getOpenFalconQueryAddr(c.OrgId)
+	if err == errOpenFalconNotConfigured {
+		return ApiError(404, err.Error(), nil)
+	} else if err != nil {
+		return ApiError(500, "Failed to query datasources", err)
+	}
+
+	counters, err := queryOpenFalconCounters(queryAddr, endpoint, c.Query("q"), limit)
+	if err != nil {
+		return ApiError(502, "Failed to query OpenFalcon counters", err)
+	}
+
+	return Json(200, counters)
+}
+
+// queryOpenFalconAlarms fetches the alarms raised between from and to from
+// the OpenFalcon alarm history api at alarmAddr.
+func queryOpenFalconAlarms(alarmAddr string, from time.Time, to time.Time, limit int) ([]openFalconEventCase, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"startTime": from.Unix(),
+		"endTime":   to.Unix(),
+		"limit":     limit,
+	})
+
+	resp, err := openFalconClient.Post(util.JoinUrlFragments(alarmAddr, "/api/v1/alarm/eventcases"), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("OpenFalcon responded with status %d", resp.StatusCode)
+	}
+
+	alarms := make([]openFalconEventCase, 0)
+	if err := json.NewDecoder(resp.Body).Decode(&alarms); err != nil {
+		return nil, err
+	}
+
+	return alarms, nil
+}
+
+// openFalconAlarmsToAnnotations converts alarms into grafana annotations,
+// skipping alarms without a parsable timestamp.
+func openFalconAlarmsToAnnotations(alarms []openFalconEventCase) []*dtos.Annotation {
+	annotations := make([]*dtos.Annotation, 0, len(alarms))
+
+	for _, alarm := range alarms {
+		ts, err := time.Parse(time.RFC3339, alarm.Timestamp)
+		if err != nil {
+			continue
+		}
+
+		text := alarm.Func + alarm.Cond
+		if alarm.Note != "" {
+			text = alarm.Note + " (" + text + ")"
+		}
+
+		annotations = append(annotations, &dtos.Annotation{
+			Time:  ts.UnixNano() / int64(time.Millisecond),
+			Title: fmt.Sprintf("[P%d %s] %s %s", alarm.Priority, alarm.Status, alarm.Endpoint, alarm.Metric),
+			Text:  text,
+			Tags:  []string{alarm.Endpoint, alarm.Metric, fmt.Sprintf("P%d", alarm.Priority), alarm.Status},
+		})
+	}
+
+	return annotations
+}
+
+// GetOpenFalconAnnotations returns the alarms of an OpenFalcon data source
+// as annotations. from and to are epoch milliseconds.
+func GetOpenFalconAnnotations(c *middleware.Context) Response {
+	from := c.QueryInt64("from")
+	to := c.QueryInt64("to")
+	if from <= 0 || to < from {
+		return ApiError(400, "Missing or invalid time range", nil)
+	}
+
+	limit := c.QueryInt("limit")
+	if limit <= 0 {
+		limit = openFalconDefaultAnnotationLimit
+	}
+	if limit > openFalconMaxAnnotationLimit {
+		limit = openFalconMaxAnnotationLimit
+	}
+
+	ds, err := getDatasource(c.ParamsInt64(":id"), c.OrgId)
+	if err == m.ErrDataSourceNotFound {
+		return ApiError(404, "Data source not found", nil)
+	} else if err != nil {
+		return ApiError(500, "Unable to load datasource meta data", err)
+	}
+	if ds.OrgId != c.OrgId {
+		return ApiError(403, "Access denied to data source", nil)
+	}
+	if ds.Type != m.DS_OPENFALCON {
+		return ApiError(400, "Annotations are only supported for OpenFalcon data sources", nil)
+	}
+
+	alarms, err := queryOpenFalconAlarms(getOpenFalconAlarmAddr(ds), msToTime(from), msToTime(to), limit)
+	if err != nil {
+		return ApiError(502, "Failed to query OpenFalcon alarms", err)
+	}
+
+	return Json(200, openFalconAlarmsToAnnotations(alarms))
+}
+
+func msToTime(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
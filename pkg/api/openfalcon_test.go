@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newOpenFalconStub(counters ...string) (*httptest.Server, *http.Request) {
+	var lastReq http.Request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		lastReq = *req
+
+		rows := make([][]interface{}, 0)
+		for _, counter := range counters {
+			rows = append(rows, []interface{}{counter, "GAUGE", 60})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"msg": "", "data": rows})
+	}))
+
+	return server, &lastReq
+}
+
+func TestOpenFalconCounters(t *testing.T) {
+
+	Convey("When querying OpenFalcon counters", t, func() {
+		server, req := newOpenFalconStub("cpu.idle", "cpu.busy", "df.bytes.free/mount=/", "cpu.idle", "mem.memfree")
+		defer server.Close()
+
+		Convey("Should post the endpoint to the counters api", func() {
+			_, err := queryOpenFalconCounters(server.URL, "host01", "", 10)
+			So(err, ShouldBeNil)
+			So(req.URL.Path, ShouldEqual, "/api/counters")
+			So(req.Form.Get("endpoints"), ShouldEqual, `["host01"]`)
+		})
+
+		Convey("Should return unique counters", func() {
+			counters, err := queryOpenFalconCounters(server.URL, "host01", "", 10)
+			So(err, ShouldBeNil)
+			So(counters, ShouldResemble, []string{"cpu.idle", "cpu.busy", "df.bytes.free/mount=/", "mem.memfree"})
+		})
+
+		Convey("Should filter by prefix", func() {
+			counters, err := queryOpenFalconCounters(server.URL, "host01", "cpu.", 10)
+			So(err, ShouldBeNil)
+			So(req.Form.Get("q"), ShouldEqual, "cpu.")
+			So(counters, ShouldResemble, []string{"cpu.idle", "cpu.busy"})
+		})
+
+		Convey("Should respect limit", func() {
+			counters, err := queryOpenFalconCounters(server.URL, "host01", "", 2)
+			So(err, ShouldBeNil)
+			So(len(counters), ShouldEqual, 2)
+		})
+	})
+
+	Convey("When OpenFalcon returns an error", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(500)
+		}))
+		defer server.Close()
+
+		_, err := queryOpenFalconCounters(server.URL, "host01", "", 10)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestOpenFalconAnnotations(t *testing.T) {
+
+	Convey("Given an OpenFalcon alarm history api", t, func() {
+		defer bus.ClearBusHandlers()
+
+		var alarmReq map[string]interface{}
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/api/v1/alarm/eventcases" {
+				http.NotFound(w, req)
+				return
+			}
+			json.NewDecoder(req.Body).Decode(&alarmReq)
+			w.Write([]byte(`[
+				{"id": "s_3_abc", "endpoint": "host01", "metric": "cpu.idle", "func": "all(#3)", "cond": "5 < 10",
+				 "note": "cpu busy", "priority": 1, "status": "PROBLEM", "timestamp": "2016-01-02T15:04:05+08:00"},
+				{"id": "s_4_def", "endpoint": "host02", "metric": "df.bytes.free.percent", "func": "all(#1)", "cond": "4 < 5",
+				 "note": "", "priority": 2, "status": "OK", "timestamp": "not a time"}
+			]`))
+		}))
+		defer backend.Close()
+
+		bus.AddHandler("test", func(query *m.GetDataSourceByIdQuery) error {
+			switch query.Id {
+			case 1:
+				query.Result = m.DataSource{Id: 1, OrgId: 1, Type: m.DS_OPENFALCON, Url: backend.URL}
+			case 2:
+				query.Result = m.DataSource{Id: 2, OrgId: 2, Type: m.DS_OPENFALCON, Url: backend.URL}
+			case 3:
+				query.Result = m.DataSource{Id: 3, OrgId: 1, Type: m.DS_GRAPHITE, Url: backend.URL}
+			default:
+				return m.ErrDataSourceNotFound
+			}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1}})
+		})
+		mac.Get("/api/datasources/proxy/:id/annotations", wrap(GetOpenFalconAnnotations))
+
+		get := func(url string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", url, nil)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should map alarms to annotations", func() {
+			resp := get("/api/datasources/proxy/1/annotations?from=1451700000000&to=1451800000000")
+			So(resp.Code, ShouldEqual, 200)
+			So(alarmReq["startTime"], ShouldEqual, 1451700000)
+			So(alarmReq["endTime"], ShouldEqual, 1451800000)
+
+			var annotations []dtos.Annotation
+			So(json.Unmarshal(resp.Body.Bytes(), &annotations), ShouldBeNil)
+			So(len(annotations), ShouldEqual, 1)
+			So(annotations[0].Time, ShouldEqual, 1451718245000)
+			So(annotations[0].Title, ShouldEqual, "[P1 PROBLEM] host01 cpu.idle")
+			So(annotations[0].Text, ShouldEqual, "cpu busy (all(#3)5 < 10)")
+			So(annotations[0].Tags, ShouldResemble, []string{"host01", "cpu.idle", "P1", "PROBLEM"})
+		})
+
+		Convey("Should require a time range", func() {
+			So(get("/api/datasources/proxy/1/annotations").Code, ShouldEqual, 400)
+		})
+
+		Convey("Should reject data sources of other orgs", func() {
+			So(get("/api/datasources/proxy/2/annotations?from=1&to=2").Code, ShouldEqual, 403)
+		})
+
+		Convey("Should reject other data source types", func() {
+			So(get("/api/datasources/proxy/3/annotations?from=1&to=2").Code, ShouldEqual, 400)
+		})
+
+		Convey("Should return 404 for unknown data sources", func() {
+			So(get("/api/datasources/proxy/4/annotations?from=1&to=2").Code, ShouldEqual, 404)
+		})
+
+		Convey("Should use the configured alarm address", func() {
+			globalConfig := configOpenFalcon
+			defer func() { configOpenFalcon = globalConfig }()
+			configOpenFalcon = &GlobalConfig{OpenFalcon: &OpenFalconConfig{Enabled: true, AlarmAddr: backend.URL}}
+
+			bus.AddHandler("test", func(query *m.GetDataSourceByIdQuery) error {
+				query.Result = m.DataSource{Id: 5, OrgId: 1, Type: m.DS_OPENFALCON, Url: "http://127.0.0.1:1"}
+				return nil
+			})
+
+			resp := get("/api/datasources/proxy/5/annotations?from=1451700000000&to=1451800000000")
+			So(resp.Code, ShouldEqual, 200)
+		})
+	})
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"golang.org/x/net/context"
+
 	"github.com/Cepave/grafana/pkg/api/dtos"
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/metrics"
@@ -20,14 +22,21 @@ func GetOrgById(c *middleware.Context) Response {
 	return getOrgHelper(c.ParamsInt64(":orgId"))
 }
 
+// GET /api/orgs/:orgId/stats
+func GetOrgStats(c *middleware.Context) Response {
+	query := m.GetOrgStatsQuery{OrgId: c.ParamsInt64(":orgId")}
+
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get organization stats", err)
+	}
+
+	return Json(200, query.Result)
+}
+
 func getOrgHelper(orgId int64) Response {
 	query := m.GetOrgByIdQuery{Id: orgId}
 
 	if err := bus.Dispatch(&query); err != nil {
-		if err == m.ErrOrgNotFound {
-			return ApiError(404, "Organization not found", err)
-		}
-
 		return ApiError(500, "Failed to get organization", err)
 	}
 
@@ -56,9 +65,6 @@ func CreateOrg(c *middleware.Context, cmd m.CreateOrgCommand) Response {
 
 	cmd.UserId = c.UserId
 	if err := bus.Dispatch(&cmd); err != nil {
-		if err == m.ErrOrgNameTaken {
-			return ApiError(400, "Organization name taken", err)
-		}
 		return ApiError(500, "Failed to create organization", err)
 	}
 
@@ -83,9 +89,6 @@ func UpdateOrg(c *middleware.Context, form dtos.UpdateOrgForm) Response {
 func updateOrgHelper(form dtos.UpdateOrgForm, orgId int64) Response {
 	cmd := m.UpdateOrgCommand{Name: form.Name, OrgId: orgId}
 	if err := bus.Dispatch(&cmd); err != nil {
-		if err == m.ErrOrgNameTaken {
-			return ApiError(400, "Organization name taken", err)
-		}
 		return ApiError(500, "Failed to update organization", err)
 	}
 
@@ -131,16 +134,23 @@ func DeleteOrgById(c *middleware.Context) Response {
 }
 
 func SearchOrgs(c *middleware.Context) Response {
+	page, perPage := queryPaging(c, 1000)
 	query := m.SearchOrgsQuery{
 		Query: c.Query("query"),
 		Name:  c.Query("name"),
-		Page:  0,
-		Limit: 1000,
+		Page:  page,
+		Limit: perPage,
 	}
 
-	if err := bus.Dispatch(&query); err != nil {
+	reqCtx, cancel := c.RequestContext()
+	defer cancel()
+
+	if err := bus.DispatchCtx(reqCtx, &query); err != nil {
+		if err == context.Canceled {
+			return ApiError(400, "Request cancelled", nil)
+		}
 		return ApiError(500, "Failed to search orgs", err)
 	}
 
-	return Json(200, query.Result)
+	return Json(200, pagedResult(c, query.Result, page, perPage, query.TotalCount))
 }
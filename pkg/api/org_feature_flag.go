@@ -0,0 +1,29 @@
+package api
+
+import (
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// GET /api/orgs/:orgId/feature-flags
+func GetOrgFeatureFlags(c *middleware.Context) Response {
+	query := m.GetOrgFeatureFlagsQuery{OrgId: c.ParamsInt64(":orgId")}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get feature flags", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// PUT /api/orgs/:orgId/feature-flags/:name
+func SetOrgFeatureFlag(c *middleware.Context, cmd m.SetOrgFeatureFlagCommand) Response {
+	cmd.OrgId = c.ParamsInt64(":orgId")
+	cmd.Name = c.Params(":name")
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to set feature flag", err)
+	}
+
+	return ApiSuccess("Feature flag updated")
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/Cepave/grafana/pkg/api/dtos"
 	"github.com/Cepave/grafana/pkg/bus"
@@ -50,7 +51,7 @@ func AddOrgInvite(c *middleware.Context, inviteDto dtos.AddInviteForm) Response
 	cmd.InvitedByUserId = c.UserId
 	cmd.Code = util.GetRandomString(30)
 	cmd.Role = inviteDto.Role
-	cmd.RemoteAddr = c.Req.RemoteAddr
+	cmd.RemoteAddr = middleware.ClientIP(c.Req.Request).String()
 
 	if err := bus.Dispatch(&cmd); err != nil {
 		return ApiError(500, "Failed to save invite to database", err)
@@ -159,6 +160,8 @@ func CompleteInvite(c *middleware.Context, completeInvite dtos.CompleteInviteFor
 		Login:        completeInvite.Username,
 		Password:     completeInvite.Password,
 		SkipOrgSetup: true,
+		// the invite was sent to that address
+		EmailVerified: strings.EqualFold(completeInvite.Email, invite.Email),
 	}
 
 	if err := bus.Dispatch(&cmd); err != nil {
@@ -23,21 +23,10 @@ func addOrgUserHelper(cmd m.AddOrgUserCommand) Response {
 		return ApiError(400, "Invalid role specified", nil)
 	}
 
-	userQuery := m.GetUserByLoginQuery{LoginOrEmail: cmd.LoginOrEmail}
-	err := bus.Dispatch(&userQuery)
-	if err != nil {
-		return ApiError(404, "User not found", nil)
-	}
-
-	userToAdd := userQuery.Result
-
-	// if userToAdd.Id == c.UserId {
-	// 	return ApiError(400, "Cannot add yourself as user", nil)
-	// }
-
-	cmd.UserId = userToAdd.Id
-
 	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrUserNotFound {
+			return ApiError(404, "User not found", nil)
+		}
 		return ApiError(500, "Could not add user to organization", err)
 	}
 
@@ -46,17 +35,29 @@ func addOrgUserHelper(cmd m.AddOrgUserCommand) Response {
 
 // GET /api/org/users
 func GetOrgUsersForCurrentOrg(c *middleware.Context) Response {
-	return getOrgUsersHelper(c.OrgId)
+	query := m.GetOrgUsersQuery{
+		OrgId: c.OrgId,
+		Role:  c.Query("role"),
+		Query: c.Query("query"),
+		Limit: c.QueryInt("limit"),
+	}
+
+	if query.Limit < 0 || query.Limit > 1000 {
+		query.Limit = 1000
+	}
+	if page := c.QueryInt("page"); page > 1 {
+		query.Page = page - 1
+	}
+
+	return getOrgUsersHelper(query)
 }
 
 // GET /api/orgs/:orgId/users
 func GetOrgUsers(c *middleware.Context) Response {
-	return getOrgUsersHelper(c.ParamsInt64(":orgId"))
+	return getOrgUsersHelper(m.GetOrgUsersQuery{OrgId: c.ParamsInt64(":orgId"), Role: c.Query("role")})
 }
 
-func getOrgUsersHelper(orgId int64) Response {
-	query := m.GetOrgUsersQuery{OrgId: orgId}
-
+func getOrgUsersHelper(query m.GetOrgUsersQuery) Response {
 	if err := bus.Dispatch(&query); err != nil {
 		return ApiError(500, "Failed to get account user", err)
 	}
@@ -68,6 +69,7 @@ func getOrgUsersHelper(orgId int64) Response {
 func UpdateOrgUserForCurrentOrg(c *middleware.Context, cmd m.UpdateOrgUserCommand) Response {
 	cmd.OrgId = c.OrgId
 	cmd.UserId = c.ParamsInt64(":userId")
+	cmd.ActorUserId = c.UserId
 	return updateOrgUserHelper(cmd)
 }
 
@@ -75,6 +77,7 @@ func UpdateOrgUserForCurrentOrg(c *middleware.Context, cmd m.UpdateOrgUserComman
 func UpdateOrgUser(c *middleware.Context, cmd m.UpdateOrgUserCommand) Response {
 	cmd.OrgId = c.ParamsInt64(":orgId")
 	cmd.UserId = c.ParamsInt64(":userId")
+	cmd.ActorUserId = c.UserId
 	return updateOrgUserHelper(cmd)
 }
 
@@ -93,6 +96,41 @@ func updateOrgUserHelper(cmd m.UpdateOrgUserCommand) Response {
 	return ApiSuccess("Organization user updated")
 }
 
+// GET /api/org/users/:userId/history
+func GetOrgUserRoleHistoryForCurrentOrg(c *middleware.Context) Response {
+	return getOrgUserRoleHistoryHelper(c.OrgId, c.ParamsInt64(":userId"))
+}
+
+// GET /api/orgs/:orgId/users/:userId/history
+func GetOrgUserRoleHistory(c *middleware.Context) Response {
+	return getOrgUserRoleHistoryHelper(c.ParamsInt64(":orgId"), c.ParamsInt64(":userId"))
+}
+
+func getOrgUserRoleHistoryHelper(orgId int64, userId int64) Response {
+	query := m.GetOrgUserRoleHistoryQuery{OrgId: orgId, UserId: userId}
+
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get org user role history", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// POST /api/orgs/:orgId/users/:userId/move
+func MoveOrgUser(c *middleware.Context, cmd m.MoveOrgUserCommand) Response {
+	cmd.OrgId = c.ParamsInt64(":orgId")
+	cmd.UserId = c.ParamsInt64(":userId")
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrLastOrgAdmin {
+			return ApiError(400, "Cannot move the last organization admin", nil)
+		}
+		return ApiError(500, "Failed to move user to organization", err)
+	}
+
+	return ApiSuccess("User moved to organization")
+}
+
 // DELETE /api/org/users/:userId
 func RemoveOrgUserForCurrentOrg(c *middleware.Context) Response {
 	userId := c.ParamsInt64(":userId")
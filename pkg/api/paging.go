@@ -0,0 +1,41 @@
+package api
+
+import (
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/middleware"
+)
+
+// queryPaging reads the 1-based ?page and the page size (?perpage, or
+// the older ?limit) and returns them as a 0-based page and a limit.
+func queryPaging(c *middleware.Context, maxPerPage int) (int, int) {
+	perPage := c.QueryInt("perpage")
+	if perPage == 0 {
+		perPage = c.QueryInt("limit")
+	}
+	if perPage <= 0 || perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	page := 0
+	if p := c.QueryInt("page"); p > 1 {
+		page = p - 1
+	}
+
+	return page, perPage
+}
+
+// pagedResult wraps items in a dtos.PagedResult. Clients that still
+// expect the bare list can ask for it with ?envelope=false; that flag
+// is kept for one release only.
+func pagedResult(c *middleware.Context, items interface{}, page, perPage int, totalCount int64) interface{} {
+	if c.Query("envelope") == "false" {
+		return items
+	}
+
+	return dtos.PagedResult{
+		Items:      items,
+		Page:       page + 1,
+		PerPage:    perPage,
+		TotalCount: totalCount,
+	}
+}
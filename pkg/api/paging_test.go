@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/search"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type pagedResponse struct {
+	Items      []map[string]interface{} `json:"items"`
+	Page       int                      `json:"page"`
+	PerPage    int                      `json:"perPage"`
+	TotalCount int64                    `json:"totalCount"`
+}
+
+func pagingScenario(route, url string, handler interface{}) *httptest.ResponseRecorder {
+	mac := macaron.New()
+	mac.Use(macaron.Renderer())
+	mac.Use(func(c *macaron.Context) {
+		c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, IsGrafanaAdmin: true}, IsSignedIn: true})
+	})
+	mac.Get(route, handler)
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", url, nil)
+	mac.ServeHTTP(resp, req)
+	So(resp.Code, ShouldEqual, 200)
+	return resp
+}
+
+func decodePaged(resp *httptest.ResponseRecorder) pagedResponse {
+	var result pagedResponse
+	So(json.Unmarshal(resp.Body.Bytes(), &result), ShouldBeNil)
+	return result
+}
+
+func TestPagedResults(t *testing.T) {
+
+	Convey("When searching orgs", t, func() {
+		var sent *m.SearchOrgsQuery
+		bus.AddHandler("test", func(ctx context.Context, query *m.SearchOrgsQuery) error {
+			sent = query
+			query.Result = []*m.OrgDTO{{Id: 3, Name: "third"}, {Id: 4, Name: "fourth"}}
+			query.TotalCount = 7
+			return nil
+		})
+		defer bus.ClearBusHandlers()
+
+		Convey("Should wrap the page in an envelope", func() {
+			result := decodePaged(pagingScenario("/api/orgs", "/api/orgs?page=2&perpage=2", wrap(SearchOrgs)))
+
+			So(sent.Page, ShouldEqual, 1)
+			So(sent.Limit, ShouldEqual, 2)
+			So(len(result.Items), ShouldEqual, 2)
+			So(result.Items[0]["name"], ShouldEqual, "third")
+			So(result.Page, ShouldEqual, 2)
+			So(result.PerPage, ShouldEqual, 2)
+			So(result.TotalCount, ShouldEqual, 7)
+		})
+
+		Convey("Should default to the first page of 1000", func() {
+			result := decodePaged(pagingScenario("/api/orgs", "/api/orgs", wrap(SearchOrgs)))
+
+			So(sent.Page, ShouldEqual, 0)
+			So(result.Page, ShouldEqual, 1)
+			So(result.PerPage, ShouldEqual, 1000)
+		})
+
+		Convey("Should return the bare list with envelope=false", func() {
+			resp := pagingScenario("/api/orgs", "/api/orgs?envelope=false", wrap(SearchOrgs))
+
+			var orgs []m.OrgDTO
+			So(json.Unmarshal(resp.Body.Bytes(), &orgs), ShouldBeNil)
+			So(len(orgs), ShouldEqual, 2)
+		})
+	})
+
+	Convey("When searching users", t, func() {
+		var sent *m.SearchUsersQuery
+		bus.AddHandler("test", func(ctx context.Context, query *m.SearchUsersQuery) error {
+			sent = query
+			query.Result = []*m.UserSearchHitDTO{{Id: 1, Login: "admin"}}
+			query.TotalCount = 11
+			return nil
+		})
+		defer bus.ClearBusHandlers()
+
+		Convey("Should wrap the page in an envelope", func() {
+			result := decodePaged(pagingScenario("/api/users", "/api/users?page=3&perpage=5", wrap(SearchUsers)))
+
+			So(sent.Page, ShouldEqual, 2)
+			So(sent.Limit, ShouldEqual, 5)
+			So(len(result.Items), ShouldEqual, 1)
+			So(result.Items[0]["login"], ShouldEqual, "admin")
+			So(result.Page, ShouldEqual, 3)
+			So(result.PerPage, ShouldEqual, 5)
+			So(result.TotalCount, ShouldEqual, 11)
+		})
+
+		Convey("Should cap the page size", func() {
+			result := decodePaged(pagingScenario("/api/users", "/api/users?perpage=5000", wrap(SearchUsers)))
+
+			So(sent.Limit, ShouldEqual, 1000)
+			So(result.PerPage, ShouldEqual, 1000)
+		})
+
+		Convey("Should return the bare list with envelope=false", func() {
+			resp := pagingScenario("/api/users", "/api/users?envelope=false", wrap(SearchUsers))
+
+			var users []m.UserSearchHitDTO
+			So(json.Unmarshal(resp.Body.Bytes(), &users), ShouldBeNil)
+			So(len(users), ShouldEqual, 1)
+		})
+	})
+
+	Convey("When searching dashboards", t, func() {
+		var sent *search.Query
+		bus.AddHandler("test", func(query *search.Query) error {
+			sent = query
+			query.Result = search.HitList{&search.Hit{Id: 5, Title: "prod"}}
+			query.TotalCount = 4
+			return nil
+		})
+		defer bus.ClearBusHandlers()
+
+		Convey("Should wrap the page in an envelope", func() {
+			result := decodePaged(pagingScenario("/api/search", "/api/search?page=4&limit=1", Search))
+
+			So(sent.Page, ShouldEqual, 3)
+			So(sent.Limit, ShouldEqual, 1)
+			So(sent.OrgId, ShouldEqual, 1)
+			So(len(result.Items), ShouldEqual, 1)
+			So(result.Items[0]["title"], ShouldEqual, "prod")
+			So(result.Page, ShouldEqual, 4)
+			So(result.PerPage, ShouldEqual, 1)
+			So(result.TotalCount, ShouldEqual, 4)
+		})
+
+		Convey("Should return the bare list with envelope=false", func() {
+			resp := pagingScenario("/api/search", "/api/search?envelope=false", Search)
+
+			var hits search.HitList
+			So(json.Unmarshal(resp.Body.Bytes(), &hits), ShouldBeNil)
+			So(len(hits), ShouldEqual, 1)
+		})
+	})
+}
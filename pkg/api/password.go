@@ -5,6 +5,7 @@ import (
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/middleware"
 	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
 	"github.com/Cepave/grafana/pkg/util"
 )
 
@@ -39,7 +40,8 @@ func ResetPassword(c *middleware.Context, form dtos.ResetUserPasswordForm) Respo
 
 	cmd := m.ChangeUserPasswordCommand{}
 	cmd.UserId = query.Result.Id
-	cmd.NewPassword = util.EncodePassword(form.NewPassword, query.Result.Salt)
+	cmd.PasswordCost = setting.PasswordHashCost
+	cmd.NewPassword = util.EncodePasswordWithCost(form.NewPassword, query.Result.Salt, cmd.PasswordCost)
 
 	if err := bus.Dispatch(&cmd); err != nil {
 		return ApiError(500, "Failed to change user password", err)
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"time"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// An empty theme or timezone means the value is inherited.
+var validThemes = map[string]bool{"": true, "light": true, "dark": true}
+
+func getPreferencesFor(orgId int64, userId int64) Response {
+	query := m.GetPreferencesQuery{OrgId: orgId, UserId: userId}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get preferences", err)
+	}
+
+	return Json(200, &dtos.Prefs{
+		Theme:           query.Result.Theme,
+		Timezone:        query.Result.Timezone,
+		HomeDashboardId: query.Result.HomeDashboardId,
+	})
+}
+
+func updatePreferencesFor(orgId int64, userId int64, dtoCmd *dtos.UpdatePrefsCmd) Response {
+	if !validThemes[dtoCmd.Theme] {
+		return ApiError(400, "Invalid theme", nil)
+	}
+	if dtoCmd.Timezone != "" {
+		if _, err := time.LoadLocation(dtoCmd.Timezone); err != nil {
+			return ApiError(400, "Invalid timezone", nil)
+		}
+	}
+
+	cmd := m.SavePreferencesCommand{
+		OrgId:           orgId,
+		UserId:          userId,
+		Theme:           dtoCmd.Theme,
+		Timezone:        dtoCmd.Timezone,
+		HomeDashboardId: dtoCmd.HomeDashboardId,
+	}
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to save preferences", err)
+	}
+
+	return ApiSuccess("Preferences updated")
+}
+
+// GET /api/user/preferences
+func GetUserPreferences(c *middleware.Context) Response {
+	return getPreferencesFor(c.OrgId, c.UserId)
+}
+
+// PUT /api/user/preferences
+func UpdateUserPreferences(c *middleware.Context, dtoCmd dtos.UpdatePrefsCmd) Response {
+	// user id 0 holds the org defaults
+	if c.UserId == 0 {
+		return ApiError(403, "Anonymous users can not save preferences", nil)
+	}
+
+	return updatePreferencesFor(c.OrgId, c.UserId, &dtoCmd)
+}
+
+// GET /api/org/preferences
+func GetOrgPreferences(c *middleware.Context) Response {
+	return getPreferencesFor(c.OrgId, 0)
+}
+
+// PUT /api/org/preferences
+func UpdateOrgPreferences(c *middleware.Context, dtoCmd dtos.UpdatePrefsCmd) Response {
+	return updatePreferencesFor(c.OrgId, 0, &dtoCmd)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
+	"github.com/macaron-contrib/binding"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type prefsKey struct {
+	orgId  int64
+	userId int64
+}
+
+func TestUserPreferences(t *testing.T) {
+
+	Convey("Given a user preferences api", t, func() {
+		defer bus.ClearBusHandlers()
+
+		stored := make(map[prefsKey]m.Preferences)
+		bus.AddHandler("test", func(query *m.GetPreferencesQuery) error {
+			prefs := stored[prefsKey{query.OrgId, query.UserId}]
+			query.Result = &prefs
+			return nil
+		})
+		bus.AddHandler("test", func(cmd *m.SavePreferencesCommand) error {
+			stored[prefsKey{cmd.OrgId, cmd.UserId}] = m.Preferences{
+				OrgId:           cmd.OrgId,
+				UserId:          cmd.UserId,
+				Theme:           cmd.Theme,
+				Timezone:        cmd.Timezone,
+				HomeDashboardId: cmd.HomeDashboardId,
+			}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, UserId: 2}})
+		})
+		mac.Get("/api/user/preferences", wrap(GetUserPreferences))
+		mac.Put("/api/user/preferences", binding.Bind(dtos.UpdatePrefsCmd{}), wrap(UpdateUserPreferences))
+
+		request := func(method string, body interface{}) *httptest.ResponseRecorder {
+			data, _ := json.Marshal(body)
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, "/api/user/preferences", bytes.NewReader(data))
+			req.Header.Set("Content-Type", "application/json")
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Should load saved preferences", func() {
+			saved := dtos.UpdatePrefsCmd{Theme: "light", Timezone: "Asia/Taipei", HomeDashboardId: 5}
+			So(request("PUT", saved).Code, ShouldEqual, 200)
+			So(stored[prefsKey{1, 2}].Theme, ShouldEqual, "light")
+
+			resp := request("GET", nil)
+			So(resp.Code, ShouldEqual, 200)
+
+			var prefs dtos.Prefs
+			So(json.Unmarshal(resp.Body.Bytes(), &prefs), ShouldBeNil)
+			So(prefs, ShouldResemble, dtos.Prefs{Theme: "light", Timezone: "Asia/Taipei", HomeDashboardId: 5})
+		})
+
+		Convey("Should reject an invalid timezone", func() {
+			resp := request("PUT", dtos.UpdatePrefsCmd{Timezone: "Mars/Olympus_Mons"})
+			So(resp.Code, ShouldEqual, 400)
+			So(len(stored), ShouldEqual, 0)
+		})
+
+		Convey("Should reject an unknown theme", func() {
+			resp := request("PUT", dtos.UpdatePrefsCmd{Theme: "pink"})
+			So(resp.Code, ShouldEqual, 400)
+			So(len(stored), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestOrgPreferences(t *testing.T) {
+
+	Convey("Given an org preferences api", t, func() {
+		defer bus.ClearBusHandlers()
+
+		var saved *m.SavePreferencesCommand
+		bus.AddHandler("test", func(cmd *m.SavePreferencesCommand) error {
+			saved = cmd
+			return nil
+		})
+		bus.AddHandler("test", func(query *m.GetPreferencesQuery) error {
+			query.Result = &m.Preferences{OrgId: query.OrgId, UserId: query.UserId, Theme: "dark", HomeDashboardId: 3}
+			return nil
+		})
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{OrgId: 1, UserId: 2, OrgRole: m.ROLE_ADMIN}})
+		})
+		mac.Get("/api/org/preferences", wrap(GetOrgPreferences))
+		mac.Put("/api/org/preferences", binding.Bind(dtos.UpdatePrefsCmd{}), wrap(UpdateOrgPreferences))
+
+		Convey("Should save the org defaults without a user", func() {
+			body, _ := json.Marshal(dtos.UpdatePrefsCmd{Theme: "dark", HomeDashboardId: 3})
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/api/org/preferences", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			mac.ServeHTTP(resp, req)
+
+			So(resp.Code, ShouldEqual, 200)
+			So(saved.OrgId, ShouldEqual, 1)
+			So(saved.UserId, ShouldEqual, 0)
+			So(saved.HomeDashboardId, ShouldEqual, 3)
+		})
+
+		Convey("Should load the org defaults", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/org/preferences", nil)
+			mac.ServeHTTP(resp, req)
+
+			var prefs dtos.Prefs
+			So(json.Unmarshal(resp.Body.Bytes(), &prefs), ShouldBeNil)
+			So(prefs.Theme, ShouldEqual, "dark")
+			So(prefs.HomeDashboardId, ShouldEqual, 3)
+		})
+	})
+}
@@ -1,23 +1,192 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Cepave/grafana/pkg/components/renderer"
+	"github.com/Cepave/grafana/pkg/log"
 	"github.com/Cepave/grafana/pkg/middleware"
 	"github.com/Cepave/grafana/pkg/setting"
 	"github.com/Cepave/grafana/pkg/util"
 )
 
+// renderToPng is swapped out in tests, phantomjs isn't available there.
+var renderToPng = renderer.RenderToPng
+
+var errRenderBusy = errors.New("Too many renders in progress")
+
+var (
+	renderSlotsOnce sync.Once
+	renderSlots     chan struct{}
+)
+
+func acquireRenderSlot() bool {
+	renderSlotsOnce.Do(func() {
+		if setting.RenderConcurrencyLimit > 0 {
+			renderSlots = make(chan struct{}, setting.RenderConcurrencyLimit)
+		}
+	})
+
+	if renderSlots == nil {
+		return true
+	}
+
+	select {
+	case renderSlots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func releaseRenderSlot() {
+	if renderSlots != nil {
+		<-renderSlots
+	}
+}
+
+type renderResult struct {
+	pngPath string
+	err     error
+}
+
+// renderWithLimits fails fast with errRenderBusy when every render slot is
+// taken and gives up after opts.Timeout. A render that is given up on keeps
+// its slot until the renderer returns, so slow renders can't pile up.
+func renderWithLimits(opts *renderer.RenderOpts) (string, error) {
+	if !acquireRenderSlot() {
+		return "", errRenderBusy
+	}
+
+	done := make(chan renderResult, 1)
+	go func() {
+		defer releaseRenderSlot()
+		pngPath, err := renderToPng(opts)
+		done <- renderResult{pngPath, err}
+	}()
+
+	var timeout <-chan time.Time
+	if opts.Timeout > 0 {
+		timeout = time.After(opts.Timeout)
+	}
+
+	select {
+	case result := <-done:
+		return result.pngPath, result.err
+	case <-timeout:
+		return "", renderer.ErrTimeout
+	}
+}
+
+// renderTargetUrl turns the path after /render/ into the url phantomjs loads.
+// Only pages on this server or on the configured allowed hosts can be
+// rendered, so the renderer can't be pointed at internal services.
+func renderTargetUrl(path string, rawQuery string) (string, error) {
+	target, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !target.IsAbs() && target.Host == "" {
+		target, err = url.Parse(setting.ToAbsUrl(strings.TrimPrefix(path, "/")))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if !isRenderHostAllowed(target) {
+		return "", fmt.Errorf("Render target %s is not allowed", target.Host)
+	}
+
+	target.User = nil
+	target.RawQuery = rawQuery
+	return target.String(), nil
+}
+
+func isRenderHostAllowed(target *url.URL) bool {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return false
+	}
+
+	appUrl, err := url.Parse(setting.AppUrl)
+	if err == nil && strings.EqualFold(target.Host, appUrl.Host) {
+		return true
+	}
+
+	for _, host := range setting.RenderAllowedHosts {
+		if host != "" && strings.EqualFold(target.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderSize reads the image size and device scale factor of a render,
+// clamped to the configured maximums.
+func renderSize(queryReader *util.UrlQueryReader) (string, string, string, error) {
+	width, err := strconv.Atoi(queryReader.Get("width", "800"))
+	if err != nil || width <= 0 {
+		return "", "", "", errors.New("width must be a positive number")
+	}
+	height, err := strconv.Atoi(queryReader.Get("height", "400"))
+	if err != nil || height <= 0 {
+		return "", "", "", errors.New("height must be a positive number")
+	}
+	scale, err := strconv.ParseFloat(queryReader.Get("scale", "1"), 64)
+	if err != nil || !(scale > 0) || math.IsInf(scale, 0) {
+		return "", "", "", errors.New("scale must be a positive number")
+	}
+
+	if setting.RenderMaxWidth > 0 && width > setting.RenderMaxWidth {
+		width = setting.RenderMaxWidth
+	}
+	if setting.RenderMaxHeight > 0 && height > setting.RenderMaxHeight {
+		height = setting.RenderMaxHeight
+	}
+	if setting.RenderMaxScale > 0 && scale > setting.RenderMaxScale {
+		scale = setting.RenderMaxScale
+	}
+
+	return strconv.Itoa(width), strconv.Itoa(height), strconv.FormatFloat(scale, 'f', -1, 64), nil
+}
+
 func RenderToPng(c *middleware.Context) {
 	queryReader := util.NewUrlQueryReader(c.Req.URL)
-	queryParams := fmt.Sprintf("?%s", c.Req.URL.RawQuery)
+	renderUrl, err := renderTargetUrl(c.Params("*"), c.Req.URL.RawQuery)
+	if err != nil {
+		c.JsonApiErr(400, "Invalid render url", err)
+		return
+	}
+
+	width, height, scale, err := renderSize(queryReader)
+	if err != nil {
+		c.JsonApiErr(400, err.Error(), nil)
+		return
+	}
+	cacheKey := renderCacheKey(c.OrgId, c.UserId, c.ApiKeyId, renderUrl, width, height, scale)
+
+	if pngPath, fresh, ok := getCachedRender(cacheKey); ok {
+		servePng(c, pngPath, fresh)
+		return
+	}
+
 	sessionId := c.Session.ID()
 
-	// Handle api calls authenticated without session
+	// Handle api calls authenticated without session, phantomjs gets a
+	// session bound to the api key so the render is scoped to the key's org
 	if sessionId == "" && c.ApiKeyId != 0 {
-		c.Session.Start(c)
+		if err := c.Session.Start(c); err != nil {
+			c.Handle(500, "Failed to start render session", err)
+			return
+		}
 		c.Session.Set(middleware.SESS_KEY_APIKEY, c.ApiKeyId)
 		// release will make sure the new session is persisted before
 		// we spin up phantomjs
@@ -27,20 +196,42 @@ func RenderToPng(c *middleware.Context) {
 	}
 
 	renderOpts := &renderer.RenderOpts{
-		Url:       c.Params("*") + queryParams,
-		Width:     queryReader.Get("width", "800"),
-		Height:    queryReader.Get("height", "400"),
+		Url:       renderUrl,
+		Width:     width,
+		Height:    height,
+		Scale:     scale,
 		SessionId: c.Session.ID(),
+		Timeout:   time.Duration(setting.RenderTimeoutSeconds) * time.Second,
 	}
 
-	renderOpts.Url = setting.ToAbsUrl(renderOpts.Url)
-	pngPath, err := renderer.RenderToPng(renderOpts)
+	pngPath, err := renderWithLimits(renderOpts)
 
+	if err == errRenderBusy {
+		c.JsonApiErr(503, "Too many renders in progress, try again later", nil)
+		return
+	}
+	if err == renderer.ErrTimeout {
+		c.JsonApiErr(504, "Rendering timed out", nil)
+		return
+	}
 	if err != nil {
 		c.Handle(500, "Failed to render to png", err)
 		return
 	}
 
+	if pngPath, err = cacheRender(cacheKey, pngPath); err != nil {
+		log.Error(3, "Failed to cache rendered png: %v", err)
+	}
+
+	servePng(c, pngPath, time.Duration(setting.RenderCacheTtlSeconds)*time.Second)
+}
+
+func servePng(c *middleware.Context, pngPath string, maxAge time.Duration) {
 	c.Resp.Header().Set("Content-Type", "image/png")
+	if maxAge > 0 {
+		c.Resp.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge/time.Second)))
+	} else {
+		c.Resp.Header().Set("Cache-Control", "no-cache")
+	}
 	http.ServeFile(c.Resp, c.Req.Request, pngPath)
 }
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/log"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// renderCacheSweepInterval is how often expired pngs are removed from the
+// cache, at most.
+const renderCacheSweepInterval = time.Minute
+
+var (
+	renderCacheSweepMutex sync.Mutex
+	renderCacheLastSweep  time.Time
+)
+
+// renderCacheKey identifies a rendered png by who asked for it, page and size.
+// The render runs with the permissions of the user or api key, so entries are
+// never shared between them. The query is sorted so the same panel, time range
+// and theme map to one entry whatever order the parameters came in.
+func renderCacheKey(orgId int64, userId int64, apiKeyId int64, renderUrl string, width string, height string, scale string) string {
+	if target, err := url.Parse(renderUrl); err == nil {
+		target.RawQuery = target.Query().Encode()
+		renderUrl = target.String()
+	}
+
+	hash := sha1.Sum([]byte(fmt.Sprintf("%d|%d|%d|%s|%sx%s@%s", orgId, userId, apiKeyId, renderUrl, width, height, scale)))
+	return hex.EncodeToString(hash[:])
+}
+
+func renderCachePath(key string) string {
+	return filepath.Join(setting.ImagesDir, "cache", key+".png")
+}
+
+// getCachedRender returns the cached png for key and how long it stays fresh.
+func getCachedRender(key string) (string, time.Duration, bool) {
+	ttl := time.Duration(setting.RenderCacheTtlSeconds) * time.Second
+	if ttl <= 0 {
+		return "", 0, false
+	}
+
+	pngPath := renderCachePath(key)
+	info, err := os.Stat(pngPath)
+	if err != nil {
+		return "", 0, false
+	}
+
+	age := time.Since(info.ModTime())
+	if age >= ttl {
+		return "", 0, false
+	}
+	return pngPath, ttl - age, true
+}
+
+// cacheRender moves a freshly rendered png into the cache, replacing any
+// expired entry for the same key.
+func cacheRender(key string, pngPath string) (string, error) {
+	if setting.RenderCacheTtlSeconds <= 0 {
+		return pngPath, nil
+	}
+
+	cachePath := renderCachePath(key)
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return pngPath, err
+	}
+	if err := os.Rename(pngPath, cachePath); err != nil {
+		return pngPath, err
+	}
+
+	sweepRenderCache(time.Now())
+	return cachePath, nil
+}
+
+// sweepRenderCache removes the expired pngs from the cache, entries are only
+// replaced when the same render is asked for again otherwise.
+func sweepRenderCache(now time.Time) {
+	renderCacheSweepMutex.Lock()
+	if now.Sub(renderCacheLastSweep) < renderCacheSweepInterval {
+		renderCacheSweepMutex.Unlock()
+		return
+	}
+	renderCacheLastSweep = now
+	renderCacheSweepMutex.Unlock()
+
+	ttl := time.Duration(setting.RenderCacheTtlSeconds) * time.Second
+	cacheDir := filepath.Dir(renderCachePath(""))
+	files, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		log.Error(3, "Failed to list the render cache: %v", err)
+		return
+	}
+
+	for _, file := range files {
+		if file.IsDir() || now.Sub(file.ModTime()) < ttl {
+			continue
+		}
+		if err := os.Remove(filepath.Join(cacheDir, file.Name())); err != nil && !os.IsNotExist(err) {
+			log.Error(3, "Failed to remove expired render: %v", err)
+		}
+	}
+}
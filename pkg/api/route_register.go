@@ -0,0 +1,84 @@
+package api
+
+import (
+	"github.com/Unknwon/macaron"
+
+	"github.com/Cepave/grafana/pkg/middleware"
+)
+
+// routeRegister wraps macaron route registration so every route gets
+// request metrics labeled with its full route template. Group handlers are
+// kept here rather than in macaron so that the metrics handler runs first
+// and also sees requests rejected by them. Routes are served under root,
+// which is left out of the metrics labels.
+type routeRegister struct {
+	*macaron.Macaron
+	root     string
+	depth    int
+	prefix   string
+	handlers []macaron.Handler
+}
+
+func newRouteRegister(m *macaron.Macaron, root string) *routeRegister {
+	return &routeRegister{Macaron: m, root: root}
+}
+
+// path adds the root to patterns registered outside of any group, macaron
+// already prefixes the ones inside.
+func (r *routeRegister) path(pattern string) string {
+	if r.depth > 0 {
+		return pattern
+	}
+	return r.root + pattern
+}
+
+func (r *routeRegister) withMetrics(pattern string, h []macaron.Handler) []macaron.Handler {
+	handlers := []macaron.Handler{middleware.RequestMetrics(r.prefix + pattern)}
+	handlers = append(handlers, r.handlers...)
+	return append(handlers, h...)
+}
+
+func (r *routeRegister) Group(pattern string, fn func(), h ...macaron.Handler) {
+	parentPrefix, parentHandlers := r.prefix, r.handlers
+	r.prefix += pattern
+	r.handlers = append(append([]macaron.Handler{}, parentHandlers...), h...)
+	defer func() { r.prefix, r.handlers = parentPrefix, parentHandlers }()
+
+	groupPattern := r.path(pattern)
+	r.depth++
+	defer func() { r.depth-- }()
+
+	r.Macaron.Group(groupPattern, fn)
+}
+
+func (r *routeRegister) Get(pattern string, h ...macaron.Handler) {
+	r.Macaron.Get(r.path(pattern), r.withMetrics(pattern, h)...)
+}
+
+func (r *routeRegister) Post(pattern string, h ...macaron.Handler) {
+	r.Macaron.Post(r.path(pattern), r.withMetrics(pattern, h)...)
+}
+
+func (r *routeRegister) Put(pattern string, h ...macaron.Handler) {
+	r.Macaron.Put(r.path(pattern), r.withMetrics(pattern, h)...)
+}
+
+func (r *routeRegister) Patch(pattern string, h ...macaron.Handler) {
+	r.Macaron.Patch(r.path(pattern), r.withMetrics(pattern, h)...)
+}
+
+func (r *routeRegister) Delete(pattern string, h ...macaron.Handler) {
+	r.Macaron.Delete(r.path(pattern), r.withMetrics(pattern, h)...)
+}
+
+func (r *routeRegister) Options(pattern string, h ...macaron.Handler) {
+	r.Macaron.Options(r.path(pattern), r.withMetrics(pattern, h)...)
+}
+
+func (r *routeRegister) Any(pattern string, h ...macaron.Handler) {
+	r.Macaron.Any(r.path(pattern), r.withMetrics(pattern, h)...)
+}
+
+func (r *routeRegister) Combo(pattern string, h ...macaron.Handler) *macaron.ComboRouter {
+	return r.Macaron.Combo(r.path(pattern), r.withMetrics(pattern, h)...)
+}
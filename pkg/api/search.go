@@ -10,19 +10,18 @@ func Search(c *middleware.Context) {
 	query := c.Query("query")
 	tags := c.QueryStrings("tag")
 	starred := c.Query("starred")
-	limit := c.QueryInt("limit")
-
-	if limit == 0 {
-		limit = 1000
-	}
+	page, limit := queryPaging(c, 1000)
 
 	searchQuery := search.Query{
 		Title:     query,
 		Tags:      tags,
 		UserId:    c.UserId,
+		OrgRole:   c.OrgRole,
 		Limit:     limit,
+		Page:      page,
 		IsStarred: starred == "true",
 		OrgId:     c.OrgId,
+		FolderId:  c.QueryInt64("folderId"),
 	}
 
 	err := bus.Dispatch(&searchQuery)
@@ -31,5 +30,5 @@ func Search(c *middleware.Context) {
 		return
 	}
 
-	c.JSON(200, searchQuery.Result)
+	c.JSON(200, pagedResult(c, searchQuery.Result, page, limit, searchQuery.TotalCount))
 }
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"strings"
+	"sync"
+
 	"github.com/Cepave/grafana/pkg/api/dtos"
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/events"
@@ -11,18 +14,62 @@ import (
 	"github.com/Cepave/grafana/pkg/util"
 )
 
+// signUpOptions is computed on first use, the settings it reads do not change
+// once the server is running.
+var signUpOptions struct {
+	sync.Mutex
+	value util.DynMap
+}
+
+func getSignUpOptions() util.DynMap {
+	signUpOptions.Lock()
+	defer signUpOptions.Unlock()
+
+	if signUpOptions.value == nil {
+		signUpOptions.value = util.DynMap{
+			"signUpEnabled":      setting.AllowUserSignUp,
+			"verifyEmailEnabled": setting.VerifyEmailEnabled,
+			"autoAssignOrg":      setting.AutoAssignOrg,
+		}
+	}
+	return signUpOptions.value
+}
+
 // GET /api/user/signup/options
 func GetSignUpOptions(c *middleware.Context) Response {
-	return Json(200, util.DynMap{
-		"verifyEmailEnabled": setting.VerifyEmailEnabled,
-		"autoAssignOrg":      setting.AutoAssignOrg,
-	})
+	return Json(200, getSignUpOptions())
+}
+
+// signUpEmailDomainAllowed checks the email against allowedSignupDomains in
+// the global config. An empty list lets every domain sign up.
+func signUpEmailDomainAllowed(email string) bool {
+	cfg := GetGlobalConfig()
+	if cfg == nil || len(cfg.AllowedSignupDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range cfg.AllowedSignupDomains {
+		if strings.ToLower(strings.TrimSpace(allowed)) == domain {
+			return true
+		}
+	}
+	return false
 }
 
 // POST /api/user/signup
 func SignUp(c *middleware.Context, form dtos.SignUpForm) Response {
 	if !setting.AllowUserSignUp {
-		return ApiError(401, "User signup is disabled", nil)
+		return ApiError(403, "User signup is disabled", nil)
+	}
+
+	if !signUpEmailDomainAllowed(form.Email) {
+		return ApiError(400, m.ErrEmailDomainNotAllowed.Error(), nil)
 	}
 
 	existing := m.GetUserByLoginQuery{LoginOrEmail: form.Email}
@@ -36,7 +83,7 @@ func SignUp(c *middleware.Context, form dtos.SignUpForm) Response {
 	cmd.Status = m.TmpUserSignUpStarted
 	cmd.InvitedByUserId = c.UserId
 	cmd.Code = util.GetRandomString(20)
-	cmd.RemoteAddr = c.Req.RemoteAddr
+	cmd.RemoteAddr = middleware.ClientIP(c.Req.Request).String()
 
 	if err := bus.Dispatch(&cmd); err != nil {
 		return ApiError(500, "Failed to create signup", err)
@@ -54,7 +101,11 @@ func SignUp(c *middleware.Context, form dtos.SignUpForm) Response {
 
 func SignUpStep2(c *middleware.Context, form dtos.SignUpStep2Form) Response {
 	if !setting.AllowUserSignUp {
-		return ApiError(401, "User signup is disabled", nil)
+		return ApiError(403, "User signup is disabled", nil)
+	}
+
+	if !signUpEmailDomainAllowed(form.Email) {
+		return ApiError(400, m.ErrEmailDomainNotAllowed.Error(), nil)
 	}
 
 	createUserCmd := m.CreateUserCommand{
@@ -110,6 +161,16 @@ func SignUpStep2(c *middleware.Context, form dtos.SignUpStep2Form) Response {
 		apiResponse["code"] = "redirect-to-select-org"
 	}
 
+	// the user can only log in once the email is verified
+	if setting.RequireEmailVerification && !user.EmailVerified {
+		if err := bus.Dispatch(&m.SendVerifyEmailCommand{User: user}); err != nil {
+			return ApiError(500, "Failed to send verification email", err)
+		}
+
+		metrics.M_Api_User_SignUpCompleted.Inc(1)
+		return Json(200, util.DynMap{"message": "User sign up completed, verify your email to log in", "code": "verify-email"})
+	}
+
 	loginUserWithUser(user, c)
 	metrics.M_Api_User_SignUpCompleted.Inc(1)
 
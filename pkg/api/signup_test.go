@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Unknwon/macaron"
+	"github.com/macaron-contrib/binding"
+)
+
+func signUpScenario(allowSignUp bool) func(path string, form interface{}) *httptest.ResponseRecorder {
+	setting.AllowUserSignUp = allowSignUp
+	signUpOptions.value = nil
+
+	mac := macaron.New()
+	mac.Use(macaron.Renderer())
+	mac.Use(func(c *macaron.Context) {
+		c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{}, Session: &middleware.SessionWrapper{}})
+	})
+	mac.Get("/api/user/signup/options", wrap(GetSignUpOptions))
+	mac.Post("/api/user/signup", binding.Bind(dtos.SignUpForm{}), wrap(SignUp))
+	mac.Post("/api/user/signup/step2", binding.Bind(dtos.SignUpStep2Form{}), wrap(SignUpStep2))
+
+	return func(path string, form interface{}) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		if form != nil {
+			body, _ := json.Marshal(form)
+			req, _ = http.NewRequest("POST", path, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+		}
+		mac.ServeHTTP(resp, req)
+		return resp
+	}
+}
+
+func TestSignUp(t *testing.T) {
+
+	Convey("Given signup is disabled", t, func() {
+		defer bus.ClearBusHandlers()
+		request := signUpScenario(false)
+
+		Convey("Should report it in the options", func() {
+			resp := request("/api/user/signup/options", nil)
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Body.String(), ShouldContainSubstring, `"signUpEnabled":false`)
+		})
+
+		Convey("Should reject starting a signup", func() {
+			resp := request("/api/user/signup", dtos.SignUpForm{Email: "new@example.com"})
+			So(resp.Code, ShouldEqual, 403)
+		})
+
+		Convey("Should reject completing a signup", func() {
+			resp := request("/api/user/signup/step2", dtos.SignUpStep2Form{Email: "new@example.com", Username: "new"})
+			So(resp.Code, ShouldEqual, 403)
+		})
+	})
+
+	Convey("Given signup is enabled", t, func() {
+		defer bus.ClearBusHandlers()
+		request := signUpScenario(true)
+
+		bus.AddHandler("test", func(query *m.GetUserByLoginQuery) error {
+			return m.ErrUserNotFound
+		})
+
+		var tempUser *m.CreateTempUserCommand
+		bus.AddHandler("test", func(cmd *m.CreateTempUserCommand) error {
+			tempUser = cmd
+			return nil
+		})
+
+		Convey("Should start a signup", func() {
+			resp := request("/api/user/signup", dtos.SignUpForm{Email: "new@example.com"})
+			So(resp.Code, ShouldEqual, 200)
+			So(tempUser, ShouldNotBeNil)
+			So(tempUser.Email, ShouldEqual, "new@example.com")
+		})
+	})
+}
+
+func TestSignUpEmailDomains(t *testing.T) {
+
+	Convey("Given signup is enabled", t, func() {
+		defer bus.ClearBusHandlers()
+
+		globalConfig := configOpenFalcon
+		defer func() { configOpenFalcon = globalConfig }()
+
+		request := signUpScenario(true)
+
+		bus.AddHandler("test", func(query *m.GetUserByLoginQuery) error {
+			return m.ErrUserNotFound
+		})
+		bus.AddHandler("test", func(cmd *m.CreateTempUserCommand) error {
+			return nil
+		})
+
+		Convey("Should allow every domain without an allow-list", func() {
+			configOpenFalcon = &GlobalConfig{}
+
+			resp := request("/api/user/signup", dtos.SignUpForm{Email: "new@example.com"})
+			So(resp.Code, ShouldEqual, 200)
+		})
+
+		Convey("With an allow-list", func() {
+			configOpenFalcon = &GlobalConfig{AllowedSignupDomains: []string{"corp.example.com"}}
+
+			Convey("Should allow a listed domain", func() {
+				resp := request("/api/user/signup", dtos.SignUpForm{Email: "new@Corp.Example.com"})
+				So(resp.Code, ShouldEqual, 200)
+			})
+
+			Convey("Should reject other domains", func() {
+				resp := request("/api/user/signup", dtos.SignUpForm{Email: "new@example.com"})
+				So(resp.Code, ShouldEqual, 400)
+				So(resp.Body.String(), ShouldContainSubstring, m.ErrEmailDomainNotAllowed.Error())
+			})
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// GET /api/teams
+func GetTeams(c *middleware.Context) Response {
+	query := m.GetTeamsByOrgQuery{OrgId: c.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get teams", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// GET /api/teams/:teamId
+func GetTeamById(c *middleware.Context) Response {
+	query := m.GetTeamByIdQuery{Id: c.ParamsInt64(":teamId"), OrgId: c.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get team", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// POST /api/teams
+func CreateTeam(c *middleware.Context, cmd m.CreateTeamCommand) Response {
+	cmd.OrgId = c.OrgId
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to create team", err)
+	}
+
+	return Json(200, cmd.Result)
+}
+
+// PUT /api/teams/:teamId
+func UpdateTeam(c *middleware.Context, cmd m.UpdateTeamCommand) Response {
+	cmd.Id = c.ParamsInt64(":teamId")
+	cmd.OrgId = c.OrgId
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to update team", err)
+	}
+
+	return ApiSuccess("Team updated")
+}
+
+// DELETE /api/teams/:teamId
+func DeleteTeam(c *middleware.Context) Response {
+	cmd := m.DeleteTeamCommand{Id: c.ParamsInt64(":teamId"), OrgId: c.OrgId}
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to delete team", err)
+	}
+
+	return ApiSuccess("Team deleted")
+}
+
+// GET /api/teams/:teamId/members
+func GetTeamMembers(c *middleware.Context) Response {
+	query := m.GetTeamMembersQuery{OrgId: c.OrgId, TeamId: c.ParamsInt64(":teamId")}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get team members", err)
+	}
+
+	return Json(200, query.Result)
+}
+
+// POST /api/teams/:teamId/members
+func AddTeamMember(c *middleware.Context, cmd m.AddTeamMemberCommand) Response {
+	cmd.OrgId = c.OrgId
+	cmd.TeamId = c.ParamsInt64(":teamId")
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to add team member", err)
+	}
+
+	return ApiSuccess("Member added to team")
+}
+
+// DELETE /api/teams/:teamId/members/:userId
+func RemoveTeamMember(c *middleware.Context) Response {
+	cmd := m.RemoveTeamMemberCommand{OrgId: c.OrgId, TeamId: c.ParamsInt64(":teamId"), UserId: c.ParamsInt64(":userId")}
+	if err := bus.Dispatch(&cmd); err != nil {
+		return ApiError(500, "Failed to remove team member", err)
+	}
+
+	return ApiSuccess("Member removed from team")
+}
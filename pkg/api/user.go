@@ -1,9 +1,13 @@
 package api
 
 import (
+	"golang.org/x/net/context"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/middleware"
 	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
 	"github.com/Cepave/grafana/pkg/util"
 )
 
@@ -12,6 +16,18 @@ func GetSignedInUser(c *middleware.Context) Response {
 	return getUserUserProfile(c.UserId)
 }
 
+// GET /api/user/whoami
+func GetWhoAmI(c *middleware.Context) Response {
+	return Json(200, &dtos.WhoAmI{
+		Login:          c.Login,
+		Email:          c.Email,
+		OrgId:          c.OrgId,
+		OrgRole:        c.OrgRole,
+		IsGrafanaAdmin: c.IsGrafanaAdmin,
+		Permissions:    middleware.GetPermissions(c),
+	})
+}
+
 // GET /api/user/:id
 func GetUserById(c *middleware.Context) Response {
 	return getUserUserProfile(c.ParamsInt64(":id"))
@@ -116,7 +132,7 @@ func ChangeUserPassword(c *middleware.Context, cmd m.ChangeUserPasswordCommand)
 		return ApiError(500, "Could not read user from database", err)
 	}
 
-	passwordHashed := util.EncodePassword(cmd.OldPassword, userQuery.Result.Salt)
+	passwordHashed := util.EncodePasswordWithCost(cmd.OldPassword, userQuery.Result.Salt, userQuery.Result.PasswordCost)
 	if passwordHashed != userQuery.Result.Password {
 		return ApiError(401, "Invalid old password", nil)
 	}
@@ -126,7 +142,8 @@ func ChangeUserPassword(c *middleware.Context, cmd m.ChangeUserPasswordCommand)
 	}
 
 	cmd.UserId = c.UserId
-	cmd.NewPassword = util.EncodePassword(cmd.NewPassword, userQuery.Result.Salt)
+	cmd.PasswordCost = setting.PasswordHashCost
+	cmd.NewPassword = util.EncodePasswordWithCost(cmd.NewPassword, userQuery.Result.Salt, cmd.PasswordCost)
 
 	if err := bus.Dispatch(&cmd); err != nil {
 		return ApiError(500, "Failed to change user password", err)
@@ -137,10 +154,18 @@ func ChangeUserPassword(c *middleware.Context, cmd m.ChangeUserPasswordCommand)
 
 // GET /api/users
 func SearchUsers(c *middleware.Context) Response {
-	query := m.SearchUsersQuery{Query: "", Page: 0, Limit: 1000}
-	if err := bus.Dispatch(&query); err != nil {
+	page, perPage := queryPaging(c, 1000)
+	query := m.SearchUsersQuery{Query: "", Page: page, Limit: perPage}
+
+	reqCtx, cancel := c.RequestContext()
+	defer cancel()
+
+	if err := bus.DispatchCtx(reqCtx, &query); err != nil {
+		if err == context.Canceled {
+			return ApiError(400, "Request cancelled", nil)
+		}
 		return ApiError(500, "Failed to fetch users", err)
 	}
 
-	return Json(200, query.Result)
+	return Json(200, pagedResult(c, query.Result, page, perPage, query.TotalCount))
 }
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// GET /api/user/sessions
+func GetUserSessions(c *middleware.Context) Response {
+	query := m.GetUserSessionsQuery{UserId: c.UserId, SeenAfter: time.Now().Add(-setting.UserSessionIdleLifetime())}
+	if setting.LoginMaxLifetime > 0 {
+		query.LoggedInAfter = time.Now().Add(-setting.LoginMaxLifetime)
+	}
+	if err := bus.Dispatch(&query); err != nil {
+		return ApiError(500, "Failed to get sessions", err)
+	}
+
+	currentId, _ := c.Session.Get(middleware.SESS_KEY_USER_SESSION).(int64)
+
+	result := make([]*m.UserSessionDTO, 0, len(query.Result))
+	for _, userSession := range query.Result {
+		result = append(result, &m.UserSessionDTO{
+			Id:        userSession.Id,
+			Created:   userSession.Created,
+			LastSeen:  userSession.Updated,
+			Ip:        userSession.Ip,
+			UserAgent: userSession.UserAgent,
+			IsCurrent: userSession.Id == currentId,
+		})
+	}
+
+	return Json(200, result)
+}
+
+// DELETE /api/user/sessions/:id
+func RevokeUserSession(c *middleware.Context) Response {
+	cmd := m.DeleteUserSessionCommand{Id: c.ParamsInt64(":id"), UserId: c.UserId}
+	if err := bus.Dispatch(&cmd); err != nil {
+		if err == m.ErrUserSessionNotFound {
+			return ApiError(404, "Session not found", err)
+		}
+		return ApiError(500, "Failed to revoke session", err)
+	}
+
+	return ApiSuccess("Session revoked")
+}
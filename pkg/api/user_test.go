@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/Cepave/grafana/pkg/api/dtos"
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/middleware"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func whoAmIScenario(user *m.SignedInUser) dtos.WhoAmI {
+	mac := macaron.New()
+	mac.Use(macaron.Renderer())
+	mac.Use(func(c *macaron.Context) {
+		c.Map(&middleware.Context{Context: c, SignedInUser: user, IsSignedIn: true})
+	})
+	mac.Get("/api/user/whoami", wrap(GetWhoAmI))
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/user/whoami", nil)
+	mac.ServeHTTP(resp, req)
+	So(resp.Code, ShouldEqual, 200)
+
+	var whoami dtos.WhoAmI
+	So(json.Unmarshal(resp.Body.Bytes(), &whoami), ShouldBeNil)
+	return whoami
+}
+
+func TestWhoAmI(t *testing.T) {
+
+	Convey("When an editor asks who they are", t, func() {
+		whoami := whoAmIScenario(&m.SignedInUser{UserId: 2, OrgId: 3, OrgRole: m.ROLE_EDITOR, Login: "editor", Email: "editor@test.com"})
+
+		So(whoami.Login, ShouldEqual, "editor")
+		So(whoami.Email, ShouldEqual, "editor@test.com")
+		So(whoami.OrgId, ShouldEqual, 3)
+		So(whoami.OrgRole, ShouldEqual, m.ROLE_EDITOR)
+		So(whoami.Permissions, ShouldResemble, middleware.Permissions{IsSignedIn: true, IsEditor: true})
+	})
+
+	Convey("When a grafana admin asks who they are", t, func() {
+		whoami := whoAmIScenario(&m.SignedInUser{UserId: 1, OrgId: 1, OrgRole: m.ROLE_ADMIN, Login: "admin", IsGrafanaAdmin: true})
+
+		So(whoami.OrgRole, ShouldEqual, m.ROLE_ADMIN)
+		So(whoami.IsGrafanaAdmin, ShouldBeTrue)
+		So(whoami.Permissions, ShouldResemble, middleware.Permissions{
+			IsSignedIn:     true,
+			IsGrafanaAdmin: true,
+			IsOrgAdmin:     true,
+			IsEditor:       true,
+		})
+	})
+}
+
+func TestSearchUsersCancellation(t *testing.T) {
+
+	Convey("When the client goes away during a user search", t, func() {
+		handlerErr := make(chan error, 1)
+		bus.AddHandler("test", func(ctx context.Context, query *m.SearchUsersQuery) error {
+			<-ctx.Done()
+			handlerErr <- ctx.Err()
+			return ctx.Err()
+		})
+		defer bus.ClearBusHandlers()
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{IsGrafanaAdmin: true}, IsSignedIn: true})
+		})
+		mac.Get("/api/users", wrap(SearchUsers))
+
+		server := httptest.NewServer(mac)
+		defer server.Close()
+
+		client := &http.Client{Timeout: 100 * time.Millisecond}
+		_, err := client.Get(server.URL + "/api/users")
+		So(err, ShouldNotBeNil)
+
+		Convey("Should abort the query with a cancellation error", func() {
+			select {
+			case err := <-handlerErr:
+				So(err, ShouldEqual, context.Canceled)
+			case <-time.After(2 * time.Second):
+				t.Fatal("search was not cancelled")
+			}
+		})
+	})
+
+	Convey("When searching users without a close notifier", t, func() {
+		bus.AddHandler("test", func(ctx context.Context, query *m.SearchUsersQuery) error {
+			query.Result = []*m.UserSearchHitDTO{{Login: "admin"}}
+			return nil
+		})
+		defer bus.ClearBusHandlers()
+
+		mac := macaron.New()
+		mac.Use(macaron.Renderer())
+		mac.Use(func(c *macaron.Context) {
+			c.Map(&middleware.Context{Context: c, SignedInUser: &m.SignedInUser{IsGrafanaAdmin: true}, IsSignedIn: true})
+		})
+		mac.Get("/api/users", wrap(SearchUsers))
+
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/users", nil)
+		mac.ServeHTTP(resp, req)
+
+		So(resp.Code, ShouldEqual, 200)
+	})
+}
@@ -3,6 +3,9 @@ package bus
 import (
 	"fmt"
 	"reflect"
+	"sync"
+
+	"golang.org/x/net/context"
 )
 
 type HandlerFunc interface{}
@@ -10,6 +13,8 @@ type Msg interface{}
 
 type Bus interface {
 	Dispatch(msg Msg) error
+	DispatchCtx(ctx context.Context, msg Msg) error
+	DispatchBatch(msgs []Msg) error
 	Publish(msg Msg) error
 
 	AddHandler(handler HandlerFunc)
@@ -17,6 +22,9 @@ type Bus interface {
 	AddWildcardListener(handler HandlerFunc)
 }
 
+// maxBatchConcurrency bounds the messages of a batch handled at once.
+const maxBatchConcurrency = 4
+
 type InProcBus struct {
 	handlers          map[string]HandlerFunc
 	listeners         map[string][]HandlerFunc
@@ -35,6 +43,12 @@ func New() Bus {
 }
 
 func (b *InProcBus) Dispatch(msg Msg) error {
+	return b.DispatchCtx(context.Background(), msg)
+}
+
+// DispatchCtx hands ctx to handlers that take a context as first argument so
+// they can stop early once it is cancelled. Other handlers are called as usual.
+func (b *InProcBus) DispatchCtx(ctx context.Context, msg Msg) error {
 	var msgName = reflect.TypeOf(msg).Elem().Name()
 
 	var handler = b.handlers[msgName]
@@ -42,8 +56,11 @@ func (b *InProcBus) Dispatch(msg Msg) error {
 		return fmt.Errorf("handler not found for %s", msgName)
 	}
 
-	var params = make([]reflect.Value, 1)
-	params[0] = reflect.ValueOf(msg)
+	var params = make([]reflect.Value, 0, 2)
+	if reflect.TypeOf(handler).NumIn() == 2 {
+		params = append(params, reflect.ValueOf(ctx))
+	}
+	params = append(params, reflect.ValueOf(msg))
 
 	ret := reflect.ValueOf(handler).Call(params)
 	err := ret[0].Interface()
@@ -54,6 +71,38 @@ func (b *InProcBus) Dispatch(msg Msg) error {
 	}
 }
 
+// DispatchBatch dispatches independent messages concurrently and waits for
+// all of them. It returns the error of the first failed message in msgs; a
+// handler that panics fails its message instead of crashing the process.
+func (b *InProcBus) DispatchBatch(msgs []Msg) error {
+	errs := make([]error, len(msgs))
+	slots := make(chan struct{}, maxBatchConcurrency)
+
+	var wg sync.WaitGroup
+	for i, msg := range msgs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, msg Msg) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("handler for %s panicked: %v", reflect.TypeOf(msg).Elem().Name(), r)
+				}
+			}()
+			errs[i] = b.Dispatch(msg)
+		}(i, msg)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (b *InProcBus) Publish(msg Msg) error {
 	var msgName = reflect.TypeOf(msg).Elem().Name()
 	var listeners = b.listeners[msgName]
@@ -86,7 +135,7 @@ func (b *InProcBus) AddWildcardListener(handler HandlerFunc) {
 
 func (b *InProcBus) AddHandler(handler HandlerFunc) {
 	handlerType := reflect.TypeOf(handler)
-	queryTypeName := handlerType.In(0).Elem().Name()
+	queryTypeName := handlerType.In(handlerType.NumIn() - 1).Elem().Name()
 	b.handlers[queryTypeName] = handler
 }
 
@@ -118,6 +167,14 @@ func Dispatch(msg Msg) error {
 	return globalBus.Dispatch(msg)
 }
 
+func DispatchCtx(ctx context.Context, msg Msg) error {
+	return globalBus.DispatchCtx(ctx, msg)
+}
+
+func DispatchBatch(msgs []Msg) error {
+	return globalBus.DispatchBatch(msgs)
+}
+
 func Publish(msg Msg) error {
 	return globalBus.Publish(msg)
 }
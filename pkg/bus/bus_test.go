@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+
+	"golang.org/x/net/context"
 )
 
 type TestQuery struct {
@@ -67,3 +69,103 @@ func TestEventListeners(t *testing.T) {
 		t.Fatal(fmt.Sprintf("Publish event failed, listeners called: %v, expected: %v", count, 11))
 	}
 }
+
+func TestQueryHandlerWithContext(t *testing.T) {
+	bus := New()
+
+	bus.AddHandler(func(ctx context.Context, q *TestQuery) error {
+		return ctx.Err()
+	})
+
+	if err := bus.Dispatch(&TestQuery{}); err != nil {
+		t.Fatal("Dispatch should use a background context, got " + err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := bus.DispatchCtx(ctx, &TestQuery{}); err != context.Canceled {
+		t.Fatal("DispatchCtx should pass the context to the handler")
+	}
+}
+
+func TestDispatchBatch(t *testing.T) {
+	bus := New()
+
+	started := make(chan bool, 2)
+	release := make(chan bool)
+	bus.AddHandler(func(q *TestQuery) error {
+		started <- true
+		<-release
+		q.Resp = fmt.Sprintf("query %d", q.Id)
+		return nil
+	})
+
+	queries := []*TestQuery{{Id: 1}, {Id: 2}}
+	done := make(chan error)
+	go func() {
+		done <- bus.DispatchBatch([]Msg{queries[0], queries[1]})
+	}()
+
+	// both handlers have to be running before either is released
+	<-started
+	<-started
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatal("DispatchBatch failed " + err.Error())
+	}
+	for _, q := range queries {
+		if q.Resp != fmt.Sprintf("query %d", q.Id) {
+			t.Fatalf("Query %d got no result: %q", q.Id, q.Resp)
+		}
+	}
+}
+
+type OtherTestQuery struct {
+	Resp string
+}
+
+func TestDispatchBatchReturnsFirstError(t *testing.T) {
+	bus := New()
+
+	bus.AddHandler(func(q *TestQuery) error {
+		return fmt.Errorf("query %d failed", q.Id)
+	})
+	bus.AddHandler(func(q *OtherTestQuery) error {
+		q.Resp = "ok"
+		return nil
+	})
+
+	other := &OtherTestQuery{}
+	err := bus.DispatchBatch([]Msg{other, &TestQuery{Id: 1}, &TestQuery{Id: 2}})
+
+	if err == nil || err.Error() != "query 1 failed" {
+		t.Fatalf("Expected the error of the first failed query, got %v", err)
+	}
+	if other.Resp != "ok" {
+		t.Fatal("Other queries of the batch should still run")
+	}
+}
+
+func TestDispatchBatchRecoversPanics(t *testing.T) {
+	bus := New()
+
+	bus.AddHandler(func(q *TestQuery) error {
+		panic("boom")
+	})
+	bus.AddHandler(func(q *OtherTestQuery) error {
+		q.Resp = "ok"
+		return nil
+	})
+
+	other := &OtherTestQuery{}
+	err := bus.DispatchBatch([]Msg{&TestQuery{Id: 1}, other})
+
+	if err == nil || err.Error() != "handler for TestQuery panicked: boom" {
+		t.Fatalf("Expected the panic to be returned as an error, got %v", err)
+	}
+	if other.Resp != "ok" {
+		t.Fatal("Other queries of the batch should still run")
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// gracefulServer wraps http.Server so that it can stop accepting
+// connections and wait for in-flight requests before exiting.
+type gracefulServer struct {
+	server   *http.Server
+	handler  http.Handler
+	listener net.Listener
+
+	mu      sync.Mutex
+	active  int
+	closing bool
+	idle    chan struct{}
+	drained bool
+}
+
+func newGracefulServer(addr string, handler http.Handler) *gracefulServer {
+	s := &gracefulServer{
+		handler: handler,
+		idle:    make(chan struct{}),
+	}
+	s.server = &http.Server{Addr: addr, Handler: s}
+	return s
+}
+
+func (s *gracefulServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !s.begin() {
+		w.Header().Set("Connection", "close")
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.end()
+
+	s.handler.ServeHTTP(w, req)
+}
+
+func (s *gracefulServer) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return false
+	}
+	s.active++
+	return true
+}
+
+func (s *gracefulServer) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	s.signalIfDrained()
+}
+
+// signalIfDrained must be called with mu held.
+func (s *gracefulServer) signalIfDrained() {
+	if s.closing && s.active == 0 && !s.drained {
+		s.drained = true
+		close(s.idle)
+	}
+}
+
+func (s *gracefulServer) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+func (s *gracefulServer) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+func (s *gracefulServer) ListenAndServeTLS(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"http/1.1"},
+	}
+	return s.Serve(tls.NewListener(l, config))
+}
+
+func (s *gracefulServer) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
+	err := s.server.Serve(l)
+	if s.isClosing() {
+		return nil
+	}
+	return err
+}
+
+// Shutdown closes the listener and waits for in-flight requests to finish,
+// giving up when ctx is done.
+func (s *gracefulServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	s.server.SetKeepAlivesEnabled(false)
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	s.signalIfDrained()
+	s.mu.Unlock()
+
+	select {
+	case <-s.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+)
+
+func startSlowServer(delay time.Duration) (*gracefulServer, string, chan bool) {
+	started := make(chan bool, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		started <- true
+		time.Sleep(delay)
+		w.Write([]byte("done"))
+	})
+
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	server := newGracefulServer(l.Addr().String(), handler)
+	go server.Serve(l)
+
+	return server, "http://" + l.Addr().String(), started
+}
+
+func TestGracefulServer(t *testing.T) {
+
+	Convey("When shutting down with a slow request in flight", t, func() {
+		server, url, started := startSlowServer(200 * time.Millisecond)
+
+		type result struct {
+			body string
+			err  error
+		}
+		resultChan := make(chan result, 1)
+		go func() {
+			resp, err := http.Get(url)
+			if err != nil {
+				resultChan <- result{err: err}
+				return
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			resultChan <- result{body: string(body), err: err}
+		}()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		err := server.Shutdown(ctx)
+
+		Convey("Should wait for the request to finish", func() {
+			So(err, ShouldBeNil)
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 100*time.Millisecond)
+
+			res := <-resultChan
+			So(res.err, ShouldBeNil)
+			So(res.body, ShouldEqual, "done")
+		})
+
+		Convey("Should stop accepting new connections", func() {
+			_, err := http.Get(url)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("When the request outlives the shutdown deadline", t, func() {
+		server, url, started := startSlowServer(time.Second)
+		go http.Get(url)
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := server.Shutdown(ctx)
+
+		Convey("Should give up with the context error", func() {
+			So(err == context.DeadlineExceeded, ShouldBeTrue)
+		})
+	})
+
+	Convey("When shutting down an idle server", t, func() {
+		server, _, _ := startSlowServer(0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		So(server.Shutdown(ctx), ShouldBeNil)
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"path"
 
 	"github.com/Unknwon/macaron"
+	"golang.org/x/net/context"
 
 	"github.com/Cepave/grafana/pkg/api"
 	"github.com/Cepave/grafana/pkg/api/static"
@@ -21,19 +22,24 @@ func newMacaron() *macaron.Macaron {
 	macaron.Env = setting.Env
 	m := macaron.New()
 
+	m.Use(middleware.Recovery())
+	m.Use(middleware.RequestId())
 	m.Use(middleware.Logger())
-	m.Use(macaron.Recovery())
+
+	if setting.EnforceHTTPS {
+		m.Use(middleware.EnforceHTTPS())
+	}
 
 	if setting.EnableGzip {
 		m.Use(middleware.Gziper())
 	}
 
-	mapStatic(m, "", "public")
-	mapStatic(m, "app", "app")
-	mapStatic(m, "css", "css")
-	mapStatic(m, "img", "img")
-	mapStatic(m, "fonts", "fonts")
-	mapStatic(m, "robots.txt", "robots.txt")
+	mapStatic(m, "", setting.RoutePath("/public"))
+	mapStatic(m, "app", setting.RoutePath("/app"))
+	mapStatic(m, "css", setting.RoutePath("/css"))
+	mapStatic(m, "img", setting.RoutePath("/img"))
+	mapStatic(m, "fonts", setting.RoutePath("/fonts"))
+	mapStatic(m, "robots.txt", setting.RoutePath("/robots.txt"))
 
 	m.Use(macaron.Renderer(macaron.RenderOptions{
 		Directory:  path.Join(setting.StaticRootPath, "views"),
@@ -47,6 +53,8 @@ func newMacaron() *macaron.Macaron {
 
 	m.Use(middleware.GetContextHandler())
 	m.Use(middleware.Sessioner(&setting.SessionOptions))
+	m.Use(middleware.CSRF())
+	m.Use(middleware.Maintenance())
 
 	return m
 }
@@ -72,19 +80,28 @@ func mapStatic(m *macaron.Macaron, dir string, prefix string) {
 	))
 }
 
+var httpServer *gracefulServer
+
 func StartServer() {
 
 	var err error
+	api.LoadConfig()
 	m := newMacaron()
 	api.Register(m)
 
+	if setting.MetricsEndpointEnabled && setting.MetricsListenAddr != "" {
+		go startMetricsServer(setting.MetricsListenAddr)
+	}
+
 	listenAddr := fmt.Sprintf("%s:%s", setting.HttpAddr, setting.HttpPort)
-	log.Info("Listen: %v://%s%s", setting.Protocol, listenAddr, setting.AppSubUrl)
+	httpServer = newGracefulServer(listenAddr, m)
+
+	log.Info("Listen: %v://%s%s", setting.Protocol, listenAddr, setting.AppPath(""))
 	switch setting.Protocol {
 	case setting.HTTP:
-		err = http.ListenAndServe(listenAddr, m)
+		err = httpServer.ListenAndServe()
 	case setting.HTTPS:
-		err = http.ListenAndServeTLS(listenAddr, setting.CertFile, setting.KeyFile, m)
+		err = httpServer.ListenAndServeTLS(setting.CertFile, setting.KeyFile)
 	default:
 		log.Fatal(4, "Invalid protocol: %s", setting.Protocol)
 	}
@@ -93,3 +110,24 @@ func StartServer() {
 		log.Fatal(4, "Fail to start server: %v", err)
 	}
 }
+
+func startMetricsServer(listenAddr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+		api.GetPrometheusMetrics(w)
+	})
+
+	log.Info("Metrics listen: http://%s/metrics", listenAddr)
+	if err := http.ListenAndServe(listenAddr, mux); err != nil {
+		log.Error(3, "Fail to start metrics server: %v", err)
+	}
+}
+
+// Shutdown stops the http server from accepting new connections and waits
+// for in-flight requests until ctx is done.
+func Shutdown(ctx context.Context) error {
+	if httpServer == nil {
+		return nil
+	}
+	return httpServer.Shutdown(ctx)
+}
@@ -1,6 +1,7 @@
 package renderer
 
 import (
+	"errors"
 	"io"
 	"os"
 	"os/exec"
@@ -12,11 +13,15 @@ import (
 	"github.com/Cepave/grafana/pkg/util"
 )
 
+var ErrTimeout = errors.New("Rendering timed out")
+
 type RenderOpts struct {
 	Url       string
 	Width     string
 	Height    string
+	Scale     string
 	SessionId string
+	Timeout   time.Duration
 }
 
 func RenderToPng(params *RenderOpts) (string, error) {
@@ -27,7 +32,7 @@ func RenderToPng(params *RenderOpts) (string, error) {
 	pngPath = pngPath + ".png"
 
 	cmd := exec.Command(binPath, "--ignore-ssl-errors=true", "--ssl-protocol=any", scriptPath, "url="+params.Url, "width="+params.Width,
-		"height="+params.Height, "png="+pngPath, "cookiename="+setting.SessionOptions.CookieName,
+		"height="+params.Height, "scale="+params.Scale, "png="+pngPath, "cookiename="+setting.SessionOptions.CookieName,
 		"domain="+setting.Domain, "sessionid="+params.SessionId)
 	stdout, err := cmd.StdoutPipe()
 
@@ -53,11 +58,17 @@ func RenderToPng(params *RenderOpts) (string, error) {
 		close(done)
 	}()
 
+	timeout := params.Timeout
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+
 	select {
-	case <-time.After(15 * time.Second):
+	case <-time.After(timeout):
 		if err := cmd.Process.Kill(); err != nil {
 			log.Error(4, "failed to kill: %v", err)
 		}
+		return "", ErrTimeout
 	case <-done:
 	}
 
@@ -62,6 +62,11 @@ type OrgUpdated struct {
 	Name      string    `json:"name"`
 }
 
+type OrgDeleted struct {
+	Timestamp time.Time `json:"timestamp"`
+	Id        int64     `json:"id"`
+}
+
 type UserCreated struct {
 	Timestamp time.Time `json:"timestamp"`
 	Id        int64     `json:"id"`
@@ -89,3 +94,12 @@ type UserUpdated struct {
 	Login     string    `json:"login"`
 	Email     string    `json:"email"`
 }
+
+type DashboardSaved struct {
+	Timestamp time.Time `json:"timestamp"`
+	Id        int64     `json:"id"`
+	OrgId     int64     `json:"orgId"`
+	Slug      string    `json:"slug"`
+	Title     string    `json:"title"`
+	Version   int       `json:"version"`
+}
@@ -10,6 +10,7 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"time"
 )
 
 type Brush func(string) string
@@ -46,8 +47,9 @@ var (
 // ConsoleWriter implements LoggerInterface and writes messages to terminal.
 type ConsoleWriter struct {
 	lg         *log.Logger
-	Level      int  `json:"level"`
-	Formatting bool `json:"formatting"`
+	Level      int    `json:"level"`
+	Formatting bool   `json:"formatting"`
+	Format     string `json:"format"`
 }
 
 // create ConsoleWriter returning as LoggerInterface.
@@ -60,13 +62,26 @@ func NewConsole() LoggerInterface {
 }
 
 func (cw *ConsoleWriter) Init(config string) error {
-	return json.Unmarshal([]byte(config), cw)
+	if err := json.Unmarshal([]byte(config), cw); err != nil {
+		return err
+	}
+	if cw.Format == FORMAT_JSON {
+		// json lines carry their own time
+		cw.lg.SetFlags(0)
+	}
+	return nil
 }
 
-func (cw *ConsoleWriter) WriteMsg(msg string, skip, level int) error {
+func (cw *ConsoleWriter) WriteMsg(msg string, fields Fields, skip, level int) error {
 	if cw.Level > level {
 		return nil
 	}
+	if cw.Format == FORMAT_JSON {
+		cw.lg.Println(formatJson(msg, fields, level, time.Now()))
+		return nil
+	}
+
+	msg += fields.String()
 	if runtime.GOOS == "windows" || !cw.Formatting {
 		cw.lg.Println(msg)
 	} else {
@@ -83,11 +98,11 @@ func (_ *ConsoleWriter) Destroy() {
 }
 
 func printConsole(level int, msg string) {
-	consoleWriter.WriteMsg(msg, 0, level)
+	consoleWriter.WriteMsg(msg, nil, 0, level)
 }
 
 func printfConsole(level int, format string, v ...interface{}) {
-	consoleWriter.WriteMsg(fmt.Sprintf(format, v...), 0, level)
+	consoleWriter.WriteMsg(fmt.Sprintf(format, v...), nil, 0, level)
 }
 
 // ConsoleTrace prints to stdout using TRACE colors
@@ -39,6 +39,8 @@ type FileLogWriter struct {
 
 	Rotate bool `json:"rotate"`
 
+	Format string `json:"format"`
+
 	startLock sync.Mutex // Only one log can write to the file
 
 	Level int `json:"level"`
@@ -100,6 +102,9 @@ func (w *FileLogWriter) Init(config string) error {
 	if len(w.Filename) == 0 {
 		return errors.New("config must have filename")
 	}
+	if w.Format == FORMAT_JSON {
+		w.Logger.SetFlags(0)
+	}
 	return w.StartLogger()
 }
 
@@ -132,10 +137,15 @@ func (w *FileLogWriter) docheck(size int) {
 }
 
 // write logger message into file.
-func (w *FileLogWriter) WriteMsg(msg string, skip, level int) error {
+func (w *FileLogWriter) WriteMsg(msg string, fields Fields, skip, level int) error {
 	if level < w.Level {
 		return nil
 	}
+	if w.Format == FORMAT_JSON {
+		msg = formatJson(msg, fields, level, time.Now())
+	} else {
+		msg += fields.String()
+	}
 	n := 24 + len(msg) // 24 stand for the length "2013/06/23 21:00:22 [T] "
 	w.docheck(n)
 	w.Logger.Println(msg)
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
+var levelNames = []string{"trace", "debug", "info", "warn", "error", "critical", "fatal"}
+
+var (
+	// "[file.go:12 fn()] " added in front of errors by writerMsg
+	callerPattern = regexp.MustCompile(`^\[([^\]\s]+:\d+ [^\]]*)\] `)
+	// "[E] " added by the Logger level methods
+	levelPrefixPattern = regexp.MustCompile(`^\[[TDIWECF]\] `)
+)
+
+// formatJson turns a message built by Logger into a single json line with
+// the level, time, message and the non empty fields.
+func formatJson(msg string, fields Fields, level int, t time.Time) string {
+	entry := map[string]string{}
+	for k, v := range fields {
+		if v != "" {
+			entry[k] = v
+		}
+	}
+
+	entry["time"] = t.Format(time.RFC3339Nano)
+	if level >= 0 && level < len(levelNames) {
+		entry["level"] = levelNames[level]
+	}
+
+	if match := callerPattern.FindStringSubmatch(msg); match != nil {
+		entry["caller"] = match[1]
+		msg = msg[len(match[0]):]
+	}
+	entry["message"] = strings.TrimSpace(levelPrefixPattern.ReplaceAllString(msg, ""))
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return msg
+	}
+	return string(line)
+}
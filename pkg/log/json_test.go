@@ -0,0 +1,73 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJsonFormat(t *testing.T) {
+
+	Convey("Given a console writer in json mode", t, func() {
+		cw := NewConsole().(*ConsoleWriter)
+		So(cw.Init(`{"level": 0, "format": "json"}`), ShouldBeNil)
+
+		var buf bytes.Buffer
+		cw.lg = log.New(&buf, "", cw.lg.Flags())
+
+		Convey("Should write one json object per message", func() {
+			cw.WriteMsg("[I] Completed /api/search 200 OK in 2ms", Fields{"request_id": "abc-123", "route": "/api/search"}, 0, INFO)
+
+			var entry map[string]string
+			So(json.Unmarshal(buf.Bytes(), &entry), ShouldBeNil)
+			So(entry["level"], ShouldEqual, "info")
+			So(entry["message"], ShouldEqual, "Completed /api/search 200 OK in 2ms")
+			So(entry["request_id"], ShouldEqual, "abc-123")
+			So(entry["route"], ShouldEqual, "/api/search")
+
+			_, err := time.Parse(time.RFC3339Nano, entry["time"])
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Should move the caller of errors into its own field", func() {
+			cw.WriteMsg("[middleware.go:233 Handle()] [E] Failed to save: boom", Fields{"request_id": ""}, 0, ERROR)
+
+			var entry map[string]string
+			So(json.Unmarshal(buf.Bytes(), &entry), ShouldBeNil)
+			So(entry["level"], ShouldEqual, "error")
+			So(entry["caller"], ShouldEqual, "middleware.go:233 Handle()")
+			So(entry["message"], ShouldEqual, "Failed to save: boom")
+			_, hasRequestId := entry["request_id"]
+			So(hasRequestId, ShouldBeFalse)
+		})
+
+		Convey("Should not read fields from the message text", func() {
+			cw.WriteMsg("[I] Completed /a request_id=forged route=/forged", Fields{"request_id": "abc-123"}, 0, INFO)
+
+			var entry map[string]string
+			So(json.Unmarshal(buf.Bytes(), &entry), ShouldBeNil)
+			So(entry["message"], ShouldEqual, "Completed /a request_id=forged route=/forged")
+			So(entry["request_id"], ShouldEqual, "abc-123")
+			_, hasRoute := entry["route"]
+			So(hasRoute, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given a console writer in text mode", t, func() {
+		cw := NewConsole().(*ConsoleWriter)
+		So(cw.Init(`{"level": 0, "formatting": false}`), ShouldBeNil)
+
+		var buf bytes.Buffer
+		cw.lg = log.New(&buf, "", 0)
+
+		Convey("Should append the fields to the message", func() {
+			cw.WriteMsg("[I] Completed /api/search", Fields{"route": "/api/search", "request_id": "abc-123"}, 0, INFO)
+
+			So(buf.String(), ShouldEqual, "[I] Completed /api/search request_id=abc-123 route=/api/search\n")
+		})
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -65,6 +66,20 @@ func Error(skip int, format string, v ...interface{}) {
 	}
 }
 
+// InfoFields is Info with fields kept apart from the message.
+func InfoFields(fields Fields, format string, v ...interface{}) {
+	for _, logger := range loggers {
+		logger.InfoFields(fields, format, v...)
+	}
+}
+
+// ErrorFields is Error with fields kept apart from the message.
+func ErrorFields(skip int, fields Fields, format string, v ...interface{}) {
+	for _, logger := range loggers {
+		logger.ErrorFields(skip, fields, format, v...)
+	}
+}
+
 func Critical(skip int, format string, v ...interface{}) {
 	for _, logger := range loggers {
 		logger.Critical(skip, format, v...)
@@ -108,10 +123,30 @@ const (
 	FATAL
 )
 
+// Fields are request correlation values such as request_id or route. They are
+// passed next to the message rather than inside it, so text that comes from
+// the request can't pass for one of them.
+type Fields map[string]string
+
+// String renders the fields as " key=value" pairs for text output.
+func (f Fields) String() string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf []string
+	for _, k := range keys {
+		buf = append(buf, " "+k+"="+f[k])
+	}
+	return strings.Join(buf, "")
+}
+
 // LoggerInterface represents behaviors of a logger provider.
 type LoggerInterface interface {
 	Init(config string) error
-	WriteMsg(msg string, skip, level int) error
+	WriteMsg(msg string, fields Fields, skip, level int) error
 	Destroy()
 	Flush()
 }
@@ -134,6 +169,7 @@ func Register(name string, log loggerType) {
 type logMsg struct {
 	skip, level int
 	msg         string
+	fields      Fields
 }
 
 // Logger is default logger in beego application.
@@ -188,13 +224,14 @@ func (l *Logger) DelLogger(adapter string) error {
 	return nil
 }
 
-func (l *Logger) writerMsg(skip, level int, msg string) error {
+func (l *Logger) writerMsg(skip, level int, msg string, fields Fields) error {
 	if l.level > level {
 		return nil
 	}
 	lm := &logMsg{
-		skip:  skip,
-		level: level,
+		skip:   skip,
+		level:  level,
+		fields: fields,
 	}
 
 	// Only error information needs locate position for debugging.
@@ -227,7 +264,7 @@ func (l *Logger) StartLogger() {
 		select {
 		case bm := <-l.msg:
 			for _, l := range l.outputs {
-				if err := l.WriteMsg(bm.msg, bm.skip, bm.level); err != nil {
+				if err := l.WriteMsg(bm.msg, bm.fields, bm.skip, bm.level); err != nil {
 					fmt.Println("ERROR, unable to WriteMsg:", err)
 				}
 			}
@@ -251,7 +288,7 @@ func (l *Logger) Close() {
 		if len(l.msg) > 0 {
 			bm := <-l.msg
 			for _, l := range l.outputs {
-				if err := l.WriteMsg(bm.msg, bm.skip, bm.level); err != nil {
+				if err := l.WriteMsg(bm.msg, bm.fields, bm.skip, bm.level); err != nil {
 					fmt.Println("ERROR, unable to WriteMsg:", err)
 				}
 			}
@@ -267,37 +304,47 @@ func (l *Logger) Close() {
 
 func (l *Logger) Trace(format string, v ...interface{}) {
 	msg := fmt.Sprintf("[T] "+format, v...)
-	l.writerMsg(0, TRACE, msg)
+	l.writerMsg(0, TRACE, msg, nil)
 }
 
 func (l *Logger) Debug(format string, v ...interface{}) {
 	msg := fmt.Sprintf("[D] "+format, v...)
-	l.writerMsg(0, DEBUG, msg)
+	l.writerMsg(0, DEBUG, msg, nil)
 }
 
 func (l *Logger) Info(format string, v ...interface{}) {
 	msg := fmt.Sprintf("[I] "+format, v...)
-	l.writerMsg(0, INFO, msg)
+	l.writerMsg(0, INFO, msg, nil)
 }
 
 func (l *Logger) Warn(format string, v ...interface{}) {
 	msg := fmt.Sprintf("[W] "+format, v...)
-	l.writerMsg(0, WARN, msg)
+	l.writerMsg(0, WARN, msg, nil)
 }
 
 func (l *Logger) Error(skip int, format string, v ...interface{}) {
 	msg := fmt.Sprintf("[E] "+format, v...)
-	l.writerMsg(skip, ERROR, msg)
+	l.writerMsg(skip, ERROR, msg, nil)
+}
+
+func (l *Logger) InfoFields(fields Fields, format string, v ...interface{}) {
+	msg := fmt.Sprintf("[I] "+format, v...)
+	l.writerMsg(0, INFO, msg, fields)
+}
+
+func (l *Logger) ErrorFields(skip int, fields Fields, format string, v ...interface{}) {
+	msg := fmt.Sprintf("[E] "+format, v...)
+	l.writerMsg(skip, ERROR, msg, fields)
 }
 
 func (l *Logger) Critical(skip int, format string, v ...interface{}) {
 	msg := fmt.Sprintf("[C] "+format, v...)
-	l.writerMsg(skip, CRITICAL, msg)
+	l.writerMsg(skip, CRITICAL, msg, nil)
 }
 
 func (l *Logger) Fatal(skip int, format string, v ...interface{}) {
 	msg := fmt.Sprintf("[F] "+format, v...)
-	l.writerMsg(skip, FATAL, msg)
+	l.writerMsg(skip, FATAL, msg, nil)
 	l.Close()
 	os.Exit(1)
 }
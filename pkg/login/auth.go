@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/log"
 	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/setting"
 	"github.com/Cepave/grafana/pkg/util"
@@ -55,11 +56,37 @@ func loginUsingGrafanaDB(query *LoginUserQuery) error {
 
 	user := userQuery.Result
 
-	passwordHashed := util.EncodePassword(query.Password, user.Salt)
+	passwordHashed := util.EncodePasswordWithCost(query.Password, user.Salt, user.PasswordCost)
 	if passwordHashed != user.Password {
 		return ErrInvalidCredentials
 	}
 
+	if setting.RequireEmailVerification && !user.EmailVerified && !user.IsAdmin {
+		return m.ErrEmailNotVerified
+	}
+
+	if user.PasswordCost != setting.PasswordHashCost {
+		rehashPassword(user, query.Password)
+	}
+
 	query.User = user
 	return nil
 }
+
+// rehashPassword upgrades the user's password hash to the configured cost,
+// on failure the old hash is kept until the next login.
+func rehashPassword(user *m.User, password string) {
+	cmd := m.ChangeUserPasswordCommand{
+		UserId:       user.Id,
+		NewPassword:  util.EncodePasswordWithCost(password, user.Salt, setting.PasswordHashCost),
+		PasswordCost: setting.PasswordHashCost,
+	}
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		log.Error(3, "Failed to rehash password of user %v: %v", user.Id, err)
+		return
+	}
+
+	user.Password = cmd.NewPassword
+	user.PasswordCost = cmd.PasswordCost
+}
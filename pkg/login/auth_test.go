@@ -0,0 +1,96 @@
+package login
+
+import (
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Cepave/grafana/pkg/util"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoginUsingGrafanaDB(t *testing.T) {
+
+	Convey("Given the configured password hash cost", t, func() {
+		defer bus.ClearBusHandlers()
+		setting.PasswordHashCost = 20000
+		defer func() { setting.PasswordHashCost = util.DefaultPasswordCost }()
+
+		user := &m.User{Id: 7, Login: "user", Salt: "salt"}
+		bus.AddHandler("test", func(query *m.GetUserByLoginQuery) error {
+			query.Result = user
+			return nil
+		})
+
+		var changed *m.ChangeUserPasswordCommand
+		bus.AddHandler("test", func(cmd *m.ChangeUserPasswordCommand) error {
+			changed = cmd
+			return nil
+		})
+
+		Convey("When logging in with a hash of an old cost", func() {
+			user.Password = util.EncodePassword("pwd", "salt")
+
+			err := loginUsingGrafanaDB(&LoginUserQuery{Username: "user", Password: "pwd"})
+			So(err, ShouldBeNil)
+
+			Convey("Should rehash the password with the configured cost", func() {
+				So(changed, ShouldNotBeNil)
+				So(changed.UserId, ShouldEqual, 7)
+				So(changed.PasswordCost, ShouldEqual, 20000)
+				So(changed.NewPassword, ShouldEqual, util.EncodePasswordWithCost("pwd", "salt", 20000))
+				So(user.PasswordCost, ShouldEqual, 20000)
+			})
+		})
+
+		Convey("When logging in with a hash of the configured cost", func() {
+			user.PasswordCost = 20000
+			user.Password = util.EncodePasswordWithCost("pwd", "salt", 20000)
+
+			err := loginUsingGrafanaDB(&LoginUserQuery{Username: "user", Password: "pwd"})
+			So(err, ShouldBeNil)
+
+			Convey("Should keep the hash", func() {
+				So(changed, ShouldBeNil)
+			})
+		})
+
+		Convey("When email verification is required", func() {
+			setting.RequireEmailVerification = true
+			defer func() { setting.RequireEmailVerification = false }()
+
+			user.PasswordCost = 20000
+			user.Password = util.EncodePasswordWithCost("pwd", "salt", 20000)
+
+			Convey("Should block the login while the email is unverified", func() {
+				err := loginUsingGrafanaDB(&LoginUserQuery{Username: "user", Password: "pwd"})
+				So(err, ShouldEqual, m.ErrEmailNotVerified)
+			})
+
+			Convey("Should allow the login once the email is verified", func() {
+				user.EmailVerified = true
+
+				query := LoginUserQuery{Username: "user", Password: "pwd"}
+				So(loginUsingGrafanaDB(&query), ShouldBeNil)
+				So(query.User, ShouldEqual, user)
+			})
+
+			Convey("Should allow grafana admins in", func() {
+				user.IsAdmin = true
+				So(loginUsingGrafanaDB(&LoginUserQuery{Username: "user", Password: "pwd"}), ShouldBeNil)
+			})
+		})
+
+		Convey("When logging in with a wrong password", func() {
+			user.Password = util.EncodePassword("pwd", "salt")
+
+			err := loginUsingGrafanaDB(&LoginUserQuery{Username: "user", Password: "other"})
+
+			Convey("Should not rehash", func() {
+				So(err, ShouldEqual, ErrInvalidCredentials)
+				So(changed, ShouldBeNil)
+			})
+		})
+	})
+}
@@ -135,9 +135,10 @@ func (a *ldapAuther) getGrafanaUserFor(ldapUser *ldapUserInfo) (*m.User, error)
 
 func (a *ldapAuther) createGrafanaUser(ldapUser *ldapUserInfo) (*m.User, error) {
 	cmd := m.CreateUserCommand{
-		Login: ldapUser.Username,
-		Email: ldapUser.Email,
-		Name:  fmt.Sprintf("%s %s", ldapUser.FirstName, ldapUser.LastName),
+		Login:         ldapUser.Username,
+		Email:         ldapUser.Email,
+		Name:          fmt.Sprintf("%s %s", ldapUser.FirstName, ldapUser.LastName),
+		EmailVerified: true,
 	}
 
 	if err := bus.Dispatch(&cmd); err != nil {
@@ -159,6 +160,7 @@ func (a *ldapAuther) syncUserInfo(user *m.User, ldapUser *ldapUserInfo) error {
 	updateCmd.Login = user.Login
 	updateCmd.Email = ldapUser.Email
 	updateCmd.Name = fmt.Sprintf("%s %s", ldapUser.FirstName, ldapUser.LastName)
+	updateCmd.EmailVerified = true
 	return bus.Dispatch(&updateCmd)
 }
 
@@ -73,6 +73,7 @@ func TestLdapAuther(t *testing.T) {
 			Convey("Should create new user", func() {
 				So(sc.createUserCmd.Login, ShouldEqual, "torkelo")
 				So(sc.createUserCmd.Email, ShouldEqual, "my@email.com")
+				So(sc.createUserCmd.EmailVerified, ShouldBeTrue)
 			})
 
 			Convey("Should return new user", func() {
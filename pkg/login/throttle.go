@@ -0,0 +1,153 @@
+package login
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// maxThrottledLogins caps how many login names are tracked, so a flood of
+// made up names can't grow the map without bounds.
+const maxThrottledLogins = 10000
+
+// Throttle counts failed logins per login name, whether or not a user with
+// that name exists, and locks the name once too many have failed within the
+// lockout period.
+type Throttle struct {
+	mutex    sync.Mutex
+	attempts map[string]*loginAttempts
+	now      func() time.Time
+	// fullUntil locks the login names that are not tracked, set when the
+	// map holds nothing but locked names and a new one can't be added.
+	fullUntil time.Time
+}
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Attempts is the throttle shared by the login form and basic auth.
+var Attempts = NewThrottle()
+
+func NewThrottle() *Throttle {
+	return &Throttle{
+		attempts: make(map[string]*loginAttempts),
+		now:      time.Now,
+	}
+}
+
+func lockoutPeriod() time.Duration {
+	return time.Duration(setting.LoginLockoutSeconds) * time.Second
+}
+
+// expired tells whether the attempts no longer matter, the lockout is over
+// or the last failure is older than the lockout period.
+func (a *loginAttempts) expired(now time.Time) bool {
+	if !a.lockedUntil.IsZero() {
+		return !now.Before(a.lockedUntil)
+	}
+	return now.Sub(a.lastFailure) >= lockoutPeriod()
+}
+
+// LockedFor returns how long the login name stays locked, zero if it is not.
+func (t *Throttle) LockedFor(login string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key := strings.ToLower(login)
+	attempts, exists := t.attempts[key]
+	now := t.now()
+	if !exists {
+		if now.Before(t.fullUntil) {
+			return t.fullUntil.Sub(now)
+		}
+		return 0
+	}
+
+	if attempts.expired(now) {
+		// start counting from scratch
+		delete(t.attempts, key)
+		return 0
+	}
+	if attempts.lockedUntil.IsZero() {
+		return 0
+	}
+
+	return attempts.lockedUntil.Sub(now)
+}
+
+func (t *Throttle) Failed(login string) {
+	if setting.LoginMaxFailedAttempts <= 0 {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	key := strings.ToLower(login)
+	attempts, exists := t.attempts[key]
+	if exists && attempts.expired(now) {
+		exists = false
+	}
+	if !exists {
+		if !t.makeRoom(now) {
+			// every tracked name is locked, fail closed rather than
+			// unlocking one of them
+			t.fullUntil = now.Add(lockoutPeriod())
+			return
+		}
+		attempts = &loginAttempts{}
+		t.attempts[key] = attempts
+	}
+
+	attempts.failures++
+	attempts.lastFailure = now
+	if attempts.failures >= setting.LoginMaxFailedAttempts {
+		attempts.lockedUntil = now.Add(lockoutPeriod())
+	}
+}
+
+func (t *Throttle) Reset(login string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.attempts, strings.ToLower(login))
+}
+
+// makeRoom drops the expired entries once the map is full, and the unlocked
+// entry failed longest ago when none has expired. Locked entries are never
+// dropped, it returns false when only those are left.
+func (t *Throttle) makeRoom(now time.Time) bool {
+	if len(t.attempts) < maxThrottledLogins {
+		return true
+	}
+
+	var oldestKey string
+	var oldest time.Time
+	for key, attempts := range t.attempts {
+		if attempts.expired(now) {
+			delete(t.attempts, key)
+			continue
+		}
+		if !attempts.lockedUntil.IsZero() {
+			continue
+		}
+		if oldestKey == "" || attempts.lastFailure.Before(oldest) {
+			oldestKey, oldest = key, attempts.lastFailure
+		}
+	}
+
+	if len(t.attempts) < maxThrottledLogins {
+		return true
+	}
+	if oldestKey == "" {
+		return false
+	}
+	delete(t.attempts, oldestKey)
+	return true
+}
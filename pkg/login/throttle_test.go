@@ -0,0 +1,93 @@
+package login
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/setting"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoginThrottle(t *testing.T) {
+
+	Convey("Given login throttling after 3 failed attempts", t, func() {
+		setting.LoginMaxFailedAttempts = 3
+		setting.LoginLockoutSeconds = 60
+
+		now := time.Now()
+		throttle := NewThrottle()
+		throttle.now = func() time.Time { return now }
+
+		Convey("Should lock the login name once the threshold is hit", func() {
+			for i := 0; i < 3; i++ {
+				So(throttle.LockedFor("admin"), ShouldEqual, 0)
+				throttle.Failed("admin")
+			}
+
+			So(throttle.LockedFor("Admin"), ShouldEqual, 60*time.Second)
+
+			Convey("And unlock it after the cooldown", func() {
+				now = now.Add(61 * time.Second)
+				So(throttle.LockedFor("admin"), ShouldEqual, 0)
+				So(len(throttle.attempts), ShouldEqual, 0)
+			})
+		})
+
+		Convey("Should forget failures older than the lockout period", func() {
+			throttle.Failed("admin")
+			throttle.Failed("admin")
+
+			now = now.Add(61 * time.Second)
+			throttle.Failed("admin")
+
+			So(throttle.LockedFor("admin"), ShouldEqual, 0)
+			So(throttle.attempts["admin"].failures, ShouldEqual, 1)
+		})
+
+		Convey("Should cap the number of tracked login names", func() {
+			for i := 0; i < maxThrottledLogins; i++ {
+				throttle.Failed(fmt.Sprintf("user%d", i))
+				now = now.Add(time.Millisecond)
+			}
+
+			throttle.Failed("one-more")
+
+			So(len(throttle.attempts), ShouldEqual, maxThrottledLogins)
+			So(throttle.attempts["user0"], ShouldBeNil)
+			So(throttle.attempts["one-more"], ShouldNotBeNil)
+		})
+
+		Convey("Should keep a locked login name when flooded with made up names", func() {
+			for i := 0; i < 3; i++ {
+				throttle.Failed("admin")
+			}
+			for i := 0; i < maxThrottledLogins+10; i++ {
+				now = now.Add(time.Millisecond)
+				throttle.Failed(fmt.Sprintf("user%d", i))
+			}
+
+			So(len(throttle.attempts), ShouldEqual, maxThrottledLogins)
+			So(throttle.LockedFor("admin"), ShouldBeGreaterThan, 0)
+		})
+
+		Convey("Should lock untracked names when only locked names are tracked", func() {
+			setting.LoginMaxFailedAttempts = 1
+			for i := 0; i < maxThrottledLogins; i++ {
+				throttle.Failed(fmt.Sprintf("user%d", i))
+			}
+
+			throttle.Failed("one-more")
+
+			So(len(throttle.attempts), ShouldEqual, maxThrottledLogins)
+			So(throttle.LockedFor("user0"), ShouldEqual, 60*time.Second)
+			So(throttle.LockedFor("one-more"), ShouldEqual, 60*time.Second)
+			So(throttle.LockedFor("another"), ShouldEqual, 60*time.Second)
+
+			Convey("And unlock them after the cooldown", func() {
+				now = now.Add(61 * time.Second)
+				So(throttle.LockedFor("another"), ShouldEqual, 0)
+			})
+		})
+	})
+}
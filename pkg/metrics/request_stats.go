@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RequestStats keeps per route request counts and latency histograms and
+// writes them in the prometheus text exposition format.
+var RequestStats = NewRequestStatsRegistry()
+
+// Upper bounds (in seconds) of the request latency histogram buckets.
+var requestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	method string
+	route  string
+}
+
+type requestCountKey struct {
+	requestKey
+	status string
+}
+
+type requestHistogram struct {
+	buckets []int64
+	count   int64
+	sum     float64
+}
+
+type RequestStatsRegistry struct {
+	mutex      sync.Mutex
+	counts     map[requestCountKey]int64
+	histograms map[requestKey]*requestHistogram
+}
+
+func NewRequestStatsRegistry() *RequestStatsRegistry {
+	return &RequestStatsRegistry{
+		counts:     make(map[requestCountKey]int64),
+		histograms: make(map[requestKey]*requestHistogram),
+	}
+}
+
+// Observe records one finished request. route should be the route template
+// (e.g. /api/dashboards/db/:slug) and not the raw path.
+func (r *RequestStatsRegistry) Observe(method string, route string, status int, duration time.Duration) {
+	key := requestKey{method: method, route: route}
+	seconds := duration.Seconds()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.counts[requestCountKey{key, statusClass(status)}]++
+
+	hist, exists := r.histograms[key]
+	if !exists {
+		hist = &requestHistogram{buckets: make([]int64, len(requestDurationBuckets))}
+		r.histograms[key] = hist
+	}
+
+	for i, bound := range requestDurationBuckets {
+		if seconds <= bound {
+			hist.buckets[i]++
+		}
+	}
+	hist.count++
+	hist.sum += seconds
+}
+
+// WritePrometheus writes all recorded stats to w.
+func (r *RequestStatsRegistry) WritePrometheus(w io.Writer) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	countKeys := make([]requestCountKey, 0, len(r.counts))
+	for key := range r.counts {
+		countKeys = append(countKeys, key)
+	}
+	sort.Sort(requestCountKeys(countKeys))
+
+	fmt.Fprintln(w, "# HELP grafana_http_requests_total Total number of http requests.")
+	fmt.Fprintln(w, "# TYPE grafana_http_requests_total counter")
+	for _, key := range countKeys {
+		fmt.Fprintf(w, "grafana_http_requests_total{%s,status=%q} %d\n", key.labels(), key.status, r.counts[key])
+	}
+
+	histKeys := make([]requestKey, 0, len(r.histograms))
+	for key := range r.histograms {
+		histKeys = append(histKeys, key)
+	}
+	sort.Sort(requestKeys(histKeys))
+
+	fmt.Fprintln(w, "# HELP grafana_http_request_duration_seconds Http request latencies in seconds.")
+	fmt.Fprintln(w, "# TYPE grafana_http_request_duration_seconds histogram")
+	for _, key := range histKeys {
+		hist := r.histograms[key]
+		labels := key.labels()
+		for i, bound := range requestDurationBuckets {
+			le := strconv.FormatFloat(bound, 'f', -1, 64)
+			fmt.Fprintf(w, "grafana_http_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, le, hist.buckets[i])
+		}
+		fmt.Fprintf(w, "grafana_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, hist.count)
+		fmt.Fprintf(w, "grafana_http_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(hist.sum, 'f', -1, 64))
+		fmt.Fprintf(w, "grafana_http_request_duration_seconds_count{%s} %d\n", labels, hist.count)
+	}
+}
+
+func (k requestKey) labels() string {
+	return fmt.Sprintf("method=%q,route=%q", k.method, k.route)
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+type requestKeys []requestKey
+
+func (k requestKeys) Len() int      { return len(k) }
+func (k requestKeys) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k requestKeys) Less(i, j int) bool {
+	if k[i].route != k[j].route {
+		return k[i].route < k[j].route
+	}
+	return k[i].method < k[j].method
+}
+
+type requestCountKeys []requestCountKey
+
+func (k requestCountKeys) Len() int      { return len(k) }
+func (k requestCountKeys) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k requestCountKeys) Less(i, j int) bool {
+	if k[i].requestKey != k[j].requestKey {
+		return requestKeys{k[i].requestKey, k[j].requestKey}.Less(0, 1)
+	}
+	return k[i].status < k[j].status
+}
@@ -3,9 +3,12 @@ package middleware
 import (
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/Unknwon/macaron"
 
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/log"
 	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/setting"
 )
@@ -15,6 +18,74 @@ type AuthOptions struct {
 	ReqSignedIn     bool
 }
 
+// Roles accepted by the RoleAuth handlers guarding the api.
+var (
+	EditorRoles   = []m.RoleType{m.ROLE_EDITOR, m.ROLE_ADMIN}
+	OrgAdminRoles = []m.RoleType{m.ROLE_ADMIN}
+)
+
+// Permissions are the access checks of Auth and RoleAuth resolved for the
+// current request.
+type Permissions struct {
+	IsSignedIn     bool `json:"isSignedIn"`
+	IsGrafanaAdmin bool `json:"isGrafanaAdmin"`
+	IsOrgAdmin     bool `json:"isOrgAdmin"`
+	IsEditor       bool `json:"isEditor"`
+}
+
+func hasRole(role m.RoleType, roles []m.RoleType) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func GetPermissions(c *Context) Permissions {
+	return Permissions{
+		IsSignedIn:     c.IsSignedIn,
+		IsGrafanaAdmin: c.IsSignedIn && c.IsGrafanaAdmin,
+		IsOrgAdmin:     hasRole(c.OrgRole, OrgAdminRoles),
+		IsEditor:       hasRole(c.OrgRole, EditorRoles),
+	}
+}
+
+// isLoginExpired reports whether the session's login is older than the
+// maximum login lifetime. Sessions from before login times were recorded
+// only expire through inactivity.
+func isLoginExpired(c *Context) bool {
+	loginTime, ok := c.Session.Get(SESS_KEY_LOGIN_TIME).(int64)
+	if !ok || setting.LoginMaxLifetime <= 0 {
+		return false
+	}
+
+	return time.Since(time.Unix(loginTime, 0)) > setting.LoginMaxLifetime
+}
+
+// checkUserSession fails with ErrUserSessionNotFound once the session's login
+// was signed out, and otherwise marks it seen. Sessions from before user
+// sessions were recorded have none to check.
+func checkUserSession(c *Context, userId int64) error {
+	id, ok := c.Session.Get(SESS_KEY_USER_SESSION).(int64)
+	if !ok {
+		return nil
+	}
+
+	query := m.GetUserSessionQuery{Id: id, UserId: userId}
+	if err := bus.Dispatch(&query); err != nil {
+		return err
+	}
+
+	if time.Since(query.Result.Updated) >= lastSeenAtThrottle {
+		cmd := m.UpdateUserSessionSeenCommand{Id: id, Ip: ClientIP(c.Req.Request).String()}
+		if err := bus.Dispatch(&cmd); err != nil {
+			log.Error(3, "Failed to update user session %v: %v", id, err)
+		}
+	}
+	return nil
+}
+
 func getRequestUserId(c *Context) int64 {
 	userId := c.Session.Get(SESS_KEY_USERID)
 
@@ -43,7 +114,7 @@ func accessForbidden(c *Context) {
 	}
 
 	c.SetCookie("redirect_to", url.QueryEscape(setting.AppSubUrl+c.Req.RequestURI), 0, setting.AppSubUrl+"/")
-	c.Redirect(setting.AppSubUrl + "/login")
+	c.Redirect(setting.AppPath("/login"))
 }
 
 func notAuthorized(c *Context) {
@@ -53,19 +124,12 @@ func notAuthorized(c *Context) {
 	}
 
 	c.SetCookie("redirect_to", url.QueryEscape(setting.AppSubUrl+c.Req.RequestURI), 0, setting.AppSubUrl+"/")
-	c.Redirect(setting.AppSubUrl + "/login")
+	c.Redirect(setting.AppPath("/login"))
 }
 
 func RoleAuth(roles ...m.RoleType) macaron.Handler {
 	return func(c *Context) {
-		ok := false
-		for _, role := range roles {
-			if role == c.OrgRole {
-				ok = true
-				break
-			}
-		}
-		if !ok {
+		if !hasRole(c.OrgRole, roles) {
 			accessForbidden(c)
 		}
 	}
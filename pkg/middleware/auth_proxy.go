@@ -40,6 +40,11 @@ func initContextWithAuthProxy(ctx *Context) bool {
 		}
 	}
 
+	if query.Result.IsDisabled {
+		ctx.JsonApiErr(401, "User is disabled", m.ErrUserDisabled)
+		return true
+	}
+
 	// initialize session
 	if err := ctx.Session.Start(ctx); err != nil {
 		log.Error(3, "Failed to start session", err)
@@ -66,7 +71,7 @@ func getSignedInUserQueryForProxyAuth(headerVal string) *m.GetSignedInUserQuery
 }
 
 func getCreateUserCommandForProxyAuth(headerVal string) *m.CreateUserCommand {
-	cmd := m.CreateUserCommand{}
+	cmd := m.CreateUserCommand{EmailVerified: true}
 	if setting.AuthProxyHeaderProperty == "username" {
 		cmd.Login = headerVal
 		cmd.Email = headerVal
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies are the peers whose X-Forwarded-For is honoured, proxies on
+// this host unless configured.
+var trustedProxies = []*net.IPNet{
+	{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)},
+}
+
+func SetTrustedProxies(nets []*net.IPNet) {
+	trustedProxies = nets
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For is only
+// honoured when the request comes from a trusted proxy, its entries are read
+// from the right up to the first untrusted one so a client can't pass for
+// another address.
+func ClientIP(req *http.Request) net.IP {
+	ip := remoteIP(req)
+
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// remoteIP returns the address of the peer the request came from.
+func remoteIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
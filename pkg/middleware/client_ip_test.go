@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Cepave/grafana/pkg/util"
+)
+
+func TestClientIP(t *testing.T) {
+
+	Convey("Given trusted proxies", t, func() {
+		defaults := trustedProxies
+		defer SetTrustedProxies(defaults)
+
+		nets, err := util.ParseCIDRs([]string{"10.0.0.0/8"})
+		So(err, ShouldBeNil)
+		SetTrustedProxies(nets)
+
+		request := func(remoteAddr, forwardedFor string) *http.Request {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.RemoteAddr = remoteAddr
+			if forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+			}
+			return req
+		}
+
+		Convey("Should use the forwarded address from a trusted peer", func() {
+			ip := ClientIP(request("10.0.0.2:51000", "203.0.113.9"))
+			So(ip.Equal(net.ParseIP("203.0.113.9")), ShouldBeTrue)
+		})
+
+		Convey("Should skip trusted proxies in the chain", func() {
+			ip := ClientIP(request("10.0.0.2:51000", "198.51.100.1, 203.0.113.9, 10.0.0.3"))
+			So(ip.Equal(net.ParseIP("203.0.113.9")), ShouldBeTrue)
+		})
+
+		Convey("Should use the peer when it isn't trusted", func() {
+			ip := ClientIP(request("192.168.1.8:51000", "203.0.113.9"))
+			So(ip.Equal(net.ParseIP("192.168.1.8")), ShouldBeTrue)
+		})
+
+		Convey("Should use the peer when it doesn't forward", func() {
+			ip := ClientIP(request("10.0.0.2:51000", ""))
+			So(ip.Equal(net.ParseIP("10.0.0.2")), ShouldBeTrue)
+		})
+
+		Convey("Should no longer trust loopback", func() {
+			ip := ClientIP(request("127.0.0.1:51000", "203.0.113.9"))
+			So(ip.Equal(net.ParseIP("127.0.0.1")), ShouldBeTrue)
+		})
+	})
+}
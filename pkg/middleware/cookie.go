@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Cepave/grafana/pkg/log"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// CookieOptions are the attributes added to the session and remember me
+// cookies, HttpOnly is always set. Name renames the session cookie and
+// prefixes the remember me ones so instances sharing a Domain don't collide.
+type CookieOptions struct {
+	Secure   bool
+	SameSite string
+	Name     string
+	Domain   string
+}
+
+var cookieOptions = &CookieOptions{}
+
+func SetCookieOptions(opts *CookieOptions) {
+	cookieOptions = opts
+
+	if sessionOptions != nil {
+		if err := applySessionCookieOptions(); err != nil {
+			log.Error(3, "Failed to apply cookie options to the session: %v", err)
+		}
+	}
+}
+
+// UserCookieName is the name of the cookie remembering the login.
+func UserCookieName() string {
+	if cookieOptions.Name != "" {
+		return cookieOptions.Name + "_user"
+	}
+	return setting.CookieUserName
+}
+
+// RememberCookieName is the name of the remember me token cookie.
+func RememberCookieName() string {
+	if cookieOptions.Name != "" {
+		return cookieOptions.Name + "_remember"
+	}
+	return setting.CookieRememberName
+}
+
+// NormalizeSameSite returns the canonical SameSite attribute value, ok is
+// false for values browsers don't understand.
+func NormalizeSameSite(value string) (string, bool) {
+	switch strings.ToLower(value) {
+	case "":
+		return "", true
+	case "lax":
+		return "Lax", true
+	case "strict":
+		return "Strict", true
+	case "none":
+		return "None", true
+	}
+	return "", false
+}
+
+// SetAuthCookie writes a cookie that holds login state, a negative maxAge
+// deletes it.
+func (ctx *Context) SetAuthCookie(name string, value string, maxAge int, path string) {
+	cookie := http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   cookieOptions.Domain,
+		HttpOnly: true,
+	}
+
+	ctx.Resp.Header().Add("Set-Cookie", withCookieOptions(cookie.String()))
+}
+
+// applyCookieOptions adds the configured attributes to the cookie called name
+// that was already written by the session manager.
+func applyCookieOptions(header http.Header, name string) {
+	cookies := header["Set-Cookie"]
+	for i, cookie := range cookies {
+		if strings.HasPrefix(cookie, name+"=") {
+			cookies[i] = withCookieOptions(cookie)
+		}
+	}
+}
+
+func withCookieOptions(cookie string) string {
+	if cookieOptions.Secure && !strings.Contains(cookie, "; Secure") {
+		cookie += "; Secure"
+	}
+	if sameSite, _ := NormalizeSameSite(cookieOptions.SameSite); sameSite != "" {
+		cookie += "; SameSite=" + sameSite
+	}
+	return cookie
+}
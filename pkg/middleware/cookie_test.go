@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCookieOptions(t *testing.T) {
+
+	Convey("Given cookie options", t, func() {
+		defer SetCookieOptions(&CookieOptions{})
+
+		cookieScenario := func(desc string, opts *CookieOptions, fn func(session, remember string)) {
+			middlewareScenario(desc, func(sc *scenarioContext) {
+				SetCookieOptions(opts)
+
+				sc.fakeReq("GET", "/").handler(func(c *Context) {
+					c.SetAuthCookie("grafana_remember", "series:token", 60, "/")
+				}).exec()
+
+				fn(findSetCookie(sc, "grafana_sess="), findSetCookie(sc, "grafana_remember="))
+			})
+		}
+
+		cookieScenario("With defaults", &CookieOptions{}, func(session, remember string) {
+			Convey("Should only set HttpOnly", func() {
+				So(session, ShouldContainSubstring, "; HttpOnly")
+				So(session, ShouldNotContainSubstring, "; Secure")
+				So(session, ShouldNotContainSubstring, "SameSite")
+				So(remember, ShouldContainSubstring, "; HttpOnly")
+				So(remember, ShouldNotContainSubstring, "; Secure")
+				So(remember, ShouldNotContainSubstring, "SameSite")
+			})
+		})
+
+		cookieScenario("With secure cookies", &CookieOptions{Secure: true}, func(session, remember string) {
+			Convey("Should mark cookies secure", func() {
+				So(session, ShouldContainSubstring, "; Secure")
+				So(session, ShouldNotContainSubstring, "SameSite")
+				So(remember, ShouldContainSubstring, "; HttpOnly")
+				So(remember, ShouldContainSubstring, "; Secure")
+			})
+		})
+
+		cookieScenario("With same site strict", &CookieOptions{SameSite: "strict"}, func(session, remember string) {
+			Convey("Should add the canonical same site attribute", func() {
+				So(session, ShouldEndWith, "; SameSite=Strict")
+				So(session, ShouldNotContainSubstring, "; Secure")
+				So(remember, ShouldContainSubstring, "; HttpOnly")
+				So(remember, ShouldEndWith, "; SameSite=Strict")
+			})
+		})
+
+		cookieScenario("With secure and same site none", &CookieOptions{Secure: true, SameSite: "None"}, func(session, remember string) {
+			Convey("Should add both attributes", func() {
+				So(session, ShouldContainSubstring, "; HttpOnly; Secure; SameSite=None")
+				So(remember, ShouldContainSubstring, "; HttpOnly; Secure; SameSite=None")
+			})
+		})
+	})
+
+	Convey("Given a cookie name and domain", t, func() {
+		defer SetCookieOptions(&CookieOptions{})
+
+		middlewareScenario("With a login", func(sc *scenarioContext) {
+			SetCookieOptions(&CookieOptions{Name: "grafana_a", Domain: "a.example.com"})
+
+			sc.fakeReq("GET", "/").handler(func(c *Context) {
+				c.SetAuthCookie(RememberCookieName(), "series:token", 60, "/")
+			}).exec()
+
+			Convey("Should name the session cookie and set its domain", func() {
+				So(findSetCookie(sc, "grafana_sess="), ShouldEqual, "")
+				So(findSetCookie(sc, "grafana_a="), ShouldContainSubstring, "; Domain=a.example.com")
+			})
+
+			Convey("Should prefix the remember me cookies", func() {
+				So(UserCookieName(), ShouldEqual, "grafana_a_user")
+				So(findSetCookie(sc, "grafana_a_remember="), ShouldContainSubstring, "; Domain=a.example.com")
+			})
+		})
+	})
+
+	Convey("When normalizing same site values", t, func() {
+		value, ok := NormalizeSameSite("LAX")
+		So(ok, ShouldBeTrue)
+		So(value, ShouldEqual, "Lax")
+
+		_, ok = NormalizeSameSite("sometimes")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func findSetCookie(sc *scenarioContext, prefix string) string {
+	for _, cookie := range sc.resp.Header()["Set-Cookie"] {
+		if strings.HasPrefix(cookie, prefix) {
+			return cookie
+		}
+	}
+	return ""
+}
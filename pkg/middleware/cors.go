@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Unknwon/macaron"
+)
+
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	defaultCORSHeaders = []string{"Accept", "Authorization", "Content-Type", REQUEST_ID_HEADER}
+)
+
+// allowOriginHeader returns the Access-Control-Allow-Origin value for the
+// origin. Listed origins are echoed back, any other origin only gets the
+// literal wildcard, which browsers refuse to combine with credentials.
+func (opts *CORSOptions) allowOriginHeader(origin string) (string, bool) {
+	wildcard := false
+	for _, allowed := range opts.AllowedOrigins {
+		if allowed == "*" {
+			wildcard = true
+		} else if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+	}
+	if wildcard {
+		return "*", true
+	}
+	return "", false
+}
+
+// CORS adds cross origin headers for requests coming from one of the allowed
+// origins and answers preflight requests. Explicitly allowed origins are
+// reflected so that credentials can be allowed for them, a wildcard never
+// allows credentials.
+func CORS(opts *CORSOptions) macaron.Handler {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	headers := opts.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+
+	return func(c *macaron.Context) {
+		origin := c.Req.Header.Get("Origin")
+		if origin == "" {
+			return
+		}
+
+		header := c.Resp.Header()
+		header.Add("Vary", "Origin")
+
+		isPreflight := c.Req.Method == "OPTIONS" && c.Req.Header.Get("Access-Control-Request-Method") != ""
+
+		allowOrigin, ok := opts.allowOriginHeader(origin)
+		if !ok {
+			if isPreflight {
+				c.Resp.WriteHeader(http.StatusForbidden)
+			}
+			return
+		}
+
+		header.Set("Access-Control-Allow-Origin", allowOrigin)
+		if opts.AllowCredentials && allowOrigin != "*" {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !isPreflight {
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		header.Set("Access-Control-Allow-Headers", allowHeaders)
+		if opts.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+		}
+		c.Resp.WriteHeader(http.StatusNoContent)
+	}
+}
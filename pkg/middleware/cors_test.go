@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+
+	Convey("Given the cors middleware", t, func() {
+		cors := CORS(&CORSOptions{
+			AllowedOrigins:   []string{"http://dashboards.example.com"},
+			AllowCredentials: true,
+		})
+
+		mac := macaron.New()
+		mac.Options("/api/*", cors)
+		mac.Get("/api/search", cors, func() string { return "[]" })
+
+		request := func(method, origin string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, "/api/search", nil)
+			req.Header.Set("Origin", origin)
+			if method == "OPTIONS" {
+				req.Header.Set("Access-Control-Request-Method", "GET")
+			}
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Preflight from allowed origin", func() {
+			resp := request("OPTIONS", "http://dashboards.example.com")
+
+			So(resp.Code, ShouldEqual, 204)
+			So(resp.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "http://dashboards.example.com")
+			So(resp.Header().Get("Access-Control-Allow-Credentials"), ShouldEqual, "true")
+			So(resp.Header().Get("Access-Control-Allow-Methods"), ShouldContainSubstring, "GET")
+			So(resp.Header().Get("Access-Control-Allow-Headers"), ShouldContainSubstring, "Content-Type")
+		})
+
+		Convey("Simple request from allowed origin", func() {
+			resp := request("GET", "http://dashboards.example.com")
+
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Body.String(), ShouldEqual, "[]")
+			So(resp.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "http://dashboards.example.com")
+		})
+
+		Convey("Preflight from disallowed origin", func() {
+			resp := request("OPTIONS", "http://evil.example.com")
+
+			So(resp.Code, ShouldEqual, 403)
+			So(resp.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "")
+		})
+
+		Convey("Simple request from disallowed origin", func() {
+			resp := request("GET", "http://evil.example.com")
+
+			So(resp.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "")
+			So(resp.Header().Get("Access-Control-Allow-Credentials"), ShouldEqual, "")
+		})
+	})
+
+	Convey("Given the cors middleware with a wildcard origin", t, func() {
+		cors := CORS(&CORSOptions{
+			AllowedOrigins:   []string{"*", "http://dashboards.example.com"},
+			AllowCredentials: true,
+		})
+
+		mac := macaron.New()
+		mac.Get("/api/search", cors, func() string { return "[]" })
+
+		request := func(origin string) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/search", nil)
+			req.Header.Set("Origin", origin)
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Unlisted origin should get the wildcard without credentials", func() {
+			resp := request("http://evil.example.com")
+
+			So(resp.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "*")
+			So(resp.Header().Get("Access-Control-Allow-Credentials"), ShouldEqual, "")
+		})
+
+		Convey("Listed origin should be reflected with credentials", func() {
+			resp := request("http://dashboards.example.com")
+
+			So(resp.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "http://dashboards.example.com")
+			So(resp.Header().Get("Access-Control-Allow-Credentials"), ShouldEqual, "true")
+		})
+	})
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Cepave/grafana/pkg/util"
+	"github.com/Unknwon/macaron"
+)
+
+// The names angular's $http uses for its built in XSRF protection, so the
+// frontend echoes the token without any extra code.
+const (
+	CSRF_COOKIE_NAME = "XSRF-TOKEN"
+	CSRF_HEADER_NAME = "X-XSRF-TOKEN"
+)
+
+// CSRF protects state changing requests from signed in browser sessions with
+// a double submit cookie, the token in the cookie has to be sent back in a
+// header. Requests authenticated with an Authorization header, like api keys
+// and basic auth, carry no ambient credentials and are exempt.
+func CSRF() macaron.Handler {
+	return func(ctx *Context) {
+		if !isCookieAuthenticated(ctx) {
+			return
+		}
+
+		token := ctx.CSRFToken()
+
+		switch ctx.Req.Method {
+		case "GET", "HEAD", "OPTIONS":
+			return
+		}
+
+		sent := ctx.Req.Header.Get(CSRF_HEADER_NAME)
+		if sent == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			ctx.JsonApiErr(403, "Invalid CSRF token", nil)
+		}
+	}
+}
+
+// CSRFToken returns the token from the request cookie, handing out a new one
+// when there is none.
+func (ctx *Context) CSRFToken() string {
+	if token := ctx.GetCookie(CSRF_COOKIE_NAME); token != "" {
+		return token
+	}
+
+	token := util.GetRandomString(32)
+	// the frontend reads this cookie, so it can't be HttpOnly
+	cookie := http.Cookie{Name: CSRF_COOKIE_NAME, Value: token, Path: setting.AppSubUrl + "/"}
+	ctx.Resp.Header().Add("Set-Cookie", withCookieOptions(cookie.String()))
+	ctx.Req.AddCookie(&cookie)
+	return token
+}
+
+func isCookieAuthenticated(ctx *Context) bool {
+	return ctx.IsSignedIn && ctx.ApiKeyId == 0 && ctx.Req.Header.Get("Authorization") == ""
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/util"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+
+	Convey("Given the csrf middleware", t, func() {
+
+		csrfScenario := func(desc string, fn scenarioFunc) {
+			middlewareScenario(desc, func(sc *scenarioContext) {
+				sc.m.Use(CSRF())
+				sc.m.Post("/", sc.defaultHandler)
+
+				bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+					query.Result = &m.SignedInUser{OrgId: 2, UserId: 12}
+					return nil
+				})
+
+				fn(sc)
+			})
+		}
+
+		signIn := func(sc *scenarioContext) string {
+			sc.fakeReq("GET", "/").handler(func(c *Context) {
+				c.Session.Set(SESS_KEY_USERID, int64(12))
+			}).exec()
+			sc.handler(nil)
+
+			sc.fakeReq("GET", "/").exec()
+			return findSetCookie(sc, CSRF_COOKIE_NAME+"=")
+		}
+
+		csrfScenario("Signed in post with a matching token", func(sc *scenarioContext) {
+			cookie := signIn(sc)
+			So(cookie, ShouldNotEqual, "")
+			token := sc.context.CSRFToken()
+
+			sc.fakeReq("POST", "/")
+			sc.req.Header.Add("Cookie", CSRF_COOKIE_NAME+"="+token)
+			sc.req.Header.Set(CSRF_HEADER_NAME, token)
+			sc.exec()
+
+			Convey("Should be allowed", func() {
+				So(sc.resp.Code, ShouldEqual, 200)
+			})
+		})
+
+		csrfScenario("Signed in post without a token", func(sc *scenarioContext) {
+			token := signIn(sc)
+			So(token, ShouldNotEqual, "")
+
+			sc.fakeReq("POST", "/")
+			sc.req.Header.Add("Cookie", CSRF_COOKIE_NAME+"="+sc.context.CSRFToken())
+			sc.exec()
+
+			Convey("Should return 403", func() {
+				So(sc.resp.Code, ShouldEqual, 403)
+				So(sc.respJson["message"], ShouldEqual, "Invalid CSRF token")
+			})
+		})
+
+		csrfScenario("Signed in post with a token that does not match the cookie", func(sc *scenarioContext) {
+			signIn(sc)
+
+			sc.fakeReq("POST", "/")
+			sc.req.Header.Add("Cookie", CSRF_COOKIE_NAME+"="+sc.context.CSRFToken())
+			sc.req.Header.Set(CSRF_HEADER_NAME, "forged")
+			sc.exec()
+
+			Convey("Should return 403", func() {
+				So(sc.resp.Code, ShouldEqual, 403)
+			})
+		})
+
+		csrfScenario("Post with a valid api key", func(sc *scenarioContext) {
+			keyhash := util.EncodePassword("v5nAwpMafFP6znaS4urhdWDLS5511M42", "asd")
+
+			bus.AddHandler("test", func(query *m.GetApiKeyByNameQuery) error {
+				query.Result = &m.ApiKey{Id: 3, OrgId: 12, Role: m.ROLE_EDITOR, Key: keyhash}
+				return nil
+			})
+
+			sc.fakeReq("POST", "/").withValidApiKey().exec()
+
+			Convey("Should be exempt", func() {
+				So(sc.resp.Code, ShouldEqual, 200)
+				So(findSetCookie(sc, CSRF_COOKIE_NAME+"="), ShouldEqual, "")
+			})
+		})
+	})
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// health checks usually probe over plain http, so they are never redirected
+const healthzPath = "/healthz"
+
+// EnforceHTTPS redirects plain http requests to https, behind a trusted proxy
+// that terminates tls the scheme comes from X-Forwarded-Proto.
+func EnforceHTTPS() macaron.Handler {
+	return func(c *macaron.Context) {
+		if c.Req.URL.Path == setting.RoutePath(healthzPath) || requestScheme(c.Req.Request) == "https" {
+			return
+		}
+
+		c.Redirect("https://"+httpsHost()+c.Req.RequestURI, 301)
+	}
+}
+
+// httpsHost is where plain http requests are sent, the host of root_url when
+// it is https already, else the domain on the default port. The Host header
+// is the client's to pick, so it is never used.
+func httpsHost() string {
+	if appUrl, err := url.Parse(setting.AppUrl); err == nil && appUrl.Scheme == "https" && appUrl.Host != "" {
+		return appUrl.Host
+	}
+	return setting.Domain
+}
+
+func requestScheme(req *http.Request) string {
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" && isTrustedProxy(remoteIP(req)) {
+		// proxies append, the last value is the one our proxy added, earlier
+		// ones come from the client
+		values := strings.Split(proto, ",")
+		return strings.ToLower(strings.TrimSpace(values[len(values)-1]))
+	}
+
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+func TestEnforceHTTPSMiddleware(t *testing.T) {
+
+	Convey("Given the enforce https middleware", t, func() {
+		defer func(appUrl, domain string) {
+			setting.AppUrl, setting.Domain = appUrl, domain
+		}(setting.AppUrl, setting.Domain)
+		setting.AppUrl = "https://grafana.example.com:8443/"
+		setting.Domain = "grafana.example.com"
+
+		mac := macaron.New()
+		mac.Use(EnforceHTTPS())
+		mac.Get("/api/search", func() string { return "[]" })
+		mac.Get("/healthz", func() string { return "ok" })
+
+		request := func(url string, prepare func(req *http.Request)) *httptest.ResponseRecorder {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", url, nil)
+			req.RequestURI = req.URL.RequestURI()
+			req.Host = "attacker.example.net"
+			if prepare != nil {
+				prepare(req)
+			}
+			mac.ServeHTTP(resp, req)
+			return resp
+		}
+
+		Convey("Plain http request should be redirected", func() {
+			resp := request("/api/search?query=a", nil)
+
+			So(resp.Code, ShouldEqual, 301)
+			So(resp.Header().Get("Location"), ShouldEqual, "https://grafana.example.com:8443/api/search?query=a")
+		})
+
+		Convey("Plain http root url should redirect to the domain", func() {
+			setting.AppUrl = "http://grafana.example.com:3000/"
+
+			resp := request("/api/search", nil)
+
+			So(resp.Code, ShouldEqual, 301)
+			So(resp.Header().Get("Location"), ShouldEqual, "https://grafana.example.com/api/search")
+		})
+
+		Convey("Request forwarded as http should be redirected", func() {
+			resp := request("/api/search", func(req *http.Request) {
+				req.RemoteAddr = "127.0.0.1:4321"
+				req.Header.Set("X-Forwarded-Proto", "http")
+			})
+
+			So(resp.Code, ShouldEqual, 301)
+		})
+
+		Convey("Request forwarded as https should be served", func() {
+			resp := request("/api/search", func(req *http.Request) {
+				req.RemoteAddr = "127.0.0.1:4321"
+				req.Header.Set("X-Forwarded-Proto", "http, https")
+			})
+
+			So(resp.Code, ShouldEqual, 200)
+		})
+
+		Convey("Scheme claimed by the client should not be trusted", func() {
+			resp := request("/api/search", func(req *http.Request) {
+				req.RemoteAddr = "127.0.0.1:4321"
+				req.Header.Set("X-Forwarded-Proto", "https, http")
+			})
+
+			So(resp.Code, ShouldEqual, 301)
+		})
+
+		Convey("Forwarded scheme from an untrusted peer should be ignored", func() {
+			resp := request("/api/search", func(req *http.Request) {
+				req.RemoteAddr = "203.0.113.7:4321"
+				req.Header.Set("X-Forwarded-Proto", "https")
+			})
+
+			So(resp.Code, ShouldEqual, 301)
+		})
+
+		Convey("Tls request should be served", func() {
+			resp := request("/api/search", func(req *http.Request) {
+				req.TLS = &tls.ConnectionState{}
+			})
+
+			So(resp.Code, ShouldEqual, 200)
+		})
+
+		Convey("Health check under the root path should be served", func() {
+			defer func(old string) { setting.RootPath = old }(setting.RootPath)
+			setting.RootPath = "/grafana"
+			mac.Get("/grafana/healthz", func() string { return "ok" })
+
+			resp := request("/grafana/healthz", nil)
+
+			So(resp.Code, ShouldEqual, 200)
+		})
+
+		Convey("Health check over plain http should be served", func() {
+			resp := request("/healthz", nil)
+
+			So(resp.Code, ShouldEqual, 200)
+			So(resp.Body.String(), ShouldEqual, "ok")
+		})
+	})
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// responses smaller than this are not worth compressing
+const gzipMinSize = 1024
+
+var gzipContentTypes = []string{"json", "javascript", "text/css", "text/html", "text/plain", "image/svg+xml"}
+
+// Gziper compresses json (and other text) responses for clients that accept
+// gzip. Small responses and responses that are already encoded (like the ones
+// coming through the data source proxy) are written as is.
+func Gziper() macaron.Handler {
+	return func(ctx *macaron.Context) {
+		requestPath := ctx.Req.URL.RequestURI()
+		// ignore datasource proxy requests
+		if strings.HasPrefix(requestPath, setting.RoutePath("/api/datasources/proxy")) {
+			return
+		}
+
+		if !strings.Contains(ctx.Req.Header.Get(macaron.HeaderAcceptEncoding), "gzip") {
+			return
+		}
+
+		gzw := &gzipResponseWriter{ResponseWriter: ctx.Resp, minSize: gzipMinSize}
+		ctx.Resp = gzw
+		ctx.MapTo(gzw, (*http.ResponseWriter)(nil))
+
+		ctx.Next()
+
+		gzw.close()
+	}
+}
+
+// gzipResponseWriter buffers the start of the response until it knows
+// whether it is large enough and of the right type to compress.
+type gzipResponseWriter struct {
+	macaron.ResponseWriter
+	minSize int
+
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipResponseWriter) shouldCompress() bool {
+	header := w.Header()
+	if header.Get(macaron.HeaderContentEncoding) != "" {
+		return false
+	}
+	if w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+
+	contentType := header.Get(macaron.HeaderContentType)
+	for _, t := range gzipContentTypes {
+		if strings.Contains(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *gzipResponseWriter) decide(compress bool) {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if compress {
+		header := w.Header()
+		header.Set(macaron.HeaderContentEncoding, "gzip")
+		header.Add(macaron.HeaderVary, macaron.HeaderAcceptEncoding)
+		header.Del(macaron.HeaderContentLength)
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		w.write(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *gzipResponseWriter) write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.decided {
+		return
+	}
+	w.status = status
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		return w.write(p)
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		w.decide(w.shouldCompress())
+	}
+	return len(p), nil
+}
+
+func (w *gzipResponseWriter) Status() int {
+	if w.decided {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *gzipResponseWriter) Written() bool {
+	return w.decided || w.status != 0
+}
+
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minSize && w.shouldCompress())
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipResponseWriter) close() {
+	if !w.decided {
+		if !w.Written() {
+			// nothing was written, leave the response to the outer handlers
+			return
+		}
+		w.decide(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
+
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the ResponseWriter doesn't support the Hijacker interface")
+	}
+	return hijacker.Hijack()
+}
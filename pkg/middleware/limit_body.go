@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Unknwon/macaron"
+)
+
+// LimitBody rejects requests with a body larger than maxBytes with 413
+// before handing them to next, usually a binding handler. A maxBytes of 0
+// uses the configured default.
+func LimitBody(maxBytes int64, next macaron.Handler) macaron.Handler {
+	if maxBytes <= 0 {
+		maxBytes = setting.MaxRequestBodyBytes
+	}
+
+	return func(ctx *Context) {
+		req := ctx.Req.Request
+		if maxBytes > 0 && req.Body != nil {
+			if req.ContentLength > maxBytes {
+				ctx.JsonApiErr(413, "Request body too large", nil)
+				return
+			}
+
+			// the content length can be missing or wrong, so read at most one
+			// byte past the limit to find out
+			body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxBytes+1))
+			req.Body.Close()
+			if err != nil {
+				ctx.JsonApiErr(400, "Failed to read request body", err)
+				return
+			}
+			if int64(len(body)) > maxBytes {
+				ctx.JsonApiErr(413, "Request body too large", nil)
+				return
+			}
+
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		if _, err := ctx.Invoke(next); err != nil {
+			panic("limit body: " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLimitBodyMiddleware(t *testing.T) {
+
+	Convey("Given a route limited to 16 bytes", t, func() {
+
+		limitScenario := func(desc string, body string, contentLength int64, fn func(sc *scenarioContext, received string)) {
+			middlewareScenario(desc, func(sc *scenarioContext) {
+				received := ""
+				sc.m.Post("/", LimitBody(16, func(c *Context) {
+					data, _ := c.Req.Body().String()
+					received = data
+				}), sc.defaultHandler)
+
+				sc.fakeReq("POST", "/")
+				sc.req.Body = ioutil.NopCloser(strings.NewReader(body))
+				sc.req.ContentLength = contentLength
+				sc.exec()
+
+				fn(sc, received)
+			})
+		}
+
+		limitScenario("With a body under the limit", `{"title":"a"}`, -1, func(sc *scenarioContext, received string) {
+			Convey("Should pass the body on", func() {
+				So(sc.resp.Code, ShouldEqual, 200)
+				So(received, ShouldEqual, `{"title":"a"}`)
+			})
+		})
+
+		limitScenario("With a body over the limit", `{"title":"way too long"}`, -1, func(sc *scenarioContext, received string) {
+			Convey("Should return 413", func() {
+				So(sc.resp.Code, ShouldEqual, 413)
+				So(sc.respJson["message"], ShouldEqual, "Request body too large")
+				So(received, ShouldEqual, "")
+			})
+		})
+
+		limitScenario("With a content length over the limit", strings.Repeat("a", 17), 17, func(sc *scenarioContext, received string) {
+			Convey("Should return 413", func() {
+				So(sc.resp.Code, ShouldEqual, 413)
+				So(received, ShouldEqual, "")
+			})
+		})
+	})
+}
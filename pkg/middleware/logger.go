@@ -33,6 +33,7 @@ func Logger() macaron.Handler {
 		c.Next()
 
 		content := fmt.Sprintf("Completed %s %v %s in %v", req.URL.Path, rw.Status(), http.StatusText(rw.Status()), time.Since(start))
+		fields := log.Fields{"request_id": getRequestId(c), "route": getRoute(c), "remote_addr": ClientIP(req).String()}
 
 		switch rw.Status() {
 		case 200, 304:
@@ -46,6 +47,6 @@ func Logger() macaron.Handler {
 			content = fmt.Sprintf("%s", content)
 		}
 
-		log.Info(content)
+		log.InfoFields(fields, "%s", content)
 	}
 }
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/log"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// maintenanceRetryAfter is the Retry-After sent with 503s, in seconds.
+const maintenanceRetryAfter = "120"
+
+// Maintenance answers 503 to everyone but grafana admins while maintenance
+// mode is on. Health checks and the login pages stay reachable so admins
+// can sign in to verify the upgrade.
+func Maintenance() macaron.Handler {
+	return func(c *Context) {
+		path := c.Req.URL.Path
+		login := setting.RoutePath("/login")
+		if path == setting.RoutePath(healthzPath) || path == login || strings.HasPrefix(path, login+"/") || path == setting.RoutePath("/logout") {
+			return
+		}
+
+		if c.IsSignedIn && c.IsGrafanaAdmin {
+			return
+		}
+
+		query := m.GetMaintenanceQuery{}
+		if err := bus.Dispatch(&query); err != nil {
+			// a failing store shouldn't take the whole site down
+			log.Error(3, "Failed to get maintenance mode: %v", err)
+			return
+		}
+
+		if !query.Result.Enabled {
+			return
+		}
+
+		c.Resp.Header().Set("Retry-After", maintenanceRetryAfter)
+		if c.IsApiRequest() {
+			c.JsonApiErr(503, "Grafana is down for maintenance", nil)
+			return
+		}
+
+		c.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		c.Resp.WriteHeader(503)
+		c.Resp.Write([]byte("Grafana is down for maintenance, please try again later.\n"))
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMaintenance(t *testing.T) {
+
+	Convey("Given the maintenance middleware", t, func() {
+
+		maintenanceScenario := func(desc string, user *m.SignedInUser, fn scenarioFunc) {
+			middlewareScenario(desc, func(sc *scenarioContext) {
+				enabled := false
+
+				sc.m.Use(Maintenance())
+				sc.m.Get("/api/search", sc.defaultHandler)
+				sc.m.Get("/dashboard/db/:slug", sc.defaultHandler)
+				sc.m.Get("/healthz", sc.defaultHandler)
+				sc.m.Get("/login", sc.defaultHandler)
+
+				bus.AddHandler("test", func(query *m.GetMaintenanceQuery) error {
+					query.Result = &m.Maintenance{Enabled: enabled}
+					return nil
+				})
+
+				if user != nil {
+					bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+						query.Result = user
+						return nil
+					})
+
+					sc.fakeReq("GET", "/").handler(func(c *Context) {
+						c.Session.Set(SESS_KEY_USERID, user.UserId)
+					}).exec()
+					sc.handler(nil)
+				}
+
+				enabled = true
+				fn(sc)
+			})
+		}
+
+		orgAdmin := &m.SignedInUser{OrgId: 1, UserId: 12, OrgRole: m.ROLE_ADMIN}
+		admin := &m.SignedInUser{OrgId: 1, UserId: 1, IsGrafanaAdmin: true}
+
+		maintenanceScenario("Org admin calling the api", orgAdmin, func(sc *scenarioContext) {
+			sc.fakeReq("GET", "/api/search").exec()
+
+			Convey("Should be unavailable", func() {
+				So(sc.resp.Code, ShouldEqual, 503)
+				So(sc.resp.Header().Get("Retry-After"), ShouldEqual, "120")
+				So(sc.respJson["message"], ShouldEqual, "Grafana is down for maintenance")
+			})
+		})
+
+		maintenanceScenario("Anonymous user asking for a page", nil, func(sc *scenarioContext) {
+			sc.fakeReq("GET", "/dashboard/db/prod").exec()
+
+			Convey("Should be unavailable", func() {
+				So(sc.resp.Code, ShouldEqual, 503)
+				So(sc.resp.Header().Get("Retry-After"), ShouldEqual, "120")
+			})
+		})
+
+		maintenanceScenario("Grafana admin calling the api", admin, func(sc *scenarioContext) {
+			sc.fakeReq("GET", "/api/search").exec()
+
+			Convey("Should be served", func() {
+				So(sc.resp.Code, ShouldEqual, 200)
+			})
+		})
+
+		maintenanceScenario("Health check", nil, func(sc *scenarioContext) {
+			sc.fakeReq("GET", "/healthz").exec()
+
+			Convey("Should be served", func() {
+				So(sc.resp.Code, ShouldEqual, 200)
+			})
+		})
+
+		maintenanceScenario("Anonymous user asking for the login page", nil, func(sc *scenarioContext) {
+			sc.fakeReq("GET", "/login").exec()
+
+			Convey("Should be served", func() {
+				So(sc.resp.Code, ShouldEqual, 200)
+			})
+		})
+
+		middlewareScenario("Anonymous user asking for the login page under a root path", func(sc *scenarioContext) {
+			defer func(old string) { setting.RootPath = old }(setting.RootPath)
+			setting.RootPath = "/grafana"
+
+			sc.m.Use(Maintenance())
+			sc.m.Get("/grafana/login", sc.defaultHandler)
+			sc.m.Get("/grafana/api/search", sc.defaultHandler)
+
+			bus.AddHandler("test", func(query *m.GetMaintenanceQuery) error {
+				query.Result = &m.Maintenance{Enabled: true}
+				return nil
+			})
+
+			sc.fakeReq("GET", "/grafana/login").exec()
+			So(sc.resp.Code, ShouldEqual, 200)
+
+			sc.fakeReq("GET", "/grafana/api/search").exec()
+			So(sc.resp.Code, ShouldEqual, 503)
+		})
+
+		middlewareScenario("Non admin calling the api outside maintenance", func(sc *scenarioContext) {
+			sc.m.Use(Maintenance())
+			sc.m.Get("/api/search", sc.defaultHandler)
+
+			bus.AddHandler("test", func(query *m.GetMaintenanceQuery) error {
+				query.Result = &m.Maintenance{}
+				return nil
+			})
+
+			sc.fakeReq("GET", "/api/search").exec()
+
+			Convey("Should be served", func() {
+				So(sc.resp.Code, ShouldEqual, 200)
+			})
+		})
+	})
+}
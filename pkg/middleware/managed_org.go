@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/Unknwon/macaron"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// RequireManagedOrg lets grafana admins and the delegated admins of the
+// org in the :orgId route parameter through.
+func RequireManagedOrg() macaron.Handler {
+	return func(c *Context) {
+		if !c.IsSignedIn {
+			notAuthorized(c)
+			return
+		}
+
+		if c.IsGrafanaAdmin {
+			return
+		}
+
+		query := m.IsManagedOrgQuery{UserId: c.UserId, OrgId: c.ParamsInt64(":orgId")}
+		if err := bus.Dispatch(&query); err != nil {
+			c.JsonApiErr(500, "Failed to check managed organizations", err)
+			return
+		}
+
+		if !query.Result {
+			accessForbidden(c)
+		}
+	}
+}
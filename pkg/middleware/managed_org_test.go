@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequireManagedOrg(t *testing.T) {
+
+	Convey("Given the managed org middleware", t, func() {
+
+		managedOrgScenario := func(desc string, user *m.SignedInUser, fn scenarioFunc) {
+			middlewareScenario(desc, func(sc *scenarioContext) {
+				sc.m.Get("/api/orgs/:orgId/users", RequireManagedOrg(), sc.defaultHandler)
+
+				bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+					query.Result = user
+					return nil
+				})
+
+				bus.AddHandler("test", func(query *m.IsManagedOrgQuery) error {
+					query.Result = query.UserId == 12 && query.OrgId == 3
+					return nil
+				})
+
+				sc.fakeReq("GET", "/").handler(func(c *Context) {
+					c.Session.Set(SESS_KEY_USERID, user.UserId)
+				}).exec()
+				sc.handler(nil)
+
+				fn(sc)
+			})
+		}
+
+		delegatedAdmin := &m.SignedInUser{OrgId: 1, UserId: 12}
+
+		managedOrgScenario("Delegated admin asking for a managed org", delegatedAdmin, func(sc *scenarioContext) {
+			sc.fakeReq("GET", "/api/orgs/3/users").exec()
+
+			Convey("Should be allowed", func() {
+				So(sc.resp.Code, ShouldEqual, 200)
+			})
+		})
+
+		managedOrgScenario("Delegated admin asking for an unmanaged org", delegatedAdmin, func(sc *scenarioContext) {
+			sc.fakeReq("GET", "/api/orgs/4/users").exec()
+
+			Convey("Should be forbidden", func() {
+				So(sc.resp.Code, ShouldEqual, 403)
+			})
+		})
+
+		managedOrgScenario("Grafana admin asking for an unmanaged org", &m.SignedInUser{OrgId: 1, UserId: 1, IsGrafanaAdmin: true}, func(sc *scenarioContext) {
+			sc.fakeReq("GET", "/api/orgs/4/users").exec()
+
+			Convey("Should be allowed", func() {
+				So(sc.resp.Code, ShouldEqual, 200)
+			})
+		})
+
+		middlewareScenario("Anonymous request for a managed org", func(sc *scenarioContext) {
+			sc.m.Get("/api/orgs/:orgId/users", RequireManagedOrg(), sc.defaultHandler)
+			sc.fakeReq("GET", "/api/orgs/3/users").exec()
+
+			Convey("Should be unauthorized", func() {
+				So(sc.resp.Code, ShouldEqual, 401)
+			})
+		})
+	})
+}
@@ -3,12 +3,14 @@ package middleware
 import (
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Unknwon/macaron"
 
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/components/apikeygen"
 	"github.com/Cepave/grafana/pkg/log"
+	"github.com/Cepave/grafana/pkg/login"
 	"github.com/Cepave/grafana/pkg/metrics"
 	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/setting"
@@ -48,10 +50,26 @@ func GetContextHandler() macaron.Handler {
 			initContextWithAnonymousUser(ctx) {
 		}
 
+		updateLastSeenAt(ctx)
+
 		c.Map(ctx)
 	}
 }
 
+// how often a signed in user's last_seen_at is written back
+const lastSeenAtThrottle = 5 * time.Minute
+
+func updateLastSeenAt(ctx *Context) {
+	if !ctx.IsSignedIn || ctx.UserId == 0 || time.Since(ctx.LastSeenAt) < lastSeenAtThrottle {
+		return
+	}
+
+	cmd := m.UpdateUserLastSeenAtCommand{UserId: ctx.UserId}
+	if err := bus.Dispatch(&cmd); err != nil {
+		log.Error(3, "Failed to update last_seen_at for user %v: %v", ctx.UserId, err)
+	}
+}
+
 func initContextWithAnonymousUser(ctx *Context) bool {
 	if !setting.AnonymousEnabled {
 		return false
@@ -84,10 +102,36 @@ func initContextWithUserSessionCookie(ctx *Context) bool {
 		return false
 	}
 
+	if isLoginExpired(ctx) {
+		if err := ctx.Session.Destory(ctx); err != nil {
+			log.Error(3, "Failed to destroy expired session: %v", err)
+		}
+		return false
+	}
+
+	if err := checkUserSession(ctx, userId); err != nil {
+		if err != m.ErrUserSessionNotFound {
+			log.Error(3, "Failed to get user session: %v", err)
+			return false
+		}
+		if err := ctx.Session.Destory(ctx); err != nil {
+			log.Error(3, "Failed to destroy signed out session: %v", err)
+		}
+		return false
+	}
+
 	query := m.GetSignedInUserQuery{UserId: userId}
 	if err := bus.Dispatch(&query); err != nil {
 		log.Error(3, "Failed to get user with id %v", userId)
 		return false
+	} else if query.Result.IsDisabled {
+		ctx.Session.Destory(ctx)
+		ctx.JsonApiErr(401, "User is disabled", m.ErrUserDisabled)
+		return true
+	} else if setting.RequireEmailVerification && !query.Result.EmailVerified && !query.Result.IsGrafanaAdmin {
+		ctx.Session.Destory(ctx)
+		ctx.JsonApiErr(403, "Email address is not verified", m.ErrEmailNotVerified)
+		return true
 	} else {
 		ctx.SignedInUser = query.Result
 		ctx.IsSignedIn = true
@@ -121,6 +165,20 @@ func initContextWithApiKey(ctx *Context) bool {
 			return true
 		}
 
+		if rejectApiKeyRequest(ctx, apikey) {
+			return true
+		}
+
+		// only the client holding the key is checked, renders replay it
+		// through a session from the server's own address
+		if allowed := apikey.AllowedIpList(); len(allowed) > 0 {
+			nets, err := util.ParseCIDRs(allowed)
+			if err != nil || !util.IPInNets(ClientIP(ctx.Req.Request), nets) {
+				ctx.JsonApiErr(403, "API key is not allowed from this address", err)
+				return true
+			}
+		}
+
 		ctx.IsSignedIn = true
 		ctx.SignedInUser = &m.SignedInUser{}
 		ctx.OrgRole = apikey.Role
@@ -130,6 +188,16 @@ func initContextWithApiKey(ctx *Context) bool {
 	}
 }
 
+// rejectApiKeyRequest answers 403 to requests the key isn't allowed to make.
+func rejectApiKeyRequest(ctx *Context, apikey *m.ApiKey) bool {
+	if apikey.ReadOnly && ctx.Req.Method != "GET" && ctx.Req.Method != "HEAD" {
+		ctx.JsonApiErr(403, "API key is read only", nil)
+		return true
+	}
+
+	return false
+}
+
 func initContextWithBasicAuth(ctx *Context) bool {
 	if !setting.BasicAuthEnabled {
 		return false
@@ -146,8 +214,16 @@ func initContextWithBasicAuth(ctx *Context) bool {
 		return true
 	}
 
+	if lockedFor := login.Attempts.LockedFor(username); lockedFor > 0 {
+		retryAfter := int((lockedFor + time.Second - 1) / time.Second)
+		ctx.Resp.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		ctx.JsonApiErr(429, "Too many failed login attempts, try again later", nil)
+		return true
+	}
+
 	loginQuery := m.GetUserByLoginQuery{LoginOrEmail: username}
 	if err := bus.Dispatch(&loginQuery); err != nil {
+		login.Attempts.Failed(username)
 		ctx.JsonApiErr(401, "Basic auth failed", err)
 		return true
 	}
@@ -155,11 +231,24 @@ func initContextWithBasicAuth(ctx *Context) bool {
 	user := loginQuery.Result
 
 	// validate password
-	if util.EncodePassword(password, user.Salt) != user.Password {
+	if util.EncodePasswordWithCost(password, user.Salt, user.PasswordCost) != user.Password {
+		login.Attempts.Failed(username)
 		ctx.JsonApiErr(401, "Invalid username or password", nil)
 		return true
 	}
 
+	login.Attempts.Reset(username)
+
+	if user.IsDisabled {
+		ctx.JsonApiErr(401, "User is disabled", m.ErrUserDisabled)
+		return true
+	}
+
+	if setting.RequireEmailVerification && !user.EmailVerified && !user.IsAdmin {
+		ctx.JsonApiErr(403, "Email address is not verified", m.ErrEmailNotVerified)
+		return true
+	}
+
 	query := m.GetSignedInUserQuery{UserId: user.Id}
 	if err := bus.Dispatch(&query); err != nil {
 		ctx.JsonApiErr(401, "Authentication error", err)
@@ -185,6 +274,10 @@ func initContextWithApiKeyFromSession(ctx *Context) bool {
 	} else {
 		apikey := keyQuery.Result
 
+		if rejectApiKeyRequest(ctx, apikey) {
+			return true
+		}
+
 		ctx.IsSignedIn = true
 		ctx.SignedInUser = &m.SignedInUser{}
 		ctx.OrgRole = apikey.Role
@@ -197,7 +290,7 @@ func initContextWithApiKeyFromSession(ctx *Context) bool {
 // Handle handles and logs error by given status.
 func (ctx *Context) Handle(status int, title string, err error) {
 	if err != nil {
-		log.Error(4, "%s: %v", title, err)
+		log.ErrorFields(4, log.Fields{"request_id": ctx.RequestId()}, "%s: %v", title, err)
 		if setting.Env != setting.PROD {
 			ctx.Data["ErrorMsg"] = err
 		}
@@ -225,14 +318,14 @@ func (ctx *Context) JsonOK(message string) {
 }
 
 func (ctx *Context) IsApiRequest() bool {
-	return strings.HasPrefix(ctx.Req.URL.Path, "/api")
+	return strings.HasPrefix(ctx.Req.URL.Path, setting.RoutePath("/api"))
 }
 
 func (ctx *Context) JsonApiErr(status int, message string, err error) {
 	resp := make(map[string]interface{})
 
 	if err != nil {
-		log.Error(4, "%s: %v", message, err)
+		log.ErrorFields(4, log.Fields{"request_id": ctx.RequestId()}, "%s: %v", message, err)
 		if setting.Env != setting.PROD {
 			resp["error"] = err.Error()
 		}
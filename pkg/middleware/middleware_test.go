@@ -6,9 +6,11 @@ import (
 	"net/http/httptest"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Unknwon/macaron"
 	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/login"
 	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/setting"
 	"github.com/Cepave/grafana/pkg/util"
@@ -95,6 +97,74 @@ func TestMiddlewareContext(t *testing.T) {
 			})
 		})
 
+		middlewareScenario("Read only api key", func(sc *scenarioContext) {
+			keyhash := util.EncodePassword("v5nAwpMafFP6znaS4urhdWDLS5511M42", "asd")
+
+			bus.AddHandler("test", func(query *m.GetApiKeyByNameQuery) error {
+				query.Result = &m.ApiKey{OrgId: 12, Role: m.ROLE_ADMIN, Key: keyhash, ReadOnly: true}
+				return nil
+			})
+
+			Convey("Should allow a GET", func() {
+				sc.fakeReq("GET", "/").withValidApiKey().exec()
+
+				So(sc.resp.Code, ShouldEqual, 200)
+				So(sc.context.IsSignedIn, ShouldBeTrue)
+				So(sc.context.OrgRole, ShouldEqual, m.ROLE_ADMIN)
+			})
+
+			Convey("Should reject a POST whatever the role", func() {
+				sc.fakeReq("POST", "/").withValidApiKey().exec()
+
+				So(sc.resp.Code, ShouldEqual, 403)
+				So(sc.respJson["message"], ShouldEqual, "API key is read only")
+			})
+		})
+
+		middlewareScenario("Api key bound to a network", func(sc *scenarioContext) {
+			keyhash := util.EncodePassword("v5nAwpMafFP6znaS4urhdWDLS5511M42", "asd")
+
+			bus.AddHandler("test", func(query *m.GetApiKeyByNameQuery) error {
+				query.Result = &m.ApiKey{OrgId: 12, Role: m.ROLE_EDITOR, Key: keyhash, AllowedIps: "10.0.0.0/8,192.168.1.7"}
+				return nil
+			})
+
+			Convey("Should allow a request from the network", func() {
+				sc.fakeReq("GET", "/").withValidApiKey()
+				sc.req.RemoteAddr = "10.1.2.3:51000"
+				sc.exec()
+
+				So(sc.resp.Code, ShouldEqual, 200)
+				So(sc.context.IsSignedIn, ShouldBeTrue)
+			})
+
+			Convey("Should reject a request from another address", func() {
+				sc.fakeReq("GET", "/").withValidApiKey()
+				sc.req.RemoteAddr = "192.168.1.8:51000"
+				sc.exec()
+
+				So(sc.resp.Code, ShouldEqual, 403)
+			})
+
+			Convey("Should ignore forwarded addresses from an untrusted peer", func() {
+				sc.fakeReq("GET", "/").withValidApiKey()
+				sc.req.RemoteAddr = "192.168.1.8:51000"
+				sc.req.Header.Set("X-Forwarded-For", "10.1.2.3")
+				sc.exec()
+
+				So(sc.resp.Code, ShouldEqual, 403)
+			})
+
+			Convey("Should use the forwarded address from a local proxy", func() {
+				sc.fakeReq("GET", "/").withValidApiKey()
+				sc.req.RemoteAddr = "127.0.0.1:51000"
+				sc.req.Header.Set("X-Forwarded-For", "10.9.9.9, 192.168.1.7")
+				sc.exec()
+
+				So(sc.resp.Code, ShouldEqual, 200)
+			})
+		})
+
 		middlewareScenario("Valid api key, but does not match db hash", func(sc *scenarioContext) {
 			keyhash := "something_not_matching"
 
@@ -130,6 +200,198 @@ func TestMiddlewareContext(t *testing.T) {
 			})
 		})
 
+		middlewareScenario("Session login within the maximum lifetime", func(sc *scenarioContext) {
+			defer func(old time.Duration) { setting.LoginMaxLifetime = old }(setting.LoginMaxLifetime)
+			setting.LoginMaxLifetime = time.Hour
+
+			sc.fakeReq("GET", "/").handler(func(c *Context) {
+				c.Session.Set(SESS_KEY_USERID, int64(12))
+				c.Session.Set(SESS_KEY_LOGIN_TIME, time.Now().Add(-time.Minute).Unix())
+			}).exec()
+
+			bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+				query.Result = &m.SignedInUser{OrgId: 2, UserId: 12}
+				return nil
+			})
+
+			sc.fakeReq("GET", "/").handler(nil).exec()
+
+			Convey("should init context with user info", func() {
+				So(sc.context.IsSignedIn, ShouldBeTrue)
+				So(sc.context.UserId, ShouldEqual, 12)
+			})
+		})
+
+		middlewareScenario("Session login past the maximum lifetime", func(sc *scenarioContext) {
+			defer func(old time.Duration) { setting.LoginMaxLifetime = old }(setting.LoginMaxLifetime)
+			setting.LoginMaxLifetime = time.Hour
+
+			sc.fakeReq("GET", "/").handler(func(c *Context) {
+				c.Session.Set(SESS_KEY_USERID, int64(12))
+				c.Session.Set(SESS_KEY_LOGIN_TIME, time.Now().Add(-2*time.Hour).Unix())
+			}).exec()
+
+			bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+				query.Result = &m.SignedInUser{OrgId: 2, UserId: 12}
+				return nil
+			})
+
+			sc.fakeReq("GET", "/").handler(nil).exec()
+
+			Convey("should not sign the user in", func() {
+				So(sc.context.IsSignedIn, ShouldBeFalse)
+				So(sc.context.UserId, ShouldEqual, 0)
+			})
+		})
+
+		middlewareScenario("Session of a login signed out everywhere", func(sc *scenarioContext) {
+			sc.fakeReq("GET", "/").handler(func(c *Context) {
+				c.Session.Set(SESS_KEY_USERID, int64(12))
+				c.Session.Set(SESS_KEY_USER_SESSION, int64(3))
+			}).exec()
+
+			bus.AddHandler("test", func(query *m.GetUserSessionQuery) error {
+				return m.ErrUserSessionNotFound
+			})
+
+			bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+				query.Result = &m.SignedInUser{OrgId: 2, UserId: 12}
+				return nil
+			})
+
+			sc.fakeReq("GET", "/").handler(nil).exec()
+
+			Convey("should not sign the user in", func() {
+				So(sc.context.IsSignedIn, ShouldBeFalse)
+				So(sc.context.UserId, ShouldEqual, 0)
+			})
+		})
+
+		middlewareScenario("Disabled user in session", func(sc *scenarioContext) {
+
+			sc.fakeReq("GET", "/").handler(func(c *Context) {
+				c.Session.Set(SESS_KEY_USERID, int64(12))
+			}).exec()
+
+			bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+				query.Result = &m.SignedInUser{OrgId: 2, UserId: 12, IsDisabled: true}
+				return nil
+			})
+
+			sc.fakeReq("GET", "/").exec()
+
+			Convey("should return 401", func() {
+				So(sc.resp.Code, ShouldEqual, 401)
+				So(sc.respJson["message"], ShouldEqual, "User is disabled")
+			})
+		})
+
+		middlewareScenario("Unverified user in session", func(sc *scenarioContext) {
+			defer func(old bool) { setting.RequireEmailVerification = old }(setting.RequireEmailVerification)
+			setting.RequireEmailVerification = true
+
+			sc.fakeReq("GET", "/").handler(func(c *Context) {
+				c.Session.Set(SESS_KEY_USERID, int64(12))
+			}).exec()
+
+			bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+				query.Result = &m.SignedInUser{OrgId: 2, UserId: 12}
+				return nil
+			})
+
+			sc.fakeReq("GET", "/").exec()
+
+			Convey("should return 403", func() {
+				So(sc.resp.Code, ShouldEqual, 403)
+				So(sc.respJson["message"], ShouldEqual, "Email address is not verified")
+			})
+		})
+
+		middlewareScenario("Disabled user using basic auth", func(sc *scenarioContext) {
+
+			bus.AddHandler("test", func(query *m.GetUserByLoginQuery) error {
+				query.Result = &m.User{
+					Password:   util.EncodePassword("myPass", "salt"),
+					Salt:       "salt",
+					IsDisabled: true,
+				}
+				return nil
+			})
+
+			setting.BasicAuthEnabled = true
+			authHeader := util.GetBasicAuthHeader("myUser", "myPass")
+			sc.fakeReq("GET", "/").withAuthoriziationHeader(authHeader).exec()
+
+			Convey("should return 401", func() {
+				So(sc.resp.Code, ShouldEqual, 401)
+				So(sc.respJson["message"], ShouldEqual, "User is disabled")
+			})
+		})
+
+		middlewareScenario("Basic auth with too many wrong passwords", func(sc *scenarioContext) {
+			setting.LoginMaxFailedAttempts = 2
+			setting.LoginLockoutSeconds = 60
+			login.Attempts = login.NewThrottle()
+
+			bus.AddHandler("test", func(query *m.GetUserByLoginQuery) error {
+				query.Result = &m.User{Password: util.EncodePassword("myPass", "salt"), Salt: "salt"}
+				return nil
+			})
+
+			setting.BasicAuthEnabled = true
+			sc.fakeReq("GET", "/").withAuthoriziationHeader(util.GetBasicAuthHeader("myUser", "wrong")).exec()
+			sc.fakeReq("GET", "/").withAuthoriziationHeader(util.GetBasicAuthHeader("myUser", "wrong")).exec()
+			sc.fakeReq("GET", "/").withAuthoriziationHeader(util.GetBasicAuthHeader("myUser", "myPass")).exec()
+
+			Convey("should lock the login name", func() {
+				So(sc.resp.Code, ShouldEqual, 429)
+				So(sc.resp.Header().Get("Retry-After"), ShouldEqual, "60")
+			})
+		})
+
+		middlewareScenario("Signed in user not seen for a while", func(sc *scenarioContext) {
+			updated := int64(0)
+			bus.AddHandler("test", func(cmd *m.UpdateUserLastSeenAtCommand) error {
+				updated = cmd.UserId
+				return nil
+			})
+			bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+				query.Result = &m.SignedInUser{OrgId: 2, UserId: 12, LastSeenAt: time.Now().Add(-time.Hour)}
+				return nil
+			})
+
+			sc.fakeReq("GET", "/").handler(func(c *Context) {
+				c.Session.Set(SESS_KEY_USERID, int64(12))
+			}).exec()
+			sc.fakeReq("GET", "/").exec()
+
+			Convey("should update last seen at", func() {
+				So(updated, ShouldEqual, 12)
+			})
+		})
+
+		middlewareScenario("Signed in user seen recently", func(sc *scenarioContext) {
+			updated := false
+			bus.AddHandler("test", func(cmd *m.UpdateUserLastSeenAtCommand) error {
+				updated = true
+				return nil
+			})
+			bus.AddHandler("test", func(query *m.GetSignedInUserQuery) error {
+				query.Result = &m.SignedInUser{OrgId: 2, UserId: 12, LastSeenAt: time.Now().Add(-time.Minute)}
+				return nil
+			})
+
+			sc.fakeReq("GET", "/").handler(func(c *Context) {
+				c.Session.Set(SESS_KEY_USERID, int64(12))
+			}).exec()
+			sc.fakeReq("GET", "/").exec()
+
+			Convey("should not update last seen at", func() {
+				So(sc.context.IsSignedIn, ShouldBeTrue)
+				So(updated, ShouldBeFalse)
+			})
+		})
+
 		middlewareScenario("When anonymous access is enabled", func(sc *scenarioContext) {
 			setting.AnonymousEnabled = true
 			setting.AnonymousOrgName = "test"
@@ -206,7 +468,7 @@ func TestMiddlewareContext(t *testing.T) {
 				So(sc.context.IsSignedIn, ShouldBeTrue)
 				So(sc.context.UserId, ShouldEqual, 33)
 				So(sc.context.OrgId, ShouldEqual, 4)
-
+				So(createUserCmd, ShouldNotBeNil)
 			})
 		})
 
@@ -312,3 +574,47 @@ func (sc *scenarioContext) exec() {
 
 type scenarioFunc func(c *scenarioContext)
 type handlerFunc func(c *Context)
+
+func TestRequestIdMiddleware(t *testing.T) {
+
+	Convey("Given the request id middleware", t, func() {
+		mac := macaron.New()
+		mac.Use(RequestId())
+
+		var upstreamId string
+		mac.Get("/", func(c *macaron.Context) string {
+			upstreamId = c.Req.Header.Get(REQUEST_ID_HEADER)
+			return getRequestId(c)
+		})
+
+		Convey("Should generate an id when none is supplied", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			mac.ServeHTTP(resp, req)
+
+			id := resp.Header().Get(REQUEST_ID_HEADER)
+			So(len(id), ShouldEqual, 36)
+			So(resp.Body.String(), ShouldEqual, id)
+			So(upstreamId, ShouldEqual, id)
+		})
+
+		Convey("Should preserve a supplied id", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set(REQUEST_ID_HEADER, "abc-123")
+			mac.ServeHTTP(resp, req)
+
+			So(resp.Header().Get(REQUEST_ID_HEADER), ShouldEqual, "abc-123")
+			So(upstreamId, ShouldEqual, "abc-123")
+		})
+
+		Convey("Should replace an id that is unsafe to log", func() {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set(REQUEST_ID_HEADER, "abc\nforged log line")
+			mac.ServeHTTP(resp, req)
+
+			So(len(resp.Header().Get(REQUEST_ID_HEADER)), ShouldEqual, 36)
+		})
+	})
+}
@@ -25,6 +25,12 @@ func Quota(target string) macaron.Handler {
 }
 
 func QuotaReached(c *Context, target string) (bool, error) {
+	return QuotaReachedBy(c, target, 1)
+}
+
+// QuotaReachedBy reports whether adding amount new items of target would
+// go over any of the quotas that apply to it.
+func QuotaReachedBy(c *Context, target string, amount int64) (bool, error) {
 	if !setting.Quota.Enabled {
 		return false, nil
 	}
@@ -60,7 +66,7 @@ func QuotaReached(c *Context, target string) (bool, error) {
 			// if err := bus.Dispatch(&query); err != nil {
 			// 	return true, err
 			// }
-			if query.Result.Used >= scope.DefaultLimit {
+			if query.Result.Used+amount > scope.DefaultLimit {
 				return true, nil
 			}
 		case "org":
@@ -78,7 +84,7 @@ func QuotaReached(c *Context, target string) (bool, error) {
 				return true, nil
 			}
 
-			if query.Result.Used >= query.Result.Limit {
+			if query.Result.Used+amount > query.Result.Limit {
 				return true, nil
 			}
 		case "user":
@@ -96,7 +102,7 @@ func QuotaReached(c *Context, target string) (bool, error) {
 				return true, nil
 			}
 
-			if query.Result.Used >= query.Result.Limit {
+			if query.Result.Used+amount > query.Result.Limit {
 				return true, nil
 			}
 		}
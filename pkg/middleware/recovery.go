@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"runtime/debug"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/Cepave/grafana/pkg/log"
+)
+
+// Recovery turns a panic in any later handler into a json 500. The stack is
+// only written to the log, tagged with the request id. Use it before every
+// other middleware so it covers them as well.
+func Recovery() macaron.Handler {
+	return func(c *macaron.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				log.ErrorFields(3, log.Fields{"request_id": getRequestId(c)}, "PANIC %s %s: %v\n%s", c.Req.Method, c.Req.URL.Path, err, debug.Stack())
+
+				if !c.Resp.Written() {
+					c.Resp.Header().Set("Content-Type", "application/json; charset=UTF-8")
+					c.Resp.WriteHeader(500)
+					c.Resp.Write([]byte(`{"message":"Internal server error"}`))
+				}
+			}
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Unknwon/macaron"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+
+	Convey("Given a handler that panics", t, func() {
+		mac := macaron.New()
+		mac.Use(Recovery())
+		mac.Use(RequestId())
+		mac.Get("/api/panic", func() string {
+			panic("secret internals")
+		})
+
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/panic", nil)
+		mac.ServeHTTP(resp, req)
+
+		Convey("Should answer a json 500 without the panic", func() {
+			So(resp.Code, ShouldEqual, 500)
+			So(resp.Header().Get("Content-Type"), ShouldStartWith, "application/json")
+			So(resp.Body.String(), ShouldEqual, `{"message":"Internal server error"}`)
+		})
+
+		Convey("Should still tag the response with the request id", func() {
+			So(resp.Header().Get(REQUEST_ID_HEADER), ShouldNotBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// RequestContext returns a context that is cancelled when the client
+// closes the connection. Callers must call the returned cancel func once
+// the request has been handled.
+func (ctx *Context) RequestContext() (context.Context, context.CancelFunc) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+
+	closed := closeNotify(ctx.Resp)
+	if closed == nil {
+		return reqCtx, cancel
+	}
+
+	go func() {
+		select {
+		case <-closed:
+			cancel()
+		case <-reqCtx.Done():
+		}
+	}()
+
+	return reqCtx, cancel
+}
+
+// macaron's response writer always claims to be a CloseNotifier and panics
+// when the underlying writer is not one, so treat that case as "never closes".
+func closeNotify(w http.ResponseWriter) (closed <-chan bool) {
+	notifier, ok := w.(http.CloseNotifier)
+	if !ok {
+		return nil
+	}
+
+	defer func() {
+		if recover() != nil {
+			closed = nil
+		}
+	}()
+
+	return notifier.CloseNotify()
+}
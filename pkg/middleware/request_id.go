@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/Cepave/grafana/pkg/util"
+)
+
+const (
+	REQUEST_ID_HEADER   = "X-Request-Id"
+	REQUEST_ID_DATA_KEY = "RequestId"
+)
+
+// only accept ids that are safe to put in log lines and headers
+var validRequestId = regexp.MustCompile(`^[\w\-.:]{1,128}$`)
+
+// RequestId makes sure every request has a correlation id. An incoming
+// X-Request-Id header is kept, otherwise a new uuid is generated. The id is
+// set on the request (so it is forwarded by the data source proxy) and echoed
+// back in the response.
+func RequestId() macaron.Handler {
+	return func(c *macaron.Context) {
+		id := c.Req.Header.Get(REQUEST_ID_HEADER)
+		if !validRequestId.MatchString(id) {
+			id = util.GenerateUUID()
+		}
+
+		c.Req.Header.Set(REQUEST_ID_HEADER, id)
+		c.Data[REQUEST_ID_DATA_KEY] = id
+
+		// set right before the header is written so proxied upstream
+		// responses can not add a second value
+		c.Resp.Before(func(rw macaron.ResponseWriter) {
+			rw.Header().Set(REQUEST_ID_HEADER, id)
+		})
+	}
+}
+
+func getRequestId(c *macaron.Context) string {
+	if id, ok := c.Data[REQUEST_ID_DATA_KEY].(string); ok {
+		return id
+	}
+	return ""
+}
+
+// RequestId returns the correlation id of the current request.
+func (ctx *Context) RequestId() string {
+	return getRequestId(ctx.Context)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/Cepave/grafana/pkg/metrics"
+)
+
+// RequestMetrics records the request count and latency for a route. The
+// route template is used as label so that ids in the path do not create
+// a new time series per request. The template is also kept for the request
+// log line.
+func RequestMetrics(route string) macaron.Handler {
+	return func(res http.ResponseWriter, req *http.Request, c *macaron.Context) {
+		start := time.Now()
+		rw := res.(macaron.ResponseWriter)
+		c.Data[ROUTE_DATA_KEY] = route
+		c.Next()
+
+		status := rw.Status()
+		if status == 0 {
+			status = 200
+		}
+
+		metrics.RequestStats.Observe(req.Method, route, status, time.Since(start))
+	}
+}
+
+const ROUTE_DATA_KEY = "Route"
+
+func getRoute(c *macaron.Context) string {
+	if route, ok := c.Data[ROUTE_DATA_KEY].(string); ok {
+		return route
+	}
+	return ""
+}
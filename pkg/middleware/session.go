@@ -11,12 +11,17 @@ import (
 )
 
 const (
-	SESS_KEY_USERID = "uid"
-	SESS_KEY_APIKEY = "apikey_id" // used fror render requests with api keys
+	SESS_KEY_USERID       = "uid"
+	SESS_KEY_APIKEY       = "apikey_id"       // used fror render requests with api keys
+	SESS_KEY_LOGIN_TIME   = "login_time"      // unix time the user logged in, kept when renewed
+	SESS_KEY_USER_SESSION = "user_session_id" // user_session row of the login, deleted to sign it out
 )
 
 var sessionManager *session.Manager
 var sessionOptions *session.Options
+
+// configuredSessionOptions are the options before the cookie options applied
+var configuredSessionOptions session.Options
 var startSessionGC func()
 var getSessionCount func() int
 
@@ -59,8 +64,8 @@ func prepareOptions(opt *session.Options) *session.Options {
 func Sessioner(options *session.Options) macaron.Handler {
 	var err error
 	sessionOptions = prepareOptions(options)
-	sessionManager, err = session.NewManager(options.Provider, *options)
-	if err != nil {
+	configuredSessionOptions = *options
+	if err = applySessionCookieOptions(); err != nil {
 		panic(err)
 	}
 
@@ -75,6 +80,26 @@ func Sessioner(options *session.Options) macaron.Handler {
 	}
 }
 
+// applySessionCookieOptions names the session cookie and sets its domain
+// from the cookie options. The manager copies its options so it is rebuilt.
+func applySessionCookieOptions() error {
+	opt := configuredSessionOptions
+	if cookieOptions.Name != "" {
+		opt.CookieName = cookieOptions.Name
+	}
+	if cookieOptions.Domain != "" {
+		opt.Domain = cookieOptions.Domain
+	}
+	*sessionOptions = opt
+
+	manager, err := session.NewManager(opt.Provider, opt)
+	if err != nil {
+		return err
+	}
+	sessionManager = manager
+	return nil
+}
+
 func GetSession() SessionStore {
 	return &SessionWrapper{manager: sessionManager}
 }
@@ -102,6 +127,7 @@ type SessionWrapper struct {
 func (s *SessionWrapper) Start(c *Context) error {
 	var err error
 	s.session, err = s.manager.Start(c.Context)
+	applyCookieOptions(c.Resp.Header(), sessionOptions.CookieName)
 	return err
 }
 
@@ -138,6 +164,7 @@ func (s *SessionWrapper) Destory(c *Context) error {
 		if err := s.manager.Destory(c.Context); err != nil {
 			return err
 		}
+		applyCookieOptions(c.Resp.Header(), sessionOptions.CookieName)
 		s.session = nil
 	}
 	return nil
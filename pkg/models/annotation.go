@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Typed errors
+var (
+	ErrAnnotationNotFound = errors.New("Annotation not found")
+)
+
+// Annotation is a user created event shown on the graphs of an org, or of
+// one of its dashboards. Epoch is in milliseconds.
+type Annotation struct {
+	Id          int64
+	OrgId       int64
+	DashboardId int64
+	UserId      int64
+	Epoch       int64
+	Text        string
+	Created     time.Time
+}
+
+// ----------------------
+// COMMANDS
+
+type CreateAnnotationCommand struct {
+	OrgId       int64    `json:"-"`
+	UserId      int64    `json:"-"`
+	DashboardId int64    `json:"dashboardId"`
+	Time        int64    `json:"time" binding:"Required"`
+	Text        string   `json:"text" binding:"Required"`
+	Tags        []string `json:"tags"`
+
+	Result *Annotation `json:"-"`
+}
+
+type DeleteAnnotationCommand struct {
+	Id    int64
+	OrgId int64
+}
+
+// ----------------------
+// QUERIES
+
+// GetAnnotationsQuery finds the annotations between From and To (epoch
+// milliseconds, 0 leaves that end open) having all the given tags. A
+// DashboardId limits them to that dashboard.
+type GetAnnotationsQuery struct {
+	OrgId       int64
+	DashboardId int64
+	From        int64
+	To          int64
+	Tags        []string
+	Limit       int
+
+	Result []*AnnotationDTO
+}
+
+// ----------------------
+// Projections and DTOs
+
+type AnnotationDTO struct {
+	Id          int64    `json:"id"`
+	DashboardId int64    `json:"dashboardId"`
+	UserId      int64    `json:"userId"`
+	Time        int64    `json:"time"`
+	Text        string   `json:"text"`
+	Tags        []string `json:"tags"`
+}
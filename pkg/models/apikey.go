@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -15,15 +16,30 @@ type ApiKey struct {
 	Role    RoleType
 	Created time.Time
 	Updated time.Time
+
+	// read only keys can only GET, whatever their role
+	ReadOnly bool
+	// comma separated networks the key can be used from, empty for any
+	AllowedIps string
+}
+
+func (k *ApiKey) AllowedIpList() []string {
+	if k.AllowedIps == "" {
+		return nil
+	}
+	return strings.Split(k.AllowedIps, ",")
 }
 
 // ---------------------
 // COMMANDS
 type AddApiKeyCommand struct {
-	Name  string   `json:"name" binding:"Required"`
-	Role  RoleType `json:"role" binding:"Required"`
-	OrgId int64    `json:"-"`
-	Key   string   `json:"-"`
+	Name     string   `json:"name" binding:"Required"`
+	Role     RoleType `json:"role" binding:"Required"`
+	ReadOnly bool     `json:"readOnly"`
+	OrgId    int64    `json:"-"`
+	Key      string   `json:"-"`
+
+	AllowedIps []string `json:"allowedIps"`
 
 	Result *ApiKey `json:"-"`
 }
@@ -64,7 +80,10 @@ type GetApiKeyByIdQuery struct {
 // DTO & Projections
 
 type ApiKeyDTO struct {
-	Id   int64    `json:"id"`
-	Name string   `json:"name"`
-	Role RoleType `json:"role"`
+	Id       int64    `json:"id"`
+	Name     string   `json:"name"`
+	Role     RoleType `json:"role"`
+	ReadOnly bool     `json:"readOnly"`
+
+	AllowedIps []string `json:"allowedIps,omitempty"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+func GetGravatarUrl(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	hasher := md5.New()
+	hasher.Write([]byte(strings.ToLower(strings.TrimSpace(text))))
+	return fmt.Sprintf("https://secure.gravatar.com/avatar/%x?s=90&default=mm", hasher.Sum(nil))
+}
+
+// GetAvatarUrl returns the gravatar for email, or the bundled default image
+// when gravatar is disabled or there is no email to hash.
+func GetAvatarUrl(email string) string {
+	if setting.DisableGravatar || email == "" {
+		return setting.AppPath("/img/user_profile.png")
+	}
+
+	return GetGravatarUrl(email)
+}
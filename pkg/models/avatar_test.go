@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/setting"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAvatarUrl(t *testing.T) {
+
+	Convey("When computing avatar urls", t, func() {
+		setting.AppSubUrl = "/grafana"
+
+		Convey("Should hash the lower cased email for gravatar", func() {
+			setting.DisableGravatar = false
+
+			url := GetAvatarUrl(" MyEmailAddress@example.com ")
+			So(url, ShouldEqual, "https://secure.gravatar.com/avatar/0bc83cb571cd1c50ba6f3e8a78ef1346?s=90&default=mm")
+		})
+
+		Convey("Should use the local image when gravatar is disabled", func() {
+			setting.DisableGravatar = true
+
+			So(GetAvatarUrl("user@example.com"), ShouldEqual, "/grafana/img/user_profile.png")
+		})
+
+		Convey("Should use the local image for users without email", func() {
+			setting.DisableGravatar = false
+
+			So(GetAvatarUrl(""), ShouldEqual, "/grafana/img/user_profile.png")
+		})
+	})
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Typed errors
+var (
+	ErrDashboardAclNotFound      = errors.New("Dashboard permission not found")
+	ErrDashboardAclInvalid       = errors.New("A dashboard permission needs one of a user, a team or a role, and a permission of 1 (view) or 2 (edit)")
+	ErrDashboardPermissionDenied = errors.New("Access denied to dashboard")
+)
+
+type PermissionType int
+
+const (
+	PERMISSION_NONE PermissionType = 0
+	PERMISSION_VIEW PermissionType = 1
+	PERMISSION_EDIT PermissionType = 2
+)
+
+func (p PermissionType) IsValid() bool {
+	return p == PERMISSION_VIEW || p == PERMISSION_EDIT
+}
+
+// DashboardAcl grants a user, the members of a team, or everyone with a
+// role in the org a permission on one dashboard.
+type DashboardAcl struct {
+	Id          int64          `json:"id"`
+	OrgId       int64          `json:"-"`
+	DashboardId int64          `json:"dashboardId"`
+	UserId      int64          `json:"userId,omitempty"`
+	TeamId      int64          `json:"teamId,omitempty"`
+	Role        RoleType       `json:"role,omitempty"`
+	Permission  PermissionType `json:"permission"`
+	Created     time.Time      `json:"created"`
+	Updated     time.Time      `json:"updated"`
+}
+
+// RolePermission is what an org role may do with dashboards that have no
+// acl.
+func RolePermission(role RoleType) PermissionType {
+	switch role {
+	case ROLE_ADMIN, ROLE_EDITOR:
+		return PERMISSION_EDIT
+	case ROLE_VIEWER, ROLE_READ_ONLY_EDITOR:
+		return PERMISSION_VIEW
+	}
+	return PERMISSION_NONE
+}
+
+// GetDashboardPermission resolves the permission of a user with the given
+// org role and teams. Without acl the role decides; with one only its items
+// count, except for org admins who keep full access to manage it.
+func GetDashboardPermission(acl []*DashboardAcl, userId int64, teamIds []int64, role RoleType) PermissionType {
+	if len(acl) == 0 || role == ROLE_ADMIN {
+		return RolePermission(role)
+	}
+
+	permission := PERMISSION_NONE
+	for _, item := range acl {
+		var matches bool
+		switch {
+		case item.UserId != 0:
+			matches = item.UserId == userId
+		case item.TeamId != 0:
+			matches = containsId(teamIds, item.TeamId)
+		default:
+			matches = item.Role == role
+		}
+		if matches && item.Permission > permission {
+			permission = item.Permission
+		}
+	}
+	return permission
+}
+
+// HasTeamItems reports whether any acl item grants a team, only then do the
+// teams of a user have to be looked up.
+func HasTeamItems(acl []*DashboardAcl) bool {
+	for _, item := range acl {
+		if item.TeamId != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func containsId(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ----------------------
+// COMMANDS
+
+type SetDashboardAclCommand struct {
+	OrgId       int64          `json:"-"`
+	DashboardId int64          `json:"-"`
+	UserId      int64          `json:"userId"`
+	TeamId      int64          `json:"teamId"`
+	Role        RoleType       `json:"role"`
+	Permission  PermissionType `json:"permission"`
+
+	Result *DashboardAcl `json:"-"`
+}
+
+type RemoveDashboardAclCommand struct {
+	OrgId       int64
+	DashboardId int64
+	AclId       int64
+}
+
+// ----------------------
+// QUERIES
+
+type GetDashboardAclQuery struct {
+	OrgId       int64
+	DashboardId int64
+	Result      []*DashboardAcl
+}
+
+// GetDashboardPermissionsQuery resolves the permission of a user on each of
+// DashboardIds at once, see GetDashboardPermission.
+type GetDashboardPermissionsQuery struct {
+	OrgId        int64
+	UserId       int64
+	OrgRole      RoleType
+	DashboardIds []int64
+
+	Result map[int64]PermissionType
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDashboardPermission(t *testing.T) {
+
+	Convey("Given a dashboard without acl", t, func() {
+		acl := []*DashboardAcl{}
+
+		Convey("Should fall back to the org role", func() {
+			So(GetDashboardPermission(acl, 1, nil, ROLE_ADMIN), ShouldEqual, PERMISSION_EDIT)
+			So(GetDashboardPermission(acl, 1, nil, ROLE_EDITOR), ShouldEqual, PERMISSION_EDIT)
+			So(GetDashboardPermission(acl, 1, nil, ROLE_READ_ONLY_EDITOR), ShouldEqual, PERMISSION_VIEW)
+			So(GetDashboardPermission(acl, 1, nil, ROLE_VIEWER), ShouldEqual, PERMISSION_VIEW)
+		})
+	})
+
+	Convey("Given a dashboard with an acl", t, func() {
+		acl := []*DashboardAcl{
+			{UserId: 5, Permission: PERMISSION_EDIT},
+			{Role: ROLE_VIEWER, Permission: PERMISSION_VIEW},
+		}
+
+		Convey("Should grant a viewer the permission of its user item", func() {
+			So(GetDashboardPermission(acl, 5, nil, ROLE_VIEWER), ShouldEqual, PERMISSION_EDIT)
+		})
+
+		Convey("Should grant other viewers the permission of their role", func() {
+			So(GetDashboardPermission(acl, 6, nil, ROLE_VIEWER), ShouldEqual, PERMISSION_VIEW)
+		})
+
+		Convey("Should deny roles that aren't listed", func() {
+			So(GetDashboardPermission(acl, 6, nil, ROLE_EDITOR), ShouldEqual, PERMISSION_NONE)
+		})
+
+		Convey("Should keep org admins in full control", func() {
+			So(GetDashboardPermission(acl, 6, nil, ROLE_ADMIN), ShouldEqual, PERMISSION_EDIT)
+		})
+	})
+
+	Convey("Given a dashboard with a team item", t, func() {
+		acl := []*DashboardAcl{
+			{TeamId: 3, Permission: PERMISSION_EDIT},
+			{Role: ROLE_VIEWER, Permission: PERMISSION_VIEW},
+		}
+
+		Convey("Should need team lookups", func() {
+			So(HasTeamItems(acl), ShouldBeTrue)
+			So(HasTeamItems(acl[1:]), ShouldBeFalse)
+		})
+
+		Convey("Should grant team members the permission of the team", func() {
+			So(GetDashboardPermission(acl, 6, []int64{2, 3}, ROLE_VIEWER), ShouldEqual, PERMISSION_EDIT)
+		})
+
+		Convey("Should not grant it to users outside the team", func() {
+			So(GetDashboardPermission(acl, 6, []int64{2}, ROLE_VIEWER), ShouldEqual, PERMISSION_VIEW)
+			So(GetDashboardPermission(acl, 6, nil, ROLE_EDITOR), ShouldEqual, PERMISSION_NONE)
+		})
+	})
+}
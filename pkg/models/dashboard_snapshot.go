@@ -13,6 +13,8 @@ type DashboardSnapshot struct {
 	External    bool
 	ExternalUrl string
 
+	IdempotencyKey string
+
 	Expires time.Time
 	Created time.Time
 	Updated time.Time
@@ -25,6 +27,7 @@ type DashboardSnapshot struct {
 
 type CreateDashboardSnapshotCommand struct {
 	Dashboard map[string]interface{} `json:"dashboard" binding:"Required"`
+	Name      string                 `json:"name"`
 	Expires   int64                  `json:"expires"`
 
 	// these are passed when storing an external snapshot ref
@@ -35,6 +38,9 @@ type CreateDashboardSnapshotCommand struct {
 	OrgId  int64 `json:"-"`
 	UserId int64 `json:"-"`
 
+	// a retry with the same key returns the snapshot created first
+	IdempotencyKey string `json:"-"`
+
 	Result *DashboardSnapshot
 }
 
@@ -47,3 +53,23 @@ type GetDashboardSnapshotQuery struct {
 
 	Result *DashboardSnapshot
 }
+
+// GetDashboardSnapshotsQuery lists the unexpired snapshots in an org. A zero
+// UserId includes snapshots from every user. Page starts at 0.
+type GetDashboardSnapshotsQuery struct {
+	OrgId  int64
+	UserId int64
+	Page   int
+	Limit  int
+
+	Result []*DashboardSnapshotDTO
+}
+
+type DashboardSnapshotDTO struct {
+	Key      string    `json:"key"`
+	Name     string    `json:"name"`
+	UserId   int64     `json:"userId"`
+	External bool      `json:"external"`
+	Expires  time.Time `json:"expires"`
+	Created  time.Time `json:"created"`
+}
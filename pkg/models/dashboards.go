@@ -14,6 +14,10 @@ var (
 	ErrDashboardSnapshotNotFound   = errors.New("Dashboard snapshot not found")
 	ErrDashboardWithSameNameExists = errors.New("A dashboard with the same name already exists")
 	ErrDashboardVersionMismatch    = errors.New("The dashboard has been changed by someone else")
+	ErrDashboardProvisioned        = errors.New("The dashboard is provisioned from a file and can't be changed")
+	ErrDashboardFolderNotFound     = errors.New("Folder not found")
+	ErrDashboardFolderCycle        = errors.New("A folder can't be moved into itself")
+	ErrDashboardFolderNotEmpty     = errors.New("Folder is not empty")
 )
 
 var (
@@ -33,8 +37,17 @@ type Dashboard struct {
 	Created time.Time
 	Updated time.Time
 
-	Title string
-	Data  map[string]interface{}
+	Title       string
+	Data        map[string]interface{}
+	Provisioned bool
+
+	// folders are dashboard rows holding other dashboards, ParentId 0 is
+	// the root
+	IsFolder bool
+	ParentId int64
+
+	// set while the dashboard is deleted but can still be restored
+	Deleted *time.Time
 }
 
 // NewDashboard creates a new dashboard
@@ -85,6 +98,7 @@ func NewDashboardFromJson(data map[string]interface{}) *Dashboard {
 func (cmd *SaveDashboardCommand) GetDashboardModel() *Dashboard {
 	dash := NewDashboardFromJson(cmd.Dashboard)
 	dash.OrgId = cmd.OrgId
+	dash.Provisioned = cmd.Provisioned
 	dash.UpdateSlug()
 	return dash
 }
@@ -109,12 +123,77 @@ type SaveDashboardCommand struct {
 	Overwrite bool                   `json:"overwrite"`
 	OrgId     int64                  `json:"-"`
 
+	// only provisioning may save over a provisioned dashboard
+	Provisioned bool `json:"-"`
+
+	// IsFolder only applies to new dashboards. ParentId moves the dashboard
+	// into that folder, 0 to the root; left out the dashboard stays put.
+	IsFolder bool   `json:"isFolder"`
+	ParentId *int64 `json:"parentId"`
+
 	Result *Dashboard
 }
 
+// ImportDashboardsCommand saves the dashboards over the ones with the same
+// title, all of them or none. FailedIndex is the item that failed, -1 if
+// none did.
+type ImportDashboardsCommand struct {
+	OrgId int64
+	Items []*ImportDashboardItem
+
+	FailedIndex int
+	Result      []*Dashboard
+}
+
+type ImportDashboardItem struct {
+	Dashboard map[string]interface{}
+	IsFolder  bool
+
+	// index of the item holding the folder the dashboard goes into, -1 for
+	// the root. Folders have to come before their content.
+	FolderIndex int
+}
+
+// DeleteDashboardCommand marks the dashboard deleted, it can be restored
+// until purged.
 type DeleteDashboardCommand struct {
 	Slug  string
 	OrgId int64
+
+	// delete the dashboards of a folder too, otherwise it has to be empty.
+	// The user has to be able to edit each of them.
+	Cascade bool
+	UserId  int64
+	OrgRole RoleType
+}
+
+// RestoreDashboardCommand undoes deleting the dashboard, with the
+// dashboards deleted along with it when it is a folder. The user has to be
+// able to edit each of them.
+type RestoreDashboardCommand struct {
+	Id      int64
+	OrgId   int64
+	UserId  int64
+	OrgRole RoleType
+}
+
+// PurgeDeletedDashboardsCommand drops the dashboards deleted before Before
+// for good. Result is the count.
+type PurgeDeletedDashboardsCommand struct {
+	Before time.Time
+
+	Result int64
+}
+
+// DeleteDashboardsByTagCommand deletes the dashboards of the org carrying
+// Tag, the user has to be able to edit each of them. Result is the count.
+type DeleteDashboardsByTagCommand struct {
+	OrgId   int64
+	Tag     string
+	UserId  int64
+	OrgRole RoleType
+
+	Result int64
 }
 
 //
@@ -122,12 +201,39 @@ type DeleteDashboardCommand struct {
 //
 
 type GetDashboardQuery struct {
+	Id    int64
 	Slug  string
 	OrgId int64
 
 	Result *Dashboard
 }
 
+// GetDashboardRefsQuery lists the dashboards of the org without their json.
+type GetDashboardRefsQuery struct {
+	OrgId  int64
+	Result []*DashboardRef
+}
+
+type DashboardRef struct {
+	Id       int64
+	Slug     string
+	Title    string
+	IsFolder bool
+	ParentId int64
+}
+
+type GetDeletedDashboardsQuery struct {
+	OrgId  int64
+	Result []*DeletedDashboardDTO
+}
+
+type DeletedDashboardDTO struct {
+	Id      int64     `json:"id"`
+	Title   string    `json:"title"`
+	Slug    string    `json:"slug"`
+	Deleted time.Time `json:"deleted"`
+}
+
 type DashboardTagCloudItem struct {
 	Term  string `json:"term"`
 	Count int    `json:"count"`
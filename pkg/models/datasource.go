@@ -14,13 +14,15 @@ const (
 	DS_CLOUDWATCH    = "cloudwatch"
 	DS_KAIROSDB      = "kairosdb"
 	DS_PROMETHEUS    = "prometheus"
+	DS_OPENFALCON    = "openfalcon"
 	DS_ACCESS_DIRECT = "direct"
 	DS_ACCESS_PROXY  = "proxy"
 )
 
 // Typed errors
 var (
-	ErrDataSourceNotFound = errors.New("Data source not found")
+	ErrDataSourceNotFound   = errors.New("Data source not found")
+	ErrDataSourceNameExists = errors.New("Data source with same name already exists")
 )
 
 type DsAccess string
@@ -43,8 +45,12 @@ type DataSource struct {
 	IsDefault         bool
 	JsonData          map[string]interface{}
 
+	// created or updated from a provisioning file
+	Provisioned bool
+
 	Created time.Time
 	Updated time.Time
+	Deleted *time.Time
 }
 
 var knownDatasourcePlugins map[string]bool = map[string]bool{
@@ -85,8 +91,10 @@ type AddDataSourceCommand struct {
 	BasicAuthPassword string                 `json:"basicAuthPassword"`
 	IsDefault         bool                   `json:"isDefault"`
 	JsonData          map[string]interface{} `json:"jsonData"`
+	TestBeforeSave    bool                   `json:"testBeforeSave"`
 
-	OrgId int64 `json:"-"`
+	OrgId       int64 `json:"-"`
+	Provisioned bool  `json:"-"`
 
 	Result *DataSource
 }
@@ -105,9 +113,27 @@ type UpdateDataSourceCommand struct {
 	BasicAuthPassword string                 `json:"basicAuthPassword"`
 	IsDefault         bool                   `json:"isDefault"`
 	JsonData          map[string]interface{} `json:"jsonData"`
+	TestBeforeSave    bool                   `json:"testBeforeSave"`
 
 	OrgId int64 `json:"-"`
 	Id    int64 `json:"-"`
+
+	// marks the datasource provisioned, false leaves the flag as it is
+	Provisioned bool `json:"-"`
+}
+
+type ImportDataSourcesCommand struct {
+	OrgId            int64
+	DataSources      []AddDataSourceCommand
+	AbortOnDuplicate bool
+
+	Result []*ImportDataSourceResult
+}
+
+type ImportDataSourceResult struct {
+	Name  string `json:"name"`
+	Id    int64  `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
 }
 
 type DeleteDataSourceCommand struct {
@@ -115,11 +141,39 @@ type DeleteDataSourceCommand struct {
 	OrgId int64
 }
 
+type RestoreDataSourceCommand struct {
+	Id    int64
+	OrgId int64
+}
+
+// PurgeDeletedDataSourcesCommand drops the data sources deleted before
+// Before for good. Result is the count.
+type PurgeDeletedDataSourcesCommand struct {
+	Before time.Time
+
+	Result int64
+}
+
 // ---------------------
 // QUERIES
 
+// GetDataSourcesQuery lists the datasources of an org ordered by name,
+// optionally only those of Type or with Query in their name.
 type GetDataSourcesQuery struct {
 	OrgId  int64
+	Type   string
+	Query  string
+	Result []*DataSource
+}
+
+// GetDataSourcesLastModifiedQuery finds when the datasources of an org last
+// changed, deletes included. Result is zero when the org never had any.
+type GetDataSourcesLastModifiedQuery struct {
+	OrgId  int64
+	Result time.Time
+}
+
+type GetProvisionedDataSourcesQuery struct {
 	Result []*DataSource
 }
 
@@ -129,12 +183,20 @@ type GetDataSourceByIdQuery struct {
 	Result DataSource
 }
 
-type GetDataSourceByNameQuery struct {
-	Name   string
+type GetDefaultDataSourceQuery struct {
 	OrgId  int64
 	Result DataSource
 }
 
+// GetDataSourceByNameQuery finds a datasource by name. With IncludeDeleted a
+// deleted one still holding on to the name is found as well.
+type GetDataSourceByNameQuery struct {
+	Name           string
+	OrgId          int64
+	IncludeDeleted bool
+	Result         DataSource
+}
+
 // ---------------------
 // EVENTS
 type DataSourceCreatedEvent struct {
@@ -20,3 +20,12 @@ type ValidateResetPasswordCodeQuery struct {
 	Code   string
 	Result *User
 }
+
+type SendVerifyEmailCommand struct {
+	User *User
+}
+
+type ValidateVerifyEmailCodeQuery struct {
+	Code   string
+	Result *User
+}
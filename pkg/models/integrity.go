@@ -0,0 +1,20 @@
+package models
+
+// IntegrityIssue counts the rows of Table pointing at a missing Reference.
+type IntegrityIssue struct {
+	Table     string `json:"table"`
+	Reference string `json:"reference"`
+	Count     int64  `json:"count"`
+	Fixed     bool   `json:"fixed"`
+}
+
+// ----------------------
+// COMMANDS
+
+// CheckIntegrityCommand looks for rows left behind by deleted orgs,
+// dashboards and users, deleting them when Fix is set.
+type CheckIntegrityCommand struct {
+	Fix bool
+
+	Result []*IntegrityIssue
+}
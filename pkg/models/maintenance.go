@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Maintenance is the single row holding the maintenance mode switch.
+type Maintenance struct {
+	Id      int64
+	Enabled bool
+	Updated time.Time
+}
+
+// ----------------------
+// COMMANDS
+
+type SetMaintenanceCommand struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ----------------------
+// QUERIES
+
+type GetMaintenanceQuery struct {
+	Result *Maintenance
+}
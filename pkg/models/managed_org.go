@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Typed errors
+var (
+	ErrManagedOrgAlreadyAdded = errors.New("Organization is already managed by user")
+)
+
+// ManagedOrg lets a user administer the users of an org without being a
+// grafana admin.
+type ManagedOrg struct {
+	Id      int64
+	UserId  int64
+	OrgId   int64
+	Created time.Time
+}
+
+// ----------------------
+// COMMANDS
+
+type AddManagedOrgCommand struct {
+	UserId int64 `json:"-"`
+	OrgId  int64 `json:"orgId" binding:"Required"`
+}
+
+type RemoveManagedOrgCommand struct {
+	UserId int64
+	OrgId  int64
+}
+
+// ----------------------
+// QUERIES
+
+type GetManagedOrgsQuery struct {
+	UserId int64
+	Result []*OrgDTO
+}
+
+type IsManagedOrgQuery struct {
+	UserId int64
+	OrgId  int64
+	Result bool
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type MigrationLogEntry struct {
+	Id        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// MigrationStatus lists the migrations run against the database, a failed
+// one that was applied on a later start is only listed as applied.
+type MigrationStatus struct {
+	Applied []*MigrationLogEntry `json:"applied"`
+	Failed  []*MigrationLogEntry `json:"failed"`
+}
+
+// ----------------------
+// QUERIES
+
+type GetMigrationStatusQuery struct {
+	Result *MigrationStatus
+}
@@ -68,7 +68,8 @@ type SearchOrgsQuery struct {
 	Limit int
 	Page  int
 
-	Result []*OrgDTO
+	Result     []*OrgDTO
+	TotalCount int64
 }
 
 type OrgDTO struct {
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Typed errors
+var (
+	ErrFeatureFlagNotFound = errors.New("Unknown feature flag")
+)
+
+// OrgFeatureFlag overrides the default of a feature flag for one org.
+type OrgFeatureFlag struct {
+	Id      int64
+	OrgId   int64
+	Name    string
+	Enabled bool
+	Created time.Time
+	Updated time.Time
+}
+
+// ----------------------
+// COMMANDS
+
+type SetOrgFeatureFlagCommand struct {
+	OrgId   int64  `json:"-"`
+	Name    string `json:"-"`
+	Enabled bool   `json:"enabled"`
+}
+
+// ----------------------
+// QUERIES
+
+// GetOrgFeatureFlagsQuery resolves every known flag for the org, its own
+// setting wins over the default.
+type GetOrgFeatureFlagsQuery struct {
+	OrgId  int64
+	Result map[string]bool
+}
@@ -35,6 +35,16 @@ type OrgUser struct {
 	Updated time.Time
 }
 
+type OrgUserRoleHistory struct {
+	Id          int64
+	OrgId       int64
+	UserId      int64
+	OldRole     RoleType
+	NewRole     RoleType
+	ActorUserId int64
+	Created     time.Time
+}
+
 // ---------------------
 // COMMANDS
 
@@ -54,6 +64,15 @@ type AddOrgUserCommand struct {
 type UpdateOrgUserCommand struct {
 	Role RoleType `json:"role" binding:"Required"`
 
+	OrgId       int64 `json:"-"`
+	UserId      int64 `json:"-"`
+	ActorUserId int64 `json:"-"`
+}
+
+type MoveOrgUserCommand struct {
+	TargetOrgId int64    `json:"targetOrgId" binding:"Required"`
+	Role        RoleType `json:"role" binding:"Required"`
+
 	OrgId  int64 `json:"-"`
 	UserId int64 `json:"-"`
 }
@@ -63,6 +82,23 @@ type UpdateOrgUserCommand struct {
 
 type GetOrgUsersQuery struct {
 	OrgId  int64
+	Role   string
+	Query  string
+	Page   int
+	Limit  int
+	Result []*OrgUserDTO
+}
+
+type GetOrgUserRoleHistoryQuery struct {
+	OrgId  int64
+	UserId int64
+	Result []*OrgUserRoleHistoryDTO
+}
+
+// Users of an org that haven't been seen since the given time.
+type GetInactiveOrgUsersQuery struct {
+	OrgId  int64
+	Since  time.Time
 	Result []*OrgUserDTO
 }
 
@@ -70,9 +106,17 @@ type GetOrgUsersQuery struct {
 // Projections and DTOs
 
 type OrgUserDTO struct {
-	OrgId  int64  `json:"orgId"`
-	UserId int64  `json:"userId"`
-	Email  string `json:"email"`
-	Login  string `json:"login"`
-	Role   string `json:"role"`
+	OrgId      int64      `json:"orgId"`
+	UserId     int64      `json:"userId"`
+	Email      string     `json:"email"`
+	Login      string     `json:"login"`
+	Role       string     `json:"role"`
+	LastSeenAt *time.Time `json:"lastSeenAt"`
+}
+
+type OrgUserRoleHistoryDTO struct {
+	OldRole     RoleType  `json:"oldRole"`
+	NewRole     RoleType  `json:"newRole"`
+	ActorUserId int64     `json:"actorUserId"`
+	Created     time.Time `json:"created"`
 }
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Preferences with a zero UserId are the defaults of the org.
+type Preferences struct {
+	Id              int64
+	OrgId           int64
+	UserId          int64
+	Version         int
+	HomeDashboardId int64
+	Timezone        string
+	Theme           string
+	Created         time.Time
+	Updated         time.Time
+}
+
+// ---------------------
+// QUERIES
+
+// GetPreferencesQuery returns empty preferences when none are stored.
+type GetPreferencesQuery struct {
+	OrgId  int64
+	UserId int64
+
+	Result *Preferences
+}
+
+// GetPreferencesWithDefaultsQuery merges the user preferences over the org
+// defaults, a field the user has not set falls back to the org value.
+type GetPreferencesWithDefaultsQuery struct {
+	OrgId  int64
+	UserId int64
+
+	Result *Preferences
+}
+
+// ---------------------
+// COMMANDS
+
+type SavePreferencesCommand struct {
+	OrgId           int64
+	UserId          int64
+	HomeDashboardId int64
+	Timezone        string
+	Theme           string
+}
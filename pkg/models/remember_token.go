@@ -0,0 +1,64 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Typed errors
+var (
+	ErrRememberTokenNotFound = errors.New("Remember token not found")
+	ErrRememberTokenReused   = errors.New("Remember token has already been used")
+	ErrRememberTokenExpired  = errors.New("Remember token has expired")
+)
+
+// RememberToken backs the remember me cookie. The cookie holds a series and a
+// token; the series stays the same for a login while the token is replaced on
+// every use. Only hashes of both are stored.
+type RememberToken struct {
+	Id            int64
+	UserId        int64
+	SeriesHash    string
+	TokenHash     string
+	PrevTokenHash string
+
+	Created time.Time
+	Updated time.Time
+}
+
+// ----------------------
+// COMMANDS
+
+type CreateRememberTokenCommand struct {
+	UserId int64
+	Series string
+	Token  string
+}
+
+// RotateRememberTokenCommand swaps Token for NewToken. The token rotated away
+// last is accepted without rotating for GracePeriod, Rotated tells the two
+// apart. Presenting any other stale token deletes the series along with its
+// user session and fails with ErrRememberTokenReused. A series created more than MaxLifetime ago is
+// deleted too and fails with ErrRememberTokenExpired.
+type RotateRememberTokenCommand struct {
+	Series      string
+	Token       string
+	NewToken    string
+	MaxLifetime time.Duration
+	GracePeriod time.Duration
+
+	Result  *RememberToken
+	Rotated bool
+}
+
+type DeleteRememberTokenCommand struct {
+	Series string
+}
+
+// PurgeRememberTokensCommand drops the tokens last used before Before, their
+// cookies have expired. Result is the count.
+type PurgeRememberTokensCommand struct {
+	Before time.Time
+
+	Result int64
+}
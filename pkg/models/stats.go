@@ -1,11 +1,29 @@
 package models
 
+import "time"
+
 type SystemStats struct {
 	DashboardCount int
 	UserCount      int
 	OrgCount       int
 }
 
+type OrgStats struct {
+	Id              int64 `json:"orgId"`
+	UserCount       int   `json:"userCount"`
+	DashboardCount  int   `json:"dashboardCount"`
+	DataSourceCount int   `json:"dataSourceCount"`
+	ApiKeyCount     int   `json:"apiKeyCount"`
+}
+
+type AdminStats struct {
+	OrgCount           int `json:"orgs"`
+	UserCount          int `json:"users"`
+	ActiveSessionCount int `json:"activeSessions"`
+	DashboardCount     int `json:"dashboards"`
+	SnapshotCount      int `json:"snapshots"`
+}
+
 type DataSourceStats struct {
 	Count int
 	Type  string
@@ -18,3 +36,14 @@ type GetSystemStatsQuery struct {
 type GetDataSourceStatsQuery struct {
 	Result []*DataSourceStats
 }
+
+type GetOrgStatsQuery struct {
+	OrgId  int64
+	Result *OrgStats
+}
+
+// Active sessions are the user sessions seen since ActiveSince.
+type GetAdminStatsQuery struct {
+	ActiveSince time.Time
+	Result      *AdminStats
+}
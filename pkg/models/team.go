@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Typed errors
+var (
+	ErrTeamNotFound           = errors.New("Team not found")
+	ErrTeamNameTaken          = errors.New("A team with the same name already exists")
+	ErrTeamMemberNotFound     = errors.New("User is not a member of the team")
+	ErrTeamMemberAlreadyAdded = errors.New("User is already a member of the team")
+)
+
+// Team groups users of an org, dashboard acls can grant a team a
+// permission.
+type Team struct {
+	Id      int64     `json:"id"`
+	OrgId   int64     `json:"orgId"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+type TeamMember struct {
+	Id      int64
+	OrgId   int64
+	TeamId  int64
+	UserId  int64
+	Created time.Time
+}
+
+// ----------------------
+// COMMANDS
+
+type CreateTeamCommand struct {
+	Name  string `json:"name" binding:"Required"`
+	OrgId int64  `json:"-"`
+
+	Result *Team `json:"-"`
+}
+
+type UpdateTeamCommand struct {
+	Id    int64  `json:"-"`
+	Name  string `json:"name" binding:"Required"`
+	OrgId int64  `json:"-"`
+}
+
+type DeleteTeamCommand struct {
+	Id    int64
+	OrgId int64
+}
+
+type AddTeamMemberCommand struct {
+	UserId int64 `json:"userId" binding:"Required"`
+	OrgId  int64 `json:"-"`
+	TeamId int64 `json:"-"`
+}
+
+type RemoveTeamMemberCommand struct {
+	OrgId  int64
+	TeamId int64
+	UserId int64
+}
+
+// ----------------------
+// QUERIES
+
+type GetTeamByIdQuery struct {
+	Id     int64
+	OrgId  int64
+	Result *Team
+}
+
+type GetTeamsByOrgQuery struct {
+	OrgId  int64
+	Result []*Team
+}
+
+type GetTeamMembersQuery struct {
+	OrgId  int64
+	TeamId int64
+	Result []*TeamMemberDTO
+}
+
+// GetUserTeamsQuery lists the teams of the org the user is a member of.
+type GetUserTeamsQuery struct {
+	OrgId  int64
+	UserId int64
+	Result []*Team
+}
+
+// ----------------------
+// Projections and DTOs
+
+type TeamMemberDTO struct {
+	TeamId int64  `json:"teamId"`
+	UserId int64  `json:"userId"`
+	Email  string `json:"email"`
+	Login  string `json:"login"`
+}
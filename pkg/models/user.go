@@ -7,7 +7,13 @@ import (
 
 // Typed errors
 var (
-	ErrUserNotFound = errors.New("User not found")
+	ErrUserNotFound          = errors.New("User not found")
+	ErrEmailDomainNotAllowed = errors.New("Email domain is not allowed to sign up")
+	ErrLastGrafanaAdmin      = errors.New("Cannot remove the last grafana admin")
+	ErrUserEmailTaken        = errors.New("A user with that email already exists")
+	ErrUserLoginTaken        = errors.New("A user with that login already exists")
+	ErrUserDisabled          = errors.New("User is disabled")
+	ErrEmailNotVerified      = errors.New("Email address is not verified")
 )
 
 type User struct {
@@ -17,17 +23,21 @@ type User struct {
 	Name          string
 	Login         string
 	Password      string
+	PasswordCost  int
 	Salt          string
 	Rands         string
 	Company       string
 	EmailVerified bool
 	Theme         string
 
-	IsAdmin bool
-	OrgId   int64
+	IsAdmin    bool
+	IsDisabled bool
+	OrgId      int64
 
 	Created time.Time
 	Updated time.Time
+	// nil until the user is first seen signed in
+	LastSeenAt *time.Time
 }
 
 func (u *User) NameOrFallback() string {
@@ -64,13 +74,16 @@ type UpdateUserCommand struct {
 	Theme string `json:"theme"`
 
 	UserId int64 `json:"-"`
+	// the new email comes from a trusted source and needs no verification
+	EmailVerified bool `json:"-"`
 }
 
 type ChangeUserPasswordCommand struct {
 	OldPassword string `json:"oldPassword"`
 	NewPassword string `json:"newPassword"`
 
-	UserId int64 `json:"-"`
+	UserId       int64 `json:"-"`
+	PasswordCost int   `json:"-"`
 }
 
 type UpdateUserPermissionsCommand struct {
@@ -87,6 +100,22 @@ type SetUsingOrgCommand struct {
 	OrgId  int64
 }
 
+type UpdateUserLastSeenAtCommand struct {
+	UserId int64
+}
+
+type DisableUserCommand struct {
+	UserId int64
+}
+
+type EnableUserCommand struct {
+	UserId int64
+}
+
+type VerifyUserEmailCommand struct {
+	UserId int64
+}
+
 // ----------------------
 // QUERIES
 
@@ -100,6 +129,12 @@ type GetUserByIdQuery struct {
 	Result *User
 }
 
+// Fetches many users in one query, unknown ids are skipped.
+type GetUsersByIdsQuery struct {
+	Ids    []int64
+	Result []*UserDTO
+}
+
 type GetSignedInUserQuery struct {
 	UserId int64
 	Login  string
@@ -117,7 +152,8 @@ type SearchUsersQuery struct {
 	Page  int
 	Limit int
 
-	Result []*UserSearchHitDTO
+	Result     []*UserSearchHitDTO
+	TotalCount int64
 }
 
 type GetUserOrgListQuery struct {
@@ -125,9 +161,38 @@ type GetUserOrgListQuery struct {
 	Result []*UserOrgDTO
 }
 
+// Resources left behind when a user is deleted. Dashboards and api keys
+// don't record who created them, so the ones in orgs the user is the only
+// admin of are listed.
+type GetUserOwnedResourcesQuery struct {
+	UserId int64
+	Result *UserOwnedResourcesDTO
+}
+
 // ------------------------
 // DTO & Projections
 
+type UserOwnedResourcesDTO struct {
+	SoleAdminOrgs []*OrgDTO                `json:"soleAdminOrgs"`
+	Dashboards    []*UserOwnedDashboardDTO `json:"dashboards"`
+	ApiKeys       []*UserOwnedApiKeyDTO    `json:"apiKeys"`
+	Snapshots     []*DashboardSnapshotDTO  `json:"snapshots"`
+}
+
+type UserOwnedDashboardDTO struct {
+	Id    int64  `json:"id"`
+	OrgId int64  `json:"orgId"`
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+}
+
+type UserOwnedApiKeyDTO struct {
+	Id    int64    `json:"id"`
+	OrgId int64    `json:"orgId"`
+	Name  string   `json:"name"`
+	Role  RoleType `json:"role"`
+}
+
 type SignedInUser struct {
 	UserId         int64
 	OrgId          int64
@@ -139,6 +204,10 @@ type SignedInUser struct {
 	Theme          string
 	ApiKeyId       int64
 	IsGrafanaAdmin bool
+	IsDisabled     bool
+	EmailVerified  bool
+	LastSeenAt     time.Time
+	AvatarUrl      string
 }
 
 type UserProfileDTO struct {
@@ -150,10 +219,18 @@ type UserProfileDTO struct {
 	IsGrafanaAdmin bool   `json:"isGrafanaAdmin"`
 }
 
+type UserDTO struct {
+	Id        int64  `json:"id"`
+	Login     string `json:"login"`
+	Email     string `json:"email"`
+	AvatarUrl string `json:"avatarUrl"`
+}
+
 type UserSearchHitDTO struct {
-	Id      int64  `json:"id"`
-	Name    string `json:"name"`
-	Login   string `json:"login"`
-	Email   string `json:"email"`
-	IsAdmin bool   `json:"isAdmin"`
+	Id         int64  `json:"id"`
+	Name       string `json:"name"`
+	Login      string `json:"login"`
+	Email      string `json:"email"`
+	IsAdmin    bool   `json:"isAdmin"`
+	IsDisabled bool   `json:"isDisabled"`
 }
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Typed errors
+var (
+	ErrUserSessionNotFound = errors.New("User session not found")
+)
+
+// UserSession is a login kept in the session store. Its id is stored in the
+// session so deleting the row signs that session out on its next request.
+// Updated is when the session was last seen, at most every few minutes.
+// Renewing the login with the remember me cookie keeps its session.
+type UserSession struct {
+	Id                 int64
+	UserId             int64
+	Ip                 string
+	UserAgent          string
+	RememberSeriesHash string
+
+	Created time.Time
+	Updated time.Time
+}
+
+// ----------------------
+// COMMANDS
+
+// CreateUserSessionCommand reuses the session of RememberSeries when the
+// series already has one, the login is being renewed then.
+type CreateUserSessionCommand struct {
+	UserId         int64
+	Ip             string
+	UserAgent      string
+	RememberSeries string
+
+	Result *UserSession
+}
+
+type UpdateUserSessionSeenCommand struct {
+	Id int64
+	Ip string
+}
+
+// DeleteUserSessionCommand signs the session out along with the remember me
+// series renewing it. It fails with ErrUserSessionNotFound when the user has
+// no session with the id.
+type DeleteUserSessionCommand struct {
+	Id     int64
+	UserId int64
+}
+
+// DeleteUserSessionsCommand signs the user out everywhere by deleting their
+// sessions and remember me tokens, except the session with KeepId and the
+// remember me series KeepSeries when set.
+type DeleteUserSessionsCommand struct {
+	UserId     int64
+	KeepId     int64
+	KeepSeries string
+}
+
+// PurgeUserSessionsCommand drops the sessions last seen before SeenBefore or
+// logged in before LoggedInBefore, they can't be used anymore. Result is the
+// count.
+type PurgeUserSessionsCommand struct {
+	SeenBefore     time.Time
+	LoggedInBefore time.Time
+
+	Result int64
+}
+
+// ----------------------
+// QUERIES
+
+type GetUserSessionQuery struct {
+	Id     int64
+	UserId int64
+
+	Result *UserSession
+}
+
+// GetUserSessionsQuery lists the user's sessions, last seen first. Sessions
+// last seen before SeenAfter or logged in before LoggedInAfter have expired
+// and are left out.
+type GetUserSessionsQuery struct {
+	UserId        int64
+	SeenAfter     time.Time
+	LoggedInAfter time.Time
+
+	Result []*UserSession
+}
+
+// ----------------------
+// DTO & Projections
+
+type UserSessionDTO struct {
+	Id        int64     `json:"id"`
+	Created   time.Time `json:"created"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Ip        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	IsCurrent bool      `json:"isCurrent"`
+}
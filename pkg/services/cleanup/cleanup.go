@@ -0,0 +1,89 @@
+package cleanup
+
+import (
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/log"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// Init starts purging deleted dashboards and data sources once the retention
+// set in [dashboards] and [datasources] has passed, and remember me tokens
+// and user sessions that have expired.
+func Init() {
+	go run()
+}
+
+func run() {
+	ticker := time.NewTicker(time.Hour)
+	for {
+		if setting.DashboardDeletedRetention > 0 {
+			purgeDeletedDashboards()
+		}
+		if setting.DataSourceDeletedRetention > 0 {
+			purgeDeletedDataSources()
+		}
+		if setting.LogInRememberDays > 0 {
+			purgeRememberTokens()
+		}
+		purgeUserSessions()
+		<-ticker.C
+	}
+}
+
+func purgeDeletedDashboards() {
+	cmd := m.PurgeDeletedDashboardsCommand{Before: time.Now().Add(-setting.DashboardDeletedRetention)}
+	if err := bus.Dispatch(&cmd); err != nil {
+		log.Error(3, "Cleanup: failed to purge deleted dashboards: %v", err)
+		return
+	}
+
+	if cmd.Result > 0 {
+		log.Info("Cleanup: purged %d deleted dashboards", cmd.Result)
+	}
+}
+
+func purgeDeletedDataSources() {
+	cmd := m.PurgeDeletedDataSourcesCommand{Before: time.Now().Add(-setting.DataSourceDeletedRetention)}
+	if err := bus.Dispatch(&cmd); err != nil {
+		log.Error(3, "Cleanup: failed to purge deleted data sources: %v", err)
+		return
+	}
+
+	if cmd.Result > 0 {
+		log.Info("Cleanup: purged %d deleted data sources", cmd.Result)
+	}
+}
+
+func purgeRememberTokens() {
+	cmd := m.PurgeRememberTokensCommand{Before: time.Now().AddDate(0, 0, -setting.LogInRememberDays)}
+	if err := bus.Dispatch(&cmd); err != nil {
+		log.Error(3, "Cleanup: failed to purge remember me tokens: %v", err)
+		return
+	}
+
+	if cmd.Result > 0 {
+		log.Info("Cleanup: purged %d expired remember me tokens", cmd.Result)
+	}
+}
+
+// userSessionPurgeMargin covers last seen being updated only every few
+// minutes.
+const userSessionPurgeMargin = time.Hour
+
+func purgeUserSessions() {
+	cmd := m.PurgeUserSessionsCommand{SeenBefore: time.Now().Add(-setting.UserSessionIdleLifetime() - userSessionPurgeMargin)}
+	if setting.LoginMaxLifetime > 0 {
+		cmd.LoggedInBefore = time.Now().Add(-setting.LoginMaxLifetime)
+	}
+	if err := bus.Dispatch(&cmd); err != nil {
+		log.Error(3, "Cleanup: failed to purge user sessions: %v", err)
+		return
+	}
+
+	if cmd.Result > 0 {
+		log.Info("Cleanup: purged %d expired user sessions", cmd.Result)
+	}
+}
@@ -0,0 +1,70 @@
+// Package eventstream fans bus events out to the clients of an org that
+// are listening for changes, e.g. over server-sent events.
+package eventstream
+
+import (
+	"sync"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/events"
+)
+
+// Events queued for a subscriber that doesn't keep up are dropped.
+const subscriberBuffer = 16
+
+var (
+	lock        sync.RWMutex
+	subscribers = make(map[chan *events.OnTheWireEvent]int64)
+)
+
+func Init() {
+	bus.AddEventListener(func(evt *events.DashboardSaved) error {
+		return publish(evt.OrgId, evt)
+	})
+	bus.AddEventListener(func(evt *events.OrgUpdated) error {
+		return publish(evt.Id, evt)
+	})
+}
+
+// Subscribe returns the events of orgId and a func that stops them, the
+// channel is closed once unsubscribed.
+func Subscribe(orgId int64) (<-chan *events.OnTheWireEvent, func()) {
+	ch := make(chan *events.OnTheWireEvent, subscriberBuffer)
+
+	lock.Lock()
+	subscribers[ch] = orgId
+	lock.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			lock.Lock()
+			delete(subscribers, ch)
+			lock.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func publish(orgId int64, event interface{}) error {
+	wireEvent, err := events.ToOnWriteEvent(event)
+	if err != nil {
+		return err
+	}
+
+	lock.RLock()
+	defer lock.RUnlock()
+
+	for ch, subscribedOrgId := range subscribers {
+		if subscribedOrgId != orgId {
+			continue
+		}
+
+		select {
+		case ch <- wireEvent:
+		default:
+		}
+	}
+
+	return nil
+}
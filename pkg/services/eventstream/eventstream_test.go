@@ -0,0 +1,55 @@
+package eventstream
+
+import (
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEventStream(t *testing.T) {
+
+	Convey("Given a subscriber of an org", t, func() {
+		bus.ClearBusHandlers()
+		Init()
+		defer bus.ClearBusHandlers()
+
+		stream, unsubscribe := Subscribe(1)
+
+		Convey("Should receive updates of that org", func() {
+			So(bus.Publish(&events.OrgUpdated{Id: 1, Name: "renamed"}), ShouldBeNil)
+
+			evt := <-stream
+			So(evt.EventType, ShouldEqual, "OrgUpdated")
+			So(evt.Payload.(*events.OrgUpdated).Name, ShouldEqual, "renamed")
+			unsubscribe()
+		})
+
+		Convey("Should not receive events of other orgs", func() {
+			So(bus.Publish(&events.OrgUpdated{Id: 2}), ShouldBeNil)
+			So(bus.Publish(&events.DashboardSaved{OrgId: 2}), ShouldBeNil)
+
+			So(len(stream), ShouldEqual, 0)
+			unsubscribe()
+		})
+
+		Convey("Should drop events it doesn't keep up with", func() {
+			for i := 0; i < subscriberBuffer+5; i++ {
+				So(bus.Publish(&events.DashboardSaved{OrgId: 1}), ShouldBeNil)
+			}
+
+			So(len(stream), ShouldEqual, subscriberBuffer)
+			unsubscribe()
+		})
+
+		Convey("Should close the stream once unsubscribed", func() {
+			unsubscribe()
+			unsubscribe()
+
+			_, open := <-stream
+			So(open, ShouldBeFalse)
+			So(len(subscribers), ShouldEqual, 0)
+		})
+	})
+}
@@ -46,7 +46,16 @@ func createTimeLimitCode(data string, minutes int, startInf interface{}) string
 
 // verify time limit code
 func validateUserEmailCode(user *m.User, code string) bool {
-	if len(code) <= 18 {
+	return validateTimeLimitCode(userEmailCodeData(user), code)
+}
+
+// validateUserVerifyEmailCode checks a code sent to verify the user's email.
+func validateUserVerifyEmailCode(user *m.User, code string) bool {
+	return validateTimeLimitCode(verifyEmailCodeData(user), code)
+}
+
+func validateTimeLimitCode(data string, code string) bool {
+	if len(code) < timeLimitCodeLength {
 		return false
 	}
 
@@ -61,9 +70,7 @@ func validateUserEmailCode(user *m.User, code string) bool {
 	}
 
 	// right active code
-	data := com.ToStr(user.Id) + user.Email + user.Login + user.Password + user.Rands
 	retCode := createTimeLimitCode(data, minutes, start)
-	fmt.Printf("code : %s\ncode2: %s", retCode, code)
 	if retCode == code && minutes > 0 {
 		// check time is expired or not
 		before, _ := time.ParseInLocation("200601021504", start, time.Local)
@@ -87,9 +94,26 @@ func getLoginForEmailCode(code string) string {
 	return string(b)
 }
 
+func userEmailCodeData(u *m.User) string {
+	return com.ToStr(u.Id) + u.Email + u.Login + u.Password + u.Rands
+}
+
+// verify email codes are made from other data than reset password codes so
+// one can't be used as the other
+func verifyEmailCodeData(u *m.User) string {
+	return "verify_email" + com.ToStr(u.Id) + u.Email + u.Login + u.Rands
+}
+
 func createUserEmailCode(u *m.User, startInf interface{}) string {
+	return createEmailCode(u, userEmailCodeData(u), startInf)
+}
+
+func createVerifyEmailCode(u *m.User, startInf interface{}) string {
+	return createEmailCode(u, verifyEmailCodeData(u), startInf)
+}
+
+func createEmailCode(u *m.User, data string, startInf interface{}) string {
 	minutes := setting.EmailCodeValidMinutes
-	data := com.ToStr(u.Id) + u.Email + u.Login + u.Password + u.Rands
 	code := createTimeLimitCode(data, minutes, startInf)
 
 	// add tail hex username
@@ -30,6 +30,31 @@ func TestEmailCodes(t *testing.T) {
 			So(validateUserEmailCode(user, code), ShouldBeFalse)
 		})
 
+		Convey("Cannot verify an email with it", func() {
+			So(validateUserVerifyEmailCode(user, code), ShouldBeFalse)
+		})
+	})
+
+	Convey("When generating a verify email code", t, func() {
+		setting.EmailCodeValidMinutes = 120
+
+		user := &m.User{Id: 10, Email: "t@a.com", Login: "asd", Password: "1", Rands: "2"}
+		code := createVerifyEmailCode(user, nil)
+
+		Convey("Can verify the email", func() {
+			So(getLoginForEmailCode(code), ShouldEqual, "asd")
+			So(validateUserVerifyEmailCode(user, code), ShouldBeTrue)
+		})
+
+		Convey("Cannot reset the password with it", func() {
+			So(validateUserEmailCode(user, code), ShouldBeFalse)
+		})
+
+		Convey("Cannot verify another email", func() {
+			user.Email = "other@a.com"
+			So(validateUserVerifyEmailCode(user, code), ShouldBeFalse)
+		})
+
 	})
 
 }
@@ -20,12 +20,15 @@ var mailTemplates *template.Template
 var tmplResetPassword = "reset_password.html"
 var tmplSignUpStarted = "signup_started.html"
 var tmplWelcomeOnSignUp = "welcome_on_signup.html"
+var tmplVerifyEmail = "verify_email.html"
 
 func Init() error {
 	initMailQueue()
 
 	bus.AddHandler("email", sendResetPasswordEmail)
 	bus.AddHandler("email", validateResetPasswordCode)
+	bus.AddHandler("email", sendVerifyEmail)
+	bus.AddHandler("email", validateVerifyEmailCode)
 	bus.AddHandler("email", sendEmailCommandHandler)
 
 	bus.AddEventListener(signUpStartedHandler)
@@ -136,6 +139,36 @@ func validateResetPasswordCode(query *m.ValidateResetPasswordCodeQuery) error {
 	return nil
 }
 
+func sendVerifyEmail(cmd *m.SendVerifyEmailCommand) error {
+	return sendEmailCommandHandler(&m.SendEmailCommand{
+		To:       []string{cmd.User.Email},
+		Template: tmplVerifyEmail,
+		Data: map[string]interface{}{
+			"Code": createVerifyEmailCode(cmd.User, nil),
+			"Name": cmd.User.NameOrFallback(),
+		},
+	})
+}
+
+func validateVerifyEmailCode(query *m.ValidateVerifyEmailCodeQuery) error {
+	login := getLoginForEmailCode(query.Code)
+	if login == "" {
+		return m.ErrInvalidEmailCode
+	}
+
+	userQuery := m.GetUserByLoginQuery{LoginOrEmail: login}
+	if err := bus.Dispatch(&userQuery); err != nil {
+		return err
+	}
+
+	if !validateUserVerifyEmailCode(userQuery.Result, query.Code) {
+		return m.ErrInvalidEmailCode
+	}
+
+	query.Result = userQuery.Result
+	return nil
+}
+
 func signUpStartedHandler(evt *events.SignUpStarted) error {
 	if !setting.VerifyEmailEnabled {
 		return nil
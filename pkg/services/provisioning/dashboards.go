@@ -0,0 +1,94 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/log"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// dashboardsOrgId is the org provisioned dashboards are saved to.
+const dashboardsOrgId = 1
+
+// ProvisionDashboards saves every *.json dashboard of the folder at path to
+// the main org. Dashboards are identified by the slug of their title and
+// marked provisioned, which keeps them from being changed through the api.
+func ProvisionDashboards(path string) error {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(path, file.Name()))
+		if err != nil {
+			return err
+		}
+
+		var dashboard map[string]interface{}
+		if err := json.Unmarshal(data, &dashboard); err != nil {
+			return fmt.Errorf("Failed to parse %s: %v", file.Name(), err)
+		}
+
+		if err := provisionDashboard(file.Name(), dashboard); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func provisionDashboard(name string, data map[string]interface{}) error {
+	title, ok := data["title"].(string)
+	if !ok || title == "" {
+		return fmt.Errorf("Provisioned dashboard %s needs a title", name)
+	}
+
+	// ids and versions belong to the database the file was exported from
+	delete(data, "id")
+	delete(data, "version")
+
+	query := m.GetDashboardQuery{Slug: m.NewDashboard(title).Slug, OrgId: dashboardsOrgId}
+	err := bus.Dispatch(&query)
+	if err != nil && err != m.ErrDashboardNotFound {
+		return err
+	}
+	if err == nil && !dashboardChanged(query.Result, data) {
+		return nil
+	}
+
+	log.Info("Provisioning: saving dashboard %s from %s", title, name)
+	return bus.Dispatch(&m.SaveDashboardCommand{
+		Dashboard:   data,
+		Overwrite:   true,
+		OrgId:       dashboardsOrgId,
+		Provisioned: true,
+	})
+}
+
+// dashboardChanged reports whether the stored dashboard differs from the
+// file, or was not provisioned before.
+func dashboardChanged(existing *m.Dashboard, data map[string]interface{}) bool {
+	if !existing.Provisioned {
+		return true
+	}
+
+	stored := make(map[string]interface{}, len(existing.Data))
+	for key, value := range existing.Data {
+		if key != "id" && key != "version" {
+			stored[key] = value
+		}
+	}
+
+	return !reflect.DeepEqual(stored, data)
+}
@@ -0,0 +1,91 @@
+package provisioning
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProvisionDashboards(t *testing.T) {
+
+	Convey("Given a folder of dashboards", t, func() {
+		defer bus.ClearBusHandlers()
+
+		// a fake dashboard store keyed by slug
+		dashboards := make(map[string]*m.Dashboard)
+		saves := 0
+
+		bus.AddHandler("test", func(query *m.GetDashboardQuery) error {
+			dash, ok := dashboards[query.Slug]
+			if !ok || dash.OrgId != query.OrgId {
+				return m.ErrDashboardNotFound
+			}
+			query.Result = dash
+			return nil
+		})
+		bus.AddHandler("test", func(cmd *m.SaveDashboardCommand) error {
+			dash := cmd.GetDashboardModel()
+			dashboards[dash.Slug] = dash
+			saves++
+			cmd.Result = dash
+			return nil
+		})
+
+		dir, err := ioutil.TempDir("", "grafana-dashboards")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		write := func(name, content string) {
+			So(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), ShouldBeNil)
+		}
+		write("web.json", `{"id": 12, "version": 3, "title": "Web servers", "tags": ["prod"]}`)
+		write("db.json", `{"title": "Databases", "rows": []}`)
+		write("README.md", `not a dashboard`)
+
+		So(ProvisionDashboards(dir), ShouldBeNil)
+
+		Convey("Should save the json dashboards to the main org", func() {
+			So(saves, ShouldEqual, 2)
+
+			web := dashboards["web-servers"]
+			So(web, ShouldNotBeNil)
+			So(web.OrgId, ShouldEqual, 1)
+			So(web.Provisioned, ShouldBeTrue)
+			So(web.Id, ShouldEqual, 0)
+			So(web.GetTags(), ShouldResemble, []string{"prod"})
+
+			So(dashboards["databases"], ShouldNotBeNil)
+		})
+
+		Convey("Should not save unchanged dashboards again", func() {
+			So(ProvisionDashboards(dir), ShouldBeNil)
+			So(saves, ShouldEqual, 2)
+		})
+
+		Convey("Should save dashboards that changed", func() {
+			write("db.json", `{"title": "Databases", "rows": [], "editable": false}`)
+
+			So(ProvisionDashboards(dir), ShouldBeNil)
+			So(saves, ShouldEqual, 3)
+			So(dashboards["databases"].Data["editable"], ShouldEqual, false)
+		})
+
+		Convey("Should take over a dashboard saved by hand", func() {
+			dashboards["databases"].Provisioned = false
+
+			So(ProvisionDashboards(dir), ShouldBeNil)
+			So(saves, ShouldEqual, 3)
+			So(dashboards["databases"].Provisioned, ShouldBeTrue)
+		})
+
+		Convey("Should reject dashboards without a title", func() {
+			write("untitled.json", `{"rows": []}`)
+			So(ProvisionDashboards(dir), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,176 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/log"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// DataSourcesConfig is the file read by ProvisionDataSources.
+type DataSourcesConfig struct {
+	DataSources []*DataSourceConfig `json:"datasources"`
+}
+
+// DataSourceConfig describes one datasource, it is identified by its org
+// and name. OrgId defaults to the main org.
+type DataSourceConfig struct {
+	OrgId             int64                  `json:"orgId"`
+	Name              string                 `json:"name"`
+	Type              string                 `json:"type"`
+	Access            m.DsAccess             `json:"access"`
+	Url               string                 `json:"url"`
+	Password          string                 `json:"password"`
+	User              string                 `json:"user"`
+	Database          string                 `json:"database"`
+	BasicAuth         bool                   `json:"basicAuth"`
+	BasicAuthUser     string                 `json:"basicAuthUser"`
+	BasicAuthPassword string                 `json:"basicAuthPassword"`
+	IsDefault         bool                   `json:"isDefault"`
+	JsonData          map[string]interface{} `json:"jsonData"`
+}
+
+// ProvisionDataSources creates the datasources of the file at path that
+// don't exist yet and updates the ones that differ from it. With prune,
+// provisioned datasources that are no longer in the file are deleted.
+func ProvisionDataSources(path string, prune bool) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var cfg DataSourcesConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("Failed to parse %s: %v", path, err)
+	}
+
+	return applyDataSources(&cfg, prune)
+}
+
+func applyDataSources(cfg *DataSourcesConfig, prune bool) error {
+	wanted := make(map[int64]map[string]bool)
+
+	for _, ds := range cfg.DataSources {
+		if ds.OrgId == 0 {
+			ds.OrgId = 1
+		}
+		if ds.Access == "" {
+			ds.Access = m.DS_ACCESS_PROXY
+		}
+		if ds.Name == "" || ds.Type == "" {
+			return fmt.Errorf("Provisioned datasources need a name and a type, got %q of type %q", ds.Name, ds.Type)
+		}
+
+		if wanted[ds.OrgId] == nil {
+			wanted[ds.OrgId] = make(map[string]bool)
+		}
+		if wanted[ds.OrgId][ds.Name] {
+			return fmt.Errorf("Datasource %q is provisioned twice in org %d", ds.Name, ds.OrgId)
+		}
+		wanted[ds.OrgId][ds.Name] = true
+
+		if err := provisionDataSource(ds); err != nil {
+			return err
+		}
+	}
+
+	if !prune {
+		return nil
+	}
+
+	query := m.GetProvisionedDataSourcesQuery{}
+	if err := bus.Dispatch(&query); err != nil {
+		return err
+	}
+
+	for _, existing := range query.Result {
+		if wanted[existing.OrgId][existing.Name] {
+			continue
+		}
+
+		log.Info("Provisioning: deleting datasource %s of org %d", existing.Name, existing.OrgId)
+		if err := bus.Dispatch(&m.DeleteDataSourceCommand{Id: existing.Id, OrgId: existing.OrgId}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func provisionDataSource(ds *DataSourceConfig) error {
+	// a datasource deleted by hand or by prune keeps its name until purged,
+	// it is restored rather than added again
+	query := m.GetDataSourceByNameQuery{OrgId: ds.OrgId, Name: ds.Name, IncludeDeleted: true}
+	err := bus.Dispatch(&query)
+	if err == nil && query.Result.Deleted != nil {
+		log.Info("Provisioning: restoring deleted datasource %s of org %d", ds.Name, ds.OrgId)
+		err = bus.Dispatch(&m.RestoreDataSourceCommand{Id: query.Result.Id, OrgId: ds.OrgId})
+	}
+
+	if err == m.ErrDataSourceNotFound {
+		log.Info("Provisioning: creating datasource %s of org %d", ds.Name, ds.OrgId)
+		return bus.Dispatch(&m.AddDataSourceCommand{
+			OrgId:             ds.OrgId,
+			Name:              ds.Name,
+			Type:              ds.Type,
+			Access:            ds.Access,
+			Url:               ds.Url,
+			Password:          ds.Password,
+			User:              ds.User,
+			Database:          ds.Database,
+			BasicAuth:         ds.BasicAuth,
+			BasicAuthUser:     ds.BasicAuthUser,
+			BasicAuthPassword: ds.BasicAuthPassword,
+			IsDefault:         ds.IsDefault,
+			JsonData:          ds.JsonData,
+			Provisioned:       true,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if !dataSourceChanged(&query.Result, ds) {
+		return nil
+	}
+
+	log.Info("Provisioning: updating datasource %s of org %d", ds.Name, ds.OrgId)
+	return bus.Dispatch(&m.UpdateDataSourceCommand{
+		Id:                query.Result.Id,
+		OrgId:             ds.OrgId,
+		Name:              ds.Name,
+		Type:              ds.Type,
+		Access:            ds.Access,
+		Url:               ds.Url,
+		Password:          ds.Password,
+		User:              ds.User,
+		Database:          ds.Database,
+		BasicAuth:         ds.BasicAuth,
+		BasicAuthUser:     ds.BasicAuthUser,
+		BasicAuthPassword: ds.BasicAuthPassword,
+		IsDefault:         ds.IsDefault,
+		JsonData:          ds.JsonData,
+		Provisioned:       true,
+	})
+}
+
+// dataSourceChanged reports whether the stored datasource differs from the
+// file, or was not provisioned before.
+func dataSourceChanged(existing *m.DataSource, ds *DataSourceConfig) bool {
+	return !existing.Provisioned ||
+		existing.Type != ds.Type ||
+		existing.Access != ds.Access ||
+		existing.Url != ds.Url ||
+		existing.Password != ds.Password ||
+		existing.User != ds.User ||
+		existing.Database != ds.Database ||
+		existing.BasicAuth != ds.BasicAuth ||
+		existing.BasicAuthUser != ds.BasicAuthUser ||
+		existing.BasicAuthPassword != ds.BasicAuthPassword ||
+		existing.IsDefault != ds.IsDefault ||
+		!reflect.DeepEqual(existing.JsonData, ds.JsonData)
+}
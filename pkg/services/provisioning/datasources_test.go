@@ -0,0 +1,228 @@
+package provisioning
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeDataSourceStore keeps datasources in memory behind the bus.
+type fakeDataSourceStore struct {
+	dataSources map[int64]*m.DataSource
+	nextId      int64
+	updates     int
+}
+
+func newFakeDataSourceStore() *fakeDataSourceStore {
+	store := &fakeDataSourceStore{dataSources: make(map[int64]*m.DataSource), nextId: 1}
+
+	bus.AddHandler("test", func(query *m.GetDataSourceByNameQuery) error {
+		for _, ds := range store.dataSources {
+			if ds.OrgId == query.OrgId && ds.Name == query.Name && (ds.Deleted == nil || query.IncludeDeleted) {
+				query.Result = *ds
+				return nil
+			}
+		}
+		return m.ErrDataSourceNotFound
+	})
+
+	bus.AddHandler("test", func(cmd *m.AddDataSourceCommand) error {
+		ds := &m.DataSource{
+			Id:          store.nextId,
+			OrgId:       cmd.OrgId,
+			Name:        cmd.Name,
+			Type:        cmd.Type,
+			Access:      cmd.Access,
+			Url:         cmd.Url,
+			IsDefault:   cmd.IsDefault,
+			JsonData:    cmd.JsonData,
+			Provisioned: cmd.Provisioned,
+		}
+		store.dataSources[ds.Id] = ds
+		store.nextId++
+		cmd.Result = ds
+		return nil
+	})
+
+	bus.AddHandler("test", func(cmd *m.UpdateDataSourceCommand) error {
+		ds := store.dataSources[cmd.Id]
+		ds.Type = cmd.Type
+		ds.Access = cmd.Access
+		ds.Url = cmd.Url
+		ds.IsDefault = cmd.IsDefault
+		ds.JsonData = cmd.JsonData
+		ds.Provisioned = ds.Provisioned || cmd.Provisioned
+		store.updates++
+		return nil
+	})
+
+	bus.AddHandler("test", func(cmd *m.DeleteDataSourceCommand) error {
+		deleted := time.Now()
+		store.dataSources[cmd.Id].Deleted = &deleted
+		return nil
+	})
+
+	bus.AddHandler("test", func(cmd *m.RestoreDataSourceCommand) error {
+		store.dataSources[cmd.Id].Deleted = nil
+		return nil
+	})
+
+	bus.AddHandler("test", func(query *m.GetProvisionedDataSourcesQuery) error {
+		query.Result = make([]*m.DataSource, 0)
+		for _, ds := range store.dataSources {
+			if ds.Provisioned && ds.Deleted == nil {
+				query.Result = append(query.Result, ds)
+			}
+		}
+		return nil
+	})
+
+	return store
+}
+
+func (store *fakeDataSourceStore) byName(orgId int64, name string) *m.DataSource {
+	for _, ds := range store.dataSources {
+		if ds.OrgId == orgId && ds.Name == name && ds.Deleted == nil {
+			return ds
+		}
+	}
+	return nil
+}
+
+func writeProvisioningFile(content string) string {
+	f, err := ioutil.TempFile("", "grafana-provisioning")
+	So(err, ShouldBeNil)
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+	So(err, ShouldBeNil)
+	return f.Name()
+}
+
+func TestProvisionDataSources(t *testing.T) {
+
+	Convey("Given an empty datasource store", t, func() {
+		defer bus.ClearBusHandlers()
+		store := newFakeDataSourceStore()
+
+		path := writeProvisioningFile(`{
+			"datasources": [
+				{"name": "graphite", "type": "graphite", "url": "http://graphite:8080", "isDefault": true},
+				{"orgId": 2, "name": "influx", "type": "influxdb", "access": "direct", "url": "http://influx:8086", "jsonData": {"timeInterval": "10s"}}
+			]
+		}`)
+		defer os.Remove(path)
+
+		So(ProvisionDataSources(path, false), ShouldBeNil)
+
+		Convey("Should create the datasources of the file", func() {
+			So(len(store.dataSources), ShouldEqual, 2)
+
+			graphite := store.byName(1, "graphite")
+			So(graphite, ShouldNotBeNil)
+			So(graphite.Access, ShouldEqual, m.DS_ACCESS_PROXY)
+			So(graphite.IsDefault, ShouldBeTrue)
+			So(graphite.Provisioned, ShouldBeTrue)
+
+			influx := store.byName(2, "influx")
+			So(influx, ShouldNotBeNil)
+			So(influx.Access, ShouldEqual, m.DS_ACCESS_DIRECT)
+			So(influx.JsonData["timeInterval"], ShouldEqual, "10s")
+		})
+
+		Convey("Should leave unchanged datasources alone when applied again", func() {
+			So(ProvisionDataSources(path, false), ShouldBeNil)
+
+			So(len(store.dataSources), ShouldEqual, 2)
+			So(store.updates, ShouldEqual, 0)
+		})
+
+		Convey("Should update datasources that changed in the file", func() {
+			err := applyDataSources(&DataSourcesConfig{DataSources: []*DataSourceConfig{
+				{Name: "graphite", Type: "graphite", Url: "http://graphite:9090", IsDefault: true},
+				{OrgId: 2, Name: "influx", Type: "influxdb", Access: m.DS_ACCESS_DIRECT, Url: "http://influx:8086", JsonData: map[string]interface{}{"timeInterval": "10s"}},
+			}}, false)
+			So(err, ShouldBeNil)
+
+			So(store.updates, ShouldEqual, 1)
+			So(store.byName(1, "graphite").Url, ShouldEqual, "http://graphite:9090")
+		})
+
+		Convey("Should take over a datasource created by hand", func() {
+			manual := &m.DataSource{Id: 10, OrgId: 1, Name: "manual", Type: "graphite", Access: m.DS_ACCESS_PROXY}
+			store.dataSources[manual.Id] = manual
+
+			err := applyDataSources(&DataSourcesConfig{DataSources: []*DataSourceConfig{
+				{Name: "manual", Type: "graphite"},
+			}}, false)
+			So(err, ShouldBeNil)
+
+			So(manual.Provisioned, ShouldBeTrue)
+		})
+
+		Convey("When a datasource is removed from the file", func() {
+			manual := &m.DataSource{Id: 10, OrgId: 1, Name: "manual", Type: "graphite"}
+			store.dataSources[manual.Id] = manual
+
+			cfg := &DataSourcesConfig{DataSources: []*DataSourceConfig{
+				{Name: "graphite", Type: "graphite", Url: "http://graphite:8080", IsDefault: true},
+			}}
+
+			Convey("Should keep it without prune", func() {
+				So(applyDataSources(cfg, false), ShouldBeNil)
+				So(store.byName(2, "influx"), ShouldNotBeNil)
+			})
+
+			Convey("Should delete it with prune", func() {
+				So(applyDataSources(cfg, true), ShouldBeNil)
+
+				So(store.byName(2, "influx"), ShouldBeNil)
+				So(store.byName(1, "graphite"), ShouldNotBeNil)
+			})
+
+			Convey("Should restore a pruned datasource once it is back in the file", func() {
+				pruned := store.byName(2, "influx")
+				So(applyDataSources(cfg, true), ShouldBeNil)
+
+				So(ProvisionDataSources(path, true), ShouldBeNil)
+
+				influx := store.byName(2, "influx")
+				So(influx, ShouldNotBeNil)
+				So(influx.Id, ShouldEqual, pruned.Id)
+				So(len(store.dataSources), ShouldEqual, 3)
+			})
+
+			Convey("Should never prune datasources it didn't provision", func() {
+				So(applyDataSources(cfg, true), ShouldBeNil)
+				So(store.byName(1, "manual"), ShouldNotBeNil)
+			})
+		})
+
+		Convey("Should reject datasources without a type", func() {
+			err := applyDataSources(&DataSourcesConfig{DataSources: []*DataSourceConfig{{Name: "typeless"}}}, false)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Should reject a datasource listed twice", func() {
+			err := applyDataSources(&DataSourcesConfig{DataSources: []*DataSourceConfig{
+				{Name: "twice", Type: "graphite"},
+				{Name: "twice", Type: "influxdb"},
+			}}, false)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a file that isn't json", t, func() {
+		path := writeProvisioningFile(`datasources: []`)
+		defer os.Remove(path)
+
+		Convey("Should fail", func() {
+			So(ProvisionDataSources(path, false), ShouldNotBeNil)
+		})
+	})
+}
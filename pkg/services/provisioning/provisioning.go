@@ -0,0 +1,22 @@
+package provisioning
+
+import (
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// Init applies the provisioning files set up in the [provisioning] section.
+func Init() error {
+	if setting.ProvisionDataSourcesPath != "" {
+		if err := ProvisionDataSources(setting.ProvisionDataSourcesPath, setting.ProvisionPruneDataSources); err != nil {
+			return err
+		}
+	}
+
+	if setting.ProvisionDashboardsPath != "" {
+		if err := ProvisionDashboards(setting.ProvisionDashboardsPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
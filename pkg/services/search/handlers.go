@@ -41,8 +41,10 @@ func searchHandler(query *Query) error {
 	dashQuery := FindPersistedDashboardsQuery{
 		Title:     query.Title,
 		UserId:    query.UserId,
+		OrgRole:   query.OrgRole,
 		IsStarred: query.IsStarred,
 		OrgId:     query.OrgId,
+		FolderId:  query.FolderId,
 	}
 
 	if err := bus.Dispatch(&dashQuery); err != nil {
@@ -51,7 +53,8 @@ func searchHandler(query *Query) error {
 
 	hits = append(hits, dashQuery.Result...)
 
-	if jsonDashIndex != nil {
+	// json dashboards live outside of folders
+	if jsonDashIndex != nil && query.FolderId == 0 {
 		jsonHits, err := jsonDashIndex.Search(query)
 		if err != nil {
 			return err
@@ -74,6 +77,15 @@ func searchHandler(query *Query) error {
 	// sort main result array
 	sort.Sort(hits)
 
+	query.TotalCount = int64(len(hits))
+
+	// page through the sorted hits
+	offset := query.Limit * query.Page
+	if offset > len(hits) {
+		offset = len(hits)
+	}
+	hits = hits[offset:]
+
 	if len(hits) > query.Limit {
 		hits = hits[0:query.Limit]
 	}
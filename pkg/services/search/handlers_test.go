@@ -57,5 +57,19 @@ func TestSearch(t *testing.T) {
 			})
 
 		})
+
+		Convey("That asks for a page", func() {
+			query.Tags = []string{"BB", "AA"}
+			query.Limit = 1
+			query.Page = 1
+			err := searchHandler(&query)
+			So(err, ShouldBeNil)
+
+			Convey("should return that page and the total count", func() {
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Title, ShouldEqual, "CCAA")
+				So(query.TotalCount, ShouldEqual, 2)
+			})
+		})
 	})
 }
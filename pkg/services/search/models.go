@@ -1,5 +1,7 @@
 package search
 
+import m "github.com/Cepave/grafana/pkg/models"
+
 type HitType string
 
 const (
@@ -7,6 +9,7 @@ const (
 	DashHitHome     HitType = "dash-home"
 	DashHitJson     HitType = "dash-json"
 	DashHitScripted HitType = "dash-scripted"
+	DashHitFolder   HitType = "dash-folder"
 )
 
 type Hit struct {
@@ -16,6 +19,10 @@ type Hit struct {
 	Type      HitType  `json:"type"`
 	Tags      []string `json:"tags"`
 	IsStarred bool     `json:"isStarred"`
+
+	// the folder holding the dashboard, 0 for the root
+	FolderId    int64  `json:"folderId"`
+	FolderTitle string `json:"folderTitle,omitempty"`
 }
 
 type HitList []*Hit
@@ -29,17 +36,25 @@ type Query struct {
 	Tags      []string
 	OrgId     int64
 	UserId    int64
+	OrgRole   m.RoleType
 	Limit     int
+	Page      int
 	IsStarred bool
+	// only return the direct children of the folder, 0 doesn't filter
+	FolderId int64
 
-	Result HitList
+	Result     HitList
+	TotalCount int64
 }
 
+// FindPersistedDashboardsQuery finds the dashboards the user may view.
 type FindPersistedDashboardsQuery struct {
 	Title     string
 	OrgId     int64
 	UserId    int64
+	OrgRole   m.RoleType
 	IsStarred bool
+	FolderId  int64
 
 	Result HitList
 }
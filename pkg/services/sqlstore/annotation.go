@@ -0,0 +1,143 @@
+package sqlstore
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", CreateAnnotation)
+	bus.AddHandler("sql", DeleteAnnotation)
+	bus.AddHandler("sql", GetAnnotations)
+}
+
+func CreateAnnotation(cmd *m.CreateAnnotationCommand) error {
+	return inTransaction2(func(sess *session) error {
+		if cmd.DashboardId != 0 {
+			if has, err := sess.Where("id=? AND org_id=? AND deleted IS NULL", cmd.DashboardId, cmd.OrgId).Get(&m.Dashboard{}); err != nil {
+				return err
+			} else if !has {
+				return m.ErrDashboardNotFound
+			}
+		}
+
+		annotation := m.Annotation{
+			OrgId:       cmd.OrgId,
+			DashboardId: cmd.DashboardId,
+			UserId:      cmd.UserId,
+			Epoch:       cmd.Time,
+			Text:        cmd.Text,
+			Created:     time.Now(),
+		}
+		if _, err := sess.Insert(&annotation); err != nil {
+			return err
+		}
+
+		seen := make(map[string]bool)
+		for _, tag := range cmd.Tags {
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			if _, err := sess.Insert(&AnnotationTag{AnnotationId: annotation.Id, Term: tag}); err != nil {
+				return err
+			}
+		}
+
+		cmd.Result = &annotation
+		return nil
+	})
+}
+
+func DeleteAnnotation(cmd *m.DeleteAnnotationCommand) error {
+	return inTransaction2(func(sess *session) error {
+		res, err := sess.Exec("DELETE FROM annotation WHERE id=? AND org_id=?", cmd.Id, cmd.OrgId)
+		if err != nil {
+			return err
+		}
+
+		if rows, err := res.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return m.ErrAnnotationNotFound
+		}
+
+		_, err = sess.Exec("DELETE FROM annotation_tag WHERE annotation_id=?", cmd.Id)
+		return err
+	})
+}
+
+func GetAnnotations(query *m.GetAnnotationsQuery) error {
+	var sql bytes.Buffer
+	params := make([]interface{}, 0)
+
+	// annotations of deleted dashboards go away with them
+	sql.WriteString("SELECT * FROM annotation WHERE org_id=?")
+	sql.WriteString(" AND NOT EXISTS (SELECT 1 FROM dashboard WHERE dashboard.id = annotation.dashboard_id AND dashboard.deleted IS NOT NULL)")
+	params = append(params, query.OrgId)
+
+	if query.DashboardId != 0 {
+		sql.WriteString(" AND dashboard_id=?")
+		params = append(params, query.DashboardId)
+	}
+	if query.From > 0 {
+		sql.WriteString(" AND epoch >= ?")
+		params = append(params, query.From)
+	}
+	if query.To > 0 {
+		sql.WriteString(" AND epoch <= ?")
+		params = append(params, query.To)
+	}
+	for _, tag := range query.Tags {
+		sql.WriteString(" AND EXISTS (SELECT 1 FROM annotation_tag WHERE annotation_tag.annotation_id = annotation.id AND annotation_tag.term = ?)")
+		params = append(params, tag)
+	}
+
+	sql.WriteString(" ORDER BY epoch DESC")
+	if query.Limit > 0 {
+		sql.WriteString(fmt.Sprintf(" LIMIT %d", query.Limit))
+	}
+
+	sess := readSession()
+	defer sess.Close()
+
+	var annotations []*m.Annotation
+	if err := sess.Sql(sql.String(), params...).Find(&annotations); err != nil {
+		return err
+	}
+
+	query.Result = make([]*m.AnnotationDTO, 0, len(annotations))
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	ids := make([]interface{}, 0, len(annotations))
+	byId := make(map[int64]*m.AnnotationDTO, len(annotations))
+	for _, item := range annotations {
+		dto := &m.AnnotationDTO{
+			Id:          item.Id,
+			DashboardId: item.DashboardId,
+			UserId:      item.UserId,
+			Time:        item.Epoch,
+			Text:        item.Text,
+			Tags:        []string{},
+		}
+		query.Result = append(query.Result, dto)
+		byId[item.Id] = dto
+		ids = append(ids, item.Id)
+	}
+
+	var tags []*AnnotationTag
+	if err := sess.In("annotation_id", ids...).Asc("term").Find(&tags); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		byId[tag.AnnotationId].Tags = append(byId[tag.AnnotationId].Tags, tag.Term)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestAnnotationDataAccess(t *testing.T) {
+
+	Convey("Testing annotations", t, func() {
+		InitTestDB(t)
+
+		orgCmd := m.CreateOrgCommand{Name: "annotated"}
+		So(CreateOrg(&orgCmd), ShouldBeNil)
+		orgId := orgCmd.Result.Id
+		dash := insertTestDashboard("annotated dash", orgId)
+
+		create := func(dashboardId, epoch int64, tags ...string) *m.Annotation {
+			cmd := m.CreateAnnotationCommand{OrgId: orgId, DashboardId: dashboardId, Time: epoch, Text: "deploy", Tags: tags}
+			So(CreateAnnotation(&cmd), ShouldBeNil)
+			return cmd.Result
+		}
+
+		find := func(query m.GetAnnotationsQuery) []*m.AnnotationDTO {
+			query.OrgId = orgId
+			So(GetAnnotations(&query), ShouldBeNil)
+			return query.Result
+		}
+
+		first := create(dash.Id, 1000, "deploy", "api", "deploy")
+		create(dash.Id, 2000, "deploy")
+		create(0, 3000, "outage")
+
+		Convey("Should list the newest first with their tags", func() {
+			res := find(m.GetAnnotationsQuery{})
+			So(len(res), ShouldEqual, 3)
+			So(res[0].Time, ShouldEqual, 3000)
+			So(res[2].Id, ShouldEqual, first.Id)
+			So(res[2].Tags, ShouldResemble, []string{"api", "deploy"})
+		})
+
+		Convey("Should filter by time range", func() {
+			res := find(m.GetAnnotationsQuery{From: 1500, To: 3000})
+			So(len(res), ShouldEqual, 2)
+
+			res = find(m.GetAnnotationsQuery{To: 1500})
+			So(len(res), ShouldEqual, 1)
+			So(res[0].Id, ShouldEqual, first.Id)
+		})
+
+		Convey("Should filter by all given tags", func() {
+			So(len(find(m.GetAnnotationsQuery{Tags: []string{"deploy"}})), ShouldEqual, 2)
+			So(len(find(m.GetAnnotationsQuery{Tags: []string{"deploy", "api"}})), ShouldEqual, 1)
+			So(len(find(m.GetAnnotationsQuery{Tags: []string{"unknown"}})), ShouldEqual, 0)
+		})
+
+		Convey("Should filter by dashboard and limit", func() {
+			So(len(find(m.GetAnnotationsQuery{DashboardId: dash.Id})), ShouldEqual, 2)
+			So(len(find(m.GetAnnotationsQuery{Limit: 1})), ShouldEqual, 1)
+		})
+
+		Convey("Should reject a dashboard of another org", func() {
+			cmd := m.CreateAnnotationCommand{OrgId: orgId + 1, DashboardId: dash.Id, Time: 1, Text: "x"}
+			So(CreateAnnotation(&cmd), ShouldEqual, m.ErrDashboardNotFound)
+		})
+
+		Convey("Should be deleted", func() {
+			cmd := m.DeleteAnnotationCommand{Id: first.Id, OrgId: orgId}
+			So(DeleteAnnotation(&cmd), ShouldBeNil)
+			So(len(find(m.GetAnnotationsQuery{})), ShouldEqual, 2)
+
+			So(DeleteAnnotation(&cmd), ShouldEqual, m.ErrAnnotationNotFound)
+		})
+
+		Convey("Should be hidden with the dashboard and purged with it", func() {
+			So(DeleteDashboard(&m.DeleteDashboardCommand{Slug: dash.Slug, OrgId: orgId}), ShouldBeNil)
+			So(len(find(m.GetAnnotationsQuery{})), ShouldEqual, 1)
+
+			So(PurgeDeletedDashboards(&m.PurgeDeletedDashboardsCommand{Before: time.Now().Add(time.Minute)}), ShouldBeNil)
+			res, err := x.Query("SELECT 1 FROM annotation")
+			So(err, ShouldBeNil)
+			So(len(res), ShouldEqual, 1)
+		})
+
+		Convey("Should be deleted with the org", func() {
+			So(DeleteOrg(&m.DeleteOrgCommand{Id: orgId}), ShouldBeNil)
+			So(len(find(m.GetAnnotationsQuery{})), ShouldEqual, 0)
+
+			res, err := x.Query("SELECT 1 FROM annotation_tag")
+			So(err, ShouldBeNil)
+			So(len(res), ShouldEqual, 0)
+		})
+	})
+}
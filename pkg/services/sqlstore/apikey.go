@@ -1,6 +1,7 @@
 package sqlstore
 
 import (
+	"strings"
 	"time"
 
 	"github.com/go-xorm/xorm"
@@ -40,6 +41,9 @@ func AddApiKey(cmd *m.AddApiKeyCommand) error {
 			Key:     cmd.Key,
 			Created: time.Now(),
 			Updated: time.Now(),
+
+			ReadOnly:   cmd.ReadOnly,
+			AllowedIps: strings.Join(cmd.AllowedIps, ","),
 		}
 
 		if _, err := sess.Insert(&t); err != nil {
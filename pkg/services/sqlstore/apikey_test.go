@@ -24,6 +24,25 @@ func TestApiKeyDataAccess(t *testing.T) {
 
 				So(err, ShouldBeNil)
 				So(query.Result, ShouldNotBeNil)
+				So(query.Result.ReadOnly, ShouldBeFalse)
+			})
+
+			Convey("Should save a read only key", func() {
+				readOnly := m.AddApiKeyCommand{OrgId: 1, Name: "reader", Key: "qwe", ReadOnly: true}
+				So(AddApiKey(&readOnly), ShouldBeNil)
+
+				query := m.GetApiKeyByIdQuery{ApiKeyId: readOnly.Result.Id}
+				So(GetApiKeyById(&query), ShouldBeNil)
+				So(query.Result.ReadOnly, ShouldBeTrue)
+			})
+
+			Convey("Should save the networks a key is bound to", func() {
+				bound := m.AddApiKeyCommand{OrgId: 1, Name: "bound", Key: "zxc", AllowedIps: []string{"10.0.0.0/8", "192.168.1.7"}}
+				So(AddApiKey(&bound), ShouldBeNil)
+
+				query := m.GetApiKeyByIdQuery{ApiKeyId: bound.Result.Id}
+				So(GetApiKeyById(&query), ShouldBeNil)
+				So(query.Result.AllowedIpList(), ShouldResemble, []string{"10.0.0.0/8", "192.168.1.7"})
 			})
 
 		})
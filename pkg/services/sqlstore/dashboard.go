@@ -3,9 +3,13 @@ package sqlstore
 import (
 	"bytes"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-xorm/xorm"
 	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/events"
+	"github.com/Cepave/grafana/pkg/log"
 	"github.com/Cepave/grafana/pkg/metrics"
 	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/services/search"
@@ -13,94 +17,224 @@ import (
 
 func init() {
 	bus.AddHandler("sql", SaveDashboard)
+	bus.AddHandler("sql", ImportDashboards)
 	bus.AddHandler("sql", GetDashboard)
 	bus.AddHandler("sql", DeleteDashboard)
+	bus.AddHandler("sql", DeleteDashboardsByTag)
+	bus.AddHandler("sql", RestoreDashboard)
+	bus.AddHandler("sql", PurgeDeletedDashboards)
+	bus.AddHandler("sql", GetDeletedDashboards)
+	bus.AddHandler("sql", GetDashboardRefs)
 	bus.AddHandler("sql", SearchDashboards)
 	bus.AddHandler("sql", GetDashboardTags)
 }
 
 func SaveDashboard(cmd *m.SaveDashboardCommand) error {
-	return inTransaction(func(sess *xorm.Session) error {
-		dash := cmd.GetDashboardModel()
+	err := inTransaction(func(sess *xorm.Session) error {
+		return saveDashboard(sess, cmd)
+	})
 
-		// try get existing dashboard
-		var existing, sameTitle m.Dashboard
+	// not inTransaction2, saving mutates the command so it can't be retried
+	if err == nil {
+		publishDashboardSaved(cmd.Result)
+	}
 
-		if dash.Id > 0 {
-			dashWithIdExists, err := sess.Where("id=? AND org_id=?", dash.Id, dash.OrgId).Get(&existing)
-			if err != nil {
-				return err
-			}
-			if !dashWithIdExists {
-				return m.ErrDashboardNotFound
+	return err
+}
+
+// ImportDashboards saves all the dashboards in one transaction, over the
+// ones with the same title. An item failing rolls back the others.
+func ImportDashboards(cmd *m.ImportDashboardsCommand) error {
+	cmd.FailedIndex = -1
+	cmd.Result = make([]*m.Dashboard, len(cmd.Items))
+
+	err := inTransaction(func(sess *xorm.Session) error {
+		for i, item := range cmd.Items {
+			save := m.SaveDashboardCommand{
+				OrgId:     cmd.OrgId,
+				Dashboard: item.Dashboard,
+				Overwrite: true,
+				IsFolder:  item.IsFolder,
 			}
 
-			// check for is someone else has written in between
-			if dash.Version != existing.Version {
-				if cmd.Overwrite {
-					dash.Version = existing.Version
-				} else {
-					return m.ErrDashboardVersionMismatch
+			var parentId int64
+			if item.FolderIndex >= 0 {
+				// folders have to be saved before their content
+				if item.FolderIndex >= i {
+					cmd.FailedIndex = i
+					return m.ErrDashboardFolderNotFound
 				}
+				parentId = cmd.Result[item.FolderIndex].Id
+			}
+			save.ParentId = &parentId
+
+			if err := saveDashboard(sess, &save); err != nil {
+				cmd.FailedIndex = i
+				return err
 			}
+			cmd.Result[i] = save.Result
 		}
+		return nil
+	})
 
-		sameTitleExists, err := sess.Where("org_id=? AND slug=?", dash.OrgId, dash.Slug).Get(&sameTitle)
+	if err == nil {
+		for _, dash := range cmd.Result {
+			publishDashboardSaved(dash)
+		}
+	}
+
+	return err
+}
+
+func publishDashboardSaved(dash *m.Dashboard) {
+	if err := bus.Publish(&events.DashboardSaved{
+		Timestamp: time.Now(),
+		Id:        dash.Id,
+		OrgId:     dash.OrgId,
+		Slug:      dash.Slug,
+		Title:     dash.Title,
+		Version:   dash.Version,
+	}); err != nil {
+		log.Error(3, "Failed to publish dashboard saved event: %v", err)
+	}
+}
+
+func saveDashboard(sess *xorm.Session, cmd *m.SaveDashboardCommand) error {
+	dash := cmd.GetDashboardModel()
+
+	// try get existing dashboard
+	var existing, sameTitle m.Dashboard
+	var current *m.Dashboard
+
+	if dash.Id > 0 {
+		dashWithIdExists, err := sess.Where("id=? AND org_id=? AND deleted IS NULL", dash.Id, dash.OrgId).Get(&existing)
 		if err != nil {
 			return err
 		}
-
-		if sameTitleExists {
-			// another dashboard with same name
-			if dash.Id != sameTitle.Id {
-				if cmd.Overwrite {
-					dash.Id = sameTitle.Id
-				} else {
-					return m.ErrDashboardWithSameNameExists
-				}
+		if !dashWithIdExists {
+			return m.ErrDashboardNotFound
+		}
+		if existing.Provisioned && !cmd.Provisioned {
+			return m.ErrDashboardProvisioned
+		}
+		current = &existing
+
+		// check for is someone else has written in between
+		if dash.Version != existing.Version {
+			if cmd.Overwrite {
+				dash.Version = existing.Version
+			} else {
+				return m.ErrDashboardVersionMismatch
 			}
 		}
+	}
 
-		affectedRows := int64(0)
+	sameTitleExists, err := sess.Where("org_id=? AND slug=?", dash.OrgId, dash.Slug).Get(&sameTitle)
+	if err != nil {
+		return err
+	}
 
-		if dash.Id == 0 {
-			metrics.M_Models_Dashboard_Insert.Inc(1)
-			affectedRows, err = sess.Insert(dash)
-		} else {
-			dash.Version += 1
-			dash.Data["version"] = dash.Version
-			affectedRows, err = sess.Id(dash.Id).Update(dash)
+	// a deleted dashboard gives up its slug but can still be restored
+	if sameTitleExists && sameTitle.Deleted != nil {
+		if _, err := sess.Exec("UPDATE dashboard SET slug=? WHERE id=?", deletedDashboardSlug(&sameTitle), sameTitle.Id); err != nil {
+			return err
 		}
+		sameTitleExists = false
+	}
 
-		if affectedRows == 0 {
-			return m.ErrDashboardNotFound
+	if sameTitleExists {
+		if sameTitle.Provisioned && !cmd.Provisioned {
+			return m.ErrDashboardProvisioned
 		}
 
-		// delete existing tabs
-		_, err = sess.Exec("DELETE FROM dashboard_tag WHERE dashboard_id=?", dash.Id)
-		if err != nil {
-			return err
+		// another dashboard with same name
+		if dash.Id != sameTitle.Id {
+			if cmd.Overwrite {
+				dash.Id = sameTitle.Id
+				current = &sameTitle
+			} else {
+				return m.ErrDashboardWithSameNameExists
+			}
 		}
+	}
 
-		// insert new tags
-		tags := dash.GetTags()
-		if len(tags) > 0 {
-			for _, tag := range tags {
-				if _, err := sess.Insert(&DashboardTag{DashboardId: dash.Id, Term: tag}); err != nil {
-					return err
-				}
+	if err := setDashboardFolder(sess, cmd, dash, current); err != nil {
+		return err
+	}
+
+	affectedRows := int64(0)
+
+	if dash.Id == 0 {
+		metrics.M_Models_Dashboard_Insert.Inc(1)
+		affectedRows, err = sess.Insert(dash)
+	} else {
+		dash.Version += 1
+		dash.Data["version"] = dash.Version
+		affectedRows, err = sess.Id(dash.Id).MustCols("parent_id").Update(dash)
+	}
+
+	if affectedRows == 0 {
+		return m.ErrDashboardNotFound
+	}
+
+	// delete existing tabs
+	_, err = sess.Exec("DELETE FROM dashboard_tag WHERE dashboard_id=?", dash.Id)
+	if err != nil {
+		return err
+	}
+
+	// insert new tags
+	tags := dash.GetTags()
+	if len(tags) > 0 {
+		for _, tag := range tags {
+			if _, err := sess.Insert(&DashboardTag{DashboardId: dash.Id, Term: tag}); err != nil {
+				return err
 			}
 		}
+	}
 
-		cmd.Result = dash
+	cmd.Result = dash
 
-		return err
-	})
+	return err
+}
+
+// setDashboardFolder places the dashboard, current is the row it replaces.
+// Folders stay folders and dashboards stay in their folder unless the
+// command moves them.
+func setDashboardFolder(sess *xorm.Session, cmd *m.SaveDashboardCommand, dash *m.Dashboard, current *m.Dashboard) error {
+	dash.IsFolder = cmd.IsFolder
+	if current != nil {
+		dash.IsFolder = current.IsFolder
+		dash.ParentId = current.ParentId
+	}
+	if cmd.ParentId != nil {
+		dash.ParentId = *cmd.ParentId
+	}
+
+	// walk up from the new parent, it has to be a folder and can't be the
+	// dashboard itself
+	for parentId := dash.ParentId; parentId != 0; {
+		if parentId == dash.Id {
+			return m.ErrDashboardFolderCycle
+		}
+
+		var parent m.Dashboard
+		has, err := sess.Where("id=? AND org_id=? AND deleted IS NULL", parentId, dash.OrgId).Get(&parent)
+		if err != nil {
+			return err
+		}
+		if !has || !parent.IsFolder {
+			return m.ErrDashboardFolderNotFound
+		}
+		parentId = parent.ParentId
+	}
+
+	return nil
 }
 
 func GetDashboard(query *m.GetDashboardQuery) error {
-	dashboard := m.Dashboard{Slug: query.Slug, OrgId: query.OrgId}
-	has, err := x.Get(&dashboard)
+	dashboard := m.Dashboard{Id: query.Id, Slug: query.Slug, OrgId: query.OrgId}
+	has, err := x.Where("deleted IS NULL").Get(&dashboard)
 	if err != nil {
 		return err
 	} else if has == false {
@@ -114,10 +248,13 @@ func GetDashboard(query *m.GetDashboardQuery) error {
 }
 
 type DashboardSearchProjection struct {
-	Id    int64
-	Title string
-	Slug  string
-	Term  string
+	Id          int64
+	Title       string
+	Slug        string
+	IsFolder    bool
+	ParentId    int64
+	FolderTitle string
+	Term        string
 }
 
 func SearchDashboards(query *search.FindPersistedDashboardsQuery) error {
@@ -128,15 +265,19 @@ func SearchDashboards(query *search.FindPersistedDashboardsQuery) error {
 					  dashboard.id,
 					  dashboard.title,
 					  dashboard.slug,
+					  dashboard.is_folder,
+					  dashboard.parent_id,
+					  folder.title AS folder_title,
 					  dashboard_tag.term
 					FROM dashboard
+					LEFT OUTER JOIN dashboard AS folder on folder.id = dashboard.parent_id
 					LEFT OUTER JOIN dashboard_tag on dashboard_tag.dashboard_id = dashboard.id`)
 
 	if query.IsStarred {
 		sql.WriteString(" INNER JOIN star on star.dashboard_id = dashboard.id")
 	}
 
-	sql.WriteString(` WHERE dashboard.org_id=?`)
+	sql.WriteString(` WHERE dashboard.org_id=? AND dashboard.deleted IS NULL`)
 
 	params = append(params, query.OrgId)
 
@@ -145,15 +286,32 @@ func SearchDashboards(query *search.FindPersistedDashboardsQuery) error {
 		params = append(params, query.UserId)
 	}
 
+	if query.FolderId > 0 {
+		sql.WriteString(` AND dashboard.parent_id=?`)
+		params = append(params, query.FolderId)
+	}
+
 	if len(query.Title) > 0 {
-		sql.WriteString(" AND dashboard.title " + dialect.LikeStr() + " ?")
+		sql.WriteString(" AND " + dialect.ILike("dashboard.title"))
 		params = append(params, "%"+query.Title+"%")
 	}
 
 	sql.WriteString(fmt.Sprintf(" ORDER BY dashboard.title ASC LIMIT 1000"))
 
+	sess := readSession()
+	defer sess.Close()
+
 	var res []DashboardSearchProjection
-	err := x.Sql(sql.String(), params...).Find(&res)
+	err := sess.Sql(sql.String(), params...).Find(&res)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(res))
+	for _, item := range res {
+		ids = append(ids, item.Id)
+	}
+	permissions, err := getDashboardPermissions(&session{Session: sess}, query.OrgId, query.UserId, query.OrgRole, ids)
 	if err != nil {
 		return err
 	}
@@ -162,14 +320,23 @@ func SearchDashboards(query *search.FindPersistedDashboardsQuery) error {
 	hits := make(map[int64]*search.Hit)
 
 	for _, item := range res {
+		if permissions[item.Id] < m.PERMISSION_VIEW {
+			continue
+		}
+
 		hit, exists := hits[item.Id]
 		if !exists {
 			hit = &search.Hit{
-				Id:    item.Id,
-				Title: item.Title,
-				Uri:   "db/" + item.Slug,
-				Type:  search.DashHitDB,
-				Tags:  []string{},
+				Id:          item.Id,
+				Title:       item.Title,
+				Uri:         "db/" + item.Slug,
+				Type:        search.DashHitDB,
+				Tags:        []string{},
+				FolderId:    item.ParentId,
+				FolderTitle: item.FolderTitle,
+			}
+			if item.IsFolder {
+				hit.Type = search.DashHitFolder
 			}
 			query.Result = append(query.Result, hit)
 			hits[item.Id] = hit
@@ -188,7 +355,7 @@ func GetDashboardTags(query *m.GetDashboardTagsQuery) error {
 						term
 					FROM dashboard
 					INNER JOIN dashboard_tag on dashboard_tag.dashboard_id = dashboard.id
-					WHERE dashboard.org_id=?
+					WHERE dashboard.org_id=? AND dashboard.deleted IS NULL
 					GROUP BY term`
 
 	query.Result = make([]*m.DashboardTagCloudItem, 0)
@@ -200,26 +367,286 @@ func GetDashboardTags(query *m.GetDashboardTagsQuery) error {
 func DeleteDashboard(cmd *m.DeleteDashboardCommand) error {
 	return inTransaction2(func(sess *session) error {
 		dashboard := m.Dashboard{Slug: cmd.Slug, OrgId: cmd.OrgId}
-		has, err := x.Get(&dashboard)
+		has, err := sess.Where("deleted IS NULL").Get(&dashboard)
 		if err != nil {
 			return err
 		} else if has == false {
 			return m.ErrDashboardNotFound
 		}
 
-		deletes := []string{
-			"DELETE FROM dashboard_tag WHERE dashboard_id = ? ",
-			"DELETE FROM star WHERE dashboard_id = ? ",
-			"DELETE FROM dashboard WHERE id = ?",
+		ids := []int64{dashboard.Id}
+		if dashboard.IsFolder {
+			children, err := getFolderDescendants(sess, dashboard.Id, false)
+			if err != nil {
+				return err
+			}
+			if len(children) > 0 && !cmd.Cascade {
+				return m.ErrDashboardFolderNotEmpty
+			}
+			if err := validateDashboardsDeletable(sess, cmd.OrgId, cmd.UserId, cmd.OrgRole, children); err != nil {
+				return err
+			}
+			ids = append(ids, children...)
+		}
+
+		return markDashboardsDeleted(sess, ids)
+	})
+}
+
+func markDashboardsDeleted(sess *session, ids []int64) error {
+	now := time.Now()
+	for _, id := range ids {
+		if _, err := sess.Exec("UPDATE dashboard SET deleted=? WHERE id=?", now, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreDashboard brings back a deleted dashboard. A folder comes back with
+// the dashboards deleted along with it, a dashboard whose folder is gone
+// moves to the root.
+func RestoreDashboard(cmd *m.RestoreDashboardCommand) error {
+	return inTransaction2(func(sess *session) error {
+		var dashboard m.Dashboard
+		has, err := sess.Where("id=? AND org_id=? AND deleted IS NOT NULL", cmd.Id, cmd.OrgId).Get(&dashboard)
+		if err != nil {
+			return err
+		} else if !has {
+			return m.ErrDashboardNotFound
+		}
+
+		if dashboard.ParentId != 0 {
+			if has, err := sess.Where("id=? AND deleted IS NULL", dashboard.ParentId).Get(&m.Dashboard{}); err != nil {
+				return err
+			} else if !has {
+				if _, err := sess.Exec("UPDATE dashboard SET parent_id=0 WHERE id=?", dashboard.Id); err != nil {
+					return err
+				}
+			}
 		}
 
-		for _, sql := range deletes {
-			_, err := sess.Exec(sql, dashboard.Id)
+		ids := []int64{dashboard.Id}
+		if dashboard.IsFolder {
+			children, err := getFolderDescendants(sess, dashboard.Id, true)
+			if err != nil {
+				return err
+			}
+
+			permissions, err := getDashboardPermissions(sess, cmd.OrgId, cmd.UserId, cmd.OrgRole, children)
 			if err != nil {
 				return err
 			}
+			for _, id := range children {
+				if permissions[id] < m.PERMISSION_EDIT {
+					return m.ErrDashboardPermissionDenied
+				}
+			}
+			ids = append(ids, children...)
+		}
+
+		for _, id := range ids {
+			if _, err := sess.Exec("UPDATE dashboard SET deleted=NULL WHERE id=?", id); err != nil {
+				return err
+			}
+			if err := restoreDashboardSlug(sess, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deletedDashboardSlug is the slug a deleted dashboard moves to when a new
+// dashboard takes its title.
+func deletedDashboardSlug(dash *m.Dashboard) string {
+	return fmt.Sprintf("%s-deleted-%d", dash.Slug, dash.Id)
+}
+
+// restoreDashboardSlug gives a restored dashboard its slug back, unless
+// another dashboard took it in the meantime.
+func restoreDashboardSlug(sess *session, id int64) error {
+	var dash m.Dashboard
+	if has, err := sess.Id(id).Cols("id", "org_id", "slug").Get(&dash); err != nil || !has {
+		return err
+	}
+
+	original := strings.TrimSuffix(dash.Slug, fmt.Sprintf("-deleted-%d", dash.Id))
+	if original == dash.Slug {
+		return nil
+	}
+
+	if taken, err := sess.Where("org_id=? AND slug=?", dash.OrgId, original).Count(&m.Dashboard{}); err != nil || taken > 0 {
+		return err
+	}
+	_, err := sess.Exec("UPDATE dashboard SET slug=? WHERE id=?", original, dash.Id)
+	return err
+}
+
+func PurgeDeletedDashboards(cmd *m.PurgeDeletedDashboardsCommand) error {
+	return inTransaction2(func(sess *session) error {
+		cmd.Result = 0
+
+		var dashboards []*m.Dashboard
+		if err := sess.Where("deleted IS NOT NULL AND deleted < ?", cmd.Before).Cols("id").Find(&dashboards); err != nil {
+			return err
+		}
+
+		for _, dash := range dashboards {
+			if err := deleteDashboardRows(sess.Session, dash.Id); err != nil {
+				return err
+			}
 		}
 
+		cmd.Result = int64(len(dashboards))
 		return nil
 	})
 }
+
+func GetDashboardRefs(query *m.GetDashboardRefsQuery) error {
+	query.Result = make([]*m.DashboardRef, 0)
+	return x.Table("dashboard").
+		Where("org_id=? AND deleted IS NULL", query.OrgId).
+		Cols("id", "slug", "title", "is_folder", "parent_id").
+		Asc("slug").
+		Find(&query.Result)
+}
+
+func GetDeletedDashboards(query *m.GetDeletedDashboardsQuery) error {
+	var dashboards []*m.Dashboard
+	err := x.Where("org_id=? AND deleted IS NOT NULL", query.OrgId).Cols("id", "title", "slug", "deleted").Desc("deleted").Find(&dashboards)
+	if err != nil {
+		return err
+	}
+
+	query.Result = make([]*m.DeletedDashboardDTO, 0, len(dashboards))
+	for _, dash := range dashboards {
+		query.Result = append(query.Result, &m.DeletedDashboardDTO{
+			Id:      dash.Id,
+			Title:   dash.Title,
+			Slug:    dash.Slug,
+			Deleted: *dash.Deleted,
+		})
+	}
+	return nil
+}
+
+// deleteDashboardRows deletes the dashboard with everything referencing it.
+func deleteDashboardRows(sess *xorm.Session, id int64) error {
+	deletes := []string{
+		"DELETE FROM dashboard_tag WHERE dashboard_id = ? ",
+		"DELETE FROM star WHERE dashboard_id = ? ",
+		"DELETE FROM dashboard_acl WHERE dashboard_id = ? ",
+		"DELETE FROM annotation_tag WHERE EXISTS (SELECT 1 FROM annotation WHERE dashboard_id = ? AND annotation_tag.annotation_id = annotation.id)",
+		"DELETE FROM annotation WHERE dashboard_id = ? ",
+		"DELETE FROM dashboard WHERE id = ?",
+	}
+
+	for _, sql := range deletes {
+		if _, err := sess.Exec(sql, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteDashboardsByTag deletes every dashboard of the org carrying the tag.
+// Nothing is deleted when one of them is provisioned, a folder that isn't
+// empty, or can't be edited by the user.
+func DeleteDashboardsByTag(cmd *m.DeleteDashboardsByTagCommand) error {
+	return inTransaction2(func(sess *session) error {
+		cmd.Result = 0
+
+		var dashboards []*m.Dashboard
+		err := sess.Table("dashboard").
+			Join("INNER", "dashboard_tag", "dashboard_tag.dashboard_id = dashboard.id").
+			Where("dashboard.org_id=? AND dashboard_tag.term=? AND dashboard.deleted IS NULL", cmd.OrgId, cmd.Tag).
+			Cols("dashboard.id", "dashboard.provisioned", "dashboard.is_folder").
+			Find(&dashboards)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, len(dashboards))
+		for _, dash := range dashboards {
+			ids = append(ids, dash.Id)
+		}
+		if err := validateDashboardsDeletable(sess, cmd.OrgId, cmd.UserId, cmd.OrgRole, ids); err != nil {
+			return err
+		}
+
+		for _, dash := range dashboards {
+			if dash.IsFolder {
+				if children, err := sess.Where("parent_id=? AND deleted IS NULL", dash.Id).Count(&m.Dashboard{}); err != nil {
+					return err
+				} else if children > 0 {
+					return m.ErrDashboardFolderNotEmpty
+				}
+			}
+		}
+
+		if err := markDashboardsDeleted(sess, ids); err != nil {
+			return err
+		}
+
+		cmd.Result = int64(len(dashboards))
+		return nil
+	})
+}
+
+// validateDashboardsDeletable fails when one of the dashboards is
+// provisioned or the user can't edit it.
+func validateDashboardsDeletable(sess *session, orgId, userId int64, role m.RoleType, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	if provisioned, err := sess.Where("provisioned=?", true).In("id", args...).Count(&m.Dashboard{}); err != nil {
+		return err
+	} else if provisioned > 0 {
+		return m.ErrDashboardProvisioned
+	}
+
+	permissions, err := getDashboardPermissions(sess, orgId, userId, role, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if permissions[id] < m.PERMISSION_EDIT {
+			return m.ErrDashboardPermissionDenied
+		}
+	}
+	return nil
+}
+
+// getFolderDescendants returns the ids of everything below the folder, the
+// remaining dashboards or the ones deleted together with the deleted folder.
+func getFolderDescendants(sess *session, folderId int64, deleted bool) ([]int64, error) {
+	ids := make([]int64, 0)
+	parents := []int64{folderId}
+
+	cond, args := "deleted IS NULL", []interface{}{}
+	if deleted {
+		// compared to the stored value, a time read back can lose precision
+		cond, args = "deleted = (SELECT folder.deleted FROM dashboard folder WHERE folder.id = ?)", []interface{}{folderId}
+	}
+
+	for len(parents) > 0 {
+		var children []*m.Dashboard
+		if err := sess.Where(cond, args...).In("parent_id", parents).Cols("id").Find(&children); err != nil {
+			return nil, err
+		}
+
+		parents = make([]int64, 0, len(children))
+		for _, child := range children {
+			ids = append(ids, child.Id)
+			parents = append(parents, child.Id)
+		}
+	}
+
+	return ids, nil
+}
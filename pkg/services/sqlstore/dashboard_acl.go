@@ -0,0 +1,148 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", GetDashboardAcl)
+	bus.AddHandler("sql", GetDashboardPermissions)
+	bus.AddHandler("sql", SetDashboardAcl)
+	bus.AddHandler("sql", RemoveDashboardAcl)
+}
+
+func GetDashboardAcl(query *m.GetDashboardAclQuery) error {
+	query.Result = make([]*m.DashboardAcl, 0)
+	return x.Where("org_id=? AND dashboard_id=?", query.OrgId, query.DashboardId).Asc("id").Find(&query.Result)
+}
+
+func GetDashboardPermissions(query *m.GetDashboardPermissionsQuery) error {
+	sess := &session{Session: readSession()}
+	defer sess.Close()
+
+	var err error
+	query.Result, err = getDashboardPermissions(sess, query.OrgId, query.UserId, query.OrgRole, query.DashboardIds)
+	return err
+}
+
+// getDashboardPermissions reads the acls of all the dashboards in one query
+// rather than one per dashboard.
+func getDashboardPermissions(sess *session, orgId, userId int64, role m.RoleType, dashboardIds []int64) (map[int64]m.PermissionType, error) {
+	result := make(map[int64]m.PermissionType, len(dashboardIds))
+	if len(dashboardIds) == 0 {
+		return result, nil
+	}
+
+	ids := make([]interface{}, 0, len(dashboardIds))
+	for _, id := range dashboardIds {
+		ids = append(ids, id)
+	}
+
+	var items []*m.DashboardAcl
+	if err := sess.Where("org_id=?", orgId).In("dashboard_id", ids...).Find(&items); err != nil {
+		return nil, err
+	}
+
+	acls := make(map[int64][]*m.DashboardAcl)
+	for _, item := range items {
+		acls[item.DashboardId] = append(acls[item.DashboardId], item)
+	}
+
+	var teamIds []int64
+	if m.HasTeamItems(items) {
+		var err error
+		if teamIds, err = getUserTeamIds(sess, orgId, userId); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range dashboardIds {
+		result[id] = m.GetDashboardPermission(acls[id], userId, teamIds, role)
+	}
+	return result, nil
+}
+
+func SetDashboardAcl(cmd *m.SetDashboardAclCommand) error {
+	targets := 0
+	for _, set := range []bool{cmd.UserId != 0, cmd.TeamId != 0, cmd.Role != ""} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 || !cmd.Permission.IsValid() {
+		return m.ErrDashboardAclInvalid
+	}
+	if cmd.Role != "" && !cmd.Role.IsValid() {
+		return m.ErrInvalidRoleType
+	}
+
+	return inTransaction2(func(sess *session) error {
+		if has, err := sess.Where("id=? AND org_id=? AND deleted IS NULL", cmd.DashboardId, cmd.OrgId).Get(&m.Dashboard{}); err != nil {
+			return err
+		} else if !has {
+			return m.ErrDashboardNotFound
+		}
+
+		if cmd.UserId != 0 {
+			if has, err := sess.Where("org_id=? AND user_id=?", cmd.OrgId, cmd.UserId).Get(&m.OrgUser{}); err != nil {
+				return err
+			} else if !has {
+				return m.ErrOrgUserNotFound
+			}
+		}
+
+		if cmd.TeamId != 0 {
+			if has, err := sess.Where("id=? AND org_id=?", cmd.TeamId, cmd.OrgId).Get(&m.Team{}); err != nil {
+				return err
+			} else if !has {
+				return m.ErrTeamNotFound
+			}
+		}
+
+		var item m.DashboardAcl
+		has, err := sess.Where("dashboard_id=? AND user_id=? AND team_id=? AND role=?", cmd.DashboardId, cmd.UserId, cmd.TeamId, cmd.Role).Get(&item)
+		if err != nil {
+			return err
+		}
+
+		item.Permission = cmd.Permission
+		item.Updated = time.Now()
+
+		if has {
+			_, err = sess.Id(item.Id).Cols("permission", "updated").Update(&item)
+		} else {
+			item.OrgId = cmd.OrgId
+			item.DashboardId = cmd.DashboardId
+			item.UserId = cmd.UserId
+			item.TeamId = cmd.TeamId
+			item.Role = cmd.Role
+			item.Created = item.Updated
+			_, err = sess.Insert(&item)
+		}
+		if err != nil {
+			return err
+		}
+
+		cmd.Result = &item
+		return nil
+	})
+}
+
+func RemoveDashboardAcl(cmd *m.RemoveDashboardAclCommand) error {
+	return inTransaction2(func(sess *session) error {
+		res, err := sess.Exec("DELETE FROM dashboard_acl WHERE id=? AND dashboard_id=? AND org_id=?", cmd.AclId, cmd.DashboardId, cmd.OrgId)
+		if err != nil {
+			return err
+		}
+
+		if rows, err := res.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return m.ErrDashboardAclNotFound
+		}
+		return nil
+	})
+}
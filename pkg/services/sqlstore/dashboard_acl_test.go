@@ -0,0 +1,96 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestDashboardAclDataAccess(t *testing.T) {
+
+	Convey("Testing dashboard acl", t, func() {
+		InitTestDB(t)
+
+		userCmd := m.CreateUserCommand{Login: "viewer", Email: "viewer@test.com"}
+		So(CreateUser(&userCmd), ShouldBeNil)
+		user := userCmd.Result
+
+		dash := insertTestDashboard("acl dash", user.OrgId)
+
+		getAcl := func() []*m.DashboardAcl {
+			query := m.GetDashboardAclQuery{OrgId: user.OrgId, DashboardId: dash.Id}
+			So(GetDashboardAcl(&query), ShouldBeNil)
+			return query.Result
+		}
+
+		Convey("Should be empty by default", func() {
+			So(len(getAcl()), ShouldEqual, 0)
+		})
+
+		Convey("When a user is granted edit", func() {
+			cmd := m.SetDashboardAclCommand{OrgId: user.OrgId, DashboardId: dash.Id, UserId: user.Id, Permission: m.PERMISSION_EDIT}
+			So(SetDashboardAcl(&cmd), ShouldBeNil)
+
+			Convey("Should be listed", func() {
+				acl := getAcl()
+				So(len(acl), ShouldEqual, 1)
+				So(acl[0].UserId, ShouldEqual, user.Id)
+				So(acl[0].Permission, ShouldEqual, m.PERMISSION_EDIT)
+			})
+
+			Convey("Should update the item when set again", func() {
+				again := m.SetDashboardAclCommand{OrgId: user.OrgId, DashboardId: dash.Id, UserId: user.Id, Permission: m.PERMISSION_VIEW}
+				So(SetDashboardAcl(&again), ShouldBeNil)
+
+				acl := getAcl()
+				So(len(acl), ShouldEqual, 1)
+				So(acl[0].Permission, ShouldEqual, m.PERMISSION_VIEW)
+			})
+
+			Convey("Should keep role items apart", func() {
+				role := m.SetDashboardAclCommand{OrgId: user.OrgId, DashboardId: dash.Id, Role: m.ROLE_VIEWER, Permission: m.PERMISSION_VIEW}
+				So(SetDashboardAcl(&role), ShouldBeNil)
+				So(len(getAcl()), ShouldEqual, 2)
+			})
+
+			Convey("Should be removable", func() {
+				remove := m.RemoveDashboardAclCommand{OrgId: user.OrgId, DashboardId: dash.Id, AclId: cmd.Result.Id}
+				So(RemoveDashboardAcl(&remove), ShouldBeNil)
+				So(len(getAcl()), ShouldEqual, 0)
+
+				So(RemoveDashboardAcl(&remove), ShouldEqual, m.ErrDashboardAclNotFound)
+			})
+
+			Convey("Should be removed when the dashboard is purged", func() {
+				So(DeleteDashboard(&m.DeleteDashboardCommand{Slug: dash.Slug, OrgId: user.OrgId}), ShouldBeNil)
+				So(len(getAcl()), ShouldEqual, 1)
+
+				So(PurgeDeletedDashboards(&m.PurgeDeletedDashboardsCommand{Before: time.Now().Add(time.Minute)}), ShouldBeNil)
+				So(len(getAcl()), ShouldEqual, 0)
+			})
+		})
+
+		Convey("Should reject an item with both a user and a role", func() {
+			cmd := m.SetDashboardAclCommand{OrgId: user.OrgId, DashboardId: dash.Id, UserId: user.Id, Role: m.ROLE_VIEWER, Permission: m.PERMISSION_VIEW}
+			So(SetDashboardAcl(&cmd), ShouldEqual, m.ErrDashboardAclInvalid)
+		})
+
+		Convey("Should reject an unknown permission", func() {
+			cmd := m.SetDashboardAclCommand{OrgId: user.OrgId, DashboardId: dash.Id, UserId: user.Id, Permission: 7}
+			So(SetDashboardAcl(&cmd), ShouldEqual, m.ErrDashboardAclInvalid)
+		})
+
+		Convey("Should reject users outside the org", func() {
+			cmd := m.SetDashboardAclCommand{OrgId: user.OrgId, DashboardId: dash.Id, UserId: 9999, Permission: m.PERMISSION_VIEW}
+			So(SetDashboardAcl(&cmd), ShouldEqual, m.ErrOrgUserNotFound)
+		})
+
+		Convey("Should reject dashboards of another org", func() {
+			cmd := m.SetDashboardAclCommand{OrgId: user.OrgId + 1, DashboardId: dash.Id, Role: m.ROLE_VIEWER, Permission: m.PERMISSION_VIEW}
+			So(SetDashboardAcl(&cmd), ShouldEqual, m.ErrDashboardNotFound)
+		})
+	})
+}
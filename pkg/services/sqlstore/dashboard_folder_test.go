@@ -0,0 +1,211 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/search"
+)
+
+func saveTestDashboard(cmd m.SaveDashboardCommand) (*m.Dashboard, error) {
+	cmd.OrgId = 1
+	err := SaveDashboard(&cmd)
+	return cmd.Result, err
+}
+
+func TestDashboardFolders(t *testing.T) {
+
+	Convey("Testing dashboard folders", t, func() {
+		InitTestDB(t)
+
+		folder, err := saveTestDashboard(m.SaveDashboardCommand{IsFolder: true, Dashboard: map[string]interface{}{"title": "ops"}})
+		So(err, ShouldBeNil)
+		dash := insertTestDashboard("disk usage", 1)
+
+		moveTo := func(dash *m.Dashboard, parentId int64) error {
+			_, err := saveTestDashboard(m.SaveDashboardCommand{
+				ParentId:  &parentId,
+				Overwrite: true,
+				Dashboard: map[string]interface{}{"id": float64(dash.Id), "title": dash.Title},
+			})
+			return err
+		}
+
+		getDashboard := func(id int64) *m.Dashboard {
+			query := m.GetDashboardQuery{Id: id, OrgId: 1}
+			So(GetDashboard(&query), ShouldBeNil)
+			return query.Result
+		}
+
+		Convey("Should create the folder", func() {
+			So(folder.IsFolder, ShouldBeTrue)
+			So(getDashboard(folder.Id).IsFolder, ShouldBeTrue)
+		})
+
+		Convey("When a dashboard is moved into the folder", func() {
+			So(moveTo(dash, folder.Id), ShouldBeNil)
+
+			Convey("Should be in the folder", func() {
+				So(getDashboard(dash.Id).ParentId, ShouldEqual, folder.Id)
+			})
+
+			Convey("Should stay in the folder when saved without a parent", func() {
+				_, err := saveTestDashboard(m.SaveDashboardCommand{
+					Overwrite: true,
+					Dashboard: map[string]interface{}{"id": float64(dash.Id), "title": dash.Title, "editable": false},
+				})
+				So(err, ShouldBeNil)
+				So(getDashboard(dash.Id).ParentId, ShouldEqual, folder.Id)
+			})
+
+			Convey("Should move back to the root", func() {
+				So(moveTo(dash, 0), ShouldBeNil)
+				So(getDashboard(dash.Id).ParentId, ShouldEqual, 0)
+			})
+
+			Convey("Should be searchable with its folder", func() {
+				query := search.FindPersistedDashboardsQuery{OrgId: 1, OrgRole: m.ROLE_VIEWER}
+				So(SearchDashboards(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 2)
+
+				So(query.Result[0].Title, ShouldEqual, "disk usage")
+				So(query.Result[0].FolderId, ShouldEqual, folder.Id)
+				So(query.Result[0].FolderTitle, ShouldEqual, "ops")
+				So(query.Result[1].Type, ShouldEqual, search.DashHitFolder)
+				So(query.Result[1].FolderId, ShouldEqual, 0)
+			})
+
+			Convey("Should list the folder content", func() {
+				query := search.FindPersistedDashboardsQuery{OrgId: 1, OrgRole: m.ROLE_VIEWER, FolderId: folder.Id}
+				So(SearchDashboards(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Id, ShouldEqual, dash.Id)
+			})
+
+			Convey("Should not delete the folder while it holds dashboards", func() {
+				err := DeleteDashboard(&m.DeleteDashboardCommand{Slug: folder.Slug, OrgId: 1})
+				So(err, ShouldEqual, m.ErrDashboardFolderNotEmpty)
+				So(getDashboard(dash.Id), ShouldNotBeNil)
+			})
+
+			Convey("Should delete the folder and its content with cascade", func() {
+				sub, err := saveTestDashboard(m.SaveDashboardCommand{IsFolder: true, Dashboard: map[string]interface{}{"title": "ops db"}})
+				So(err, ShouldBeNil)
+				So(moveTo(sub, folder.Id), ShouldBeNil)
+				nested := insertTestDashboard("replication", 1)
+				So(moveTo(nested, sub.Id), ShouldBeNil)
+
+				So(DeleteDashboard(&m.DeleteDashboardCommand{Slug: folder.Slug, OrgId: 1, Cascade: true, OrgRole: m.ROLE_EDITOR}), ShouldBeNil)
+
+				for _, id := range []int64{folder.Id, dash.Id, sub.Id, nested.Id} {
+					query := m.GetDashboardQuery{Id: id, OrgId: 1}
+					So(GetDashboard(&query), ShouldEqual, m.ErrDashboardNotFound)
+				}
+
+				Convey("Should restore the folder with its content", func() {
+					So(RestoreDashboard(&m.RestoreDashboardCommand{Id: folder.Id, OrgId: 1, OrgRole: m.ROLE_EDITOR}), ShouldBeNil)
+					So(getDashboard(nested.Id).ParentId, ShouldEqual, sub.Id)
+					So(getDashboard(dash.Id).ParentId, ShouldEqual, folder.Id)
+				})
+
+				Convey("Should not restore the folder when the user can't edit its content", func() {
+					// acls can't be set on deleted dashboards through the command
+					_, err := x.Insert(&m.DashboardAcl{OrgId: 1, DashboardId: nested.Id, Role: m.ROLE_EDITOR, Permission: m.PERMISSION_VIEW})
+					So(err, ShouldBeNil)
+
+					err = RestoreDashboard(&m.RestoreDashboardCommand{Id: folder.Id, OrgId: 1, UserId: 1, OrgRole: m.ROLE_EDITOR})
+					So(err, ShouldEqual, m.ErrDashboardPermissionDenied)
+
+					query := m.GetDashboardQuery{Id: folder.Id, OrgId: 1}
+					So(GetDashboard(&query), ShouldEqual, m.ErrDashboardNotFound)
+				})
+
+				Convey("Should leave the dashboards deleted before the folder", func() {
+					earlier := insertTestDashboard("earlier", 1)
+					_, err := x.Exec("UPDATE dashboard SET parent_id=?, deleted=? WHERE id=?", folder.Id, time.Now().Add(-time.Hour), earlier.Id)
+					So(err, ShouldBeNil)
+
+					So(RestoreDashboard(&m.RestoreDashboardCommand{Id: folder.Id, OrgId: 1, OrgRole: m.ROLE_EDITOR}), ShouldBeNil)
+					So(getDashboard(dash.Id).ParentId, ShouldEqual, folder.Id)
+
+					query := m.GetDashboardQuery{Id: earlier.Id, OrgId: 1}
+					So(GetDashboard(&query), ShouldEqual, m.ErrDashboardNotFound)
+				})
+
+				Convey("Should restore a dashboard of a deleted folder to the root", func() {
+					So(RestoreDashboard(&m.RestoreDashboardCommand{Id: dash.Id, OrgId: 1}), ShouldBeNil)
+					So(getDashboard(dash.Id).ParentId, ShouldEqual, 0)
+				})
+			})
+
+			Convey("Should not cascade to a dashboard the user can't edit", func() {
+				acl := m.SetDashboardAclCommand{OrgId: 1, DashboardId: dash.Id, Role: m.ROLE_EDITOR, Permission: m.PERMISSION_VIEW}
+				So(SetDashboardAcl(&acl), ShouldBeNil)
+
+				err := DeleteDashboard(&m.DeleteDashboardCommand{Slug: folder.Slug, OrgId: 1, Cascade: true, UserId: 1, OrgRole: m.ROLE_EDITOR})
+				So(err, ShouldEqual, m.ErrDashboardPermissionDenied)
+				So(getDashboard(folder.Id), ShouldNotBeNil)
+				So(getDashboard(dash.Id), ShouldNotBeNil)
+			})
+
+			Convey("Should not cascade to a provisioned dashboard", func() {
+				parentId := folder.Id
+				provisioned, err := saveTestDashboard(m.SaveDashboardCommand{
+					ParentId:    &parentId,
+					Provisioned: true,
+					Dashboard:   map[string]interface{}{"title": "provisioned disk usage"},
+				})
+				So(err, ShouldBeNil)
+
+				err = DeleteDashboard(&m.DeleteDashboardCommand{Slug: folder.Slug, OrgId: 1, Cascade: true, OrgRole: m.ROLE_ADMIN})
+				So(err, ShouldEqual, m.ErrDashboardProvisioned)
+				So(getDashboard(provisioned.Id), ShouldNotBeNil)
+				So(getDashboard(dash.Id), ShouldNotBeNil)
+			})
+		})
+
+		Convey("Should import dashboards into new folders", func() {
+			cmd := m.ImportDashboardsCommand{OrgId: 1, Items: []*m.ImportDashboardItem{
+				{Dashboard: map[string]interface{}{"title": "imported folder"}, IsFolder: true, FolderIndex: -1},
+				{Dashboard: map[string]interface{}{"title": "disk usage", "tags": []interface{}{"imported"}}, FolderIndex: 0},
+			}}
+			So(ImportDashboards(&cmd), ShouldBeNil)
+
+			So(cmd.Result[0].IsFolder, ShouldBeTrue)
+			So(cmd.Result[1].Id, ShouldEqual, dash.Id)
+			So(getDashboard(dash.Id).ParentId, ShouldEqual, cmd.Result[0].Id)
+		})
+
+		Convey("Should import nothing when one dashboard fails", func() {
+			cmd := m.ImportDashboardsCommand{OrgId: 1, Items: []*m.ImportDashboardItem{
+				{Dashboard: map[string]interface{}{"title": "imported"}, FolderIndex: -1},
+				{Dashboard: map[string]interface{}{"title": "misplaced"}, FolderIndex: 0},
+			}}
+			So(ImportDashboards(&cmd), ShouldEqual, m.ErrDashboardFolderNotFound)
+			So(cmd.FailedIndex, ShouldEqual, 1)
+
+			query := m.GetDashboardQuery{Slug: "imported", OrgId: 1}
+			So(GetDashboard(&query), ShouldEqual, m.ErrDashboardNotFound)
+		})
+
+		Convey("Should delete an empty folder", func() {
+			So(DeleteDashboard(&m.DeleteDashboardCommand{Slug: folder.Slug, OrgId: 1}), ShouldBeNil)
+		})
+
+		Convey("Should not move into a dashboard that isn't a folder", func() {
+			other := insertTestDashboard("cpu", 1)
+			So(moveTo(dash, other.Id), ShouldEqual, m.ErrDashboardFolderNotFound)
+		})
+
+		Convey("Should not move a folder into itself", func() {
+			sub, err := saveTestDashboard(m.SaveDashboardCommand{IsFolder: true, ParentId: &folder.Id, Dashboard: map[string]interface{}{"title": "ops db"}})
+			So(err, ShouldBeNil)
+
+			So(moveTo(folder, folder.Id), ShouldEqual, m.ErrDashboardFolderCycle)
+			So(moveTo(folder, sub.Id), ShouldEqual, m.ErrDashboardFolderCycle)
+		})
+	})
+}
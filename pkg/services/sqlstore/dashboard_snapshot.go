@@ -12,11 +12,29 @@ func init() {
 	bus.AddHandler("sql", CreateDashboardSnapshot)
 	bus.AddHandler("sql", GetDashboardSnapshot)
 	bus.AddHandler("sql", DeleteDashboardSnapshot)
+	bus.AddHandler("sql", GetDashboardSnapshots)
 }
 
+// snapshotIdempotencyWindow is how long an idempotency key maps to the
+// snapshot it created.
+const snapshotIdempotencyWindow = time.Hour
+
 func CreateDashboardSnapshot(cmd *m.CreateDashboardSnapshotCommand) error {
 	return inTransaction(func(sess *xorm.Session) error {
 
+		if cmd.IdempotencyKey != "" {
+			var existing m.DashboardSnapshot
+			has, err := sess.Where("org_id=? AND user_id=? AND idempotency_key=? AND created > ?",
+				cmd.OrgId, cmd.UserId, cmd.IdempotencyKey, time.Now().Add(-snapshotIdempotencyWindow)).Get(&existing)
+			if err != nil {
+				return err
+			}
+			if has {
+				cmd.Result = &existing
+				return nil
+			}
+		}
+
 		// never
 		var expires = time.Now().Add(time.Hour * 24 * 365 * 50)
 		if cmd.Expires > 0 {
@@ -24,6 +42,7 @@ func CreateDashboardSnapshot(cmd *m.CreateDashboardSnapshotCommand) error {
 		}
 
 		snapshot := &m.DashboardSnapshot{
+			Name:      cmd.Name,
 			Key:       cmd.Key,
 			DeleteKey: cmd.DeleteKey,
 			OrgId:     cmd.OrgId,
@@ -33,6 +52,8 @@ func CreateDashboardSnapshot(cmd *m.CreateDashboardSnapshotCommand) error {
 			Expires:   expires,
 			Created:   time.Now(),
 			Updated:   time.Now(),
+
+			IdempotencyKey: cmd.IdempotencyKey,
 		}
 
 		_, err := sess.Insert(snapshot)
@@ -63,3 +84,15 @@ func GetDashboardSnapshot(query *m.GetDashboardSnapshotQuery) error {
 	query.Result = &snapshot
 	return nil
 }
+
+func GetDashboardSnapshots(query *m.GetDashboardSnapshotsQuery) error {
+	sess := x.Table("dashboard_snapshot")
+	sess.Where("org_id=? AND expires > ?", query.OrgId, time.Now())
+	if query.UserId != 0 {
+		sess.And("user_id=?", query.UserId)
+	}
+	sess.Desc("created").Limit(query.Limit, query.Limit*query.Page)
+
+	query.Result = make([]*m.DashboardSnapshotDTO, 0)
+	return sess.Find(&query.Result)
+}
@@ -2,6 +2,7 @@ package sqlstore
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 
@@ -33,5 +34,79 @@ func TestDashboardSnapshotDBAccess(t *testing.T) {
 			})
 
 		})
+
+		Convey("Given a snapshot created with an idempotency key", func() {
+			cmd := m.CreateDashboardSnapshotCommand{
+				Key:            "first",
+				DeleteKey:      "first-delete",
+				OrgId:          1,
+				UserId:         1,
+				IdempotencyKey: "retry-me",
+				Dashboard:      map[string]interface{}{"hello": "mupp"},
+			}
+			So(CreateDashboardSnapshot(&cmd), ShouldBeNil)
+			So(cmd.Result.Key, ShouldEqual, "first")
+
+			Convey("A retry with the same key should return the first snapshot", func() {
+				retry := cmd
+				retry.Key, retry.DeleteKey, retry.Result = "second", "second-delete", nil
+				So(CreateDashboardSnapshot(&retry), ShouldBeNil)
+				So(retry.Result.Key, ShouldEqual, "first")
+				So(retry.Result.DeleteKey, ShouldEqual, "first-delete")
+
+				query := m.GetDashboardSnapshotQuery{Key: "second"}
+				So(GetDashboardSnapshot(&query), ShouldEqual, m.ErrDashboardSnapshotNotFound)
+			})
+
+			Convey("The same key from another user should create a new snapshot", func() {
+				other := cmd
+				other.Key, other.DeleteKey, other.UserId, other.Result = "second", "second-delete", 2, nil
+				So(CreateDashboardSnapshot(&other), ShouldBeNil)
+				So(other.Result.Key, ShouldEqual, "second")
+			})
+		})
+
+		Convey("Given snapshots from two users", func() {
+			for _, cmd := range []m.CreateDashboardSnapshotCommand{
+				{Key: "u1-a", DeleteKey: "d-u1-a", Name: "first", OrgId: 1, UserId: 1},
+				{Key: "u1-b", DeleteKey: "d-u1-b", Name: "second", OrgId: 1, UserId: 1},
+				{Key: "u2-a", DeleteKey: "d-u2-a", Name: "other", OrgId: 1, UserId: 2},
+				{Key: "o2-a", DeleteKey: "d-o2-a", Name: "other org", OrgId: 2, UserId: 3},
+			} {
+				cmd.Dashboard = map[string]interface{}{"hello": "mupp"}
+				So(CreateDashboardSnapshot(&cmd), ShouldBeNil)
+			}
+
+			Convey("Should list only the snapshots of one user", func() {
+				query := m.GetDashboardSnapshotsQuery{OrgId: 1, UserId: 1, Limit: 10}
+				So(GetDashboardSnapshots(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 2)
+				for _, snapshot := range query.Result {
+					So(snapshot.UserId, ShouldEqual, 1)
+				}
+			})
+
+			Convey("Should list the whole org without a user", func() {
+				query := m.GetDashboardSnapshotsQuery{OrgId: 1, Limit: 10}
+				So(GetDashboardSnapshots(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 3)
+			})
+
+			Convey("Should page through the results", func() {
+				query := m.GetDashboardSnapshotsQuery{OrgId: 1, Page: 1, Limit: 2}
+				So(GetDashboardSnapshots(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+			})
+
+			Convey("Should leave out expired snapshots", func() {
+				_, err := x.Exec("UPDATE dashboard_snapshot SET expires=? WHERE "+dialect.Quote("key")+"=?", time.Now().Add(-time.Minute), "u1-a")
+				So(err, ShouldBeNil)
+
+				query := m.GetDashboardSnapshotsQuery{OrgId: 1, UserId: 1, Limit: 10}
+				So(GetDashboardSnapshots(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Key, ShouldEqual, "u1-b")
+			})
+		})
 	})
 }
@@ -2,9 +2,12 @@ package sqlstore
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/events"
 	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/services/search"
 )
@@ -39,6 +42,21 @@ func TestDashboardDataAccess(t *testing.T) {
 				So(savedDash.Id, ShouldNotEqual, 0)
 			})
 
+			Convey("Should publish the saved dashboard", func() {
+				var saved *events.DashboardSaved
+				bus.AddEventListener(func(evt *events.DashboardSaved) error {
+					saved = evt
+					return nil
+				})
+
+				dash := insertTestDashboard("test dash 24", 2)
+
+				So(saved, ShouldNotBeNil)
+				So(saved.Id, ShouldEqual, dash.Id)
+				So(saved.OrgId, ShouldEqual, 2)
+				So(saved.Slug, ShouldEqual, "test-dash-24")
+			})
+
 			Convey("Should be able to get dashboard", func() {
 				query := m.GetDashboardQuery{
 					Slug:  "test-dash-23",
@@ -52,6 +70,53 @@ func TestDashboardDataAccess(t *testing.T) {
 				So(query.Result.Slug, ShouldEqual, "test-dash-23")
 			})
 
+			Convey("Given a provisioned dashboard", func() {
+				cmd := m.SaveDashboardCommand{
+					OrgId:       1,
+					Provisioned: true,
+					Dashboard:   map[string]interface{}{"title": "provisioned dash"},
+				}
+				So(SaveDashboard(&cmd), ShouldBeNil)
+				provisioned := cmd.Result
+
+				Convey("Should mark it provisioned", func() {
+					query := m.GetDashboardQuery{Slug: "provisioned-dash", OrgId: 1}
+					So(GetDashboard(&query), ShouldBeNil)
+					So(query.Result.Provisioned, ShouldBeTrue)
+				})
+
+				Convey("Should reject saving over it by id", func() {
+					err := SaveDashboard(&m.SaveDashboardCommand{
+						OrgId: 1,
+						Dashboard: map[string]interface{}{
+							"id":      float64(provisioned.Id),
+							"version": float64(provisioned.Version),
+							"title":   "provisioned dash",
+						},
+					})
+					So(err, ShouldEqual, m.ErrDashboardProvisioned)
+				})
+
+				Convey("Should reject overwriting it by title", func() {
+					err := SaveDashboard(&m.SaveDashboardCommand{
+						OrgId:     1,
+						Overwrite: true,
+						Dashboard: map[string]interface{}{"title": "provisioned dash"},
+					})
+					So(err, ShouldEqual, m.ErrDashboardProvisioned)
+				})
+
+				Convey("Should let provisioning update it", func() {
+					err := SaveDashboard(&m.SaveDashboardCommand{
+						OrgId:       1,
+						Overwrite:   true,
+						Provisioned: true,
+						Dashboard:   map[string]interface{}{"title": "provisioned dash", "editable": false},
+					})
+					So(err, ShouldBeNil)
+				})
+			})
+
 			Convey("Should return error if no dashboard is updated", func() {
 				cmd := m.SaveDashboardCommand{
 					OrgId:     1,
@@ -87,8 +152,9 @@ func TestDashboardDataAccess(t *testing.T) {
 
 			Convey("Should be able to search for dashboard", func() {
 				query := search.FindPersistedDashboardsQuery{
-					Title: "test",
-					OrgId: 1,
+					Title:   "test",
+					OrgId:   1,
+					OrgRole: m.ROLE_VIEWER,
 				}
 
 				err := SearchDashboards(&query)
@@ -99,6 +165,18 @@ func TestDashboardDataAccess(t *testing.T) {
 				So(len(hit.Tags), ShouldEqual, 2)
 			})
 
+			Convey("Should leave dashboards out of search when their acl doesn't let the user view them", func() {
+				So(SetDashboardAcl(&m.SetDashboardAclCommand{OrgId: 1, DashboardId: savedDash.Id, Role: m.ROLE_EDITOR, Permission: m.PERMISSION_VIEW}), ShouldBeNil)
+
+				viewer := search.FindPersistedDashboardsQuery{Title: "test", OrgId: 1, UserId: 3, OrgRole: m.ROLE_VIEWER}
+				So(SearchDashboards(&viewer), ShouldBeNil)
+				So(len(viewer.Result), ShouldEqual, 0)
+
+				editor := search.FindPersistedDashboardsQuery{Title: "test", OrgId: 1, UserId: 3, OrgRole: m.ROLE_EDITOR}
+				So(SearchDashboards(&editor), ShouldBeNil)
+				So(len(editor.Result), ShouldEqual, 1)
+			})
+
 			Convey("Should not be able to save dashboard with same name", func() {
 				cmd := m.SaveDashboardCommand{
 					OrgId: 1,
@@ -113,6 +191,13 @@ func TestDashboardDataAccess(t *testing.T) {
 				So(err, ShouldNotBeNil)
 			})
 
+			Convey("Should list dashboard refs", func() {
+				query := m.GetDashboardRefsQuery{OrgId: 1}
+				So(GetDashboardRefs(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Slug, ShouldEqual, "test-dash-23")
+			})
+
 			Convey("Should be able to get dashboard tags", func() {
 				query := m.GetDashboardTagsQuery{OrgId: 1}
 
@@ -135,7 +220,7 @@ func TestDashboardDataAccess(t *testing.T) {
 				})
 
 				Convey("Should be able to search for starred dashboards", func() {
-					query := search.FindPersistedDashboardsQuery{OrgId: 1, UserId: 10, IsStarred: true}
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, OrgRole: m.ROLE_VIEWER, UserId: 10, IsStarred: true}
 					err := SearchDashboards(&query)
 
 					So(err, ShouldBeNil)
@@ -143,6 +228,163 @@ func TestDashboardDataAccess(t *testing.T) {
 					So(query.Result[0].Title, ShouldEqual, "starred dash")
 				})
 			})
+
+			Convey("Given a deleted dashboard", func() {
+				StarDashboard(&m.StarDashboardCommand{DashboardId: savedDash.Id, UserId: 1})
+				So(DeleteDashboard(&m.DeleteDashboardCommand{Slug: savedDash.Slug, OrgId: 1}), ShouldBeNil)
+
+				purge := func(before time.Time) int64 {
+					cmd := m.PurgeDeletedDashboardsCommand{Before: before}
+					So(PurgeDeletedDashboards(&cmd), ShouldBeNil)
+					return cmd.Result
+				}
+
+				Convey("Should hide it from get, search and tags", func() {
+					So(GetDashboard(&m.GetDashboardQuery{Slug: savedDash.Slug, OrgId: 1}), ShouldEqual, m.ErrDashboardNotFound)
+
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, OrgRole: m.ROLE_VIEWER}
+					So(SearchDashboards(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 0)
+
+					starred := search.FindPersistedDashboardsQuery{OrgId: 1, OrgRole: m.ROLE_VIEWER, UserId: 1, IsStarred: true}
+					So(SearchDashboards(&starred), ShouldBeNil)
+					So(len(starred.Result), ShouldEqual, 0)
+
+					tags := m.GetDashboardTagsQuery{OrgId: 1}
+					So(GetDashboardTags(&tags), ShouldBeNil)
+					So(len(tags.Result), ShouldEqual, 0)
+
+					refs := m.GetDashboardRefsQuery{OrgId: 1}
+					So(GetDashboardRefs(&refs), ShouldBeNil)
+					So(len(refs.Result), ShouldEqual, 0)
+				})
+
+				Convey("Should list it as deleted", func() {
+					query := m.GetDeletedDashboardsQuery{OrgId: 1}
+					So(GetDeletedDashboards(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Id, ShouldEqual, savedDash.Id)
+				})
+
+				Convey("Should not delete it twice", func() {
+					So(DeleteDashboard(&m.DeleteDashboardCommand{Slug: savedDash.Slug, OrgId: 1}), ShouldEqual, m.ErrDashboardNotFound)
+				})
+
+				Convey("Should restore it within the retention", func() {
+					So(purge(time.Now().Add(-time.Hour)), ShouldEqual, 0)
+					So(RestoreDashboard(&m.RestoreDashboardCommand{Id: savedDash.Id, OrgId: 1}), ShouldBeNil)
+
+					query := m.GetDashboardQuery{Slug: savedDash.Slug, OrgId: 1}
+					So(GetDashboard(&query), ShouldBeNil)
+					So(query.Result.Id, ShouldEqual, savedDash.Id)
+
+					starred := search.FindPersistedDashboardsQuery{OrgId: 1, OrgRole: m.ROLE_VIEWER, UserId: 1, IsStarred: true}
+					So(SearchDashboards(&starred), ShouldBeNil)
+					So(len(starred.Result), ShouldEqual, 1)
+
+					So(RestoreDashboard(&m.RestoreDashboardCommand{Id: savedDash.Id, OrgId: 1}), ShouldEqual, m.ErrDashboardNotFound)
+				})
+
+				Convey("Should not restore it from another org", func() {
+					So(RestoreDashboard(&m.RestoreDashboardCommand{Id: savedDash.Id, OrgId: 2}), ShouldEqual, m.ErrDashboardNotFound)
+				})
+
+				Convey("Should purge it after the retention", func() {
+					So(purge(time.Now().Add(time.Minute)), ShouldEqual, 1)
+					So(RestoreDashboard(&m.RestoreDashboardCommand{Id: savedDash.Id, OrgId: 1}), ShouldEqual, m.ErrDashboardNotFound)
+
+					stars, err := x.Query("SELECT 1 FROM star WHERE dashboard_id=?", savedDash.Id)
+					So(err, ShouldBeNil)
+					So(len(stars), ShouldEqual, 0)
+				})
+
+				Convey("Should give up its title to a new dashboard", func() {
+					created := insertTestDashboard("test dash 23", 1)
+					So(created.Id, ShouldNotEqual, savedDash.Id)
+					So(created.Slug, ShouldEqual, savedDash.Slug)
+
+					Convey("Should still be restorable under another slug", func() {
+						So(RestoreDashboard(&m.RestoreDashboardCommand{Id: savedDash.Id, OrgId: 1}), ShouldBeNil)
+
+						query := m.GetDashboardQuery{Id: savedDash.Id, OrgId: 1}
+						So(GetDashboard(&query), ShouldBeNil)
+						So(query.Result.Slug, ShouldNotEqual, savedDash.Slug)
+					})
+
+					Convey("Should get its slug back once it is free again", func() {
+						So(SaveDashboard(&m.SaveDashboardCommand{
+							OrgId:     1,
+							Overwrite: true,
+							Dashboard: map[string]interface{}{"id": float64(created.Id), "title": "renamed dash"},
+						}), ShouldBeNil)
+						So(RestoreDashboard(&m.RestoreDashboardCommand{Id: savedDash.Id, OrgId: 1}), ShouldBeNil)
+
+						query := m.GetDashboardQuery{Slug: savedDash.Slug, OrgId: 1}
+						So(GetDashboard(&query), ShouldBeNil)
+						So(query.Result.Id, ShouldEqual, savedDash.Id)
+					})
+				})
+			})
+
+			Convey("Given dashboards tagged deprecated", func() {
+				old1 := insertTestDashboard("old one", 1, "deprecated")
+				insertTestDashboard("old two", 1, "deprecated", "prod")
+				StarDashboard(&m.StarDashboardCommand{DashboardId: old1.Id, UserId: 10})
+
+				deleteByTag := func(tag string) (int64, error) {
+					cmd := m.DeleteDashboardsByTagCommand{OrgId: 1, Tag: tag, UserId: 10, OrgRole: m.ROLE_EDITOR}
+					err := DeleteDashboardsByTag(&cmd)
+					return cmd.Result, err
+				}
+
+				Convey("Should delete only the tagged dashboards", func() {
+					deleted, err := deleteByTag("deprecated")
+					So(err, ShouldBeNil)
+					So(deleted, ShouldEqual, 2)
+
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, OrgRole: m.ROLE_VIEWER}
+					So(SearchDashboards(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Id, ShouldEqual, savedDash.Id)
+
+					deletedQuery := m.GetDeletedDashboardsQuery{OrgId: 1}
+					So(GetDeletedDashboards(&deletedQuery), ShouldBeNil)
+					So(len(deletedQuery.Result), ShouldEqual, 2)
+
+					tags := m.GetDashboardTagsQuery{OrgId: 1}
+					So(GetDashboardTags(&tags), ShouldBeNil)
+					So(len(tags.Result), ShouldEqual, 2)
+				})
+
+				Convey("Should delete nothing for an unused tag", func() {
+					deleted, err := deleteByTag("unused")
+					So(err, ShouldBeNil)
+					So(deleted, ShouldEqual, 0)
+				})
+
+				Convey("Should keep them all when one is provisioned", func() {
+					So(SaveDashboard(&m.SaveDashboardCommand{
+						OrgId:       1,
+						Provisioned: true,
+						Dashboard:   map[string]interface{}{"title": "provisioned old", "tags": []interface{}{"deprecated"}},
+					}), ShouldBeNil)
+
+					_, err := deleteByTag("deprecated")
+					So(err, ShouldEqual, m.ErrDashboardProvisioned)
+
+					query := search.FindPersistedDashboardsQuery{OrgId: 1, OrgRole: m.ROLE_VIEWER}
+					So(SearchDashboards(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 4)
+				})
+
+				Convey("Should keep them all when the user can't edit one", func() {
+					acl := m.SetDashboardAclCommand{OrgId: 1, DashboardId: old1.Id, Role: m.ROLE_EDITOR, Permission: m.PERMISSION_VIEW}
+					So(SetDashboardAcl(&acl), ShouldBeNil)
+
+					_, err := deleteByTag("deprecated")
+					So(err, ShouldEqual, m.ErrDashboardPermissionDenied)
+				})
+			})
 		})
 	})
 }
@@ -11,81 +11,212 @@ import (
 
 func init() {
 	bus.AddHandler("sql", GetDataSources)
+	bus.AddHandler("sql", GetDataSourcesLastModified)
+	bus.AddHandler("sql", GetProvisionedDataSources)
 	bus.AddHandler("sql", AddDataSource)
+	bus.AddHandler("sql", ImportDataSources)
 	bus.AddHandler("sql", DeleteDataSource)
+	bus.AddHandler("sql", RestoreDataSource)
+	bus.AddHandler("sql", PurgeDeletedDataSources)
 	bus.AddHandler("sql", UpdateDataSource)
 	bus.AddHandler("sql", GetDataSourceById)
 	bus.AddHandler("sql", GetDataSourceByName)
+	bus.AddHandler("sql", GetDefaultDataSource)
 }
 
 func GetDataSourceById(query *m.GetDataSourceByIdQuery) error {
-	sess := x.Limit(100, 0).Where("org_id=? AND id=?", query.OrgId, query.Id)
+	sess := x.Limit(100, 0).Where("org_id=? AND id=? AND deleted IS NULL", query.OrgId, query.Id)
 	has, err := sess.Get(&query.Result)
 
+	if err != nil {
+		return err
+	}
 	if !has {
 		return m.ErrDataSourceNotFound
 	}
-	return err
+	return nil
 }
 
 func GetDataSourceByName(query *m.GetDataSourceByNameQuery) error {
 	sess := x.Limit(100, 0).Where("org_id=? AND name=?", query.OrgId, query.Name)
+	if !query.IncludeDeleted {
+		sess.And("deleted IS NULL")
+	}
 	has, err := sess.Get(&query.Result)
 
+	if err != nil {
+		return err
+	}
 	if !has {
 		return m.ErrDataSourceNotFound
 	}
-	return err
+	return nil
+}
+
+func GetDefaultDataSource(query *m.GetDefaultDataSourceQuery) error {
+	sess := x.Limit(1, 0).Where("org_id=? AND is_default=? AND deleted IS NULL", query.OrgId, true)
+	has, err := sess.Get(&query.Result)
+
+	if err != nil {
+		return err
+	}
+	if !has {
+		return m.ErrDataSourceNotFound
+	}
+	return nil
 }
 
 func GetDataSources(query *m.GetDataSourcesQuery) error {
-	sess := x.Limit(100, 0).Where("org_id=?", query.OrgId).Asc("name")
+	sess := x.Limit(100, 0).Where("org_id=? AND deleted IS NULL", query.OrgId)
+	if query.Type != "" {
+		sess.And("type=?", query.Type)
+	}
+	if query.Query != "" {
+		sess.And(dialect.ILike("name"), "%"+query.Query+"%")
+	}
+	sess.Asc("name", "id")
 
 	query.Result = make([]*m.DataSource, 0)
 	return sess.Find(&query.Result)
 }
 
+func GetProvisionedDataSources(query *m.GetProvisionedDataSourcesQuery) error {
+	query.Result = make([]*m.DataSource, 0)
+	return x.Where("provisioned=? AND deleted IS NULL", true).Asc("org_id", "name").Find(&query.Result)
+}
+
+func GetDataSourcesLastModified(query *m.GetDataSourcesLastModifiedQuery) error {
+	var updated, deleted m.DataSource
+
+	if _, err := x.Where("org_id=?", query.OrgId).Desc("updated").Cols("updated").Get(&updated); err != nil {
+		return err
+	}
+	if _, err := x.Where("org_id=? AND deleted IS NOT NULL", query.OrgId).Desc("deleted").Cols("deleted").Get(&deleted); err != nil {
+		return err
+	}
+
+	query.Result = updated.Updated
+	if deleted.Deleted != nil && deleted.Deleted.After(query.Result) {
+		query.Result = *deleted.Deleted
+	}
+	return nil
+}
+
 func DeleteDataSource(cmd *m.DeleteDataSourceCommand) error {
 	return inTransaction(func(sess *xorm.Session) error {
-		var rawSql = "DELETE FROM data_source WHERE id=? and org_id=?"
-		_, err := sess.Exec(rawSql, cmd.Id, cmd.OrgId)
+		var rawSql = "UPDATE data_source SET deleted=? WHERE id=? and org_id=? AND deleted IS NULL"
+		_, err := sess.Exec(rawSql, time.Now(), cmd.Id, cmd.OrgId)
 		return err
 	})
 }
 
-func AddDataSource(cmd *m.AddDataSourceCommand) error {
-
+func RestoreDataSource(cmd *m.RestoreDataSourceCommand) error {
 	return inTransaction(func(sess *xorm.Session) error {
-		ds := &m.DataSource{
-			OrgId:             cmd.OrgId,
-			Name:              cmd.Name,
-			Type:              cmd.Type,
-			Access:            cmd.Access,
-			Url:               cmd.Url,
-			User:              cmd.User,
-			Password:          cmd.Password,
-			Database:          cmd.Database,
-			IsDefault:         cmd.IsDefault,
-			BasicAuth:         cmd.BasicAuth,
-			BasicAuthUser:     cmd.BasicAuthUser,
-			BasicAuthPassword: cmd.BasicAuthPassword,
-			JsonData:          cmd.JsonData,
-			Created:           time.Now(),
-			Updated:           time.Now(),
+		var rawSql = "UPDATE data_source SET deleted=NULL, updated=? WHERE id=? and org_id=? AND deleted IS NOT NULL"
+		result, err := sess.Exec(rawSql, time.Now(), cmd.Id, cmd.OrgId)
+		if err != nil {
+			return err
 		}
 
-		if _, err := sess.Insert(ds); err != nil {
-			return err
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return m.ErrDataSourceNotFound
 		}
-		if err := updateIsDefaultFlag(ds, sess); err != nil {
+		return nil
+	})
+}
+
+func PurgeDeletedDataSources(cmd *m.PurgeDeletedDataSourcesCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		result, err := sess.Exec("DELETE FROM data_source WHERE deleted IS NOT NULL AND deleted < ?", cmd.Before)
+		if err != nil {
 			return err
 		}
 
-		cmd.Result = ds
+		cmd.Result, _ = result.RowsAffected()
 		return nil
 	})
 }
 
+// freeDeletedDataSourceName purges the deleted data sources still holding on
+// to the name, a data source added or renamed to it takes the name over.
+func freeDeletedDataSourceName(sess *xorm.Session, orgId int64, name string) error {
+	_, err := sess.Exec("DELETE FROM data_source WHERE org_id=? AND name=? AND deleted IS NOT NULL", orgId, name)
+	return err
+}
+
+func AddDataSource(cmd *m.AddDataSourceCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		return addDataSource(cmd, sess)
+	})
+}
+
+func ImportDataSources(cmd *m.ImportDataSourcesCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		cmd.Result = make([]*m.ImportDataSourceResult, 0, len(cmd.DataSources))
+
+		for i := range cmd.DataSources {
+			item := &cmd.DataSources[i]
+			item.OrgId = cmd.OrgId
+			result := &m.ImportDataSourceResult{Name: item.Name}
+			cmd.Result = append(cmd.Result, result)
+
+			exists, err := sess.Where("org_id=? AND name=? AND deleted IS NULL", cmd.OrgId, item.Name).Get(&m.DataSource{})
+			if err != nil {
+				return err
+			}
+			if exists {
+				if cmd.AbortOnDuplicate {
+					return m.ErrDataSourceNameExists
+				}
+				result.Error = m.ErrDataSourceNameExists.Error()
+				continue
+			}
+
+			if err := addDataSource(item, sess); err != nil {
+				return err
+			}
+			result.Id = item.Result.Id
+		}
+
+		return nil
+	})
+}
+
+func addDataSource(cmd *m.AddDataSourceCommand, sess *xorm.Session) error {
+	ds := &m.DataSource{
+		OrgId:             cmd.OrgId,
+		Name:              cmd.Name,
+		Type:              cmd.Type,
+		Access:            cmd.Access,
+		Url:               cmd.Url,
+		User:              cmd.User,
+		Password:          cmd.Password,
+		Database:          cmd.Database,
+		IsDefault:         cmd.IsDefault,
+		BasicAuth:         cmd.BasicAuth,
+		BasicAuthUser:     cmd.BasicAuthUser,
+		BasicAuthPassword: cmd.BasicAuthPassword,
+		JsonData:          cmd.JsonData,
+		Provisioned:       cmd.Provisioned,
+		Created:           time.Now(),
+		Updated:           time.Now(),
+	}
+
+	if err := freeDeletedDataSourceName(sess, ds.OrgId, ds.Name); err != nil {
+		return err
+	}
+
+	if _, err := sess.Insert(ds); err != nil {
+		return err
+	}
+	if err := updateIsDefaultFlag(ds, sess); err != nil {
+		return err
+	}
+
+	cmd.Result = ds
+	return nil
+}
+
 func updateIsDefaultFlag(ds *m.DataSource, sess *xorm.Session) error {
 	// Handle is default flag
 	if ds.IsDefault {
@@ -115,13 +246,18 @@ func UpdateDataSource(cmd *m.UpdateDataSourceCommand) error {
 			BasicAuthUser:     cmd.BasicAuthUser,
 			BasicAuthPassword: cmd.BasicAuthPassword,
 			JsonData:          cmd.JsonData,
+			Provisioned:       cmd.Provisioned,
 			Updated:           time.Now(),
 		}
 
+		if err := freeDeletedDataSourceName(sess, ds.OrgId, ds.Name); err != nil {
+			return err
+		}
+
 		sess.UseBool("is_default")
 		sess.UseBool("basic_auth")
 
-		_, err := sess.Where("id=? and org_id=?", ds.Id, ds.OrgId).Update(ds)
+		_, err := sess.Where("id=? and org_id=? AND deleted IS NULL", ds.Id, ds.OrgId).Update(ds)
 		if err != nil {
 			return err
 		}
@@ -2,6 +2,7 @@ package sqlstore
 
 import (
 	"testing"
+	"time"
 
 	"github.com/go-xorm/xorm"
 
@@ -63,6 +64,136 @@ func TestDataAccess(t *testing.T) {
 			So(ds.Database, ShouldEqual, "site")
 		})
 
+		Convey("Setting a new default datasource clears the previous one", func() {
+			first := m.AddDataSourceCommand{OrgId: 10, Name: "first", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_DIRECT, IsDefault: true}
+			So(AddDataSource(&first), ShouldBeNil)
+
+			other := m.AddDataSourceCommand{OrgId: 11, Name: "other", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_DIRECT, IsDefault: true}
+			So(AddDataSource(&other), ShouldBeNil)
+
+			second := m.AddDataSourceCommand{OrgId: 10, Name: "second", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_DIRECT, IsDefault: true}
+			So(AddDataSource(&second), ShouldBeNil)
+
+			query := m.GetDefaultDataSourceQuery{OrgId: 10}
+			So(GetDefaultDataSource(&query), ShouldBeNil)
+			So(query.Result.Id, ShouldEqual, second.Result.Id)
+
+			byId := m.GetDataSourceByIdQuery{Id: first.Result.Id, OrgId: 10}
+			So(GetDataSourceById(&byId), ShouldBeNil)
+			So(byId.Result.IsDefault, ShouldBeFalse)
+
+			Convey("Should not touch the default of other orgs", func() {
+				query := m.GetDefaultDataSourceQuery{OrgId: 11}
+				So(GetDefaultDataSource(&query), ShouldBeNil)
+				So(query.Result.Id, ShouldEqual, other.Result.Id)
+			})
+
+			Convey("Updating a datasource to default clears the previous one", func() {
+				err := UpdateDataSource(&m.UpdateDataSourceCommand{
+					Id: first.Result.Id, OrgId: 10, Name: "first", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_DIRECT, IsDefault: true,
+				})
+				So(err, ShouldBeNil)
+
+				query := m.GetDefaultDataSourceQuery{OrgId: 10}
+				So(GetDefaultDataSource(&query), ShouldBeNil)
+				So(query.Result.Id, ShouldEqual, first.Result.Id)
+			})
+		})
+
+		Convey("Can import datasources", func() {
+			cmd := m.ImportDataSourcesCommand{
+				OrgId: 10,
+				DataSources: []m.AddDataSourceCommand{
+					{Name: "graphite", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_PROXY},
+					{Name: "falcon", Type: m.DS_OPENFALCON, Access: m.DS_ACCESS_PROXY},
+				},
+			}
+			So(ImportDataSources(&cmd), ShouldBeNil)
+			So(len(cmd.Result), ShouldEqual, 2)
+			So(cmd.Result[0].Id, ShouldBeGreaterThan, 0)
+			So(cmd.Result[1].Error, ShouldEqual, "")
+
+			query := m.GetDataSourcesQuery{OrgId: 10}
+			GetDataSources(&query)
+			So(len(query.Result), ShouldEqual, 2)
+
+			Convey("Duplicate names fail only that item", func() {
+				cmd := m.ImportDataSourcesCommand{
+					OrgId: 10,
+					DataSources: []m.AddDataSourceCommand{
+						{Name: "graphite", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_PROXY},
+						{Name: "influx", Type: m.DS_INFLUXDB, Access: m.DS_ACCESS_PROXY},
+						{Name: "influx", Type: m.DS_INFLUXDB, Access: m.DS_ACCESS_PROXY},
+					},
+				}
+				So(ImportDataSources(&cmd), ShouldBeNil)
+				So(cmd.Result[0].Error, ShouldEqual, m.ErrDataSourceNameExists.Error())
+				So(cmd.Result[1].Id, ShouldBeGreaterThan, 0)
+				So(cmd.Result[2].Error, ShouldEqual, m.ErrDataSourceNameExists.Error())
+
+				GetDataSources(&query)
+				So(len(query.Result), ShouldEqual, 3)
+			})
+
+			Convey("Duplicate names can abort the whole batch", func() {
+				cmd := m.ImportDataSourcesCommand{
+					OrgId:            10,
+					AbortOnDuplicate: true,
+					DataSources: []m.AddDataSourceCommand{
+						{Name: "influx", Type: m.DS_INFLUXDB, Access: m.DS_ACCESS_PROXY},
+						{Name: "graphite", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_PROXY},
+					},
+				}
+				So(ImportDataSources(&cmd), ShouldEqual, m.ErrDataSourceNameExists)
+
+				GetDataSources(&query)
+				So(len(query.Result), ShouldEqual, 2)
+			})
+		})
+
+		Convey("Given datasources of different types", func() {
+			for _, cmd := range []m.AddDataSourceCommand{
+				{OrgId: 13, Name: "prod-graphite", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_PROXY},
+				{OrgId: 13, Name: "influx", Type: m.DS_INFLUXDB, Access: m.DS_ACCESS_PROXY},
+				{OrgId: 13, Name: "dev-graphite", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_PROXY},
+				{OrgId: 13, Name: "prod-influx", Type: m.DS_INFLUXDB, Access: m.DS_ACCESS_PROXY},
+				{OrgId: 14, Name: "prod-elsewhere", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_PROXY},
+			} {
+				cmd := cmd
+				So(AddDataSource(&cmd), ShouldBeNil)
+			}
+
+			names := func(query m.GetDataSourcesQuery) []string {
+				So(GetDataSources(&query), ShouldBeNil)
+				result := make([]string, 0)
+				for _, ds := range query.Result {
+					result = append(result, ds.Name)
+				}
+				return result
+			}
+
+			Convey("Should list them by name", func() {
+				So(names(m.GetDataSourcesQuery{OrgId: 13}), ShouldResemble, []string{"dev-graphite", "influx", "prod-graphite", "prod-influx"})
+			})
+
+			Convey("Should filter by type", func() {
+				So(names(m.GetDataSourcesQuery{OrgId: 13, Type: m.DS_GRAPHITE}), ShouldResemble, []string{"dev-graphite", "prod-graphite"})
+			})
+
+			Convey("Should filter by part of the name", func() {
+				So(names(m.GetDataSourcesQuery{OrgId: 13, Query: "prod"}), ShouldResemble, []string{"prod-graphite", "prod-influx"})
+			})
+
+			Convey("Should combine the filters", func() {
+				So(names(m.GetDataSourcesQuery{OrgId: 13, Type: m.DS_INFLUXDB, Query: "prod"}), ShouldResemble, []string{"prod-influx"})
+			})
+		})
+
+		Convey("Getting the default datasource of an org without one", func() {
+			query := m.GetDefaultDataSourceQuery{OrgId: 12}
+			So(GetDefaultDataSource(&query), ShouldEqual, m.ErrDataSourceNotFound)
+		})
+
 		Convey("Given a datasource", func() {
 
 			AddDataSource(&m.AddDataSourceCommand{
@@ -84,6 +215,16 @@ func TestDataAccess(t *testing.T) {
 				So(len(query.Result), ShouldEqual, 0)
 			})
 
+			Convey("Can not get datasource with wrong orgId", func() {
+				byId := m.GetDataSourceByIdQuery{Id: ds.Id, OrgId: 123123}
+				So(GetDataSourceById(&byId), ShouldEqual, m.ErrDataSourceNotFound)
+			})
+
+			Convey("Can not get datasource with unknown id", func() {
+				byId := m.GetDataSourceByIdQuery{Id: ds.Id + 1000, OrgId: ds.OrgId}
+				So(GetDataSourceById(&byId), ShouldEqual, m.ErrDataSourceNotFound)
+			})
+
 			Convey("Can not delete datasource with wrong orgId", func() {
 				err := DeleteDataSource(&m.DeleteDataSourceCommand{Id: ds.Id, OrgId: 123123})
 				So(err, ShouldBeNil)
@@ -92,6 +233,90 @@ func TestDataAccess(t *testing.T) {
 				So(len(query.Result), ShouldEqual, 1)
 			})
 
+			Convey("Can find a deleted datasource by name only when asked to", func() {
+				So(DeleteDataSource(&m.DeleteDataSourceCommand{Id: ds.Id, OrgId: ds.OrgId}), ShouldBeNil)
+
+				byName := m.GetDataSourceByNameQuery{Name: ds.Name, OrgId: ds.OrgId}
+				So(GetDataSourceByName(&byName), ShouldEqual, m.ErrDataSourceNotFound)
+
+				byName.IncludeDeleted = true
+				So(GetDataSourceByName(&byName), ShouldBeNil)
+				So(byName.Result.Id, ShouldEqual, ds.Id)
+				So(byName.Result.Deleted, ShouldNotBeNil)
+			})
+
+			Convey("Can restore deleted datasource with its original id", func() {
+				err := DeleteDataSource(&m.DeleteDataSourceCommand{Id: ds.Id, OrgId: ds.OrgId})
+				So(err, ShouldBeNil)
+
+				byId := m.GetDataSourceByIdQuery{Id: ds.Id, OrgId: ds.OrgId}
+				So(GetDataSourceById(&byId), ShouldEqual, m.ErrDataSourceNotFound)
+
+				err = RestoreDataSource(&m.RestoreDataSourceCommand{Id: ds.Id, OrgId: ds.OrgId})
+				So(err, ShouldBeNil)
+
+				So(GetDataSourceById(&byId), ShouldBeNil)
+				So(byId.Result.Id, ShouldEqual, ds.Id)
+				So(byId.Result.Deleted, ShouldBeNil)
+
+				GetDataSources(&query)
+				So(len(query.Result), ShouldEqual, 1)
+			})
+
+			Convey("Tracks when the datasources of the org last changed", func() {
+				lastModified := m.GetDataSourcesLastModifiedQuery{OrgId: 10}
+				So(GetDataSourcesLastModified(&lastModified), ShouldBeNil)
+				So(lastModified.Result.Unix(), ShouldEqual, ds.Updated.Unix())
+
+				So(DeleteDataSource(&m.DeleteDataSourceCommand{Id: ds.Id, OrgId: ds.OrgId}), ShouldBeNil)
+				deleted := ds.Updated.Add(time.Hour)
+				_, err := x.Exec("UPDATE data_source SET deleted=? WHERE id=?", deleted, ds.Id)
+				So(err, ShouldBeNil)
+
+				So(GetDataSourcesLastModified(&lastModified), ShouldBeNil)
+				So(lastModified.Result.Unix(), ShouldEqual, deleted.Unix())
+
+				never := m.GetDataSourcesLastModifiedQuery{OrgId: 12}
+				So(GetDataSourcesLastModified(&never), ShouldBeNil)
+				So(never.Result.IsZero(), ShouldBeTrue)
+			})
+
+			Convey("Adding a datasource with the name of a deleted one purges it", func() {
+				So(DeleteDataSource(&m.DeleteDataSourceCommand{Id: ds.Id, OrgId: ds.OrgId}), ShouldBeNil)
+
+				err := AddDataSource(&m.AddDataSourceCommand{
+					OrgId:  10,
+					Name:   ds.Name,
+					Type:   m.DS_GRAPHITE,
+					Access: m.DS_ACCESS_DIRECT,
+					Url:    "http://test",
+				})
+				So(err, ShouldBeNil)
+
+				err = RestoreDataSource(&m.RestoreDataSourceCommand{Id: ds.Id, OrgId: ds.OrgId})
+				So(err, ShouldEqual, m.ErrDataSourceNotFound)
+			})
+
+			Convey("Can purge deleted datasources", func() {
+				So(DeleteDataSource(&m.DeleteDataSourceCommand{Id: ds.Id, OrgId: ds.OrgId}), ShouldBeNil)
+
+				notYet := m.PurgeDeletedDataSourcesCommand{Before: time.Now().Add(-time.Hour)}
+				So(PurgeDeletedDataSources(&notYet), ShouldBeNil)
+				So(notYet.Result, ShouldEqual, 0)
+
+				purge := m.PurgeDeletedDataSourcesCommand{Before: time.Now().Add(time.Hour)}
+				So(PurgeDeletedDataSources(&purge), ShouldBeNil)
+				So(purge.Result, ShouldEqual, 1)
+
+				err := RestoreDataSource(&m.RestoreDataSourceCommand{Id: ds.Id, OrgId: ds.OrgId})
+				So(err, ShouldEqual, m.ErrDataSourceNotFound)
+			})
+
+			Convey("Can not restore datasource that is not deleted", func() {
+				err := RestoreDataSource(&m.RestoreDataSourceCommand{Id: ds.Id, OrgId: ds.OrgId})
+				So(err, ShouldEqual, m.ErrDataSourceNotFound)
+			})
+
 		})
 
 	})
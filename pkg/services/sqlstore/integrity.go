@@ -0,0 +1,61 @@
+package sqlstore
+
+import (
+	"fmt"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", CheckIntegrity)
+}
+
+type integrityCheck struct {
+	table, column, reference string
+}
+
+// integrityChecks are the references DeleteOrg and DeleteUser clean up by
+// hand, rows they missed point at ids that no longer exist.
+var integrityChecks = []integrityCheck{
+	{"star", "dashboard_id", "dashboard"},
+	{"star", "user_id", "user"},
+	{"dashboard_tag", "dashboard_id", "dashboard"},
+	{"org_user", "org_id", "org"},
+	{"org_user", "user_id", "user"},
+	{"data_source", "org_id", "org"},
+	{"api_key", "org_id", "org"},
+}
+
+func (check integrityCheck) where() string {
+	reference := dialect.Quote(check.reference)
+	return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.id = %s.%s)", reference, reference, check.table, check.column)
+}
+
+func CheckIntegrity(cmd *m.CheckIntegrityCommand) error {
+	return inTransaction2(func(sess *session) error {
+		cmd.Result = make([]*m.IntegrityIssue, 0)
+
+		for _, check := range integrityChecks {
+			rawSql := fmt.Sprintf("SELECT COUNT(*) as count FROM %s WHERE %s", check.table, check.where())
+			resp := make([]*targetCount, 0)
+			if err := sess.Sql(rawSql).Find(&resp); err != nil {
+				return err
+			}
+			if resp[0].Count == 0 {
+				continue
+			}
+
+			issue := &m.IntegrityIssue{Table: check.table, Reference: check.reference, Count: resp[0].Count}
+			if cmd.Fix {
+				if _, err := sess.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", check.table, check.where())); err != nil {
+					return err
+				}
+				issue.Fixed = true
+			}
+			cmd.Result = append(cmd.Result, issue)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,74 @@
+package sqlstore
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+func findIntegrityIssue(issues []*m.IntegrityIssue, table, reference string) *m.IntegrityIssue {
+	for _, issue := range issues {
+		if issue.Table == table && issue.Reference == reference {
+			return issue
+		}
+	}
+	return nil
+}
+
+func TestIntegrityCheck(t *testing.T) {
+
+	Convey("Testing integrity check", t, func() {
+		InitTestDB(t)
+		setting.AutoAssignOrg = false
+
+		userCmd := m.CreateUserCommand{Login: "starrer", Email: "starrer@test.com"}
+		So(CreateUser(&userCmd), ShouldBeNil)
+		dash := insertTestDashboard("kept dash", userCmd.Result.OrgId)
+
+		So(StarDashboard(&m.StarDashboardCommand{UserId: userCmd.Result.Id, DashboardId: dash.Id}), ShouldBeNil)
+
+		Convey("Should report nothing when every reference exists", func() {
+			cmd := m.CheckIntegrityCommand{}
+			So(CheckIntegrity(&cmd), ShouldBeNil)
+			So(cmd.Result, ShouldBeEmpty)
+		})
+
+		Convey("Given a star of a missing dashboard", func() {
+			_, err := x.Insert(&m.Star{UserId: userCmd.Result.Id, DashboardId: 9999})
+			So(err, ShouldBeNil)
+
+			Convey("Should report it", func() {
+				cmd := m.CheckIntegrityCommand{}
+				So(CheckIntegrity(&cmd), ShouldBeNil)
+				So(len(cmd.Result), ShouldEqual, 1)
+
+				issue := findIntegrityIssue(cmd.Result, "star", "dashboard")
+				So(issue, ShouldNotBeNil)
+				So(issue.Count, ShouldEqual, 1)
+				So(issue.Fixed, ShouldBeFalse)
+
+				count, err := x.Count(&m.Star{})
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 2)
+			})
+
+			Convey("Should delete it when fixing", func() {
+				cmd := m.CheckIntegrityCommand{Fix: true}
+				So(CheckIntegrity(&cmd), ShouldBeNil)
+				So(findIntegrityIssue(cmd.Result, "star", "dashboard").Fixed, ShouldBeTrue)
+
+				var stars []*m.Star
+				So(x.Find(&stars), ShouldBeNil)
+				So(len(stars), ShouldEqual, 1)
+				So(stars[0].DashboardId, ShouldEqual, dash.Id)
+
+				check := m.CheckIntegrityCommand{}
+				So(CheckIntegrity(&check), ShouldBeNil)
+				So(check.Result, ShouldBeEmpty)
+			})
+		})
+	})
+}
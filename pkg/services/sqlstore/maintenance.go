@@ -0,0 +1,80 @@
+package sqlstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+// maintenanceCacheTTL bounds how long a server keeps serving a switch
+// flipped through another server sharing the database.
+const maintenanceCacheTTL = 10 * time.Second
+
+// maintenanceCache holds the maintenance row, it is checked on every
+// request.
+var maintenanceCache struct {
+	sync.Mutex
+	value   *m.Maintenance
+	expires time.Time
+}
+
+func init() {
+	bus.AddHandler("sql", GetMaintenance)
+	bus.AddHandler("sql", SetMaintenance)
+}
+
+func GetMaintenance(query *m.GetMaintenanceQuery) error {
+	maintenanceCache.Lock()
+	defer maintenanceCache.Unlock()
+
+	if maintenanceCache.value == nil || time.Now().After(maintenanceCache.expires) {
+		var maintenance m.Maintenance
+		if _, err := x.Get(&maintenance); err != nil {
+			return err
+		}
+		cacheMaintenance(&maintenance)
+	}
+
+	result := *maintenanceCache.value
+	query.Result = &result
+	return nil
+}
+
+func SetMaintenance(cmd *m.SetMaintenanceCommand) error {
+	var maintenance m.Maintenance
+
+	err := inTransaction2(func(sess *session) error {
+		maintenance = m.Maintenance{}
+		exists, err := sess.Get(&maintenance)
+		if err != nil {
+			return err
+		}
+
+		maintenance.Enabled = cmd.Enabled
+		maintenance.Updated = time.Now()
+
+		if exists {
+			_, err = sess.Id(maintenance.Id).Cols("enabled", "updated").Update(&maintenance)
+		} else {
+			_, err = sess.Insert(&maintenance)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	maintenanceCache.Lock()
+	cacheMaintenance(&maintenance)
+	maintenanceCache.Unlock()
+	return nil
+}
+
+// cacheMaintenance expects maintenanceCache to be locked.
+func cacheMaintenance(maintenance *m.Maintenance) {
+	cached := *maintenance
+	maintenanceCache.value = &cached
+	maintenanceCache.expires = time.Now().Add(maintenanceCacheTTL)
+}
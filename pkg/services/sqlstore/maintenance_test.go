@@ -0,0 +1,54 @@
+package sqlstore
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestMaintenanceDataAccess(t *testing.T) {
+
+	Convey("Testing maintenance mode", t, func() {
+		InitTestDB(t)
+		maintenanceCache.value = nil
+
+		Convey("Should be off on a fresh database", func() {
+			query := m.GetMaintenanceQuery{}
+			So(GetMaintenance(&query), ShouldBeNil)
+			So(query.Result.Enabled, ShouldBeFalse)
+		})
+
+		Convey("When turned on", func() {
+			So(SetMaintenance(&m.SetMaintenanceCommand{Enabled: true}), ShouldBeNil)
+
+			Convey("Should be on", func() {
+				query := m.GetMaintenanceQuery{}
+				So(GetMaintenance(&query), ShouldBeNil)
+				So(query.Result.Enabled, ShouldBeTrue)
+			})
+
+			Convey("Should be persisted", func() {
+				maintenanceCache.value = nil
+
+				query := m.GetMaintenanceQuery{}
+				So(GetMaintenance(&query), ShouldBeNil)
+				So(query.Result.Enabled, ShouldBeTrue)
+			})
+
+			Convey("Should be off when turned off again", func() {
+				So(SetMaintenance(&m.SetMaintenanceCommand{Enabled: false}), ShouldBeNil)
+				maintenanceCache.value = nil
+
+				query := m.GetMaintenanceQuery{}
+				So(GetMaintenance(&query), ShouldBeNil)
+				So(query.Result.Enabled, ShouldBeFalse)
+
+				count, err := x.Count(&m.Maintenance{})
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 1)
+			})
+		})
+	})
+}
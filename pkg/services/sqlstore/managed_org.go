@@ -0,0 +1,72 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", AddManagedOrg)
+	bus.AddHandler("sql", RemoveManagedOrg)
+	bus.AddHandler("sql", GetManagedOrgs)
+	bus.AddHandler("sql", IsManagedOrg)
+}
+
+func AddManagedOrg(cmd *m.AddManagedOrgCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		if has, err := sess.Id(cmd.UserId).Get(&m.User{}); err != nil {
+			return err
+		} else if !has {
+			return m.ErrUserNotFound
+		}
+
+		if has, err := sess.Id(cmd.OrgId).Get(&m.Org{}); err != nil {
+			return err
+		} else if !has {
+			return m.ErrOrgNotFound
+		}
+
+		if has, err := sess.Where("user_id=? AND org_id=?", cmd.UserId, cmd.OrgId).Get(&m.ManagedOrg{}); err != nil {
+			return err
+		} else if has {
+			return m.ErrManagedOrgAlreadyAdded
+		}
+
+		entity := m.ManagedOrg{
+			UserId:  cmd.UserId,
+			OrgId:   cmd.OrgId,
+			Created: time.Now(),
+		}
+
+		_, err := sess.Insert(&entity)
+		return err
+	})
+}
+
+func RemoveManagedOrg(cmd *m.RemoveManagedOrgCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		_, err := sess.Exec("DELETE FROM managed_org WHERE user_id=? AND org_id=?", cmd.UserId, cmd.OrgId)
+		return err
+	})
+}
+
+func GetManagedOrgs(query *m.GetManagedOrgsQuery) error {
+	query.Result = make([]*m.OrgDTO, 0)
+	sess := x.Table("managed_org")
+	sess.Join("INNER", "org", "managed_org.org_id=org.id")
+	sess.Where("managed_org.user_id=?", query.UserId)
+	sess.Cols("org.id", "org.name")
+	sess.Asc("org.name")
+
+	return sess.Find(&query.Result)
+}
+
+func IsManagedOrg(query *m.IsManagedOrgQuery) error {
+	has, err := x.Where("user_id=? AND org_id=?", query.UserId, query.OrgId).Get(&m.ManagedOrg{})
+	query.Result = has
+	return err
+}
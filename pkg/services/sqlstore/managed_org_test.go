@@ -0,0 +1,73 @@
+package sqlstore
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+func TestManagedOrgDataAccess(t *testing.T) {
+
+	Convey("Testing managed org data access", t, func() {
+		InitTestDB(t)
+		setting.AutoAssignOrg = false
+
+		userCmd := m.CreateUserCommand{Login: "delegate", Email: "delegate@test.com"}
+		So(CreateUser(&userCmd), ShouldBeNil)
+
+		child := m.CreateOrgCommand{Name: "child"}
+		So(CreateOrg(&child), ShouldBeNil)
+
+		other := m.CreateOrgCommand{Name: "other"}
+		So(CreateOrg(&other), ShouldBeNil)
+
+		userId := userCmd.Result.Id
+		So(AddManagedOrg(&m.AddManagedOrgCommand{UserId: userId, OrgId: child.Result.Id}), ShouldBeNil)
+
+		Convey("Should only report the managed org as managed", func() {
+			managed := m.IsManagedOrgQuery{UserId: userId, OrgId: child.Result.Id}
+			So(IsManagedOrg(&managed), ShouldBeNil)
+			So(managed.Result, ShouldBeTrue)
+
+			unmanaged := m.IsManagedOrgQuery{UserId: userId, OrgId: other.Result.Id}
+			So(IsManagedOrg(&unmanaged), ShouldBeNil)
+			So(unmanaged.Result, ShouldBeFalse)
+		})
+
+		Convey("Should list the managed orgs", func() {
+			query := m.GetManagedOrgsQuery{UserId: userId}
+			So(GetManagedOrgs(&query), ShouldBeNil)
+			So(len(query.Result), ShouldEqual, 1)
+			So(query.Result[0].Name, ShouldEqual, "child")
+		})
+
+		Convey("Should not add the same org twice", func() {
+			err := AddManagedOrg(&m.AddManagedOrgCommand{UserId: userId, OrgId: child.Result.Id})
+			So(err, ShouldEqual, m.ErrManagedOrgAlreadyAdded)
+		})
+
+		Convey("Should not add an unknown org", func() {
+			err := AddManagedOrg(&m.AddManagedOrgCommand{UserId: userId, OrgId: 999})
+			So(err, ShouldEqual, m.ErrOrgNotFound)
+		})
+
+		Convey("Should forget the org once removed", func() {
+			So(RemoveManagedOrg(&m.RemoveManagedOrgCommand{UserId: userId, OrgId: child.Result.Id}), ShouldBeNil)
+
+			query := m.IsManagedOrgQuery{UserId: userId, OrgId: child.Result.Id}
+			So(IsManagedOrg(&query), ShouldBeNil)
+			So(query.Result, ShouldBeFalse)
+		})
+
+		Convey("Should forget the org when it is deleted", func() {
+			So(DeleteOrg(&m.DeleteOrgCommand{Id: child.Result.Id}), ShouldBeNil)
+
+			query := m.GetManagedOrgsQuery{UserId: userId}
+			So(GetManagedOrgs(&query), ShouldBeNil)
+			So(len(query.Result), ShouldEqual, 0)
+		})
+	})
+}
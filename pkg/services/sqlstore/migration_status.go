@@ -0,0 +1,40 @@
+package sqlstore
+
+import (
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+)
+
+func init() {
+	bus.AddHandler("sql", GetMigrationStatus)
+}
+
+func GetMigrationStatus(query *m.GetMigrationStatusQuery) error {
+	var logs []*migrator.MigrationLog
+	if err := x.Asc("id").Find(&logs); err != nil {
+		return err
+	}
+
+	status := &m.MigrationStatus{
+		Applied: make([]*m.MigrationLogEntry, 0, len(logs)),
+		Failed:  make([]*m.MigrationLogEntry, 0),
+	}
+
+	applied := make(map[string]bool, len(logs))
+	for _, record := range logs {
+		if record.Success {
+			applied[record.MigrationId] = true
+			status.Applied = append(status.Applied, &m.MigrationLogEntry{Id: record.MigrationId, Timestamp: record.Timestamp})
+		}
+	}
+
+	for _, record := range logs {
+		if !record.Success && !applied[record.MigrationId] {
+			status.Failed = append(status.Failed, &m.MigrationLogEntry{Id: record.MigrationId, Timestamp: record.Timestamp, Error: record.Error})
+		}
+	}
+
+	query.Result = status
+	return nil
+}
@@ -0,0 +1,48 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+)
+
+func TestMigrationStatus(t *testing.T) {
+
+	Convey("Testing migration status", t, func() {
+		InitTestDB(t)
+
+		Convey("Should list the migrations applied to a fresh store", func() {
+			query := m.GetMigrationStatusQuery{}
+			So(GetMigrationStatus(&query), ShouldBeNil)
+
+			count, err := x.Where("success=?", true).Count(&migrator.MigrationLog{})
+			So(err, ShouldBeNil)
+			So(len(query.Result.Applied), ShouldEqual, count)
+			So(query.Result.Applied[0].Id, ShouldEqual, "create migration_log table")
+			So(query.Result.Applied[0].Timestamp.IsZero(), ShouldBeFalse)
+			So(query.Result.Failed, ShouldBeEmpty)
+		})
+
+		Convey("Should list a failed migration until it is applied", func() {
+			failed := migrator.MigrationLog{MigrationId: "add broken column", Error: "no such table", Timestamp: time.Now()}
+			_, err := x.Insert(&failed)
+			So(err, ShouldBeNil)
+
+			query := m.GetMigrationStatusQuery{}
+			So(GetMigrationStatus(&query), ShouldBeNil)
+			So(len(query.Result.Failed), ShouldEqual, 1)
+			So(query.Result.Failed[0].Error, ShouldEqual, "no such table")
+
+			_, err = x.Insert(&migrator.MigrationLog{MigrationId: "add broken column", Success: true, Timestamp: time.Now()})
+			So(err, ShouldBeNil)
+
+			So(GetMigrationStatus(&query), ShouldBeNil)
+			So(query.Result.Failed, ShouldBeEmpty)
+			So(query.Result.Applied[len(query.Result.Applied)-1].Id, ShouldEqual, "add broken column")
+		})
+	})
+}
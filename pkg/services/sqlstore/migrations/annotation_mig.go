@@ -0,0 +1,46 @@
+package migrations
+
+import . "github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+
+func addAnnotationMigrations(mg *Migrator) {
+
+	annotationV1 := Table{
+		Name: "annotation",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "dashboard_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "epoch", Type: DB_BigInt, Nullable: false},
+			{Name: "text", Type: DB_Text, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "epoch"}, Type: IndexType},
+			{Cols: []string{"dashboard_id", "epoch"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create annotation table v1", NewAddTableMigration(annotationV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", annotationV1)
+
+	annotationTagV1 := Table{
+		Name: "annotation_tag",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "annotation_id", Type: DB_BigInt, Nullable: false},
+			{Name: "term", Type: DB_NVarchar, Length: 50, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"annotation_id", "term"}, Type: UniqueIndex},
+			{Cols: []string{"term"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create annotation_tag table v1", NewAddTableMigration(annotationTagV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", annotationTagV1)
+}
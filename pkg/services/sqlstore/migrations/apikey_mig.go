@@ -72,4 +72,10 @@ func addApiKeyMigrations(mg *Migrator) {
 	}))
 
 	mg.AddMigration("Drop old table api_key_v1", NewDropTableMigration("api_key_v1"))
+
+	mg.AddMigration("Add column read_only to api_key", new(AddColumnMigration).
+		Table("api_key").Column(&Column{Name: "read_only", Type: DB_Bool, Nullable: true}))
+
+	mg.AddMigration("Add column allowed_ips to api_key", new(AddColumnMigration).
+		Table("api_key").Column(&Column{Name: "allowed_ips", Type: DB_NVarchar, Length: 1024, Nullable: true}))
 }
@@ -0,0 +1,29 @@
+package migrations
+
+import . "github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+
+func addDashboardAclMigrations(mg *Migrator) {
+
+	dashboardAclV1 := Table{
+		Name: "dashboard_acl",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "dashboard_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "role", Type: DB_NVarchar, Length: 20, Nullable: true},
+			{Name: "permission", Type: DB_SmallInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"dashboard_id"}, Type: IndexType},
+			{Cols: []string{"user_id"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create dashboard_acl table v1", NewAddTableMigration(dashboardAclV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", dashboardAclV1)
+}
@@ -92,4 +92,19 @@ func addDashboardMigration(mg *Migrator) {
 		Sqlite("SELECT 0 WHERE 0;").
 		Postgres("SELECT 0;").
 		Mysql("ALTER TABLE dashboard MODIFY data MEDIUMTEXT;"))
+
+	mg.AddMigration("Add column provisioned to dashboard", new(AddColumnMigration).
+		Table("dashboard").Column(&Column{Name: "provisioned", Type: DB_Bool, Nullable: true}))
+
+	mg.AddMigration("Add column is_folder to dashboard", new(AddColumnMigration).
+		Table("dashboard").Column(&Column{Name: "is_folder", Type: DB_Bool, Nullable: true}))
+
+	mg.AddMigration("Add column parent_id to dashboard", new(AddColumnMigration).
+		Table("dashboard").Column(&Column{Name: "parent_id", Type: DB_BigInt, Nullable: true}))
+
+	mg.AddMigration("Add index dashboard.parent_id", NewAddIndexMigration(dashboardV2, &Index{Cols: []string{"parent_id"}}))
+
+	// soft delete, rows with a deleted timestamp can be restored until purged
+	mg.AddMigration("Add column deleted to dashboard", new(AddColumnMigration).
+		Table("dashboard").Column(&Column{Name: "deleted", Type: DB_DateTime, Nullable: true}))
 }
@@ -54,4 +54,10 @@ func addDashboardSnapshotMigrations(mg *Migrator) {
 		Sqlite("SELECT 0 WHERE 0;").
 		Postgres("SELECT 0;").
 		Mysql("ALTER TABLE dashboard_snapshot MODIFY dashboard MEDIUMTEXT;"))
+
+	// lets clients retry a create without getting a second snapshot
+	mg.AddMigration("Add column idempotency_key to dashboard_snapshot", new(AddColumnMigration).
+		Table("dashboard_snapshot").Column(&Column{Name: "idempotency_key", Type: DB_NVarchar, Length: 255, Nullable: true}))
+	mg.AddMigration("add index dashboard_snapshot.idempotency_key", NewAddIndexMigration(snapshotV5,
+		&Index{Cols: []string{"org_id", "user_id", "idempotency_key"}}))
 }
@@ -96,4 +96,11 @@ func addDataSourceMigration(mg *Migrator) {
 	}))
 
 	mg.AddMigration("Drop old table data_source_v1 #2", NewDropTableMigration("data_source_v1"))
+
+	// soft delete, rows with a deleted timestamp are hidden until restored
+	mg.AddMigration("Add column deleted to data_source", new(AddColumnMigration).
+		Table("data_source").Column(&Column{Name: "deleted", Type: DB_DateTime, Nullable: true}))
+
+	mg.AddMigration("Add column provisioned to data_source", new(AddColumnMigration).
+		Table("data_source").Column(&Column{Name: "provisioned", Type: DB_Bool, Nullable: true}))
 }
@@ -0,0 +1,17 @@
+package migrations
+
+import . "github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+
+func addMaintenanceMigrations(mg *Migrator) {
+
+	maintenanceV1 := Table{
+		Name: "maintenance",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "enabled", Type: DB_Bool, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+	}
+
+	mg.AddMigration("create maintenance table v1", NewAddTableMigration(maintenanceV1))
+}
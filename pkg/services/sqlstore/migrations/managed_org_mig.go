@@ -0,0 +1,25 @@
+package migrations
+
+import . "github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+
+func addManagedOrgMigrations(mg *Migrator) {
+
+	managedOrgV1 := Table{
+		Name: "managed_org",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id", "org_id"}, Type: UniqueIndex},
+			{Cols: []string{"org_id"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create managed_org table v1", NewAddTableMigration(managedOrgV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", managedOrgV1)
+}
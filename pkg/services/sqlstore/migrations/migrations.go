@@ -18,6 +18,15 @@ func AddMigrations(mg *Migrator) {
 	addApiKeyMigrations(mg)
 	addDashboardSnapshotMigrations(mg)
 	addQuotaMigration(mg)
+	addPreferencesMigrations(mg)
+	addRememberTokenMigrations(mg)
+	addManagedOrgMigrations(mg)
+	addMaintenanceMigrations(mg)
+	addOrgFeatureFlagMigrations(mg)
+	addDashboardAclMigrations(mg)
+	addTeamMigrations(mg)
+	addAnnotationMigrations(mg)
+	addUserSessionMigrations(mg)
 }
 
 func addMigrationLogMigrations(mg *Migrator) {
@@ -0,0 +1,26 @@
+package migrations
+
+import . "github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+
+func addOrgFeatureFlagMigrations(mg *Migrator) {
+
+	orgFeatureFlagV1 := Table{
+		Name: "org_feature_flag",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "enabled", Type: DB_Bool, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "name"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create org_feature_flag table v1", NewAddTableMigration(orgFeatureFlagV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", orgFeatureFlagV1)
+}
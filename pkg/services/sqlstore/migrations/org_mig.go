@@ -68,4 +68,24 @@ func addOrgMigrations(mg *Migrator) {
 
 	mg.AddMigration("Drop old table account", NewDropTableMigration("account"))
 	mg.AddMigration("Drop old table account_user", NewDropTableMigration("account_user"))
+
+	orgUserRoleHistoryV1 := Table{
+		Name: "org_user_role_history",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "old_role", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "new_role", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "actor_user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "user_id"}},
+		},
+	}
+
+	//-------  org_user_role_history table -------------------
+	mg.AddMigration("create org_user_role_history table v1", NewAddTableMigration(orgUserRoleHistoryV1))
+	addTableIndicesMigrations(mg, "v1", orgUserRoleHistoryV1)
 }
@@ -0,0 +1,29 @@
+package migrations
+
+import . "github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+
+func addPreferencesMigrations(mg *Migrator) {
+
+	preferencesV1 := Table{
+		Name: "preferences",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "version", Type: DB_Int, Nullable: false},
+			{Name: "home_dashboard_id", Type: DB_BigInt, Nullable: false},
+			{Name: "timezone", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "theme", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "user_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create preferences table v1", NewAddTableMigration(preferencesV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", preferencesV1)
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import . "github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+
+func addRememberTokenMigrations(mg *Migrator) {
+
+	rememberTokenV1 := Table{
+		Name: "remember_token",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "series_hash", Type: DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "token_hash", Type: DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"series_hash"}, Type: UniqueIndex},
+			{Cols: []string{"user_id"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create remember_token table v1", NewAddTableMigration(rememberTokenV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", rememberTokenV1)
+
+	// the token rotated away last, still accepted for a moment so that
+	// concurrent requests carrying it don't look like a stolen cookie
+	mg.AddMigration("Add column prev_token_hash to remember_token", new(AddColumnMigration).
+		Table("remember_token").Column(&Column{Name: "prev_token_hash", Type: DB_NVarchar, Length: 64, Nullable: true}))
+}
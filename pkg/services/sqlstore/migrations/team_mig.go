@@ -0,0 +1,49 @@
+package migrations
+
+import . "github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+
+func addTeamMigrations(mg *Migrator) {
+
+	teamV1 := Table{
+		Name: "team",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "name"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create team table v1", NewAddTableMigration(teamV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", teamV1)
+
+	teamMemberV1 := Table{
+		Name: "team_member",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "team_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"team_id", "user_id"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "user_id"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create team_member table v1", NewAddTableMigration(teamMemberV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", teamMemberV1)
+
+	// acl items granting a team, 0 for user and role items
+	mg.AddMigration("Add column team_id to dashboard_acl", new(AddColumnMigration).
+		Table("dashboard_acl").Column(&Column{Name: "team_id", Type: DB_BigInt, Nullable: false, Default: "0"}))
+}
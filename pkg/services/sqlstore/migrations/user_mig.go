@@ -88,4 +88,19 @@ func addUserMigrations(mg *Migrator) {
 	}))
 
 	mg.AddMigration("Drop old table user_v1", NewDropTableMigration("user_v1"))
+
+	mg.AddMigration("Add column last_seen_at to user", new(AddColumnMigration).
+		Table("user").Column(&Column{Name: "last_seen_at", Type: DB_DateTime, Nullable: true}))
+
+	mg.AddMigration("Add column is_disabled to user", new(AddColumnMigration).
+		Table("user").Column(&Column{Name: "is_disabled", Type: DB_Bool, Nullable: true}))
+
+	mg.AddMigration("Add column password_cost to user", new(AddColumnMigration).
+		Table("user").Column(&Column{Name: "password_cost", Type: DB_Int, Nullable: true}))
+
+	// users from before email verification could be required keep logging in
+	mg.AddMigration("Mark existing users email verified", new(RawSqlMigration).
+		Sqlite("UPDATE user SET email_verified = 1;").
+		Postgres("UPDATE \"user\" SET email_verified = true;").
+		Mysql("UPDATE `user` SET email_verified = 1;"))
 }
@@ -0,0 +1,34 @@
+package migrations
+
+import . "github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+
+func addUserSessionMigrations(mg *Migrator) {
+
+	userSessionV1 := Table{
+		Name: "user_session",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create user_session table v1", NewAddTableMigration(userSessionV1))
+
+	//-------  indexes ------------------
+	addTableIndicesMigrations(mg, "v1", userSessionV1)
+
+	mg.AddMigration("Add column ip to user_session", new(AddColumnMigration).
+		Table("user_session").Column(&Column{Name: "ip", Type: DB_NVarchar, Length: 64, Nullable: true}))
+
+	mg.AddMigration("Add column user_agent to user_session", new(AddColumnMigration).
+		Table("user_session").Column(&Column{Name: "user_agent", Type: DB_NVarchar, Length: 255, Nullable: true}))
+
+	// the remember me series renewing the login, it keeps its session
+	mg.AddMigration("Add column remember_series_hash to user_session", new(AddColumnMigration).
+		Table("user_session").Column(&Column{Name: "remember_series_hash", Type: DB_NVarchar, Length: 64, Nullable: true}))
+}
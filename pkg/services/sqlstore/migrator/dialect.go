@@ -17,6 +17,8 @@ type Dialect interface {
 	SqlType(col *Column) string
 	SupportEngine() bool
 	LikeStr() string
+	ILike(column string) string
+	Concat(strs ...string) string
 
 	CreateIndexSql(tableName string, index *Index) string
 	CreateTableSql(table *Table) string
@@ -63,6 +65,15 @@ func (b *BaseDialect) LikeStr() string {
 	return "LIKE"
 }
 
+// ILike returns a case insensitive "column LIKE ?" condition.
+func (b *BaseDialect) ILike(column string) string {
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", column)
+}
+
+func (b *BaseDialect) Concat(strs ...string) string {
+	return strings.Join(strs, " || ")
+}
+
 func (b *BaseDialect) OrStr() string {
 	return "OR"
 }
@@ -1,6 +1,9 @@
 package migrator
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 type Mysql struct {
 	BaseDialect
@@ -25,6 +28,15 @@ func (db *Mysql) QuoteStr() string {
 	return "`"
 }
 
+// ILike relies on the default collations, which ignore case.
+func (db *Mysql) ILike(column string) string {
+	return column + " LIKE ?"
+}
+
+func (db *Mysql) Concat(strs ...string) string {
+	return "CONCAT(" + strings.Join(strs, ", ") + ")"
+}
+
 func (db *Mysql) AutoIncrStr() string {
 	return "AUTO_INCREMENT"
 }
@@ -32,6 +32,10 @@ func (b *Postgres) LikeStr() string {
 	return "ILIKE"
 }
 
+func (b *Postgres) ILike(column string) string {
+	return column + " ILIKE ?"
+}
+
 func (db *Postgres) AutoIncrStr() string {
 	return ""
 }
@@ -1,11 +1,16 @@
 package sqlstore
 
 import (
+	"strings"
 	"time"
 
+	"github.com/go-xorm/xorm"
+	"golang.org/x/net/context"
+
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/events"
 	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
 )
 
 func init() {
@@ -18,24 +23,75 @@ func init() {
 	bus.AddHandler("sql", DeleteOrg)
 }
 
-func SearchOrgs(query *m.SearchOrgsQuery) error {
-	query.Result = make([]*m.OrgDTO, 0)
-	sess := x.Table("org")
+func SearchOrgs(ctx context.Context, query *m.SearchOrgsQuery) error {
+	result := make([]*m.OrgDTO, 0)
+	err := withContext(ctx, func() error {
+		sess := readSession()
+		defer sess.Close()
+
+		_, args := orgSearchFilter(dialect, query)
+
+		filterOrgs(sess.Table("org"), query)
+		sess.Limit(query.Limit, query.Limit*query.Page)
+		sess.Cols("id", "name")
+
+		return timeQuery("search orgs", args, func() error {
+			return sess.Find(&result)
+		})
+	}, func() error {
+		countSess := readSession()
+		defer countSess.Close()
+
+		total, err := filterOrgs(countSess, query).Count(&m.Org{})
+		query.TotalCount = total
+		return err
+	})
+
+	if err == nil {
+		query.Result = result
+	}
+	return err
+}
+
+func filterOrgs(sess *xorm.Session, query *m.SearchOrgsQuery) *xorm.Session {
+	if where, args := orgSearchFilter(dialect, query); where != "" {
+		sess.Where(where, args...)
+	}
+	return sess
+}
+
+// orgSearchFilter returns the where clause of an org search, names are
+// matched by prefix ignoring case.
+func orgSearchFilter(d migrator.Dialect, query *m.SearchOrgsQuery) (string, []interface{}) {
+	conds := make([]string, 0, 2)
+	args := make([]interface{}, 0, 2)
+
 	if query.Query != "" {
-		sess.Where("name LIKE ?", query.Query+"%")
+		conds = append(conds, d.ILike("name"))
+		args = append(args, query.Query+"%")
 	}
 	if query.Name != "" {
-		sess.Where("name=?", query.Name)
+		conds = append(conds, "name=?")
+		args = append(args, query.Name)
 	}
-	sess.Limit(query.Limit, query.Limit*query.Page)
-	sess.Cols("id", "name")
-	err := sess.Find(&query.Result)
-	return err
+
+	return strings.Join(conds, " AND "), args
 }
 
 func GetOrgById(query *m.GetOrgByIdQuery) error {
+	if org, ok := orgsById.get(query.Id); ok {
+		query.Result = org
+		return nil
+	}
+
+	version := orgsById.currentVersion()
+
 	var org m.Org
-	exists, err := x.Id(query.Id).Get(&org)
+	var exists bool
+	err := timeQuery("get org by id", []interface{}{query.Id}, func() (err error) {
+		exists, err = x.Id(query.Id).Get(&org)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -44,13 +100,18 @@ func GetOrgById(query *m.GetOrgByIdQuery) error {
 		return m.ErrOrgNotFound
 	}
 
+	orgsById.add(&org, version)
 	query.Result = &org
 	return nil
 }
 
 func GetOrgByName(query *m.GetOrgByNameQuery) error {
 	var org m.Org
-	exists, err := x.Where("name=?", query.Name).Get(&org)
+	var exists bool
+	err := timeQuery("get org by name", []interface{}{query.Name}, func() (err error) {
+		exists, err = x.Where("name=?", query.Name).Get(&org)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -66,7 +127,11 @@ func GetOrgByName(query *m.GetOrgByNameQuery) error {
 func isOrgNameTaken(name string, existingId int64, sess *session) (bool, error) {
 	// check if org name is taken
 	var org m.Org
-	exists, err := sess.Where("name=?", name).Get(&org)
+	var exists bool
+	err := timeQuery("check org name", []interface{}{name}, func() (err error) {
+		exists, err = sess.Where("name=?", name).Get(&org)
+		return err
+	})
 
 	if err != nil {
 		return false, nil
@@ -94,7 +159,11 @@ func CreateOrg(cmd *m.CreateOrgCommand) error {
 			Updated: time.Now(),
 		}
 
-		if _, err := sess.Insert(&org); err != nil {
+		err := timeQuery("insert org", nil, func() error {
+			_, err := sess.Insert(&org)
+			return err
+		})
+		if err != nil {
 			return err
 		}
 
@@ -106,7 +175,10 @@ func CreateOrg(cmd *m.CreateOrgCommand) error {
 			Updated: time.Now(),
 		}
 
-		_, err := sess.Insert(&user)
+		err = timeQuery("insert org user", nil, func() error {
+			_, err := sess.Insert(&user)
+			return err
+		})
 		cmd.Result = org
 
 		sess.publishAfterCommit(&events.OrgCreated{
@@ -133,13 +205,17 @@ func UpdateOrg(cmd *m.UpdateOrgCommand) error {
 			Updated: time.Now(),
 		}
 
-		if _, err := sess.Id(cmd.OrgId).Update(&org); err != nil {
+		err := timeQuery("update org", []interface{}{cmd.OrgId}, func() error {
+			_, err := sess.Id(cmd.OrgId).Update(&org)
+			return err
+		})
+		if err != nil {
 			return err
 		}
 
 		sess.publishAfterCommit(&events.OrgUpdated{
 			Timestamp: org.Updated,
-			Id:        org.Id,
+			Id:        cmd.OrgId,
 			Name:      org.Name,
 		})
 
@@ -160,13 +236,17 @@ func UpdateOrgAddress(cmd *m.UpdateOrgAddressCommand) error {
 			Updated: time.Now(),
 		}
 
-		if _, err := sess.Id(cmd.OrgId).Update(&org); err != nil {
+		err := timeQuery("update org address", []interface{}{cmd.OrgId}, func() error {
+			_, err := sess.Id(cmd.OrgId).Update(&org)
+			return err
+		})
+		if err != nil {
 			return err
 		}
 
 		sess.publishAfterCommit(&events.OrgUpdated{
 			Timestamp: org.Updated,
-			Id:        org.Id,
+			Id:        cmd.OrgId,
 			Name:      org.Name,
 		})
 
@@ -184,17 +264,34 @@ func DeleteOrg(cmd *m.DeleteOrgCommand) error {
 			"DELETE FROM api_key WHERE org_id = ?",
 			"DELETE FROM data_source WHERE org_id = ?",
 			"DELETE FROM org_user WHERE org_id = ?",
+			"DELETE FROM org_user_role_history WHERE org_id = ?",
+			"DELETE FROM managed_org WHERE org_id = ?",
+			"DELETE FROM dashboard_acl WHERE org_id = ?",
+			"DELETE FROM org_feature_flag WHERE org_id = ?",
+			"DELETE FROM team_member WHERE org_id = ?",
+			"DELETE FROM team WHERE org_id = ?",
+			"DELETE FROM annotation_tag WHERE EXISTS (SELECT 1 FROM annotation WHERE org_id = ? AND annotation_tag.annotation_id = annotation.id)",
+			"DELETE FROM annotation WHERE org_id = ?",
 			"DELETE FROM org WHERE id = ?",
 			"DELETE FROM temp_user WHERE org_id = ?",
+			"DELETE FROM preferences WHERE org_id = ?",
 		}
 
 		for _, sql := range deletes {
-			_, err := sess.Exec(sql, cmd.Id)
+			err := timeQuery(sql, []interface{}{cmd.Id}, func() error {
+				_, err := sess.Exec(sql, cmd.Id)
+				return err
+			})
 			if err != nil {
 				return err
 			}
 		}
 
+		sess.publishAfterCommit(&events.OrgDeleted{
+			Timestamp: time.Now(),
+			Id:        cmd.Id,
+		})
+
 		return nil
 	})
 }
@@ -0,0 +1,135 @@
+package sqlstore
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/events"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+const (
+	orgCacheSize = 1000
+	// orgCacheTTL bounds how long an org changed by another instance sharing
+	// the database is served stale, updates here drop it right away.
+	orgCacheTTL = time.Minute
+)
+
+// orgCache is a small lru cache of orgs by id. Orgs change rarely but are
+// looked up on most requests. It hands out copies so callers can't change
+// the cached org.
+type orgCache struct {
+	sync.Mutex
+	size  int
+	ttl   time.Duration
+	now   func() time.Time
+	order *list.List
+	items map[int64]*list.Element
+	// version changes on every invalidation, an org read from the database
+	// before that is not cached.
+	version uint64
+}
+
+type orgCacheEntry struct {
+	org     m.Org
+	expires time.Time
+}
+
+var orgsById = newOrgCache(orgCacheSize, orgCacheTTL)
+
+func init() {
+	bus.AddEventListener(func(evt *events.OrgUpdated) error {
+		orgsById.remove(evt.Id)
+		return nil
+	})
+	bus.AddEventListener(func(evt *events.OrgDeleted) error {
+		orgsById.remove(evt.Id)
+		return nil
+	})
+}
+
+func newOrgCache(size int, ttl time.Duration) *orgCache {
+	return &orgCache{
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		order: list.New(),
+		items: make(map[int64]*list.Element),
+	}
+}
+
+func (c *orgCache) get(id int64) (*m.Org, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	elem, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*orgCacheEntry)
+	if c.now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.items, id)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	org := entry.org
+	return &org, true
+}
+
+// currentVersion is taken before reading an org from the database and
+// handed to add along with it.
+func (c *orgCache) currentVersion() uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.version
+}
+
+// add caches org unless the cache was invalidated since version was taken,
+// the org may then be older than the change that invalidated it.
+func (c *orgCache) add(org *m.Org, version uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	if version != c.version {
+		return
+	}
+
+	entry := &orgCacheEntry{org: *org, expires: c.now().Add(c.ttl)}
+	if elem, ok := c.items[org.Id]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[org.Id] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*orgCacheEntry).org.Id)
+	}
+}
+
+func (c *orgCache) remove(id int64) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.version++
+	if elem, ok := c.items[id]; ok {
+		c.order.Remove(elem)
+		delete(c.items, id)
+	}
+}
+
+func (c *orgCache) clear() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.version++
+	c.order.Init()
+	c.items = make(map[int64]*list.Element)
+}
@@ -0,0 +1,101 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestOrgCache(t *testing.T) {
+
+	Convey("Testing org by id cache", t, func() {
+		InitTestDB(t)
+
+		cmd := m.CreateOrgCommand{Name: "cached org"}
+		So(CreateOrg(&cmd), ShouldBeNil)
+		orgId := cmd.Result.Id
+
+		query := m.GetOrgByIdQuery{Id: orgId}
+		So(GetOrgById(&query), ShouldBeNil)
+
+		Convey("Should serve a repeated lookup from the cache", func() {
+			_, err := x.Exec("UPDATE org SET name=? WHERE id=?", "changed behind the cache", orgId)
+			So(err, ShouldBeNil)
+
+			cached := m.GetOrgByIdQuery{Id: orgId}
+			So(GetOrgById(&cached), ShouldBeNil)
+			So(cached.Result.Name, ShouldEqual, "cached org")
+
+			Convey("And hand out copies", func() {
+				cached.Result.Name = "changed by caller"
+
+				again := m.GetOrgByIdQuery{Id: orgId}
+				So(GetOrgById(&again), ShouldBeNil)
+				So(again.Result.Name, ShouldEqual, "cached org")
+			})
+		})
+
+		Convey("Should drop an updated org", func() {
+			So(UpdateOrg(&m.UpdateOrgCommand{OrgId: orgId, Name: "renamed org"}), ShouldBeNil)
+
+			updated := m.GetOrgByIdQuery{Id: orgId}
+			So(GetOrgById(&updated), ShouldBeNil)
+			So(updated.Result.Name, ShouldEqual, "renamed org")
+		})
+
+		Convey("Should drop a deleted org", func() {
+			So(DeleteOrg(&m.DeleteOrgCommand{Id: orgId}), ShouldBeNil)
+
+			deleted := m.GetOrgByIdQuery{Id: orgId}
+			So(GetOrgById(&deleted), ShouldEqual, m.ErrOrgNotFound)
+		})
+
+		Convey("Should evict the least recently used org when full", func() {
+			cache := newOrgCache(2, time.Minute)
+			cache.add(&m.Org{Id: 1, Name: "one"}, 0)
+			cache.add(&m.Org{Id: 2, Name: "two"}, 0)
+
+			_, ok := cache.get(1)
+			So(ok, ShouldBeTrue)
+
+			cache.add(&m.Org{Id: 3, Name: "three"}, 0)
+
+			_, ok = cache.get(2)
+			So(ok, ShouldBeFalse)
+			_, ok = cache.get(1)
+			So(ok, ShouldBeTrue)
+			_, ok = cache.get(3)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Should expire orgs after the ttl", func() {
+			now := time.Now()
+			cache := newOrgCache(2, time.Minute)
+			cache.now = func() time.Time { return now }
+			cache.add(&m.Org{Id: 1, Name: "one"}, 0)
+
+			now = now.Add(30 * time.Second)
+			_, ok := cache.get(1)
+			So(ok, ShouldBeTrue)
+
+			now = now.Add(time.Minute)
+			_, ok = cache.get(1)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Should not cache an org read before an update", func() {
+			cache := newOrgCache(2, time.Minute)
+			version := cache.currentVersion()
+			stale := &m.Org{Id: 1, Name: "before the update"}
+
+			cache.remove(1)
+			cache.add(stale, version)
+
+			_, ok := cache.get(1)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,117 @@
+package sqlstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/events"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+// orgFeatureFlagCache holds the flags each org overrides, they are read
+// on every frontend settings request.
+type orgFeatureFlagCache struct {
+	sync.Mutex
+	items map[int64]map[string]bool
+}
+
+var orgFeatureFlags = &orgFeatureFlagCache{items: make(map[int64]map[string]bool)}
+
+func init() {
+	bus.AddHandler("sql", GetOrgFeatureFlags)
+	bus.AddHandler("sql", SetOrgFeatureFlag)
+
+	bus.AddEventListener(func(evt *events.OrgDeleted) error {
+		orgFeatureFlags.remove(evt.Id)
+		return nil
+	})
+}
+
+func (c *orgFeatureFlagCache) get(orgId int64) (map[string]bool, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	flags, ok := c.items[orgId]
+	return flags, ok
+}
+
+func (c *orgFeatureFlagCache) add(orgId int64, flags map[string]bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.items[orgId] = flags
+}
+
+func (c *orgFeatureFlagCache) remove(orgId int64) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.items, orgId)
+}
+
+func GetOrgFeatureFlags(query *m.GetOrgFeatureFlagsQuery) error {
+	overrides, ok := orgFeatureFlags.get(query.OrgId)
+	if !ok {
+		var rows []*m.OrgFeatureFlag
+		if err := x.Where("org_id=?", query.OrgId).Find(&rows); err != nil {
+			return err
+		}
+
+		overrides = make(map[string]bool, len(rows))
+		for _, row := range rows {
+			overrides[row.Name] = row.Enabled
+		}
+		orgFeatureFlags.add(query.OrgId, overrides)
+	}
+
+	// flags dropped from the config are ignored even if an org set them
+	query.Result = make(map[string]bool, len(setting.FeatureFlags))
+	for name, enabled := range setting.FeatureFlags {
+		if override, ok := overrides[name]; ok {
+			enabled = override
+		}
+		query.Result[name] = enabled
+	}
+
+	return nil
+}
+
+func SetOrgFeatureFlag(cmd *m.SetOrgFeatureFlagCommand) error {
+	if _, ok := setting.FeatureFlags[cmd.Name]; !ok {
+		return m.ErrFeatureFlagNotFound
+	}
+
+	err := inTransaction2(func(sess *session) error {
+		if has, err := sess.Id(cmd.OrgId).Get(&m.Org{}); err != nil {
+			return err
+		} else if !has {
+			return m.ErrOrgNotFound
+		}
+
+		var flag m.OrgFeatureFlag
+		has, err := sess.Where("org_id=? AND name=?", cmd.OrgId, cmd.Name).Get(&flag)
+		if err != nil {
+			return err
+		}
+
+		flag.Enabled = cmd.Enabled
+		flag.Updated = time.Now()
+
+		if has {
+			_, err = sess.Id(flag.Id).Cols("enabled", "updated").Update(&flag)
+			return err
+		}
+
+		flag.OrgId = cmd.OrgId
+		flag.Name = cmd.Name
+		flag.Created = flag.Updated
+		_, err = sess.Insert(&flag)
+		return err
+	})
+
+	// drop the cache even on failure, the row may have changed underneath
+	orgFeatureFlags.remove(cmd.OrgId)
+	return err
+}
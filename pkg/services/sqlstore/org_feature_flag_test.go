@@ -0,0 +1,85 @@
+package sqlstore
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+func TestOrgFeatureFlags(t *testing.T) {
+
+	Convey("Testing org feature flags", t, func() {
+		InitTestDB(t)
+		orgFeatureFlags.items = make(map[int64]map[string]bool)
+
+		defaults := setting.FeatureFlags
+		setting.FeatureFlags = map[string]bool{"new_editor": false, "live": true}
+		defer func() { setting.FeatureFlags = defaults }()
+
+		org1 := m.CreateOrgCommand{Name: "flagged org"}
+		So(CreateOrg(&org1), ShouldBeNil)
+		org2 := m.CreateOrgCommand{Name: "other org"}
+		So(CreateOrg(&org2), ShouldBeNil)
+
+		getFlags := func(orgId int64) map[string]bool {
+			query := m.GetOrgFeatureFlagsQuery{OrgId: orgId}
+			So(GetOrgFeatureFlags(&query), ShouldBeNil)
+			return query.Result
+		}
+
+		Convey("Should resolve to the defaults", func() {
+			So(getFlags(org1.Result.Id), ShouldResemble, map[string]bool{"new_editor": false, "live": true})
+		})
+
+		Convey("When a flag is set for an org", func() {
+			// fill the cache first, setting must invalidate it
+			getFlags(org1.Result.Id)
+
+			So(SetOrgFeatureFlag(&m.SetOrgFeatureFlagCommand{OrgId: org1.Result.Id, Name: "new_editor", Enabled: true}), ShouldBeNil)
+			So(SetOrgFeatureFlag(&m.SetOrgFeatureFlagCommand{OrgId: org1.Result.Id, Name: "live", Enabled: false}), ShouldBeNil)
+
+			Convey("Should be resolved for that org", func() {
+				So(getFlags(org1.Result.Id), ShouldResemble, map[string]bool{"new_editor": true, "live": false})
+			})
+
+			Convey("Should not change other orgs", func() {
+				So(getFlags(org2.Result.Id), ShouldResemble, map[string]bool{"new_editor": false, "live": true})
+			})
+
+			Convey("Should update the flag when set again", func() {
+				So(SetOrgFeatureFlag(&m.SetOrgFeatureFlagCommand{OrgId: org1.Result.Id, Name: "new_editor", Enabled: false}), ShouldBeNil)
+				So(getFlags(org1.Result.Id)["new_editor"], ShouldBeFalse)
+
+				count, err := x.Where("org_id=?", org1.Result.Id).Count(&m.OrgFeatureFlag{})
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 2)
+			})
+
+			Convey("Should ignore flags dropped from the config", func() {
+				setting.FeatureFlags = map[string]bool{"live": true}
+				So(getFlags(org1.Result.Id), ShouldResemble, map[string]bool{"live": false})
+			})
+
+			Convey("Should remove the flags with the org", func() {
+				So(DeleteOrg(&m.DeleteOrgCommand{Id: org1.Result.Id}), ShouldBeNil)
+
+				count, err := x.Where("org_id=?", org1.Result.Id).Count(&m.OrgFeatureFlag{})
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 0)
+			})
+		})
+
+		Convey("Should reject unknown flags", func() {
+			err := SetOrgFeatureFlag(&m.SetOrgFeatureFlagCommand{OrgId: org1.Result.Id, Name: "unknown", Enabled: true})
+			So(err, ShouldEqual, m.ErrFeatureFlagNotFound)
+		})
+
+		Convey("Should reject unknown orgs", func() {
+			err := SetOrgFeatureFlag(&m.SetOrgFeatureFlagCommand{OrgId: 9999, Name: "live", Enabled: true})
+			So(err, ShouldEqual, m.ErrOrgNotFound)
+		})
+	})
+}
@@ -1,11 +1,16 @@
 package sqlstore
 
 import (
+	"fmt"
 	"testing"
+	"time"
+
+	"golang.org/x/net/context"
 
 	. "github.com/smartystreets/goconvey/convey"
 
 	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
 	"github.com/Cepave/grafana/pkg/setting"
 )
 
@@ -15,6 +20,7 @@ func TestAccountDataAccess(t *testing.T) {
 
 		Convey("Given single org mode", func() {
 			setting.AutoAssignOrg = true
+			setting.AutoAssignOrgId = 1
 			setting.AutoAssignOrgRole = "Viewer"
 
 			Convey("Users should be added to default organization", func() {
@@ -36,6 +42,48 @@ func TestAccountDataAccess(t *testing.T) {
 			})
 		})
 
+		Convey("Given auto assign to a configured org", func() {
+			setting.AutoAssignOrg = true
+			setting.AutoAssignOrgId = 2
+			setting.AutoAssignOrgRole = "Editor"
+
+			Convey("Users should be added to that org with the configured role", func() {
+				cmd := m.CreateUserCommand{Login: "ac1", Email: "ac1@test.com"}
+				So(CreateUser(&cmd), ShouldBeNil)
+
+				query := m.GetUserOrgListQuery{UserId: cmd.Result.Id}
+				So(GetUserOrgList(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].OrgId, ShouldEqual, 2)
+				So(query.Result[0].Role, ShouldEqual, "Editor")
+			})
+
+			Convey("Unknown roles should be rejected", func() {
+				setting.AutoAssignOrgRole = "Owner"
+
+				cmd := m.CreateUserCommand{Login: "ac1", Email: "ac1@test.com"}
+				So(CreateUser(&cmd), ShouldEqual, m.ErrInvalidRoleType)
+
+				query := m.GetUserByLoginQuery{LoginOrEmail: "ac1"}
+				So(GetUserByLogin(&query), ShouldEqual, m.ErrUserNotFound)
+			})
+		})
+
+		Convey("Given auto assign is off", func() {
+			setting.AutoAssignOrg = false
+
+			Convey("Users should get an org of their own as admin", func() {
+				cmd := m.CreateUserCommand{Login: "ac1", Email: "ac1@test.com", OrgName: "ac1 org"}
+				So(CreateUser(&cmd), ShouldBeNil)
+
+				query := m.GetUserOrgListQuery{UserId: cmd.Result.Id}
+				So(GetUserOrgList(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Name, ShouldEqual, "ac1 org")
+				So(query.Result[0].Role, ShouldEqual, m.ROLE_ADMIN)
+			})
+		})
+
 		Convey("Given two saved users", func() {
 			setting.AutoAssignOrg = false
 
@@ -58,15 +106,210 @@ func TestAccountDataAccess(t *testing.T) {
 				So(query.Result.Login, ShouldEqual, "ac1")
 			})
 
+			Convey("Can get many users by id", func() {
+				query := m.GetUsersByIdsQuery{Ids: []int64{ac2.Id, 1000, ac1.Id}}
+				So(GetUsersByIds(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 2)
+				So(query.Result[0].Login, ShouldEqual, "ac1")
+				So(query.Result[0].Email, ShouldEqual, "ac1@test.com")
+				So(query.Result[1].Login, ShouldEqual, "ac2")
+				So(query.Result[1].AvatarUrl, ShouldEqual, m.GetAvatarUrl("ac2@test.com"))
+
+				Convey("Should return no users for no ids", func() {
+					empty := m.GetUsersByIdsQuery{}
+					So(GetUsersByIds(&empty), ShouldBeNil)
+					So(len(empty.Result), ShouldEqual, 0)
+				})
+			})
+
+			Convey("Can disable and enable a user", func() {
+				So(DisableUser(&m.DisableUserCommand{UserId: ac1.Id}), ShouldBeNil)
+
+				signedIn := m.GetSignedInUserQuery{UserId: ac1.Id}
+				So(GetSignedInUser(&signedIn), ShouldBeNil)
+				So(signedIn.Result.IsDisabled, ShouldBeTrue)
+
+				Convey("Should still list the disabled user in searches", func() {
+					query := m.SearchUsersQuery{Query: "ac1"}
+					So(SearchUsers(context.Background(), &query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].IsDisabled, ShouldBeTrue)
+				})
+
+				Convey("Should clear the flag when enabled", func() {
+					So(EnableUser(&m.EnableUserCommand{UserId: ac1.Id}), ShouldBeNil)
+
+					So(GetSignedInUser(&signedIn), ShouldBeNil)
+					So(signedIn.Result.IsDisabled, ShouldBeFalse)
+				})
+			})
+
+			Convey("Cannot disable an unknown user", func() {
+				err := DisableUser(&m.DisableUserCommand{UserId: 1000})
+				So(err, ShouldEqual, m.ErrUserNotFound)
+			})
+
 			Convey("Can search users", func() {
 				query := m.SearchUsersQuery{Query: ""}
-				err := SearchUsers(&query)
+				err := SearchUsers(context.Background(), &query)
 
 				So(err, ShouldBeNil)
 				So(query.Result[0].Email, ShouldEqual, "ac1@test.com")
 				So(query.Result[1].Email, ShouldEqual, "ac2@test.com")
 			})
 
+			Convey("Should count all matches when searching a page", func() {
+				users := m.SearchUsersQuery{Query: "", Page: 1, Limit: 1}
+				So(SearchUsers(context.Background(), &users), ShouldBeNil)
+				So(len(users.Result), ShouldEqual, 1)
+				So(users.Result[0].Email, ShouldEqual, "ac2@test.com")
+				So(users.TotalCount, ShouldEqual, 2)
+
+				orgs := m.SearchOrgsQuery{Query: "ac", Page: 0, Limit: 1}
+				So(SearchOrgs(context.Background(), &orgs), ShouldBeNil)
+				So(len(orgs.Result), ShouldEqual, 1)
+				So(orgs.TotalCount, ShouldEqual, 2)
+			})
+
+			Convey("Should search orgs ignoring case", func() {
+				orgs := m.SearchOrgsQuery{Query: "AC", Limit: 10}
+				So(SearchOrgs(context.Background(), &orgs), ShouldBeNil)
+				So(len(orgs.Result), ShouldEqual, 2)
+				So(orgs.TotalCount, ShouldEqual, 2)
+			})
+
+			Convey("Should stop searching once the context is cancelled", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				users := m.SearchUsersQuery{Query: ""}
+				So(SearchUsers(ctx, &users), ShouldEqual, context.Canceled)
+				So(users.Result, ShouldBeNil)
+
+				orgs := m.SearchOrgsQuery{}
+				So(SearchOrgs(ctx, &orgs), ShouldEqual, context.Canceled)
+				So(orgs.Result, ShouldBeNil)
+			})
+
+			Convey("Cannot create a user with an email that only differs in case", func() {
+				cmd := m.CreateUserCommand{Login: "a", Email: "a@x.com"}
+				So(CreateUser(&cmd), ShouldBeNil)
+
+				dup := m.CreateUserCommand{Login: "a2", Email: "A@X.com"}
+				So(CreateUser(&dup), ShouldEqual, m.ErrUserEmailTaken)
+
+				query := m.GetUserByLoginQuery{LoginOrEmail: "a2"}
+				So(GetUserByLogin(&query), ShouldEqual, m.ErrUserNotFound)
+			})
+
+			Convey("Cannot create a user with a login that only differs in case", func() {
+				cmd := m.CreateUserCommand{Login: "Bob", Email: "bob@x.com"}
+				So(CreateUser(&cmd), ShouldBeNil)
+				So(cmd.Result.Login, ShouldEqual, "Bob")
+
+				dup := m.CreateUserCommand{Login: "bob", Email: "bob2@x.com"}
+				So(CreateUser(&dup), ShouldEqual, m.ErrUserLoginTaken)
+			})
+
+			Convey("Logins should be trimmed before they are stored and compared", func() {
+				cmd := m.CreateUserCommand{Login: "  Carol ", Email: "carol@x.com"}
+				So(CreateUser(&cmd), ShouldBeNil)
+				So(cmd.Result.Login, ShouldEqual, "Carol")
+
+				query := m.GetUserByLoginQuery{LoginOrEmail: "Carol"}
+				So(GetUserByLogin(&query), ShouldBeNil)
+
+				dup := m.CreateUserCommand{Login: "carol\t", Email: "carol2@x.com"}
+				So(CreateUser(&dup), ShouldEqual, m.ErrUserLoginTaken)
+			})
+
+			Convey("Deleting a user should remove the rows that belong to them", func() {
+				So(StarDashboard(&m.StarDashboardCommand{UserId: ac1.Id, DashboardId: 1}), ShouldBeNil)
+				So(SavePreferences(&m.SavePreferencesCommand{UserId: ac1.Id, OrgId: ac1.OrgId, Theme: "dark"}), ShouldBeNil)
+				So(UpdateUserQuota(&m.UpdateUserQuotaCmd{UserId: ac1.Id, Target: "org_user", Limit: 5}), ShouldBeNil)
+				So(CreateRememberToken(&m.CreateRememberTokenCommand{UserId: ac1.Id, Series: "s", Token: "t"}), ShouldBeNil)
+
+				So(DeleteUser(&m.DeleteUserCommand{UserId: ac1.Id}), ShouldBeNil)
+
+				for _, table := range []string{"star", "org_user", "preferences", "quota", "remember_token"} {
+					rows, err := x.Query("SELECT 1 FROM "+table+" WHERE user_id=?", ac1.Id)
+					So(err, ShouldBeNil)
+					So(len(rows), ShouldEqual, 0)
+				}
+
+				query := m.GetUserByLoginQuery{LoginOrEmail: "ac1"}
+				So(GetUserByLogin(&query), ShouldEqual, m.ErrUserNotFound)
+			})
+
+			Convey("Cannot delete the last admin of an org with other members", func() {
+				So(AddOrgUser(&m.AddOrgUserCommand{OrgId: ac1.OrgId, UserId: ac2.Id, Role: m.ROLE_VIEWER}), ShouldBeNil)
+				So(DeleteUser(&m.DeleteUserCommand{UserId: ac1.Id}), ShouldEqual, m.ErrLastOrgAdmin)
+
+				Convey("Unless there is another admin", func() {
+					So(UpdateOrgUser(&m.UpdateOrgUserCommand{OrgId: ac1.OrgId, UserId: ac2.Id, Role: m.ROLE_ADMIN}), ShouldBeNil)
+					So(DeleteUser(&m.DeleteUserCommand{UserId: ac1.Id}), ShouldBeNil)
+				})
+			})
+
+			Convey("Cannot delete the last grafana admin", func() {
+				So(DeleteUser(&m.DeleteUserCommand{UserId: ac2.Id}), ShouldEqual, m.ErrLastGrafanaAdmin)
+
+				Convey("Unless there is another one", func() {
+					So(UpdateUserPermissions(&m.UpdateUserPermissionsCommand{UserId: ac1.Id, IsGrafanaAdmin: true}), ShouldBeNil)
+					So(DeleteUser(&m.DeleteUserCommand{UserId: ac2.Id}), ShouldBeNil)
+				})
+
+				Convey("Not even when the other one is disabled", func() {
+					So(UpdateUserPermissions(&m.UpdateUserPermissionsCommand{UserId: ac1.Id, IsGrafanaAdmin: true}), ShouldBeNil)
+					_, err := x.Exec("UPDATE "+dialect.Quote("user")+" SET is_disabled=? WHERE id=?", true, ac1.Id)
+					So(err, ShouldBeNil)
+					So(DeleteUser(&m.DeleteUserCommand{UserId: ac2.Id}), ShouldEqual, m.ErrLastGrafanaAdmin)
+				})
+			})
+
+			Convey("Cannot downgrade the last grafana admin", func() {
+				cmd := m.UpdateUserPermissionsCommand{UserId: ac2.Id, IsGrafanaAdmin: false}
+				So(UpdateUserPermissions(&cmd), ShouldEqual, m.ErrLastGrafanaAdmin)
+
+				query := m.GetSignedInUserQuery{UserId: ac2.Id}
+				So(GetSignedInUser(&query), ShouldBeNil)
+				So(query.Result.IsGrafanaAdmin, ShouldBeTrue)
+
+				Convey("Unless there is another one", func() {
+					So(UpdateUserPermissions(&m.UpdateUserPermissionsCommand{UserId: ac1.Id, IsGrafanaAdmin: true}), ShouldBeNil)
+					So(UpdateUserPermissions(&cmd), ShouldBeNil)
+				})
+			})
+
+			Convey("Can list resources a user would leave behind", func() {
+				insertTestDashboard("ac1 dash", ac1.OrgId)
+				insertTestDashboard("ac2 dash", ac2.OrgId)
+				So(AddApiKey(&m.AddApiKeyCommand{OrgId: ac1.OrgId, Name: "ac1 key", Role: m.ROLE_VIEWER, Key: "k1"}), ShouldBeNil)
+				So(CreateDashboardSnapshot(&m.CreateDashboardSnapshotCommand{Key: "ac1-snap", UserId: ac1.Id, OrgId: ac1.OrgId, Dashboard: map[string]interface{}{}}), ShouldBeNil)
+
+				query := m.GetUserOwnedResourcesQuery{UserId: ac1.Id}
+				So(GetUserOwnedResources(&query), ShouldBeNil)
+
+				So(len(query.Result.SoleAdminOrgs), ShouldEqual, 1)
+				So(query.Result.SoleAdminOrgs[0].Id, ShouldEqual, ac1.OrgId)
+				So(len(query.Result.Dashboards), ShouldEqual, 1)
+				So(query.Result.Dashboards[0].Title, ShouldEqual, "ac1 dash")
+				So(len(query.Result.ApiKeys), ShouldEqual, 1)
+				So(query.Result.ApiKeys[0].Name, ShouldEqual, "ac1 key")
+				So(len(query.Result.Snapshots), ShouldEqual, 1)
+				So(query.Result.Snapshots[0].Key, ShouldEqual, "ac1-snap")
+
+				Convey("Should not list orgs that have another admin", func() {
+					So(AddOrgUser(&m.AddOrgUserCommand{OrgId: ac1.OrgId, UserId: ac2.Id, Role: m.ROLE_ADMIN}), ShouldBeNil)
+
+					query := m.GetUserOwnedResourcesQuery{UserId: ac1.Id}
+					So(GetUserOwnedResources(&query), ShouldBeNil)
+					So(len(query.Result.SoleAdminOrgs), ShouldEqual, 0)
+					So(len(query.Result.Dashboards), ShouldEqual, 0)
+					So(len(query.Result.ApiKeys), ShouldEqual, 0)
+				})
+			})
+
 			Convey("Given an added org user", func() {
 				cmd := m.AddOrgUserCommand{
 					OrgId:  ac1.OrgId,
@@ -92,6 +335,70 @@ func TestAccountDataAccess(t *testing.T) {
 
 				})
 
+				Convey("Should record who changed an org user role", func() {
+					updateCmd := m.UpdateOrgUserCommand{OrgId: ac1.OrgId, UserId: ac2.Id, Role: m.ROLE_EDITOR, ActorUserId: ac1.Id}
+					So(UpdateOrgUser(&updateCmd), ShouldBeNil)
+
+					query := m.GetOrgUserRoleHistoryQuery{OrgId: ac1.OrgId, UserId: ac2.Id}
+					So(GetOrgUserRoleHistory(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].OldRole, ShouldEqual, m.ROLE_VIEWER)
+					So(query.Result[0].NewRole, ShouldEqual, m.ROLE_EDITOR)
+					So(query.Result[0].ActorUserId, ShouldEqual, ac1.Id)
+					So(query.Result[0].Created.IsZero(), ShouldBeFalse)
+
+					Convey("Should not record an update that keeps the role", func() {
+						So(UpdateOrgUser(&updateCmd), ShouldBeNil)
+
+						query := m.GetOrgUserRoleHistoryQuery{OrgId: ac1.OrgId, UserId: ac2.Id}
+						So(GetOrgUserRoleHistory(&query), ShouldBeNil)
+						So(len(query.Result), ShouldEqual, 1)
+					})
+				})
+
+				Convey("Given a third org", func() {
+					orgCmd := m.CreateOrgCommand{Name: "third org", UserId: ac1.Id}
+					So(CreateOrg(&orgCmd), ShouldBeNil)
+					targetOrgId := orgCmd.Result.Id
+
+					Convey("Can move an org user to it", func() {
+						cmd := m.MoveOrgUserCommand{OrgId: ac1.OrgId, UserId: ac2.Id, TargetOrgId: targetOrgId, Role: m.ROLE_EDITOR}
+						So(MoveOrgUser(&cmd), ShouldBeNil)
+
+						source := m.GetOrgUsersQuery{OrgId: ac1.OrgId}
+						So(GetOrgUsers(&source), ShouldBeNil)
+						So(len(source.Result), ShouldEqual, 1)
+
+						target := m.GetOrgUsersQuery{OrgId: targetOrgId, Role: string(m.ROLE_EDITOR)}
+						So(GetOrgUsers(&target), ShouldBeNil)
+						So(len(target.Result), ShouldEqual, 1)
+						So(target.Result[0].UserId, ShouldEqual, ac2.Id)
+					})
+
+					Convey("Cannot move the last admin out of an org", func() {
+						cmd := m.MoveOrgUserCommand{OrgId: ac2.OrgId, UserId: ac2.Id, TargetOrgId: targetOrgId, Role: m.ROLE_ADMIN}
+						So(MoveOrgUser(&cmd), ShouldEqual, m.ErrLastOrgAdmin)
+
+						source := m.GetOrgUsersQuery{OrgId: ac2.OrgId}
+						So(GetOrgUsers(&source), ShouldBeNil)
+						So(len(source.Result), ShouldEqual, 1)
+
+						target := m.GetOrgUsersQuery{OrgId: targetOrgId}
+						So(GetOrgUsers(&target), ShouldBeNil)
+						So(len(target.Result), ShouldEqual, 1)
+					})
+
+					Convey("Cannot move a user that isn't a member", func() {
+						cmd := m.MoveOrgUserCommand{OrgId: targetOrgId, UserId: ac2.Id, TargetOrgId: ac1.OrgId, Role: m.ROLE_EDITOR}
+						So(MoveOrgUser(&cmd), ShouldEqual, m.ErrOrgUserNotFound)
+					})
+
+					Convey("Cannot move to a missing org", func() {
+						cmd := m.MoveOrgUserCommand{OrgId: ac1.OrgId, UserId: ac2.Id, TargetOrgId: 999, Role: m.ROLE_EDITOR}
+						So(MoveOrgUser(&cmd), ShouldEqual, m.ErrOrgNotFound)
+					})
+				})
+
 				Convey("Can get logged in user projection", func() {
 					query := m.GetSignedInUserQuery{UserId: ac2.Id}
 					err := GetSignedInUser(&query)
@@ -123,6 +430,123 @@ func TestAccountDataAccess(t *testing.T) {
 					So(query.Result[0].Role, ShouldEqual, "Admin")
 				})
 
+				Convey("Can filter organization users by role", func() {
+					ac3cmd := m.CreateUserCommand{Login: "ac3", Email: "ac3@test.com"}
+					So(CreateUser(&ac3cmd), ShouldBeNil)
+					So(AddOrgUser(&m.AddOrgUserCommand{OrgId: ac1.OrgId, UserId: ac3cmd.Result.Id, Role: m.ROLE_EDITOR}), ShouldBeNil)
+
+					expected := map[m.RoleType]string{
+						m.ROLE_ADMIN:  "ac1",
+						m.ROLE_EDITOR: "ac3",
+						m.ROLE_VIEWER: "ac2",
+					}
+
+					for role, login := range expected {
+						query := m.GetOrgUsersQuery{OrgId: ac1.OrgId, Role: string(role)}
+						So(GetOrgUsers(&query), ShouldBeNil)
+						So(len(query.Result), ShouldEqual, 1)
+						So(query.Result[0].Login, ShouldEqual, login)
+					}
+				})
+
+				Convey("Can search organization users by login or email", func() {
+					for i := 0; i < 28; i++ {
+						login := fmt.Sprintf("member%02d", i)
+						email := login + "@example.com"
+						if i%5 == 0 {
+							email = login + "@Ops.Example.com"
+						}
+
+						cmd := m.CreateUserCommand{Login: login, Email: email}
+						So(CreateUser(&cmd), ShouldBeNil)
+						So(AddOrgUser(&m.AddOrgUserCommand{OrgId: ac1.OrgId, UserId: cmd.Result.Id, Role: m.ROLE_VIEWER}), ShouldBeNil)
+					}
+
+					all := m.GetOrgUsersQuery{OrgId: ac1.OrgId}
+					So(GetOrgUsers(&all), ShouldBeNil)
+					So(len(all.Result), ShouldEqual, 30)
+
+					query := m.GetOrgUsersQuery{OrgId: ac1.OrgId, Query: "OPS.example"}
+					So(GetOrgUsers(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 6)
+					So(query.Result[0].Login, ShouldEqual, "member00")
+
+					query = m.GetOrgUsersQuery{OrgId: ac1.OrgId, Query: "ac"}
+					So(GetOrgUsers(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 2)
+
+					Convey("Should page through the matches", func() {
+						page := m.GetOrgUsersQuery{OrgId: ac1.OrgId, Query: "ops", Limit: 4, Page: 1}
+						So(GetOrgUsers(&page), ShouldBeNil)
+						So(len(page.Result), ShouldEqual, 2)
+						So(page.Result[0].Login, ShouldEqual, "member20")
+					})
+				})
+
+				Convey("Can track when org users were last seen", func() {
+					So(UpdateUserLastSeenAt(&m.UpdateUserLastSeenAtCommand{UserId: ac2.Id}), ShouldBeNil)
+
+					signedIn := m.GetSignedInUserQuery{UserId: ac2.Id}
+					So(GetSignedInUser(&signedIn), ShouldBeNil)
+					So(time.Since(signedIn.Result.LastSeenAt), ShouldBeLessThan, time.Minute)
+
+					query := m.GetOrgUsersQuery{OrgId: ac1.OrgId}
+					So(GetOrgUsers(&query), ShouldBeNil)
+					So(query.Result[1].Login, ShouldEqual, "ac2")
+					So(time.Since(*query.Result[1].LastSeenAt), ShouldBeLessThan, time.Minute)
+					So(query.Result[0].LastSeenAt, ShouldBeNil)
+
+					Convey("Should only list users not seen since the given time as inactive", func() {
+						inactive := m.GetInactiveOrgUsersQuery{OrgId: ac1.OrgId, Since: time.Now().AddDate(0, 0, -30)}
+						So(GetInactiveOrgUsers(&inactive), ShouldBeNil)
+						So(len(inactive.Result), ShouldEqual, 1)
+						So(inactive.Result[0].Login, ShouldEqual, "ac1")
+					})
+				})
+
+				Convey("Can add org user by email", func() {
+					ac3cmd := m.CreateUserCommand{Login: "ac3", Email: "ac3@test.com"}
+					So(CreateUser(&ac3cmd), ShouldBeNil)
+
+					cmd := m.AddOrgUserCommand{OrgId: ac1.OrgId, LoginOrEmail: "ac3@test.com", Role: m.ROLE_EDITOR}
+					So(AddOrgUser(&cmd), ShouldBeNil)
+					So(cmd.UserId, ShouldEqual, ac3cmd.Result.Id)
+
+					query := m.GetOrgUsersQuery{OrgId: ac1.OrgId, Role: string(m.ROLE_EDITOR)}
+					So(GetOrgUsers(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Login, ShouldEqual, "ac3")
+				})
+
+				Convey("Can add org user by email and login in another case", func() {
+					ac3cmd := m.CreateUserCommand{Login: "ac3", Email: "ac3@test.com"}
+					So(CreateUser(&ac3cmd), ShouldBeNil)
+					ac4cmd := m.CreateUserCommand{Login: "ac4", Email: "ac4@test.com"}
+					So(CreateUser(&ac4cmd), ShouldBeNil)
+
+					cmd := m.AddOrgUserCommand{OrgId: ac1.OrgId, LoginOrEmail: "AC3@Test.com", Role: m.ROLE_EDITOR}
+					So(AddOrgUser(&cmd), ShouldBeNil)
+					So(cmd.UserId, ShouldEqual, ac3cmd.Result.Id)
+
+					cmd = m.AddOrgUserCommand{OrgId: ac1.OrgId, LoginOrEmail: "AC4", Role: m.ROLE_EDITOR}
+					So(AddOrgUser(&cmd), ShouldBeNil)
+					So(cmd.UserId, ShouldEqual, ac4cmd.Result.Id)
+				})
+
+				Convey("Cannot add org user by an unknown email", func() {
+					cmd := m.AddOrgUserCommand{OrgId: ac1.OrgId, LoginOrEmail: "nobody@test.com", Role: m.ROLE_EDITOR}
+					So(AddOrgUser(&cmd), ShouldEqual, m.ErrUserNotFound)
+
+					query := m.GetOrgUsersQuery{OrgId: ac1.OrgId}
+					So(GetOrgUsers(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 2)
+				})
+
+				Convey("Cannot filter organization users by an unknown role", func() {
+					query := m.GetOrgUsersQuery{OrgId: ac1.OrgId, Role: "Owner"}
+					So(GetOrgUsers(&query), ShouldEqual, m.ErrInvalidRoleType)
+				})
+
 				Convey("Can set using org", func() {
 					cmd := m.SetUsingOrgCommand{UserId: ac2.Id, OrgId: ac1.Id}
 					err := SetUsingOrg(&cmd)
@@ -158,3 +582,38 @@ func TestAccountDataAccess(t *testing.T) {
 		})
 	})
 }
+
+func TestOrgSearchSql(t *testing.T) {
+
+	Convey("Org search should build sql for the dialect", t, func() {
+		query := &m.SearchOrgsQuery{Query: "Main", Name: "Main Org."}
+
+		Convey("For sqlite3", func() {
+			where, args := orgSearchFilter(migrator.NewDialect(migrator.SQLITE), query)
+			So(where, ShouldEqual, "LOWER(name) LIKE LOWER(?) AND name=?")
+			So(args, ShouldResemble, []interface{}{"Main%", "Main Org."})
+		})
+
+		Convey("For postgres", func() {
+			where, _ := orgSearchFilter(migrator.NewDialect(migrator.POSTGRES), query)
+			So(where, ShouldEqual, "name ILIKE ? AND name=?")
+		})
+
+		Convey("For mysql", func() {
+			where, _ := orgSearchFilter(migrator.NewDialect(migrator.MYSQL), query)
+			So(where, ShouldEqual, "name LIKE ? AND name=?")
+		})
+
+		Convey("Without filters", func() {
+			where, args := orgSearchFilter(migrator.NewDialect(migrator.SQLITE), &m.SearchOrgsQuery{})
+			So(where, ShouldEqual, "")
+			So(args, ShouldBeEmpty)
+		})
+	})
+
+	Convey("Concat should use the dialect operator", t, func() {
+		So(migrator.NewDialect(migrator.SQLITE).Concat("login", "email"), ShouldEqual, "login || email")
+		So(migrator.NewDialect(migrator.POSTGRES).Concat("login", "email"), ShouldEqual, "login || email")
+		So(migrator.NewDialect(migrator.MYSQL).Concat("login", "email"), ShouldEqual, "CONCAT(login, email)")
+	})
+}
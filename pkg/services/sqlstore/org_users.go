@@ -14,11 +14,28 @@ func init() {
 	bus.AddHandler("sql", AddOrgUser)
 	bus.AddHandler("sql", RemoveOrgUser)
 	bus.AddHandler("sql", GetOrgUsers)
+	bus.AddHandler("sql", GetInactiveOrgUsers)
+	bus.AddHandler("sql", GetOrgUserRoleHistory)
 	bus.AddHandler("sql", UpdateOrgUser)
+	bus.AddHandler("sql", MoveOrgUser)
 }
 
 func AddOrgUser(cmd *m.AddOrgUserCommand) error {
 	return inTransaction(func(sess *xorm.Session) error {
+		// resolve the user when only a login or email was given
+		if cmd.UserId == 0 {
+			if cmd.LoginOrEmail == "" {
+				return m.ErrUserNotFound
+			}
+
+			user, err := getUserByLoginOrEmail(sess, cmd.LoginOrEmail)
+			if err != nil {
+				return err
+			}
+
+			cmd.UserId = user.Id
+		}
+
 		// check if user exists
 		if res, err := sess.Query("SELECT 1 from org_user WHERE org_id=? and user_id=?", cmd.OrgId, cmd.UserId); err != nil {
 			return err
@@ -51,6 +68,19 @@ func UpdateOrgUser(cmd *m.UpdateOrgUserCommand) error {
 			return m.ErrOrgUserNotFound
 		}
 
+		if orgUser.Role == cmd.Role {
+			return nil
+		}
+
+		history := m.OrgUserRoleHistory{
+			OrgId:       cmd.OrgId,
+			UserId:      cmd.UserId,
+			OldRole:     orgUser.Role,
+			NewRole:     cmd.Role,
+			ActorUserId: cmd.ActorUserId,
+			Created:     time.Now(),
+		}
+
 		orgUser.Role = cmd.Role
 		orgUser.Updated = time.Now()
 		_, err = sess.Id(orgUser.Id).Update(&orgUser)
@@ -58,22 +88,63 @@ func UpdateOrgUser(cmd *m.UpdateOrgUserCommand) error {
 			return err
 		}
 
+		if _, err := sess.Insert(&history); err != nil {
+			return err
+		}
+
 		return validateOneAdminLeftInOrg(cmd.OrgId, sess)
 	})
 }
 
+func GetOrgUserRoleHistory(query *m.GetOrgUserRoleHistoryQuery) error {
+	query.Result = make([]*m.OrgUserRoleHistoryDTO, 0)
+	sess := x.Table("org_user_role_history")
+	sess.Where("org_id=? AND user_id=?", query.OrgId, query.UserId)
+	sess.Desc("created", "id")
+
+	return sess.Find(&query.Result)
+}
+
 func GetOrgUsers(query *m.GetOrgUsersQuery) error {
+	if query.Role != "" && !m.RoleType(query.Role).IsValid() {
+		return m.ErrInvalidRoleType
+	}
+
 	query.Result = make([]*m.OrgUserDTO, 0)
 	sess := x.Table("org_user")
 	sess.Join("INNER", "user", fmt.Sprintf("org_user.user_id=%s.id", x.Dialect().Quote("user")))
 	sess.Where("org_user.org_id=?", query.OrgId)
-	sess.Cols("org_user.org_id", "org_user.user_id", "user.email", "user.login", "org_user.role")
+	if query.Role != "" {
+		sess.And("org_user.role=?", query.Role)
+	}
+	if query.Query != "" {
+		user := dialect.Quote("user")
+		sess.And(dialect.ILike(dialect.Concat(user+".login", "' '", user+".email")), "%"+query.Query+"%")
+	}
+	if query.Limit > 0 {
+		sess.Limit(query.Limit, query.Limit*query.Page)
+	}
+	sess.Cols("org_user.org_id", "org_user.user_id", "user.email", "user.login", "org_user.role", "user.last_seen_at")
 	sess.Asc("user.email", "user.login")
 
 	err := sess.Find(&query.Result)
 	return err
 }
 
+func GetInactiveOrgUsers(query *m.GetInactiveOrgUsersQuery) error {
+	user := x.Dialect().Quote("user")
+
+	query.Result = make([]*m.OrgUserDTO, 0)
+	sess := x.Table("org_user")
+	sess.Join("INNER", "user", fmt.Sprintf("org_user.user_id=%s.id", user))
+	sess.Where("org_user.org_id=?", query.OrgId)
+	sess.And(fmt.Sprintf("(%[1]s.last_seen_at IS NULL OR %[1]s.last_seen_at < ?)", user), query.Since)
+	sess.Cols("org_user.org_id", "org_user.user_id", "user.email", "user.login", "org_user.role", "user.last_seen_at")
+	sess.Asc("user.last_seen_at", "user.email")
+
+	return sess.Find(&query.Result)
+}
+
 func RemoveOrgUser(cmd *m.RemoveOrgUserCommand) error {
 	return inTransaction(func(sess *xorm.Session) error {
 		var rawSql = "DELETE FROM org_user WHERE org_id=? and user_id=?"
@@ -82,10 +153,98 @@ func RemoveOrgUser(cmd *m.RemoveOrgUserCommand) error {
 			return err
 		}
 
+		// the user leaves the teams of the org too
+		if _, err := sess.Exec("DELETE FROM team_member WHERE org_id=? and user_id=?", cmd.OrgId, cmd.UserId); err != nil {
+			return err
+		}
+
 		return validateOneAdminLeftInOrg(cmd.OrgId, sess)
 	})
 }
 
+// MoveOrgUser moves a member of one org to another, the source org must be
+// left with an admin.
+func MoveOrgUser(cmd *m.MoveOrgUserCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		if !cmd.Role.IsValid() {
+			return m.ErrInvalidRoleType
+		}
+
+		for _, orgId := range []int64{cmd.OrgId, cmd.TargetOrgId} {
+			if exists, err := sess.Id(orgId).Get(&m.Org{}); err != nil {
+				return err
+			} else if !exists {
+				return m.ErrOrgNotFound
+			}
+		}
+
+		var orgUser m.OrgUser
+		if exists, err := sess.Where("org_id=? AND user_id=?", cmd.OrgId, cmd.UserId).Get(&orgUser); err != nil {
+			return err
+		} else if !exists {
+			return m.ErrOrgUserNotFound
+		}
+
+		if res, err := sess.Query("SELECT 1 from org_user WHERE org_id=? and user_id=?", cmd.TargetOrgId, cmd.UserId); err != nil {
+			return err
+		} else if len(res) == 1 {
+			return m.ErrOrgUserAlreadyAdded
+		}
+
+		if _, err := sess.Exec("DELETE FROM org_user WHERE id=?", orgUser.Id); err != nil {
+			return err
+		}
+
+		if err := validateOneAdminLeftInOrg(cmd.OrgId, sess); err != nil {
+			return err
+		}
+
+		entity := m.OrgUser{
+			OrgId:   cmd.TargetOrgId,
+			UserId:  cmd.UserId,
+			Role:    cmd.Role,
+			Created: time.Now(),
+			Updated: time.Now(),
+		}
+
+		if _, err := sess.Insert(&entity); err != nil {
+			return err
+		}
+
+		// users currently using the source org switch to the target org
+		var rawSql = "UPDATE " + dialect.Quote("user") + " SET org_id=? WHERE id=? AND org_id=?"
+		_, err := sess.Exec(rawSql, cmd.TargetOrgId, cmd.UserId, cmd.OrgId)
+		return err
+	})
+}
+
+// validateOrgsKeepAnAdmin fails when removing userId from all their orgs
+// leaves one with members but no admin. An org the user is the only member
+// of is just left empty.
+func validateOrgsKeepAnAdmin(userId int64, sess *xorm.Session) error {
+	adminOf := make([]*m.OrgUser, 0)
+	if err := sess.Where("user_id=? AND role=?", userId, m.ROLE_ADMIN).Find(&adminOf); err != nil {
+		return err
+	}
+
+	for _, orgUser := range adminOf {
+		others, err := sess.Where("org_id=? AND user_id<>?", orgUser.OrgId, userId).Count(&m.OrgUser{})
+		if err != nil {
+			return err
+		}
+		otherAdmins, err := sess.Where("org_id=? AND user_id<>? AND role=?", orgUser.OrgId, userId, m.ROLE_ADMIN).Count(&m.OrgUser{})
+		if err != nil {
+			return err
+		}
+
+		if others > 0 && otherAdmins == 0 {
+			return m.ErrLastOrgAdmin
+		}
+	}
+
+	return nil
+}
+
 func validateOneAdminLeftInOrg(orgId int64, sess *xorm.Session) error {
 	// validate that there is an admin user left
 	res, err := sess.Query("SELECT 1 from org_user WHERE org_id=? and role='Admin'", orgId)
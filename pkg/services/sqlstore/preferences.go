@@ -0,0 +1,89 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", GetPreferences)
+	bus.AddHandler("sql", GetPreferencesWithDefaults)
+	bus.AddHandler("sql", SavePreferences)
+}
+
+func GetPreferences(query *m.GetPreferencesQuery) error {
+	prefs := m.Preferences{}
+	has, err := x.Where("org_id=? AND user_id=?", query.OrgId, query.UserId).Get(&prefs)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		prefs = m.Preferences{OrgId: query.OrgId, UserId: query.UserId}
+	}
+
+	query.Result = &prefs
+	return nil
+}
+
+func GetPreferencesWithDefaults(query *m.GetPreferencesWithDefaultsQuery) error {
+	prefs := make([]*m.Preferences, 0)
+	err := x.Where("org_id=? AND user_id IN (0, ?)", query.OrgId, query.UserId).Asc("user_id").Find(&prefs)
+	if err != nil {
+		return err
+	}
+
+	// org defaults sort first, user values override them
+	res := &m.Preferences{OrgId: query.OrgId, UserId: query.UserId}
+	for _, p := range prefs {
+		if p.HomeDashboardId != 0 {
+			res.HomeDashboardId = p.HomeDashboardId
+		}
+		if p.Timezone != "" {
+			res.Timezone = p.Timezone
+		}
+		if p.Theme != "" {
+			res.Theme = p.Theme
+		}
+	}
+
+	query.Result = res
+	return nil
+}
+
+func SavePreferences(cmd *m.SavePreferencesCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		var prefs m.Preferences
+		exists, err := sess.Where("org_id=? AND user_id=?", cmd.OrgId, cmd.UserId).Get(&prefs)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			prefs = m.Preferences{
+				OrgId:           cmd.OrgId,
+				UserId:          cmd.UserId,
+				HomeDashboardId: cmd.HomeDashboardId,
+				Timezone:        cmd.Timezone,
+				Theme:           cmd.Theme,
+				Created:         time.Now(),
+				Updated:         time.Now(),
+			}
+			_, err = sess.Insert(&prefs)
+			return err
+		}
+
+		prefs.HomeDashboardId = cmd.HomeDashboardId
+		prefs.Timezone = cmd.Timezone
+		prefs.Theme = cmd.Theme
+		prefs.Updated = time.Now()
+		prefs.Version += 1
+
+		_, err = sess.Id(prefs.Id).Cols("home_dashboard_id", "timezone", "theme", "updated", "version").Update(&prefs)
+		return err
+	})
+}
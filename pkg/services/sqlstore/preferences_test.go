@@ -0,0 +1,81 @@
+package sqlstore
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestPreferencesDataAccess(t *testing.T) {
+
+	Convey("Testing preferences data access", t, func() {
+		InitTestDB(t)
+
+		Convey("Without stored preferences nothing is set", func() {
+			query := m.GetPreferencesWithDefaultsQuery{OrgId: 1, UserId: 1}
+			So(GetPreferencesWithDefaults(&query), ShouldBeNil)
+			So(query.Result.HomeDashboardId, ShouldEqual, 0)
+
+			single := m.GetPreferencesQuery{OrgId: 1, UserId: 1}
+			So(GetPreferences(&single), ShouldBeNil)
+			So(single.Result.Id, ShouldEqual, 0)
+		})
+
+		Convey("Given org preferences", func() {
+			err := SavePreferences(&m.SavePreferencesCommand{OrgId: 1, HomeDashboardId: 10, Theme: "light"})
+			So(err, ShouldBeNil)
+
+			Convey("Users without their own preferences get the org ones", func() {
+				query := m.GetPreferencesWithDefaultsQuery{OrgId: 1, UserId: 1}
+				So(GetPreferencesWithDefaults(&query), ShouldBeNil)
+				So(query.Result.HomeDashboardId, ShouldEqual, 10)
+				So(query.Result.Theme, ShouldEqual, "light")
+			})
+
+			Convey("User preferences win over the org ones", func() {
+				err := SavePreferences(&m.SavePreferencesCommand{OrgId: 1, UserId: 1, HomeDashboardId: 20})
+				So(err, ShouldBeNil)
+
+				query := m.GetPreferencesWithDefaultsQuery{OrgId: 1, UserId: 1}
+				So(GetPreferencesWithDefaults(&query), ShouldBeNil)
+				So(query.Result.HomeDashboardId, ShouldEqual, 20)
+				So(query.Result.Theme, ShouldEqual, "light")
+
+				Convey("And do not leak to other users", func() {
+					query := m.GetPreferencesWithDefaultsQuery{OrgId: 1, UserId: 2}
+					So(GetPreferencesWithDefaults(&query), ShouldBeNil)
+					So(query.Result.HomeDashboardId, ShouldEqual, 10)
+				})
+			})
+
+			Convey("Saving again updates the stored preferences", func() {
+				err := SavePreferences(&m.SavePreferencesCommand{OrgId: 1, HomeDashboardId: 11})
+				So(err, ShouldBeNil)
+
+				query := m.GetPreferencesQuery{OrgId: 1}
+				So(GetPreferences(&query), ShouldBeNil)
+				So(query.Result.HomeDashboardId, ShouldEqual, 11)
+				So(query.Result.Theme, ShouldEqual, "")
+				So(query.Result.Version, ShouldEqual, 1)
+			})
+		})
+
+		Convey("Deleting an org removes its preferences", func() {
+			So(SavePreferences(&m.SavePreferencesCommand{OrgId: 1, Theme: "light"}), ShouldBeNil)
+			So(SavePreferences(&m.SavePreferencesCommand{OrgId: 1, UserId: 1, Theme: "dark"}), ShouldBeNil)
+			So(SavePreferences(&m.SavePreferencesCommand{OrgId: 2, Theme: "dark"}), ShouldBeNil)
+
+			So(DeleteOrg(&m.DeleteOrgCommand{Id: 1}), ShouldBeNil)
+
+			query := m.GetPreferencesWithDefaultsQuery{OrgId: 1, UserId: 1}
+			So(GetPreferencesWithDefaults(&query), ShouldBeNil)
+			So(query.Result.Theme, ShouldEqual, "")
+
+			other := m.GetPreferencesQuery{OrgId: 2}
+			So(GetPreferences(&other), ShouldBeNil)
+			So(other.Result.Theme, ShouldEqual, "dark")
+		})
+	})
+}
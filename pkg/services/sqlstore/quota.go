@@ -2,6 +2,8 @@ package sqlstore
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/Cepave/grafana/pkg/bus"
 	m "github.com/Cepave/grafana/pkg/models"
 	"github.com/Cepave/grafana/pkg/setting"
@@ -21,6 +23,29 @@ type targetCount struct {
 	Count int64
 }
 
+// softDeleteTargets keep their rows around after a delete, those don't
+// count against the quota.
+var softDeleteTargets = map[string]bool{
+	"data_source": true,
+	"dashboard":   true,
+}
+
+func quotaUsedSql(target string, filter string) string {
+	rawSql := fmt.Sprintf("SELECT COUNT(*) as count from %s", dialect.Quote(target))
+
+	conditions := make([]string, 0, 2)
+	if filter != "" {
+		conditions = append(conditions, filter)
+	}
+	if softDeleteTargets[target] {
+		conditions = append(conditions, "deleted IS NULL")
+	}
+	if len(conditions) > 0 {
+		rawSql += " where " + strings.Join(conditions, " AND ")
+	}
+	return rawSql
+}
+
 func GetOrgQuotaByTarget(query *m.GetOrgQuotaByTargetQuery) error {
 	quota := m.Quota{
 		Target: query.Target,
@@ -34,7 +59,7 @@ func GetOrgQuotaByTarget(query *m.GetOrgQuotaByTargetQuery) error {
 	}
 
 	//get quota used.
-	rawSql := fmt.Sprintf("SELECT COUNT(*) as count from %s where org_id=?", dialect.Quote(query.Target))
+	rawSql := quotaUsedSql(query.Target, "org_id=?")
 	resp := make([]*targetCount, 0)
 	if err := x.Sql(rawSql, query.OrgId).Find(&resp); err != nil {
 		return err
@@ -77,7 +102,7 @@ func GetOrgQuotas(query *m.GetOrgQuotasQuery) error {
 	result := make([]*m.OrgQuotaDTO, len(quotas))
 	for i, q := range quotas {
 		//get quota used.
-		rawSql := fmt.Sprintf("SELECT COUNT(*) as count from %s where org_id=?", dialect.Quote(q.Target))
+		rawSql := quotaUsedSql(q.Target, "org_id=?")
 		resp := make([]*targetCount, 0)
 		if err := x.Sql(rawSql, q.OrgId).Find(&resp); err != nil {
 			return err
@@ -134,7 +159,7 @@ func GetUserQuotaByTarget(query *m.GetUserQuotaByTargetQuery) error {
 	}
 
 	//get quota used.
-	rawSql := fmt.Sprintf("SELECT COUNT(*) as count from %s where user_id=?", dialect.Quote(query.Target))
+	rawSql := quotaUsedSql(query.Target, "user_id=?")
 	resp := make([]*targetCount, 0)
 	if err := x.Sql(rawSql, query.UserId).Find(&resp); err != nil {
 		return err
@@ -177,7 +202,7 @@ func GetUserQuotas(query *m.GetUserQuotasQuery) error {
 	result := make([]*m.UserQuotaDTO, len(quotas))
 	for i, q := range quotas {
 		//get quota used.
-		rawSql := fmt.Sprintf("SELECT COUNT(*) as count from %s where user_id=?", dialect.Quote(q.Target))
+		rawSql := quotaUsedSql(q.Target, "user_id=?")
 		resp := make([]*targetCount, 0)
 		if err := x.Sql(rawSql, q.UserId).Find(&resp); err != nil {
 			return err
@@ -223,7 +248,7 @@ func UpdateUserQuota(cmd *m.UpdateUserQuotaCmd) error {
 
 func GetGlobalQuotaByTarget(query *m.GetGlobalQuotaByTargetQuery) error {
 	//get quota used.
-	rawSql := fmt.Sprintf("SELECT COUNT(*) as count from %s", dialect.Quote(query.Target))
+	rawSql := quotaUsedSql(query.Target, "")
 	resp := make([]*targetCount, 0)
 	if err := x.Sql(rawSql).Find(&resp); err != nil {
 		return err
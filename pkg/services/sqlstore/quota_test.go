@@ -3,8 +3,8 @@ package sqlstore
 import (
 	"testing"
 
-	m "github.com/grafana/grafana/pkg/models"
-	"github.com/grafana/grafana/pkg/setting"
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -103,6 +103,23 @@ func TestQuotaCommandsAndQueries(t *testing.T) {
 				}
 			})
 		})
+		Convey("Deleted data sources should not count against the org quota", func() {
+			ds := m.AddDataSourceCommand{OrgId: orgId, Name: "gone", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_DIRECT, Url: "http://test"}
+			So(AddDataSource(&ds), ShouldBeNil)
+			So(DeleteDataSource(&m.DeleteDataSourceCommand{Id: ds.Result.Id, OrgId: orgId}), ShouldBeNil)
+
+			query := m.GetOrgQuotaByTargetQuery{OrgId: orgId, Target: "data_source", Default: 1}
+			So(GetOrgQuotaByTarget(&query), ShouldBeNil)
+			So(query.Result.Used, ShouldEqual, 0)
+		})
+		Convey("Deleted dashboards should not count against the org quota", func() {
+			dash := insertTestDashboard("gone", orgId)
+			So(DeleteDashboard(&m.DeleteDashboardCommand{Slug: dash.Slug, OrgId: orgId}), ShouldBeNil)
+
+			query := m.GetOrgQuotaByTargetQuery{OrgId: orgId, Target: "dashboard", Default: 1}
+			So(GetOrgQuotaByTarget(&query), ShouldBeNil)
+			So(query.Result.Used, ShouldEqual, 0)
+		})
 		Convey("Given saved user quota for org", func() {
 			userQoutaCmd := m.UpdateUserQuotaCmd{
 				UserId: userId,
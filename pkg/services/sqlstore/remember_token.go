@@ -0,0 +1,120 @@
+package sqlstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-xorm/xorm"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", CreateRememberToken)
+	bus.AddHandler("sql", RotateRememberToken)
+	bus.AddHandler("sql", DeleteRememberToken)
+	bus.AddHandler("sql", PurgeRememberTokens)
+}
+
+func hashRememberToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func CreateRememberToken(cmd *m.CreateRememberTokenCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		token := m.RememberToken{
+			UserId:     cmd.UserId,
+			SeriesHash: hashRememberToken(cmd.Series),
+			TokenHash:  hashRememberToken(cmd.Token),
+			Created:    time.Now(),
+			Updated:    time.Now(),
+		}
+
+		_, err := sess.Insert(&token)
+		return err
+	})
+}
+
+func RotateRememberToken(cmd *m.RotateRememberTokenCommand) error {
+	var reused, expired bool
+
+	err := inTransaction(func(sess *xorm.Session) error {
+		var token m.RememberToken
+		exists, err := sess.Where("series_hash=?", hashRememberToken(cmd.Series)).Get(&token)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return m.ErrRememberTokenNotFound
+		}
+
+		tokenHash := hashRememberToken(cmd.Token)
+		current := token.TokenHash == tokenHash
+		// requests sent before the previous rotation reached the client
+		justRotated := token.PrevTokenHash == tokenHash && time.Since(token.Updated) < cmd.GracePeriod
+
+		// a valid series with a stale token means the cookie was copied,
+		// drop the series and the session it renewed so neither copy can be
+		// used again
+		if !current && !justRotated {
+			reused = true
+			if _, err = sess.Exec("DELETE FROM user_session WHERE user_id=? AND remember_series_hash=?", token.UserId, token.SeriesHash); err != nil {
+				return err
+			}
+			_, err = sess.Exec("DELETE FROM remember_token WHERE id=?", token.Id)
+			return err
+		}
+
+		// renewing never extends a login past its maximum lifetime
+		if cmd.MaxLifetime > 0 && time.Since(token.Created) > cmd.MaxLifetime {
+			expired = true
+			_, err = sess.Exec("DELETE FROM remember_token WHERE id=?", token.Id)
+			return err
+		}
+
+		cmd.Result = &token
+		if !current {
+			return nil
+		}
+
+		token.PrevTokenHash = token.TokenHash
+		token.TokenHash = hashRememberToken(cmd.NewToken)
+		token.Updated = time.Now()
+		if _, err = sess.Id(token.Id).Cols("prev_token_hash", "token_hash", "updated").Update(&token); err != nil {
+			return err
+		}
+
+		cmd.Rotated = true
+		return nil
+	})
+
+	if err == nil && reused {
+		return m.ErrRememberTokenReused
+	}
+	if err == nil && expired {
+		return m.ErrRememberTokenExpired
+	}
+	return err
+}
+
+func DeleteRememberToken(cmd *m.DeleteRememberTokenCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		_, err := sess.Exec("DELETE FROM remember_token WHERE series_hash=?", hashRememberToken(cmd.Series))
+		return err
+	})
+}
+
+func PurgeRememberTokens(cmd *m.PurgeRememberTokensCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		result, err := sess.Exec("DELETE FROM remember_token WHERE updated < ?", cmd.Before)
+		if err != nil {
+			return err
+		}
+
+		cmd.Result, _ = result.RowsAffected()
+		return nil
+	})
+}
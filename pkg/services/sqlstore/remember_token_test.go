@@ -0,0 +1,119 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestRememberTokenDataAccess(t *testing.T) {
+
+	Convey("Testing remember token data access", t, func() {
+		InitTestDB(t)
+
+		err := CreateRememberToken(&m.CreateRememberTokenCommand{UserId: 1, Series: "series", Token: "first"})
+		So(err, ShouldBeNil)
+
+		Convey("Should not store the plain series or token", func() {
+			var token m.RememberToken
+			_, err := x.Where("user_id=?", 1).Get(&token)
+			So(err, ShouldBeNil)
+			So(token.SeriesHash, ShouldNotEqual, "series")
+			So(token.TokenHash, ShouldNotEqual, "first")
+		})
+
+		Convey("Should rotate a valid token", func() {
+			cmd := m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "second"}
+			So(RotateRememberToken(&cmd), ShouldBeNil)
+			So(cmd.Result.UserId, ShouldEqual, 1)
+
+			Convey("And accept the new token next time", func() {
+				cmd := m.RotateRememberTokenCommand{Series: "series", Token: "second", NewToken: "third"}
+				So(RotateRememberToken(&cmd), ShouldBeNil)
+			})
+
+			Convey("And accept the old token without rotating within the grace period", func() {
+				cmd := m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "third", GracePeriod: time.Minute}
+				So(RotateRememberToken(&cmd), ShouldBeNil)
+				So(cmd.Rotated, ShouldBeFalse)
+				So(cmd.Result.UserId, ShouldEqual, 1)
+
+				cmd = m.RotateRememberTokenCommand{Series: "series", Token: "second", NewToken: "third"}
+				So(RotateRememberToken(&cmd), ShouldBeNil)
+				So(cmd.Rotated, ShouldBeTrue)
+			})
+
+			Convey("And drop the series when the old token is reused", func() {
+				cmd := m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "third"}
+				So(RotateRememberToken(&cmd), ShouldEqual, m.ErrRememberTokenReused)
+
+				cmd = m.RotateRememberTokenCommand{Series: "series", Token: "second", NewToken: "third"}
+				So(RotateRememberToken(&cmd), ShouldEqual, m.ErrRememberTokenNotFound)
+			})
+
+			Convey("And sign out the session of the series when the old token is reused", func() {
+				renewed := m.CreateUserSessionCommand{UserId: 1, RememberSeries: "series"}
+				So(CreateUserSession(&renewed), ShouldBeNil)
+				other := m.CreateUserSessionCommand{UserId: 1, RememberSeries: "other series"}
+				So(CreateUserSession(&other), ShouldBeNil)
+
+				cmd := m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "third"}
+				So(RotateRememberToken(&cmd), ShouldEqual, m.ErrRememberTokenReused)
+
+				So(GetUserSession(&m.GetUserSessionQuery{Id: renewed.Result.Id, UserId: 1}), ShouldEqual, m.ErrUserSessionNotFound)
+				So(GetUserSession(&m.GetUserSessionQuery{Id: other.Result.Id, UserId: 1}), ShouldBeNil)
+			})
+		})
+
+		Convey("Should rotate a token within its maximum lifetime", func() {
+			cmd := m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "second", MaxLifetime: time.Hour}
+			So(RotateRememberToken(&cmd), ShouldBeNil)
+		})
+
+		Convey("Given a token created past its maximum lifetime", func() {
+			_, err := x.Exec("UPDATE remember_token SET created=?", time.Now().Add(-2*time.Hour))
+			So(err, ShouldBeNil)
+
+			Convey("Should refuse to rotate it and drop the series", func() {
+				cmd := m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "second", MaxLifetime: time.Hour}
+				So(RotateRememberToken(&cmd), ShouldEqual, m.ErrRememberTokenExpired)
+
+				cmd = m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "second"}
+				So(RotateRememberToken(&cmd), ShouldEqual, m.ErrRememberTokenNotFound)
+			})
+
+			Convey("Should rotate it when there is no maximum lifetime", func() {
+				cmd := m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "second"}
+				So(RotateRememberToken(&cmd), ShouldBeNil)
+			})
+		})
+
+		Convey("Should purge tokens not used since the cutoff", func() {
+			So(CreateRememberToken(&m.CreateRememberTokenCommand{UserId: 2, Series: "old", Token: "first"}), ShouldBeNil)
+			_, err := x.Exec("UPDATE remember_token SET updated=? WHERE user_id=2", time.Now().Add(-2*time.Hour))
+			So(err, ShouldBeNil)
+
+			purge := m.PurgeRememberTokensCommand{Before: time.Now().Add(-time.Hour)}
+			So(PurgeRememberTokens(&purge), ShouldBeNil)
+			So(purge.Result, ShouldEqual, 1)
+
+			cmd := m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "second"}
+			So(RotateRememberToken(&cmd), ShouldBeNil)
+		})
+
+		Convey("Should not rotate a deleted series", func() {
+			So(DeleteRememberToken(&m.DeleteRememberTokenCommand{Series: "series"}), ShouldBeNil)
+
+			cmd := m.RotateRememberTokenCommand{Series: "series", Token: "first", NewToken: "second"}
+			So(RotateRememberToken(&cmd), ShouldEqual, m.ErrRememberTokenNotFound)
+		})
+
+		Convey("Should fail for an unknown series", func() {
+			cmd := m.RotateRememberTokenCommand{Series: "other", Token: "first", NewToken: "second"}
+			So(RotateRememberToken(&cmd), ShouldEqual, m.ErrRememberTokenNotFound)
+		})
+	})
+}
@@ -0,0 +1,76 @@
+package sqlstore
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-xorm/xorm"
+)
+
+var replicas struct {
+	sync.Mutex
+	engines []*xorm.Engine
+	next    int
+}
+
+// AddReadReplica opens a connection to a read replica of the primary
+// database. Read-only queries are spread across the replicas, writes always
+// go to the primary. An empty driver uses the one of the primary, queries
+// are built for its dialect so another driver is refused.
+func AddReadReplica(driver, connStr string, maxIdle, maxOpen int) error {
+	if driver == "" {
+		driver = x.DriverName()
+	}
+	if driver != x.DriverName() {
+		return fmt.Errorf("read replica type %s differs from the primary database type %s", driver, x.DriverName())
+	}
+
+	engine, err := xorm.NewEngine(driver, connStr)
+	if err != nil {
+		return err
+	}
+
+	if maxIdle > 0 {
+		engine.SetMaxIdleConns(maxIdle)
+	}
+	if maxOpen > 0 {
+		engine.SetMaxOpenConns(maxOpen)
+	}
+
+	if err := engine.Ping(); err != nil {
+		engine.Close()
+		return err
+	}
+
+	replicas.Lock()
+	defer replicas.Unlock()
+	replicas.engines = append(replicas.engines, engine)
+	return nil
+}
+
+// SetReadEngines replaces the read replicas, passing none routes all reads
+// back to the primary.
+func SetReadEngines(engines ...*xorm.Engine) {
+	replicas.Lock()
+	defer replicas.Unlock()
+	replicas.engines = engines
+	replicas.next = 0
+}
+
+func readEngine() *xorm.Engine {
+	replicas.Lock()
+	defer replicas.Unlock()
+
+	if len(replicas.engines) == 0 {
+		return x
+	}
+
+	engine := replicas.engines[replicas.next%len(replicas.engines)]
+	replicas.next++
+	return engine
+}
+
+// readSession returns a session for read-only queries, callers must close it.
+func readSession() *xorm.Session {
+	return readEngine().NewSession()
+}
@@ -0,0 +1,87 @@
+package sqlstore
+
+import (
+	"testing"
+
+	"github.com/go-xorm/xorm"
+	"golang.org/x/net/context"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/services/sqlstore/migrations"
+	"github.com/Cepave/grafana/pkg/services/sqlstore/migrator"
+	"github.com/Cepave/grafana/pkg/services/sqlstore/sqlutil"
+)
+
+func initTestReplica(t *testing.T) *xorm.Engine {
+	replica, err := xorm.NewEngine(sqlutil.TestDB_Sqlite3.DriverName, sqlutil.TestDB_Sqlite3.ConnStr)
+	if err != nil {
+		t.Fatalf("Failed to init in memory sqllite3 replica %v", err)
+	}
+
+	mg := migrator.NewMigrator(replica)
+	migrations.AddMigrations(mg)
+	if err := mg.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return replica
+}
+
+func TestReadReplicas(t *testing.T) {
+
+	Convey("Testing read replica routing", t, func() {
+		InitTestDB(t)
+
+		Convey("Without replicas reads should use the primary", func() {
+			So(readEngine(), ShouldEqual, x)
+
+			So(CreateOrg(&m.CreateOrgCommand{Name: "primary org"}), ShouldBeNil)
+
+			query := m.SearchOrgsQuery{Name: "primary org", Limit: 10}
+			So(SearchOrgs(context.Background(), &query), ShouldBeNil)
+			So(len(query.Result), ShouldEqual, 1)
+		})
+
+		Convey("With a replica configured", func() {
+			replica := initTestReplica(t)
+			SetReadEngines(replica)
+			defer SetReadEngines()
+
+			_, err := replica.Insert(&m.Org{Name: "replica org"})
+			So(err, ShouldBeNil)
+			_, err = replica.Insert(&m.User{Login: "replica", Email: "replica@test.com"})
+			So(err, ShouldBeNil)
+
+			Convey("Org search should read from the replica", func() {
+				query := m.SearchOrgsQuery{Name: "replica org", Limit: 10}
+				So(SearchOrgs(context.Background(), &query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+			})
+
+			Convey("User search should read from the replica", func() {
+				query := m.SearchUsersQuery{Query: "replica", Limit: 10}
+				So(SearchUsers(context.Background(), &query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Login, ShouldEqual, "replica")
+			})
+
+			Convey("Writes should go to the primary", func() {
+				cmd := m.CreateOrgCommand{Name: "written org"}
+				So(CreateOrg(&cmd), ShouldBeNil)
+
+				onPrimary, err := x.Where("name=?", "written org").Count(&m.Org{})
+				So(err, ShouldBeNil)
+				So(onPrimary, ShouldEqual, 1)
+
+				onReplica, err := replica.Where("name=?", "written org").Count(&m.Org{})
+				So(err, ShouldBeNil)
+				So(onReplica, ShouldEqual, 0)
+
+				byId := m.GetOrgByIdQuery{Id: cmd.Result.Id}
+				So(GetOrgById(&byId), ShouldBeNil)
+			})
+		})
+	})
+}
@@ -1,7 +1,15 @@
 package sqlstore
 
 import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 	"github.com/go-xorm/xorm"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/net/context"
+
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/log"
 )
@@ -40,7 +48,23 @@ func inTransaction(callback dbTransactionFunc) error {
 	return nil
 }
 
+// inTransaction2 retries the whole transaction when it fails with a transient
+// error such as a deadlock, backing off exponentially between attempts.
 func inTransaction2(callback dbTransactionFunc2) error {
+	delay := DbCfg.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := runTransaction2(callback)
+		if err == nil || attempt > DbCfg.MaxRetries || !isTransientError(err) {
+			return err
+		}
+
+		log.Warn("Sqlstore: retrying transaction after transient error (attempt %d of %d): %v", attempt, DbCfg.MaxRetries, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func runTransaction2(callback dbTransactionFunc2) error {
 	var err error
 
 	sess := session{Session: x.NewSession()}
@@ -69,3 +93,39 @@ func inTransaction2(callback dbTransactionFunc2) error {
 
 	return nil
 }
+
+// withContext runs the statements of a query one after the other and skips
+// the rest once ctx is cancelled. The xorm version we ship can't interrupt a
+// statement, one already sent runs to the end.
+func withContext(ctx context.Context, statements ...func() error) error {
+	for _, statement := range statements {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := statement(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isTransientError reports whether err is worth retrying the transaction for.
+func isTransientError(err error) bool {
+	if err == driver.ErrBadConn {
+		return true
+	}
+
+	switch e := err.(type) {
+	case *mysql.MySQLError:
+		// deadlock found, lock wait timeout
+		return e.Number == 1213 || e.Number == 1205
+	case *pq.Error:
+		// serialization_failure, deadlock_detected
+		return e.Code == "40001" || e.Code == "40P01"
+	case sqlite3.Error:
+		return e.Code == sqlite3.ErrBusy || e.Code == sqlite3.ErrLocked
+	}
+
+	return false
+}
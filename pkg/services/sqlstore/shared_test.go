@@ -0,0 +1,87 @@
+package sqlstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
+)
+
+func TestTransactionRetries(t *testing.T) {
+
+	Convey("Testing transaction retries", t, func() {
+		InitTestDB(t)
+
+		DbCfg.MaxRetries = 3
+		DbCfg.RetryDelay = time.Millisecond
+		defer func() {
+			DbCfg.MaxRetries = 0
+			DbCfg.RetryDelay = 0
+		}()
+
+		deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+		Convey("Should retry a deadlocked transaction until it succeeds", func() {
+			calls := 0
+			err := inTransaction2(func(sess *session) error {
+				calls++
+				if calls <= 2 {
+					return deadlock
+				}
+				return nil
+			})
+
+			So(err, ShouldBeNil)
+			So(calls, ShouldEqual, 3)
+		})
+
+		Convey("Should give up after the configured number of retries", func() {
+			calls := 0
+			err := inTransaction2(func(sess *session) error {
+				calls++
+				return deadlock
+			})
+
+			So(err, ShouldEqual, deadlock)
+			So(calls, ShouldEqual, 4)
+		})
+
+		Convey("Should not retry other errors", func() {
+			failure := errors.New("constraint failed")
+			calls := 0
+			err := inTransaction2(func(sess *session) error {
+				calls++
+				return failure
+			})
+
+			So(err, ShouldEqual, failure)
+			So(calls, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestWithContext(t *testing.T) {
+
+	Convey("Given a query of two statements", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ran := 0
+		first := func() error { ran++; return nil }
+		second := func() error { ran++; return nil }
+
+		Convey("Should run both when not cancelled", func() {
+			So(withContext(ctx, first, second), ShouldBeNil)
+			So(ran, ShouldEqual, 2)
+		})
+
+		Convey("Should skip the rest once cancelled", func() {
+			cancelling := func() error { ran++; cancel(); return nil }
+			So(withContext(ctx, cancelling, second), ShouldEqual, context.Canceled)
+			So(ran, ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,26 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/Cepave/grafana/pkg/log"
+)
+
+var logSlowQuery = log.Warn
+
+// timeQuery runs query and logs it at warn level when it takes longer than
+// the configured slow query threshold. xorm doesn't expose the statement it
+// built, so name is the sql when it is executed as is and a label naming the
+// query otherwise.
+func timeQuery(name string, args []interface{}, query func() error) error {
+	start := time.Now()
+	err := query()
+
+	if threshold := DbCfg.SlowQueryThreshold; threshold > 0 {
+		if elapsed := time.Since(start); elapsed > threshold {
+			logSlowQuery("Sqlstore: slow query took %v: %s args=%v", elapsed, name, args)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,68 @@
+package sqlstore
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Cepave/grafana/pkg/log"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestSlowQueryLogging(t *testing.T) {
+
+	Convey("Testing slow query logging", t, func() {
+		logged := make([]string, 0)
+		logSlowQuery = func(format string, v ...interface{}) {
+			logged = append(logged, fmt.Sprintf(format, v...))
+		}
+		DbCfg.SlowQueryThreshold = 10 * time.Millisecond
+		defer func() {
+			logSlowQuery = log.Warn
+			DbCfg.SlowQueryThreshold = 0
+		}()
+
+		Convey("Should warn about a query slower than the threshold", func() {
+			err := timeQuery("SELECT * FROM org WHERE id=?", []interface{}{1}, func() error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			})
+
+			So(err, ShouldBeNil)
+			So(len(logged), ShouldEqual, 1)
+			So(logged[0], ShouldContainSubstring, "SELECT * FROM org WHERE id=?")
+			So(logged[0], ShouldContainSubstring, "args=[1]")
+		})
+
+		Convey("Should not warn about a fast query", func() {
+			err := timeQuery("SELECT * FROM org WHERE id=?", []interface{}{1}, func() error {
+				return nil
+			})
+
+			So(err, ShouldBeNil)
+			So(len(logged), ShouldEqual, 0)
+		})
+
+		Convey("Should not warn when disabled", func() {
+			DbCfg.SlowQueryThreshold = 0
+			timeQuery("SELECT * FROM org WHERE id=?", []interface{}{1}, func() error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			})
+
+			So(len(logged), ShouldEqual, 0)
+		})
+
+		Convey("Should time the org store queries", func() {
+			InitTestDB(t)
+			DbCfg.SlowQueryThreshold = time.Nanosecond
+
+			query := m.GetOrgByNameQuery{Name: "missing"}
+			So(GetOrgByName(&query), ShouldEqual, m.ErrOrgNotFound)
+			So(len(logged), ShouldEqual, 1)
+			So(logged[0], ShouldContainSubstring, "get org by name args=[missing]")
+		})
+	})
+}
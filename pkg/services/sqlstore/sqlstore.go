@@ -6,6 +6,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/log"
@@ -28,6 +29,13 @@ var (
 
 	DbCfg struct {
 		Type, Host, Name, User, Pwd, Path, SslMode string
+
+		MaxRetries         int
+		RetryDelay         time.Duration
+		SlowQueryThreshold time.Duration
+
+		// the server aborts statements running longer, 0 disables it
+		QueryTimeout time.Duration
 	}
 
 	UseSQLite3 bool
@@ -76,6 +84,7 @@ func NewEngine() {
 func SetEngine(engine *xorm.Engine, enableLog bool) (err error) {
 	x = engine
 	dialect = migrator.NewDialect(x.DriverName())
+	orgsById.clear()
 
 	migrator := migrator.NewMigrator(x)
 	migrator.LogLevel = log.INFO
@@ -110,11 +119,27 @@ func SetEngine(engine *xorm.Engine, enableLog bool) (err error) {
 func getEngine() (*xorm.Engine, error) {
 	LoadConfig()
 
+	cnnstr, err := connectionString()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Database: %v", DbCfg.Type)
+
+	return xorm.NewEngine(DbCfg.Type, cnnstr)
+}
+
+// connectionString builds the DSN handed to the driver of DbCfg.Type, the
+// query timeout goes in it so the server aborts slow statements.
+func connectionString() (string, error) {
 	cnnstr := ""
 	switch DbCfg.Type {
 	case "mysql":
 		cnnstr = fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8",
 			DbCfg.User, DbCfg.Pwd, DbCfg.Host, DbCfg.Name)
+		if DbCfg.QueryTimeout > 0 {
+			cnnstr += fmt.Sprintf("&max_execution_time=%d", queryTimeoutMs())
+		}
 	case "postgres":
 		var host, port = "127.0.0.1", "5432"
 		fields := strings.Split(DbCfg.Host, ":")
@@ -126,19 +151,23 @@ func getEngine() (*xorm.Engine, error) {
 		}
 		cnnstr = fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s sslmode=%s",
 			DbCfg.User, DbCfg.Pwd, host, port, DbCfg.Name, DbCfg.SslMode)
+		if DbCfg.QueryTimeout > 0 {
+			cnnstr += fmt.Sprintf(" statement_timeout=%d", queryTimeoutMs())
+		}
 	case "sqlite3":
 		if !filepath.IsAbs(DbCfg.Path) {
 			DbCfg.Path = filepath.Join(setting.DataPath, DbCfg.Path)
 		}
 		os.MkdirAll(path.Dir(DbCfg.Path), os.ModePerm)
 		cnnstr = "file:" + DbCfg.Path + "?cache=shared&mode=rwc&_loc=Local"
+		if DbCfg.QueryTimeout > 0 {
+			log.Warn("Database: query_timeout_ms is not supported by sqlite3, ignoring it")
+		}
 	default:
-		return nil, fmt.Errorf("Unknown database type: %s", DbCfg.Type)
+		return "", fmt.Errorf("Unknown database type: %s", DbCfg.Type)
 	}
 
-	log.Info("Database: %v", DbCfg.Type)
-
-	return xorm.NewEngine(DbCfg.Type, cnnstr)
+	return cnnstr, nil
 }
 
 func LoadConfig() {
@@ -156,4 +185,14 @@ func LoadConfig() {
 	}
 	DbCfg.SslMode = sec.Key("ssl_mode").String()
 	DbCfg.Path = sec.Key("path").MustString("data/grafana.db")
+	DbCfg.MaxRetries = sec.Key("transaction_retries").MustInt(3)
+	DbCfg.RetryDelay = time.Duration(sec.Key("transaction_retry_delay_ms").MustInt(50)) * time.Millisecond
+	DbCfg.SlowQueryThreshold = time.Duration(sec.Key("slow_query_threshold_ms").MustInt(200)) * time.Millisecond
+	DbCfg.QueryTimeout = time.Duration(sec.Key("query_timeout_ms").MustInt(0)) * time.Millisecond
+}
+
+// queryTimeoutMs is the query timeout as handed to the server, unknown DSN
+// options are set as session variables by both drivers.
+func queryTimeoutMs() int64 {
+	return int64(DbCfg.QueryTimeout / time.Millisecond)
 }
@@ -0,0 +1,53 @@
+package sqlstore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQueryTimeout(t *testing.T) {
+
+	Convey("Testing the query timeout in the connection string", t, func() {
+		saved := DbCfg
+		defer func() { DbCfg = saved }()
+
+		DbCfg.User, DbCfg.Pwd, DbCfg.Name = "grafana", "secret", "grafana"
+		DbCfg.QueryTimeout = 1500 * time.Millisecond
+
+		Convey("Should set max_execution_time for mysql", func() {
+			DbCfg.Type, DbCfg.Host = "mysql", "127.0.0.1:3306"
+
+			cnnstr, err := connectionString()
+			So(err, ShouldBeNil)
+			So(cnnstr, ShouldEqual, "grafana:secret@tcp(127.0.0.1:3306)/grafana?charset=utf8&max_execution_time=1500")
+		})
+
+		Convey("Should set statement_timeout for postgres", func() {
+			DbCfg.Type, DbCfg.Host, DbCfg.SslMode = "postgres", "db:5433", "disable"
+
+			cnnstr, err := connectionString()
+			So(err, ShouldBeNil)
+			So(cnnstr, ShouldEqual, "user=grafana password=secret host=db port=5433 dbname=grafana sslmode=disable statement_timeout=1500")
+		})
+
+		Convey("Should leave sqlite3 alone", func() {
+			DbCfg.Type, DbCfg.Path = "sqlite3", filepath.Join(t.TempDir(), "grafana.db")
+
+			cnnstr, err := connectionString()
+			So(err, ShouldBeNil)
+			So(cnnstr, ShouldEqual, "file:"+DbCfg.Path+"?cache=shared&mode=rwc&_loc=Local")
+		})
+
+		Convey("Should not bound queries when unset", func() {
+			DbCfg.Type, DbCfg.Host = "mysql", "127.0.0.1:3306"
+			DbCfg.QueryTimeout = 0
+
+			cnnstr, err := connectionString()
+			So(err, ShouldBeNil)
+			So(cnnstr, ShouldNotContainSubstring, "max_execution_time")
+		})
+	})
+}
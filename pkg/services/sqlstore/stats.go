@@ -8,10 +8,12 @@ import (
 func init() {
 	bus.AddHandler("sql", GetSystemStats)
 	bus.AddHandler("sql", GetDataSourceStats)
+	bus.AddHandler("sql", GetOrgStats)
+	bus.AddHandler("sql", GetAdminStats)
 }
 
 func GetDataSourceStats(query *m.GetDataSourceStatsQuery) error {
-	var rawSql = `SELECT COUNT(*) as count, type FROM data_source GROUP BY type`
+	var rawSql = `SELECT COUNT(*) as count, type FROM data_source WHERE deleted IS NULL GROUP BY type`
 	query.Result = make([]*m.DataSourceStats, 0)
 	err := x.Sql(rawSql).Find(&query.Result)
 	if err != nil {
@@ -34,6 +36,7 @@ func GetSystemStats(query *m.GetSystemStatsQuery) error {
       (
         SELECT COUNT(*)
         FROM ` + dialect.Quote("dashboard") + `
+        WHERE deleted IS NULL
       ) AS dashboard_count
 			`
 
@@ -46,3 +49,78 @@ func GetSystemStats(query *m.GetSystemStatsQuery) error {
 	query.Result = &stats
 	return err
 }
+
+func GetOrgStats(query *m.GetOrgStatsQuery) error {
+	var rawSql = `SELECT
+			org.id,
+			(
+				SELECT COUNT(*)
+				FROM org_user
+				WHERE org_user.org_id = org.id
+			) AS user_count,
+			(
+				SELECT COUNT(*)
+				FROM dashboard
+				WHERE dashboard.org_id = org.id AND dashboard.deleted IS NULL
+			) AS dashboard_count,
+			(
+				SELECT COUNT(*)
+				FROM data_source
+				WHERE data_source.org_id = org.id AND data_source.deleted IS NULL
+			) AS data_source_count,
+			(
+				SELECT COUNT(*)
+				FROM api_key
+				WHERE api_key.org_id = org.id
+			) AS api_key_count
+			FROM ` + dialect.Quote("org") + ` AS org
+			WHERE org.id = ?`
+
+	var stats m.OrgStats
+	has, err := x.Sql(rawSql, query.OrgId).Get(&stats)
+	if err != nil {
+		return err
+	}
+
+	if !has {
+		return m.ErrOrgNotFound
+	}
+
+	query.Result = &stats
+	return nil
+}
+
+func GetAdminStats(query *m.GetAdminStatsQuery) error {
+	var rawSql = `SELECT
+			(
+				SELECT COUNT(*)
+				FROM ` + dialect.Quote("org") + `
+			) AS org_count,
+			(
+				SELECT COUNT(*)
+				FROM ` + dialect.Quote("user") + `
+			) AS user_count,
+			(
+				SELECT COUNT(*)
+				FROM user_session
+				WHERE updated >= ?
+			) AS active_session_count,
+			(
+				SELECT COUNT(*)
+				FROM dashboard
+				WHERE deleted IS NULL
+			) AS dashboard_count,
+			(
+				SELECT COUNT(*)
+				FROM dashboard_snapshot
+			) AS snapshot_count`
+
+	var stats m.AdminStats
+	_, err := x.Sql(rawSql, query.ActiveSince).Get(&stats)
+	if err != nil {
+		return err
+	}
+
+	query.Result = &stats
+	return nil
+}
@@ -0,0 +1,92 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+)
+
+func TestStatsDataAccess(t *testing.T) {
+
+	Convey("Testing stats data access", t, func() {
+		InitTestDB(t)
+
+		Convey("Given an org with users, dashboards, data sources and api keys", func() {
+			orgCmd := m.CreateOrgCommand{Name: "stats org", UserId: 1}
+			So(CreateOrg(&orgCmd), ShouldBeNil)
+			orgId := orgCmd.Result.Id
+
+			otherCmd := m.CreateOrgCommand{Name: "other org", UserId: 1}
+			So(CreateOrg(&otherCmd), ShouldBeNil)
+
+			So(AddOrgUser(&m.AddOrgUserCommand{OrgId: orgId, UserId: 2, Role: m.ROLE_VIEWER}), ShouldBeNil)
+
+			insertTestDashboard("stats dash 1", orgId)
+			insertTestDashboard("stats dash 2", orgId)
+			insertTestDashboard("other dash", otherCmd.Result.Id)
+
+			ds1 := m.AddDataSourceCommand{OrgId: orgId, Name: "ds1", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_PROXY}
+			So(AddDataSource(&ds1), ShouldBeNil)
+			ds2 := m.AddDataSourceCommand{OrgId: orgId, Name: "ds2", Type: m.DS_GRAPHITE, Access: m.DS_ACCESS_PROXY}
+			So(AddDataSource(&ds2), ShouldBeNil)
+			So(DeleteDataSource(&m.DeleteDataSourceCommand{Id: ds2.Result.Id, OrgId: orgId}), ShouldBeNil)
+
+			So(AddApiKey(&m.AddApiKeyCommand{OrgId: orgId, Name: "key", Role: m.ROLE_VIEWER, Key: "secret"}), ShouldBeNil)
+
+			Convey("Should count everything belonging to the org", func() {
+				query := m.GetOrgStatsQuery{OrgId: orgId}
+				So(GetOrgStats(&query), ShouldBeNil)
+
+				So(query.Result.Id, ShouldEqual, orgId)
+				So(query.Result.UserCount, ShouldEqual, 2)
+				So(query.Result.DashboardCount, ShouldEqual, 2)
+				So(query.Result.DataSourceCount, ShouldEqual, 1)
+				So(query.Result.ApiKeyCount, ShouldEqual, 1)
+			})
+
+			Convey("Should return not found for a missing org", func() {
+				query := m.GetOrgStatsQuery{OrgId: 999}
+				So(GetOrgStats(&query), ShouldEqual, m.ErrOrgNotFound)
+			})
+		})
+
+		Convey("Given several orgs, users, logins, dashboards and snapshots", func() {
+			setting.AutoAssignOrg = false
+
+			users := make([]int64, 0)
+			for _, login := range []string{"a", "b", "c"} {
+				cmd := m.CreateUserCommand{Login: login, Email: login + "@test.com", OrgName: "org " + login}
+				So(CreateUser(&cmd), ShouldBeNil)
+				users = append(users, cmd.Result.Id)
+			}
+
+			for _, userId := range []int64{users[0], users[0], users[1], users[2]} {
+				So(CreateUserSession(&m.CreateUserSessionCommand{UserId: userId}), ShouldBeNil)
+			}
+			So(CreateRememberToken(&m.CreateRememberTokenCommand{UserId: users[2], Series: "c1", Token: "t"}), ShouldBeNil)
+			_, err := x.Exec("UPDATE user_session SET updated=? WHERE user_id=?", time.Now().AddDate(0, 0, -30), users[2])
+			So(err, ShouldBeNil)
+
+			insertTestDashboard("admin dash 1", 1)
+			insertTestDashboard("admin dash 2", 2)
+
+			snapshot := m.CreateDashboardSnapshotCommand{Key: "admin-stats", Dashboard: map[string]interface{}{}}
+			So(CreateDashboardSnapshot(&snapshot), ShouldBeNil)
+
+			Convey("Should return the instance wide totals", func() {
+				query := m.GetAdminStatsQuery{ActiveSince: time.Now().Add(-time.Hour)}
+				So(GetAdminStats(&query), ShouldBeNil)
+
+				So(query.Result.OrgCount, ShouldEqual, 3)
+				So(query.Result.UserCount, ShouldEqual, 3)
+				So(query.Result.ActiveSessionCount, ShouldEqual, 3)
+				So(query.Result.DashboardCount, ShouldEqual, 2)
+				So(query.Result.SnapshotCount, ShouldEqual, 1)
+			})
+		})
+	})
+}
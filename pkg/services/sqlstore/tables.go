@@ -7,3 +7,9 @@ type DashboardTag struct {
 	DashboardId int64
 	Term        string
 }
+
+type AnnotationTag struct {
+	Id           int64
+	AnnotationId int64
+	Term         string
+}
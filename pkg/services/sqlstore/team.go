@@ -0,0 +1,226 @@
+package sqlstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", CreateTeam)
+	bus.AddHandler("sql", UpdateTeam)
+	bus.AddHandler("sql", DeleteTeam)
+	bus.AddHandler("sql", GetTeamById)
+	bus.AddHandler("sql", GetTeamsByOrg)
+	bus.AddHandler("sql", AddTeamMember)
+	bus.AddHandler("sql", RemoveTeamMember)
+	bus.AddHandler("sql", GetTeamMembers)
+	bus.AddHandler("sql", GetUserTeams)
+}
+
+func isTeamNameTaken(orgId int64, name string, existingId int64, sess *session) (bool, error) {
+	var team m.Team
+	exists, err := sess.Where("org_id=? AND name=?", orgId, name).Get(&team)
+	if err != nil {
+		return false, err
+	}
+
+	return exists && team.Id != existingId, nil
+}
+
+func CreateTeam(cmd *m.CreateTeamCommand) error {
+	return inTransaction2(func(sess *session) error {
+		if taken, err := isTeamNameTaken(cmd.OrgId, cmd.Name, 0, sess); err != nil {
+			return err
+		} else if taken {
+			return m.ErrTeamNameTaken
+		}
+
+		team := m.Team{
+			OrgId:   cmd.OrgId,
+			Name:    cmd.Name,
+			Created: time.Now(),
+			Updated: time.Now(),
+		}
+		if _, err := sess.Insert(&team); err != nil {
+			return err
+		}
+
+		cmd.Result = &team
+		return nil
+	})
+}
+
+func UpdateTeam(cmd *m.UpdateTeamCommand) error {
+	return inTransaction2(func(sess *session) error {
+		if taken, err := isTeamNameTaken(cmd.OrgId, cmd.Name, cmd.Id, sess); err != nil {
+			return err
+		} else if taken {
+			return m.ErrTeamNameTaken
+		}
+
+		team := m.Team{Name: cmd.Name, Updated: time.Now()}
+		affected, err := sess.Where("id=? AND org_id=?", cmd.Id, cmd.OrgId).Cols("name", "updated").Update(&team)
+		if err != nil {
+			return err
+		} else if affected == 0 {
+			return m.ErrTeamNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteTeam removes the team with its members and the dashboard
+// permissions granted to it. A dashboard left without any acl would be open
+// to every org role again, so it keeps an admin only acl instead.
+func DeleteTeam(cmd *m.DeleteTeamCommand) error {
+	return inTransaction2(func(sess *session) error {
+		if has, err := sess.Where("id=? AND org_id=?", cmd.Id, cmd.OrgId).Get(&m.Team{}); err != nil {
+			return err
+		} else if !has {
+			return m.ErrTeamNotFound
+		}
+
+		var granted []*m.DashboardAcl
+		if err := sess.Where("team_id=?", cmd.Id).Find(&granted); err != nil {
+			return err
+		}
+
+		deletes := []string{
+			"DELETE FROM team_member WHERE team_id = ?",
+			"DELETE FROM dashboard_acl WHERE team_id = ?",
+			"DELETE FROM team WHERE id = ?",
+		}
+
+		for _, sql := range deletes {
+			if _, err := sess.Exec(sql, cmd.Id); err != nil {
+				return err
+			}
+		}
+
+		for _, item := range granted {
+			if left, err := sess.Where("dashboard_id=?", item.DashboardId).Count(&m.DashboardAcl{}); err != nil {
+				return err
+			} else if left > 0 {
+				continue
+			}
+
+			now := time.Now()
+			restricted := m.DashboardAcl{
+				OrgId:       item.OrgId,
+				DashboardId: item.DashboardId,
+				Role:        m.ROLE_ADMIN,
+				Permission:  m.PERMISSION_EDIT,
+				Created:     now,
+				Updated:     now,
+			}
+			if _, err := sess.Insert(&restricted); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func GetTeamById(query *m.GetTeamByIdQuery) error {
+	var team m.Team
+	has, err := x.Where("id=? AND org_id=?", query.Id, query.OrgId).Get(&team)
+	if err != nil {
+		return err
+	} else if !has {
+		return m.ErrTeamNotFound
+	}
+
+	query.Result = &team
+	return nil
+}
+
+func GetTeamsByOrg(query *m.GetTeamsByOrgQuery) error {
+	query.Result = make([]*m.Team, 0)
+	return x.Where("org_id=?", query.OrgId).Asc("name").Find(&query.Result)
+}
+
+func AddTeamMember(cmd *m.AddTeamMemberCommand) error {
+	return inTransaction2(func(sess *session) error {
+		if has, err := sess.Where("id=? AND org_id=?", cmd.TeamId, cmd.OrgId).Get(&m.Team{}); err != nil {
+			return err
+		} else if !has {
+			return m.ErrTeamNotFound
+		}
+
+		if has, err := sess.Where("org_id=? AND user_id=?", cmd.OrgId, cmd.UserId).Get(&m.OrgUser{}); err != nil {
+			return err
+		} else if !has {
+			return m.ErrOrgUserNotFound
+		}
+
+		if has, err := sess.Where("team_id=? AND user_id=?", cmd.TeamId, cmd.UserId).Get(&m.TeamMember{}); err != nil {
+			return err
+		} else if has {
+			return m.ErrTeamMemberAlreadyAdded
+		}
+
+		_, err := sess.Insert(&m.TeamMember{
+			OrgId:   cmd.OrgId,
+			TeamId:  cmd.TeamId,
+			UserId:  cmd.UserId,
+			Created: time.Now(),
+		})
+		return err
+	})
+}
+
+func RemoveTeamMember(cmd *m.RemoveTeamMemberCommand) error {
+	return inTransaction2(func(sess *session) error {
+		res, err := sess.Exec("DELETE FROM team_member WHERE org_id=? AND team_id=? AND user_id=?", cmd.OrgId, cmd.TeamId, cmd.UserId)
+		if err != nil {
+			return err
+		}
+
+		if rows, err := res.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return m.ErrTeamMemberNotFound
+		}
+		return nil
+	})
+}
+
+func GetTeamMembers(query *m.GetTeamMembersQuery) error {
+	query.Result = make([]*m.TeamMemberDTO, 0)
+	sess := x.Table("team_member")
+	sess.Join("INNER", "user", fmt.Sprintf("team_member.user_id=%s.id", x.Dialect().Quote("user")))
+	sess.Where("team_member.org_id=? AND team_member.team_id=?", query.OrgId, query.TeamId)
+	sess.Cols("team_member.team_id", "team_member.user_id", "user.email", "user.login")
+	sess.Asc("user.login")
+
+	return sess.Find(&query.Result)
+}
+
+func GetUserTeams(query *m.GetUserTeamsQuery) error {
+	query.Result = make([]*m.Team, 0)
+	sess := x.Table("team")
+	sess.Join("INNER", "team_member", "team_member.team_id=team.id")
+	sess.Where("team.org_id=? AND team_member.user_id=?", query.OrgId, query.UserId)
+	sess.Cols("team.id", "team.org_id", "team.name", "team.created", "team.updated")
+	sess.Asc("team.name")
+
+	return sess.Find(&query.Result)
+}
+
+// getUserTeamIds lists the teams of the org the user is a member of, within
+// a transaction.
+func getUserTeamIds(sess *session, orgId, userId int64) ([]int64, error) {
+	var members []*m.TeamMember
+	if err := sess.Where("org_id=? AND user_id=?", orgId, userId).Cols("team_id").Find(&members); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.TeamId)
+	}
+	return ids, nil
+}
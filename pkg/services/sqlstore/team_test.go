@@ -0,0 +1,143 @@
+package sqlstore
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestTeamDataAccess(t *testing.T) {
+
+	Convey("Testing teams", t, func() {
+		InitTestDB(t)
+
+		userCmd := m.CreateUserCommand{Login: "member", Email: "member@test.com"}
+		So(CreateUser(&userCmd), ShouldBeNil)
+		user := userCmd.Result
+		orgId := user.OrgId
+
+		createCmd := m.CreateTeamCommand{OrgId: orgId, Name: "ops"}
+		So(CreateTeam(&createCmd), ShouldBeNil)
+		team := createCmd.Result
+
+		countRows := func(table string) int {
+			res, err := x.Query("SELECT 1 FROM "+table+" WHERE org_id=?", orgId)
+			So(err, ShouldBeNil)
+			return len(res)
+		}
+
+		Convey("Should be listed for the org", func() {
+			query := m.GetTeamsByOrgQuery{OrgId: orgId}
+			So(GetTeamsByOrg(&query), ShouldBeNil)
+			So(len(query.Result), ShouldEqual, 1)
+			So(query.Result[0].Name, ShouldEqual, "ops")
+		})
+
+		Convey("Should not be found from another org", func() {
+			query := m.GetTeamByIdQuery{Id: team.Id, OrgId: orgId + 1}
+			So(GetTeamById(&query), ShouldEqual, m.ErrTeamNotFound)
+		})
+
+		Convey("Should reject a taken name", func() {
+			So(CreateTeam(&m.CreateTeamCommand{OrgId: orgId, Name: "ops"}), ShouldEqual, m.ErrTeamNameTaken)
+		})
+
+		Convey("Should be renamed", func() {
+			So(UpdateTeam(&m.UpdateTeamCommand{Id: team.Id, OrgId: orgId, Name: "sre"}), ShouldBeNil)
+
+			query := m.GetTeamByIdQuery{Id: team.Id, OrgId: orgId}
+			So(GetTeamById(&query), ShouldBeNil)
+			So(query.Result.Name, ShouldEqual, "sre")
+
+			So(UpdateTeam(&m.UpdateTeamCommand{Id: team.Id + 1, OrgId: orgId, Name: "x"}), ShouldEqual, m.ErrTeamNotFound)
+		})
+
+		Convey("Should only add members of the org", func() {
+			cmd := m.AddTeamMemberCommand{OrgId: orgId, TeamId: team.Id, UserId: user.Id + 100}
+			So(AddTeamMember(&cmd), ShouldEqual, m.ErrOrgUserNotFound)
+		})
+
+		Convey("When a user is added", func() {
+			So(AddTeamMember(&m.AddTeamMemberCommand{OrgId: orgId, TeamId: team.Id, UserId: user.Id}), ShouldBeNil)
+
+			Convey("Should be listed as member", func() {
+				query := m.GetTeamMembersQuery{OrgId: orgId, TeamId: team.Id}
+				So(GetTeamMembers(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Login, ShouldEqual, "member")
+			})
+
+			Convey("Should list the team for the user", func() {
+				query := m.GetUserTeamsQuery{OrgId: orgId, UserId: user.Id}
+				So(GetUserTeams(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Id, ShouldEqual, team.Id)
+			})
+
+			Convey("Should not be added twice", func() {
+				cmd := m.AddTeamMemberCommand{OrgId: orgId, TeamId: team.Id, UserId: user.Id}
+				So(AddTeamMember(&cmd), ShouldEqual, m.ErrTeamMemberAlreadyAdded)
+			})
+
+			Convey("Should be removable", func() {
+				cmd := m.RemoveTeamMemberCommand{OrgId: orgId, TeamId: team.Id, UserId: user.Id}
+				So(RemoveTeamMember(&cmd), ShouldBeNil)
+				So(RemoveTeamMember(&cmd), ShouldEqual, m.ErrTeamMemberNotFound)
+			})
+
+			Convey("Should be granted dashboard permissions", func() {
+				dash := insertTestDashboard("team dash", orgId)
+				aclCmd := m.SetDashboardAclCommand{OrgId: orgId, DashboardId: dash.Id, TeamId: team.Id, Permission: m.PERMISSION_EDIT}
+				So(SetDashboardAcl(&aclCmd), ShouldBeNil)
+				So(aclCmd.Result.TeamId, ShouldEqual, team.Id)
+
+				Convey("Should lose them with the team", func() {
+					viewer := m.SetDashboardAclCommand{OrgId: orgId, DashboardId: dash.Id, Role: m.ROLE_VIEWER, Permission: m.PERMISSION_VIEW}
+					So(SetDashboardAcl(&viewer), ShouldBeNil)
+
+					So(DeleteTeam(&m.DeleteTeamCommand{Id: team.Id, OrgId: orgId}), ShouldBeNil)
+
+					query := m.GetDashboardAclQuery{OrgId: orgId, DashboardId: dash.Id}
+					So(GetDashboardAcl(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Role, ShouldEqual, m.ROLE_VIEWER)
+				})
+
+				Convey("Should keep the dashboard restricted to admins when it was the only acl item", func() {
+					So(DeleteTeam(&m.DeleteTeamCommand{Id: team.Id, OrgId: orgId}), ShouldBeNil)
+
+					query := m.GetDashboardAclQuery{OrgId: orgId, DashboardId: dash.Id}
+					So(GetDashboardAcl(&query), ShouldBeNil)
+					So(len(query.Result), ShouldEqual, 1)
+					So(query.Result[0].Role, ShouldEqual, m.ROLE_ADMIN)
+					So(m.GetDashboardPermission(query.Result, user.Id, nil, m.ROLE_EDITOR), ShouldEqual, m.PERMISSION_NONE)
+				})
+			})
+
+			Convey("Should delete the members with the team", func() {
+				So(DeleteTeam(&m.DeleteTeamCommand{Id: team.Id, OrgId: orgId}), ShouldBeNil)
+				So(countRows("team"), ShouldEqual, 0)
+				So(countRows("team_member"), ShouldEqual, 0)
+
+				So(DeleteTeam(&m.DeleteTeamCommand{Id: team.Id, OrgId: orgId}), ShouldEqual, m.ErrTeamNotFound)
+			})
+
+			Convey("Should delete teams and members with the org", func() {
+				So(DeleteOrg(&m.DeleteOrgCommand{Id: orgId}), ShouldBeNil)
+				So(countRows("team"), ShouldEqual, 0)
+				So(countRows("team_member"), ShouldEqual, 0)
+			})
+		})
+
+		Convey("Should reject an acl item for a team of another org", func() {
+			dash := insertTestDashboard("other dash", orgId)
+			team2 := m.CreateTeamCommand{OrgId: orgId + 1, Name: "elsewhere"}
+			So(CreateTeam(&team2), ShouldBeNil)
+
+			cmd := m.SetDashboardAclCommand{OrgId: orgId, DashboardId: dash.Id, TeamId: team2.Result.Id, Permission: m.PERMISSION_VIEW}
+			So(SetDashboardAcl(&cmd), ShouldEqual, m.ErrTeamNotFound)
+		})
+	})
+}
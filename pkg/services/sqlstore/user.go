@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/go-xorm/xorm"
+	"golang.org/x/net/context"
 
 	"github.com/Cepave/grafana/pkg/bus"
 	"github.com/Cepave/grafana/pkg/events"
@@ -16,6 +17,7 @@ import (
 func init() {
 	bus.AddHandler("sql", CreateUser)
 	bus.AddHandler("sql", GetUserById)
+	bus.AddHandler("sql", GetUsersByIds)
 	bus.AddHandler("sql", UpdateUser)
 	bus.AddHandler("sql", ChangeUserPassword)
 	bus.AddHandler("sql", GetUserByLogin)
@@ -23,6 +25,11 @@ func init() {
 	bus.AddHandler("sql", GetUserProfile)
 	bus.AddHandler("sql", GetSignedInUser)
 	bus.AddHandler("sql", SearchUsers)
+	bus.AddHandler("sql", UpdateUserLastSeenAt)
+	bus.AddHandler("sql", GetUserOwnedResources)
+	bus.AddHandler("sql", DisableUser)
+	bus.AddHandler("sql", EnableUser)
+	bus.AddHandler("sql", VerifyUserEmail)
 	bus.AddHandler("sql", GetUserOrgList)
 	bus.AddHandler("sql", DeleteUser)
 	bus.AddHandler("sql", SetUsingOrg)
@@ -37,8 +44,11 @@ func getOrgIdForNewUser(cmd *m.CreateUserCommand, sess *session) (int64, error)
 	var org m.Org
 
 	if setting.AutoAssignOrg {
-		// right now auto assign to org with id 1
-		has, err := sess.Where("id=?", 1).Get(&org)
+		if !m.RoleType(setting.AutoAssignOrgRole).IsValid() {
+			return 0, m.ErrInvalidRoleType
+		}
+
+		has, err := sess.Where("id=?", setting.AutoAssignOrgId).Get(&org)
 		if err != nil {
 			return 0, err
 		}
@@ -46,7 +56,7 @@ func getOrgIdForNewUser(cmd *m.CreateUserCommand, sess *session) (int64, error)
 			return org.Id, nil
 		} else {
 			org.Name = "Main Org."
-			org.Id = 1
+			org.Id = setting.AutoAssignOrgId
 		}
 	} else {
 		org.Name = cmd.OrgName
@@ -73,15 +83,30 @@ func getOrgIdForNewUser(cmd *m.CreateUserCommand, sess *session) (int64, error)
 
 func CreateUser(cmd *m.CreateUserCommand) error {
 	return inTransaction2(func(sess *session) error {
-		orgId, err := getOrgIdForNewUser(cmd, sess)
-		if err != nil {
+		// logins keep their casing for display but are compared trimmed and lowercased
+		cmd.Login = strings.TrimSpace(cmd.Login)
+		if taken, err := sess.Where("LOWER(login)=?", strings.ToLower(cmd.Login)).Get(&m.User{}); err != nil {
 			return err
+		} else if taken {
+			return m.ErrUserLoginTaken
 		}
 
 		if cmd.Email == "" {
 			cmd.Email = cmd.Login
 		}
 
+		// emails are compared case insensitively, they are used to log in and reset passwords
+		if taken, err := sess.Where("LOWER(email)=?", strings.ToLower(cmd.Email)).Get(&m.User{}); err != nil {
+			return err
+		} else if taken {
+			return m.ErrUserEmailTaken
+		}
+
+		orgId, err := getOrgIdForNewUser(cmd, sess)
+		if err != nil {
+			return err
+		}
+
 		// create user
 		user := m.User{
 			Email:         cmd.Email,
@@ -98,7 +123,8 @@ func CreateUser(cmd *m.CreateUserCommand) error {
 		if len(cmd.Password) > 0 {
 			user.Salt = util.GetRandomString(10)
 			user.Rands = util.GetRandomString(10)
-			user.Password = util.EncodePassword(cmd.Password, user.Salt)
+			user.PasswordCost = setting.PasswordHashCost
+			user.Password = util.EncodePasswordWithCost(cmd.Password, user.Salt, user.PasswordCost)
 		}
 
 		sess.UseBool("is_admin")
@@ -155,24 +181,36 @@ func GetUserById(query *m.GetUserByIdQuery) error {
 	return nil
 }
 
+func GetUsersByIds(query *m.GetUsersByIdsQuery) error {
+	query.Result = make([]*m.UserDTO, 0)
+	if len(query.Ids) == 0 {
+		return nil
+	}
+
+	ids := make([]interface{}, 0, len(query.Ids))
+	for _, id := range query.Ids {
+		ids = append(ids, id)
+	}
+
+	err := x.Table("user").In("id", ids...).Cols("id", "login", "email").Asc("id").Find(&query.Result)
+	for _, user := range query.Result {
+		user.AvatarUrl = m.GetAvatarUrl(user.Email)
+	}
+
+	return err
+}
+
 func GetUserByLogin(query *m.GetUserByLoginQuery) error {
 	if query.LoginOrEmail == "" {
 		return m.ErrUserNotFound
 	}
 
-	user := new(m.User)
-	if strings.Contains(query.LoginOrEmail, "@") {
-		user = &m.User{Email: query.LoginOrEmail}
-	} else {
-		user = &m.User{Login: query.LoginOrEmail}
-	}
-
-	has, err := x.Get(user)
+	sess := x.NewSession()
+	defer sess.Close()
 
+	user, err := getUserByLoginOrEmail(sess, query.LoginOrEmail)
 	if err != nil {
 		return err
-	} else if has == false {
-		return m.ErrUserNotFound
 	}
 
 	query.Result = user
@@ -180,6 +218,32 @@ func GetUserByLogin(query *m.GetUserByLoginQuery) error {
 	return nil
 }
 
+// getUserByLoginOrEmail finds the user by email when loginOrEmail has an @ and
+// by login otherwise. An exact match wins, the case is ignored when only one
+// user matches that way.
+func getUserByLoginOrEmail(sess *xorm.Session, loginOrEmail string) (*m.User, error) {
+	column := "login"
+	if strings.Contains(loginOrEmail, "@") {
+		column = "email"
+	}
+
+	users := make([]*m.User, 0)
+	if err := sess.Where("LOWER("+column+")=?", strings.ToLower(loginOrEmail)).Limit(10).Find(&users); err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if user.Login == loginOrEmail || user.Email == loginOrEmail {
+			return user, nil
+		}
+	}
+	if len(users) != 1 {
+		return nil, m.ErrUserNotFound
+	}
+
+	return users[0], nil
+}
+
 func UpdateUser(cmd *m.UpdateUserCommand) error {
 	return inTransaction2(func(sess *session) error {
 
@@ -191,6 +255,14 @@ func UpdateUser(cmd *m.UpdateUserCommand) error {
 			Updated: time.Now(),
 		}
 
+		// a new email address has to be verified again
+		if cmd.Email != "" && !cmd.EmailVerified {
+			rawSql := "UPDATE " + dialect.Quote("user") + " SET email_verified=? WHERE id=? AND email<>?"
+			if _, err := sess.Exec(rawSql, false, cmd.UserId, cmd.Email); err != nil {
+				return err
+			}
+		}
+
 		if _, err := sess.Id(cmd.UserId).Update(&user); err != nil {
 			return err
 		}
@@ -211,11 +283,12 @@ func ChangeUserPassword(cmd *m.ChangeUserPasswordCommand) error {
 	return inTransaction2(func(sess *session) error {
 
 		user := m.User{
-			Password: cmd.NewPassword,
-			Updated:  time.Now(),
+			Password:     cmd.NewPassword,
+			PasswordCost: cmd.PasswordCost,
+			Updated:      time.Now(),
 		}
 
-		if _, err := sess.Id(cmd.UserId).Update(&user); err != nil {
+		if _, err := sess.Id(cmd.UserId).MustCols("password_cost").Update(&user); err != nil {
 			return err
 		}
 
@@ -234,6 +307,55 @@ func SetUsingOrg(cmd *m.SetUsingOrgCommand) error {
 	})
 }
 
+// DisableUser keeps the user and their history but stops them from signing
+// in, remember me logins are revoked.
+func DisableUser(cmd *m.DisableUserCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		if err := setUserDisabled(sess, cmd.UserId, true); err != nil {
+			return err
+		}
+
+		_, err := sess.Exec("DELETE FROM remember_token WHERE user_id = ?", cmd.UserId)
+		return err
+	})
+}
+
+func VerifyUserEmail(cmd *m.VerifyUserEmailCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		user := m.User{EmailVerified: true, Updated: time.Now()}
+		_, err := sess.Id(cmd.UserId).Cols("email_verified", "updated").Update(&user)
+		return err
+	})
+}
+
+func EnableUser(cmd *m.EnableUserCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		return setUserDisabled(sess, cmd.UserId, false)
+	})
+}
+
+func setUserDisabled(sess *xorm.Session, userId int64, disabled bool) error {
+	if exists, err := sess.Id(userId).Get(&m.User{}); err != nil {
+		return err
+	} else if !exists {
+		return m.ErrUserNotFound
+	}
+
+	user := m.User{IsDisabled: disabled, Updated: time.Now()}
+	sess.UseBool("is_disabled")
+	_, err := sess.Id(userId).Cols("is_disabled", "updated").Update(&user)
+	return err
+}
+
+func UpdateUserLastSeenAt(cmd *m.UpdateUserLastSeenAtCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		now := time.Now()
+		user := m.User{LastSeenAt: &now}
+		_, err := sess.Id(cmd.UserId).Cols("last_seen_at").Update(&user)
+		return err
+	})
+}
+
 func GetUserProfile(query *m.GetUserProfileQuery) error {
 	var user m.User
 	has, err := x.Id(query.UserId).Get(&user)
@@ -274,6 +396,9 @@ func GetSignedInUser(query *m.GetSignedInUserQuery) error {
 	                u.login        as login,
 									u.name         as name,
 									u.theme        as theme,
+									u.last_seen_at as last_seen_at,
+									u.is_disabled  as is_disabled,
+									u.email_verified as email_verified,
 	                org.name       as org_name,
 	                org_user.role  as org_role,
 	                org.id         as org_id
@@ -303,24 +428,56 @@ func GetSignedInUser(query *m.GetSignedInUserQuery) error {
 		user.OrgName = "Org missing"
 	}
 
+	user.AvatarUrl = m.GetAvatarUrl(user.Email)
 	query.Result = &user
 	return err
 }
 
-func SearchUsers(query *m.SearchUsersQuery) error {
-	query.Result = make([]*m.UserSearchHitDTO, 0)
-	sess := x.Table("user")
-	sess.Where("email LIKE ?", query.Query+"%")
-	sess.Limit(query.Limit, query.Limit*query.Page)
-	sess.Cols("id", "email", "name", "login", "is_admin")
-	err := sess.Find(&query.Result)
+func SearchUsers(ctx context.Context, query *m.SearchUsersQuery) error {
+	result := make([]*m.UserSearchHitDTO, 0)
+	err := withContext(ctx, func() error {
+		sess := readSession()
+		defer sess.Close()
+
+		sess.Table("user")
+		sess.Where(dialect.ILike("email"), query.Query+"%")
+		sess.Limit(query.Limit, query.Limit*query.Page)
+		sess.Cols("id", "email", "name", "login", "is_admin", "is_disabled")
+		return sess.Find(&result)
+	}, func() error {
+		countSess := readSession()
+		defer countSess.Close()
+
+		total, err := countSess.Where(dialect.ILike("email"), query.Query+"%").Count(&m.User{})
+		query.TotalCount = total
+		return err
+	})
+
+	if err == nil {
+		query.Result = result
+	}
 	return err
 }
 
 func DeleteUser(cmd *m.DeleteUserCommand) error {
 	return inTransaction(func(sess *xorm.Session) error {
+		if err := validateOtherGrafanaAdminLeft(cmd.UserId, sess); err != nil {
+			return err
+		}
+		if err := validateOrgsKeepAnAdmin(cmd.UserId, sess); err != nil {
+			return err
+		}
+
 		deletes := []string{
 			"DELETE FROM star WHERE user_id = ?",
+			"DELETE FROM org_user WHERE user_id = ?",
+			"DELETE FROM preferences WHERE user_id = ?",
+			"DELETE FROM quota WHERE user_id = ?",
+			"DELETE FROM remember_token WHERE user_id = ?",
+			"DELETE FROM user_session WHERE user_id = ?",
+			"DELETE FROM managed_org WHERE user_id = ?",
+			"DELETE FROM dashboard_acl WHERE user_id = ?",
+			"DELETE FROM team_member WHERE user_id = ?",
 			"DELETE FROM " + dialect.Quote("user") + " WHERE id = ?",
 		}
 
@@ -335,8 +492,35 @@ func DeleteUser(cmd *m.DeleteUserCommand) error {
 	})
 }
 
+// validateOtherGrafanaAdminLeft fails when userId is the only grafana admin
+// able to sign in, so removing or downgrading them would lock everyone out of
+// administration.
+func validateOtherGrafanaAdminLeft(userId int64, sess *xorm.Session) error {
+	var user m.User
+	if exists, err := sess.Id(userId).Get(&user); err != nil || !exists || !user.IsAdmin {
+		return err
+	}
+
+	others, err := sess.Where("is_admin=? AND is_disabled=? AND id<>?", true, false, userId).Count(&m.User{})
+	if err != nil {
+		return err
+	}
+
+	if others == 0 {
+		return m.ErrLastGrafanaAdmin
+	}
+
+	return nil
+}
+
 func UpdateUserPermissions(cmd *m.UpdateUserPermissionsCommand) error {
 	return inTransaction(func(sess *xorm.Session) error {
+		if !cmd.IsGrafanaAdmin {
+			if err := validateOtherGrafanaAdminLeft(cmd.UserId, sess); err != nil {
+				return err
+			}
+		}
+
 		user := m.User{}
 		sess.Id(cmd.UserId).Get(&user)
 
@@ -346,3 +530,51 @@ func UpdateUserPermissions(cmd *m.UpdateUserPermissionsCommand) error {
 		return err
 	})
 }
+
+func GetUserOwnedResources(query *m.GetUserOwnedResourcesQuery) error {
+	result := &m.UserOwnedResourcesDTO{
+		SoleAdminOrgs: make([]*m.OrgDTO, 0),
+		Dashboards:    make([]*m.UserOwnedDashboardDTO, 0),
+		ApiKeys:       make([]*m.UserOwnedApiKeyDTO, 0),
+		Snapshots:     make([]*m.DashboardSnapshotDTO, 0),
+	}
+
+	var rawSql = `SELECT org.id, org.name
+		FROM org_user
+		INNER JOIN org ON org.id = org_user.org_id
+		WHERE org_user.user_id = ? AND org_user.role = ?
+		AND NOT EXISTS (
+			SELECT 1 FROM org_user AS other
+			WHERE other.org_id = org_user.org_id AND other.role = ? AND other.user_id <> org_user.user_id
+		)
+		ORDER BY org.name`
+
+	if err := x.Sql(rawSql, query.UserId, m.ROLE_ADMIN, m.ROLE_ADMIN).Find(&result.SoleAdminOrgs); err != nil {
+		return err
+	}
+
+	if len(result.SoleAdminOrgs) > 0 {
+		orgIds := make([]interface{}, 0, len(result.SoleAdminOrgs))
+		for _, org := range result.SoleAdminOrgs {
+			orgIds = append(orgIds, org.Id)
+		}
+
+		err := x.Table("dashboard").In("org_id", orgIds...).Cols("id", "org_id", "slug", "title").Asc("org_id", "title").Find(&result.Dashboards)
+		if err != nil {
+			return err
+		}
+
+		err = x.Table("api_key").In("org_id", orgIds...).Cols("id", "org_id", "name", "role").Asc("org_id", "name").Find(&result.ApiKeys)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := x.Table("dashboard_snapshot").Where("user_id=?", query.UserId).Desc("created").Find(&result.Snapshots)
+	if err != nil {
+		return err
+	}
+
+	query.Result = result
+	return nil
+}
@@ -0,0 +1,143 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", CreateUserSession)
+	bus.AddHandler("sql", GetUserSession)
+	bus.AddHandler("sql", GetUserSessions)
+	bus.AddHandler("sql", UpdateUserSessionSeen)
+	bus.AddHandler("sql", DeleteUserSession)
+	bus.AddHandler("sql", DeleteUserSessions)
+	bus.AddHandler("sql", PurgeUserSessions)
+}
+
+func CreateUserSession(cmd *m.CreateUserSessionCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		var userSession m.UserSession
+		renewed := false
+		if cmd.RememberSeries != "" {
+			var err error
+			renewed, err = sess.Where("user_id=? AND remember_series_hash=?", cmd.UserId, hashRememberToken(cmd.RememberSeries)).Get(&userSession)
+			if err != nil {
+				return err
+			}
+		}
+
+		userSession.Ip = cmd.Ip
+		userSession.UserAgent = truncateUserAgent(cmd.UserAgent)
+		userSession.Updated = time.Now()
+
+		if renewed {
+			if _, err := sess.Id(userSession.Id).Cols("ip", "user_agent", "updated").Update(&userSession); err != nil {
+				return err
+			}
+			cmd.Result = &userSession
+			return nil
+		}
+
+		userSession.UserId = cmd.UserId
+		userSession.Created = userSession.Updated
+		if cmd.RememberSeries != "" {
+			userSession.RememberSeriesHash = hashRememberToken(cmd.RememberSeries)
+		}
+		if _, err := sess.Insert(&userSession); err != nil {
+			return err
+		}
+
+		cmd.Result = &userSession
+		return nil
+	})
+}
+
+func GetUserSession(query *m.GetUserSessionQuery) error {
+	var userSession m.UserSession
+	exists, err := x.Where("id=? AND user_id=?", query.Id, query.UserId).Get(&userSession)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return m.ErrUserSessionNotFound
+	}
+
+	query.Result = &userSession
+	return nil
+}
+
+func GetUserSessions(query *m.GetUserSessionsQuery) error {
+	query.Result = make([]*m.UserSession, 0)
+	return x.Where("user_id=? AND updated >= ? AND created >= ?", query.UserId, query.SeenAfter, query.LoggedInAfter).
+		Desc("updated").
+		Find(&query.Result)
+}
+
+func UpdateUserSessionSeen(cmd *m.UpdateUserSessionSeenCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		userSession := m.UserSession{Ip: cmd.Ip, Updated: time.Now()}
+		_, err := sess.Id(cmd.Id).Cols("ip", "updated").Update(&userSession)
+		return err
+	})
+}
+
+func DeleteUserSession(cmd *m.DeleteUserSessionCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		var userSession m.UserSession
+		if has, err := sess.Where("id=? AND user_id=?", cmd.Id, cmd.UserId).Get(&userSession); err != nil {
+			return err
+		} else if !has {
+			return m.ErrUserSessionNotFound
+		}
+
+		if userSession.RememberSeriesHash != "" {
+			if _, err := sess.Exec("DELETE FROM remember_token WHERE series_hash=?", userSession.RememberSeriesHash); err != nil {
+				return err
+			}
+		}
+
+		_, err := sess.Exec("DELETE FROM user_session WHERE id=?", userSession.Id)
+		return err
+	})
+}
+
+func DeleteUserSessions(cmd *m.DeleteUserSessionsCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		if _, err := sess.Exec("DELETE FROM user_session WHERE user_id=? AND id<>?", cmd.UserId, cmd.KeepId); err != nil {
+			return err
+		}
+
+		if cmd.KeepSeries == "" {
+			_, err := sess.Exec("DELETE FROM remember_token WHERE user_id=?", cmd.UserId)
+			return err
+		}
+
+		_, err := sess.Exec("DELETE FROM remember_token WHERE user_id=? AND series_hash<>?", cmd.UserId, hashRememberToken(cmd.KeepSeries))
+		return err
+	})
+}
+
+func PurgeUserSessions(cmd *m.PurgeUserSessionsCommand) error {
+	return inTransaction(func(sess *xorm.Session) error {
+		result, err := sess.Exec("DELETE FROM user_session WHERE updated < ? OR created < ?", cmd.SeenBefore, cmd.LoggedInBefore)
+		if err != nil {
+			return err
+		}
+
+		cmd.Result, _ = result.RowsAffected()
+		return nil
+	})
+}
+
+// the user_agent column holds 255 characters
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > 255 {
+		return userAgent[:255]
+	}
+	return userAgent
+}
@@ -0,0 +1,177 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+)
+
+func TestUserSessionDataAccess(t *testing.T) {
+
+	Convey("Testing user session data access", t, func() {
+		InitTestDB(t)
+
+		first := m.CreateUserSessionCommand{UserId: 1, Ip: "10.0.0.1", UserAgent: "curl/7.47.0"}
+		So(CreateUserSession(&first), ShouldBeNil)
+		second := m.CreateUserSessionCommand{UserId: 1}
+		So(CreateUserSession(&second), ShouldBeNil)
+		other := m.CreateUserSessionCommand{UserId: 2}
+		So(CreateUserSession(&other), ShouldBeNil)
+
+		So(CreateRememberToken(&m.CreateRememberTokenCommand{UserId: 1, Series: "first", Token: "token"}), ShouldBeNil)
+		So(CreateRememberToken(&m.CreateRememberTokenCommand{UserId: 1, Series: "second", Token: "token"}), ShouldBeNil)
+		So(CreateRememberToken(&m.CreateRememberTokenCommand{UserId: 2, Series: "other", Token: "token"}), ShouldBeNil)
+
+		sessionExists := func(userId, id int64) bool {
+			err := GetUserSession(&m.GetUserSessionQuery{Id: id, UserId: userId})
+			if err != nil {
+				So(err, ShouldEqual, m.ErrUserSessionNotFound)
+			}
+			return err == nil
+		}
+
+		rememberTokenExists := func(series string) bool {
+			cmd := m.RotateRememberTokenCommand{Series: series, Token: "token", NewToken: "token"}
+			err := RotateRememberToken(&cmd)
+			if err != nil {
+				So(err, ShouldEqual, m.ErrRememberTokenNotFound)
+			}
+			return err == nil
+		}
+
+		Convey("Should only find a session of its user", func() {
+			So(sessionExists(1, first.Result.Id), ShouldBeTrue)
+			So(sessionExists(2, first.Result.Id), ShouldBeFalse)
+		})
+
+		Convey("Should list only the sessions of the user", func() {
+			query := m.GetUserSessionsQuery{UserId: 1}
+			So(GetUserSessions(&query), ShouldBeNil)
+			So(len(query.Result), ShouldEqual, 2)
+
+			Convey("With the last seen first", func() {
+				_, err := x.Exec("UPDATE user_session SET updated=? WHERE user_id=1", time.Now().Add(-time.Hour))
+				So(err, ShouldBeNil)
+				So(UpdateUserSessionSeen(&m.UpdateUserSessionSeenCommand{Id: first.Result.Id, Ip: "10.0.0.2"}), ShouldBeNil)
+
+				query := m.GetUserSessionsQuery{UserId: 1}
+				So(GetUserSessions(&query), ShouldBeNil)
+				So(query.Result[0].Id, ShouldEqual, first.Result.Id)
+				So(query.Result[0].Ip, ShouldEqual, "10.0.0.2")
+				So(query.Result[0].UserAgent, ShouldEqual, "curl/7.47.0")
+			})
+
+			Convey("Without the expired ones", func() {
+				_, err := x.Exec("UPDATE user_session SET updated=? WHERE id=?", time.Now().Add(-48*time.Hour), first.Result.Id)
+				So(err, ShouldBeNil)
+				_, err = x.Exec("UPDATE user_session SET created=? WHERE id=?", time.Now().Add(-48*time.Hour), second.Result.Id)
+				So(err, ShouldBeNil)
+
+				query := m.GetUserSessionsQuery{UserId: 1, SeenAfter: time.Now().Add(-24 * time.Hour)}
+				So(GetUserSessions(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Id, ShouldEqual, second.Result.Id)
+
+				query = m.GetUserSessionsQuery{UserId: 1, LoggedInAfter: time.Now().Add(-24 * time.Hour)}
+				So(GetUserSessions(&query), ShouldBeNil)
+				So(len(query.Result), ShouldEqual, 1)
+				So(query.Result[0].Id, ShouldEqual, first.Result.Id)
+			})
+		})
+
+		Convey("Should keep the session when the remember me series renews the login", func() {
+			login := m.CreateUserSessionCommand{UserId: 1, Ip: "10.0.0.1", RememberSeries: "first"}
+			So(CreateUserSession(&login), ShouldBeNil)
+
+			renewal := m.CreateUserSessionCommand{UserId: 1, Ip: "10.0.0.3", RememberSeries: "first"}
+			So(CreateUserSession(&renewal), ShouldBeNil)
+			So(renewal.Result.Id, ShouldEqual, login.Result.Id)
+
+			query := m.GetUserSessionsQuery{UserId: 1}
+			So(GetUserSessions(&query), ShouldBeNil)
+			So(len(query.Result), ShouldEqual, 3)
+
+			other := m.CreateUserSessionCommand{UserId: 2, RememberSeries: "first"}
+			So(CreateUserSession(&other), ShouldBeNil)
+			So(other.Result.Id, ShouldNotEqual, login.Result.Id)
+		})
+
+		Convey("When purging expired sessions", func() {
+			_, err := x.Exec("UPDATE user_session SET updated=? WHERE id=?", time.Now().Add(-48*time.Hour), first.Result.Id)
+			So(err, ShouldBeNil)
+			_, err = x.Exec("UPDATE user_session SET created=? WHERE id=?", time.Now().Add(-48*time.Hour), other.Result.Id)
+			So(err, ShouldBeNil)
+
+			cmd := m.PurgeUserSessionsCommand{SeenBefore: time.Now().Add(-24 * time.Hour), LoggedInBefore: time.Now().Add(-24 * time.Hour)}
+			So(PurgeUserSessions(&cmd), ShouldBeNil)
+
+			Convey("Should drop the ones not seen or logged in for too long", func() {
+				So(cmd.Result, ShouldEqual, 2)
+				So(sessionExists(1, first.Result.Id), ShouldBeFalse)
+				So(sessionExists(2, other.Result.Id), ShouldBeFalse)
+				So(sessionExists(1, second.Result.Id), ShouldBeTrue)
+			})
+		})
+
+		Convey("When revoking a session", func() {
+			So(DeleteUserSession(&m.DeleteUserSessionCommand{Id: second.Result.Id, UserId: 1}), ShouldBeNil)
+
+			Convey("Should only invalidate that session", func() {
+				So(sessionExists(1, first.Result.Id), ShouldBeTrue)
+				So(sessionExists(1, second.Result.Id), ShouldBeFalse)
+			})
+		})
+
+		Convey("When revoking a session renewed by a remember me series", func() {
+			remembered := m.CreateUserSessionCommand{UserId: 1, RememberSeries: "second"}
+			So(CreateUserSession(&remembered), ShouldBeNil)
+			So(DeleteUserSession(&m.DeleteUserSessionCommand{Id: remembered.Result.Id, UserId: 1}), ShouldBeNil)
+
+			Convey("Should invalidate the remember me series too", func() {
+				So(sessionExists(1, remembered.Result.Id), ShouldBeFalse)
+				So(rememberTokenExists("second"), ShouldBeFalse)
+				So(rememberTokenExists("first"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When revoking a session of another user", func() {
+			err := DeleteUserSession(&m.DeleteUserSessionCommand{Id: other.Result.Id, UserId: 1})
+
+			Convey("Should not find it", func() {
+				So(err, ShouldEqual, m.ErrUserSessionNotFound)
+				So(sessionExists(2, other.Result.Id), ShouldBeTrue)
+			})
+		})
+
+		Convey("When signing the user out everywhere", func() {
+			So(DeleteUserSessions(&m.DeleteUserSessionsCommand{UserId: 1}), ShouldBeNil)
+
+			Convey("Should invalidate both sessions and remember me tokens", func() {
+				So(sessionExists(1, first.Result.Id), ShouldBeFalse)
+				So(sessionExists(1, second.Result.Id), ShouldBeFalse)
+				So(rememberTokenExists("first"), ShouldBeFalse)
+				So(rememberTokenExists("second"), ShouldBeFalse)
+			})
+
+			Convey("Should keep the other user signed in", func() {
+				So(sessionExists(2, other.Result.Id), ShouldBeTrue)
+				So(rememberTokenExists("other"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When signing the user out everywhere else", func() {
+			cmd := m.DeleteUserSessionsCommand{UserId: 1, KeepId: first.Result.Id, KeepSeries: "first"}
+			So(DeleteUserSessions(&cmd), ShouldBeNil)
+
+			Convey("Should keep the current session", func() {
+				So(sessionExists(1, first.Result.Id), ShouldBeTrue)
+				So(rememberTokenExists("first"), ShouldBeTrue)
+				So(sessionExists(1, second.Result.Id), ShouldBeFalse)
+				So(rememberTokenExists("second"), ShouldBeFalse)
+			})
+		})
+	})
+}
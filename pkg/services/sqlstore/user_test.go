@@ -0,0 +1,89 @@
+package sqlstore
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	m "github.com/Cepave/grafana/pkg/models"
+	"github.com/Cepave/grafana/pkg/setting"
+	"github.com/Cepave/grafana/pkg/util"
+)
+
+func TestUserPasswordCost(t *testing.T) {
+
+	Convey("Testing user password cost", t, func() {
+		InitTestDB(t)
+		setting.PasswordHashCost = 20000
+		defer func() { setting.PasswordHashCost = util.DefaultPasswordCost }()
+
+		cmd := m.CreateUserCommand{Login: "user", Email: "user@test.com", Password: "pwd"}
+		So(CreateUser(&cmd), ShouldBeNil)
+
+		Convey("New users should be hashed with the configured cost", func() {
+			query := m.GetUserByIdQuery{Id: cmd.Result.Id}
+			So(GetUserById(&query), ShouldBeNil)
+			So(query.Result.PasswordCost, ShouldEqual, 20000)
+			So(query.Result.Password, ShouldEqual, util.EncodePasswordWithCost("pwd", query.Result.Salt, 20000))
+		})
+
+		Convey("Changing the password should store its cost", func() {
+			change := m.ChangeUserPasswordCommand{UserId: cmd.Result.Id, NewPassword: "hash"}
+			So(ChangeUserPassword(&change), ShouldBeNil)
+
+			query := m.GetUserByIdQuery{Id: cmd.Result.Id}
+			So(GetUserById(&query), ShouldBeNil)
+			So(query.Result.Password, ShouldEqual, "hash")
+			So(query.Result.PasswordCost, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestUserEmailVerification(t *testing.T) {
+
+	Convey("Testing user email verification", t, func() {
+		InitTestDB(t)
+
+		cmd := m.CreateUserCommand{Login: "user", Email: "user@test.com"}
+		So(CreateUser(&cmd), ShouldBeNil)
+
+		emailVerified := func() bool {
+			query := m.GetUserByIdQuery{Id: cmd.Result.Id}
+			So(GetUserById(&query), ShouldBeNil)
+			return query.Result.EmailVerified
+		}
+
+		Convey("New users should not be verified", func() {
+			So(emailVerified(), ShouldBeFalse)
+		})
+
+		Convey("When verifying the email", func() {
+			So(VerifyUserEmail(&m.VerifyUserEmailCommand{UserId: cmd.Result.Id}), ShouldBeNil)
+			So(emailVerified(), ShouldBeTrue)
+
+			Convey("Updating the user keeps the email verified", func() {
+				update := m.UpdateUserCommand{UserId: cmd.Result.Id, Login: "user", Email: "user@test.com", Name: "User"}
+				So(UpdateUser(&update), ShouldBeNil)
+				So(emailVerified(), ShouldBeTrue)
+			})
+
+			Convey("Changing the email has to be verified again", func() {
+				update := m.UpdateUserCommand{UserId: cmd.Result.Id, Login: "user", Email: "new@test.com"}
+				So(UpdateUser(&update), ShouldBeNil)
+				So(emailVerified(), ShouldBeFalse)
+			})
+
+			Convey("Changing the email to a trusted one keeps it verified", func() {
+				update := m.UpdateUserCommand{UserId: cmd.Result.Id, Login: "user", Email: "new@test.com", EmailVerified: true}
+				So(UpdateUser(&update), ShouldBeNil)
+				So(emailVerified(), ShouldBeTrue)
+			})
+
+			Convey("Should be part of the signed in user", func() {
+				query := m.GetSignedInUserQuery{UserId: cmd.Result.Id}
+				So(GetSignedInUser(&query), ShouldBeNil)
+				So(query.Result.EmailVerified, ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,135 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/events"
+	"github.com/Cepave/grafana/pkg/log"
+)
+
+var (
+	client      = &http.Client{Timeout: 5 * time.Second}
+	maxAttempts = 3
+	retryDelay  = 2 * time.Second
+)
+
+// Headers carrying the signature of a payload. The signature is the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the shared secret, the
+// timestamp is in unix seconds so receivers can reject replayed payloads.
+const (
+	SIGNATURE_HEADER           = "X-Signature"
+	SIGNATURE_TIMESTAMP_HEADER = "X-Signature-Timestamp"
+)
+
+type webhook struct {
+	url    string
+	secret string
+}
+
+// Init posts org lifecycle events to orgEventsUrl, nothing is sent when it
+// is empty. Payloads are signed when secret is set.
+func Init(orgEventsUrl, secret string) {
+	if orgEventsUrl == "" {
+		return
+	}
+
+	hook := &webhook{url: orgEventsUrl, secret: secret}
+
+	bus.AddEventListener(func(evt *events.OrgCreated) error {
+		return hook.notify(evt)
+	})
+	bus.AddEventListener(func(evt *events.OrgUpdated) error {
+		return hook.notify(evt)
+	})
+	bus.AddEventListener(func(evt *events.OrgDeleted) error {
+		return hook.notify(evt)
+	})
+}
+
+// Sign returns the signature of body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// notify delivers the event in the background. Events are published after
+// their transaction commits, a slow or failing receiver never holds up or
+// fails the request that triggered it.
+func (hook *webhook) notify(event interface{}) error {
+	wireEvent, err := events.ToOnWriteEvent(event)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(wireEvent)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := hook.deliver(body); err != nil {
+			log.Error(3, "Webhooks: failed to deliver %s to %s: %v", wireEvent.EventType, hook.url, err)
+		}
+	}()
+
+	return nil
+}
+
+// deliver posts body, retrying failed connections and server errors.
+func (hook *webhook) deliver(body []byte) error {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(retryDelay)
+		}
+
+		var retry bool
+		if retry, err = hook.post(body); err == nil || !retry {
+			return err
+		}
+	}
+
+	return err
+}
+
+// post sends body once and reports whether a failure is worth retrying.
+// Every attempt is signed with a fresh timestamp.
+func (hook *webhook) post(body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", hook.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if hook.secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(SIGNATURE_TIMESTAMP_HEADER, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(SIGNATURE_HEADER, Sign(hook.secret, timestamp, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return true, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,140 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Cepave/grafana/pkg/bus"
+	"github.com/Cepave/grafana/pkg/events"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+func TestOrgWebhooks(t *testing.T) {
+
+	Convey("Given an org events webhook", t, func() {
+		retryDelay = time.Millisecond
+		defer bus.ClearBusHandlers()
+
+		var failures int32
+		deliveries := make(chan delivery, 10)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&failures, -1) >= 0 {
+				w.WriteHeader(503)
+				return
+			}
+
+			body, _ := ioutil.ReadAll(r.Body)
+			deliveries <- delivery{header: r.Header, body: body}
+		}))
+		defer server.Close()
+
+		Init(server.URL, "")
+
+		received := func() map[string]interface{} {
+			select {
+			case d := <-deliveries:
+				So(d.header.Get("Content-Type"), ShouldEqual, "application/json")
+				So(d.header.Get(SIGNATURE_HEADER), ShouldEqual, "")
+
+				payload := map[string]interface{}{}
+				So(json.Unmarshal(d.body, &payload), ShouldBeNil)
+				return payload
+			case <-time.After(2 * time.Second):
+				t.Fatal("webhook was not delivered")
+				return nil
+			}
+		}
+
+		Convey("Should post created orgs", func() {
+			So(bus.Publish(&events.OrgCreated{Id: 3, Name: "child"}), ShouldBeNil)
+
+			payload := received()
+			So(payload["event_type"], ShouldEqual, "OrgCreated")
+			So(payload["payload"], ShouldResemble, map[string]interface{}{
+				"timestamp": "0001-01-01T00:00:00Z",
+				"id":        float64(3),
+				"name":      "child",
+			})
+		})
+
+		Convey("Should post updated and deleted orgs", func() {
+			So(bus.Publish(&events.OrgUpdated{Id: 3, Name: "renamed"}), ShouldBeNil)
+			So(received()["event_type"], ShouldEqual, "OrgUpdated")
+
+			So(bus.Publish(&events.OrgDeleted{Id: 3}), ShouldBeNil)
+			So(received()["event_type"], ShouldEqual, "OrgDeleted")
+		})
+
+		Convey("Should retry when the receiver fails", func() {
+			atomic.StoreInt32(&failures, 2)
+			So(bus.Publish(&events.OrgDeleted{Id: 4}), ShouldBeNil)
+
+			payload := received()
+			So(payload["payload"].(map[string]interface{})["id"], ShouldEqual, 4)
+		})
+
+		Convey("Should not retry rejected events", func() {
+			var attempts int32
+			rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				w.WriteHeader(400)
+			}))
+			defer rejecting.Close()
+
+			hook := &webhook{url: rejecting.URL}
+			So(hook.deliver([]byte("{}")), ShouldNotBeNil)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestSignedWebhooks(t *testing.T) {
+
+	Convey("Given a webhook with a shared secret", t, func() {
+		deliveries := make(chan delivery, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			deliveries <- delivery{header: r.Header, body: body}
+		}))
+		defer server.Close()
+
+		hook := &webhook{url: server.URL, secret: "s3cret"}
+		body := []byte(`{"event_type":"OrgDeleted"}`)
+		So(hook.deliver(body), ShouldBeNil)
+		d := <-deliveries
+
+		Convey("Should sign the timestamp and body with the secret", func() {
+			timestamp, err := strconv.ParseInt(d.header.Get(SIGNATURE_TIMESTAMP_HEADER), 10, 64)
+			So(err, ShouldBeNil)
+			So(time.Since(time.Unix(timestamp, 0)), ShouldBeLessThan, time.Minute)
+
+			mac := hmac.New(sha256.New, []byte("s3cret"))
+			mac.Write([]byte(d.header.Get(SIGNATURE_TIMESTAMP_HEADER) + "."))
+			mac.Write(d.body)
+
+			So(string(d.body), ShouldEqual, string(body))
+			So(d.header.Get(SIGNATURE_HEADER), ShouldEqual, hex.EncodeToString(mac.Sum(nil)))
+		})
+
+		Convey("Should not match another secret or timestamp", func() {
+			timestamp, _ := strconv.ParseInt(d.header.Get(SIGNATURE_TIMESTAMP_HEADER), 10, 64)
+
+			So(Sign("other", timestamp, d.body), ShouldNotEqual, d.header.Get(SIGNATURE_HEADER))
+			So(Sign("s3cret", timestamp+1, d.body), ShouldNotEqual, d.header.Get(SIGNATURE_HEADER))
+		})
+	})
+}
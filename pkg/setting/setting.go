@@ -15,6 +15,7 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/macaron-contrib/session"
 	"gopkg.in/ini.v1"
@@ -41,6 +42,9 @@ var (
 	Env       string = DEV
 	AppUrl    string
 	AppSubUrl string
+	// RootPath is the path every route and static file is served under, from
+	// rootPath in the global config. It is "" when serving at the root.
+	RootPath string
 
 	// build
 	BuildVersion string
@@ -55,6 +59,7 @@ var (
 	// Log settings.
 	LogModes   []string
 	LogConfigs []util.DynMap
+	LogFormat  string
 
 	// Http server options
 	Protocol           Scheme
@@ -66,6 +71,11 @@ var (
 	StaticRootPath     string
 	EnableGzip         bool
 	EnforceDomain      bool
+	EnforceHTTPS       bool
+
+	// Request body limits in bytes, dashboards get their own larger limit
+	MaxRequestBodyBytes   int64
+	MaxDashboardBodyBytes int64
 
 	// Security settings.
 	SecretKey             string
@@ -76,13 +86,42 @@ var (
 	EmailCodeValidMinutes int
 	DataProxyWhiteList    map[string]bool
 
+	// PBKDF2 iterations of new password hashes, older ones are rehashed on login
+	PasswordHashCost int
+
+	// Login throttling, 0 max failed attempts disables it
+	LoginMaxFailedAttempts int
+	LoginLockoutSeconds    int
+
+	// How long a login lasts however often it is renewed, 0 means forever
+	LoginMaxLifetime time.Duration
+
+	// Snapshots
+	ExternalSnapshotEnabled bool
+
+	// How long deleted dashboards can be restored before they are purged,
+	// 0 keeps them forever
+	DashboardDeletedRetention time.Duration
+
+	// How long deleted data sources can be restored before they are purged,
+	// 0 keeps them forever
+	DataSourceDeletedRetention time.Duration
+
+	// Largest zip accepted by the dashboard import
+	MaxDashboardImportBytes int64
+
 	// User settings
 	AllowUserSignUp    bool
 	AllowUserOrgCreate bool
 	AutoAssignOrg      bool
+	AutoAssignOrgId    int64
 	AutoAssignOrgRole  string
 	VerifyEmailEnabled bool
 
+	// Users have to verify their email before they can log in, grafana
+	// admins are exempt so the server can't lock itself out
+	RequireEmailVerification bool
+
 	// Http auth
 	AdminUser     string
 	AdminPassword string
@@ -109,8 +148,21 @@ var (
 	IsWindows    bool
 
 	// PhantomJs Rendering
-	ImagesDir  string
-	PhantomDir string
+	ImagesDir          string
+	PhantomDir         string
+	RenderAllowedHosts []string
+
+	// seconds a rendered png is served from cache, 0 disables caching
+	RenderCacheTtlSeconds int
+
+	// larger requested sizes are clamped to these
+	RenderMaxWidth  int
+	RenderMaxHeight int
+	RenderMaxScale  float64
+
+	// renders running at once, 0 means no limit
+	RenderConcurrencyLimit int
+	RenderTimeoutSeconds   int
 
 	// for logging purposes
 	configFiles                  []string
@@ -125,11 +177,23 @@ var (
 	LdapEnabled    bool
 	LdapConfigFile string
 
+	// Provisioning, an empty path turns it off
+	ProvisionDataSourcesPath  string
+	ProvisionPruneDataSources bool
+	ProvisionDashboardsPath   string
+
+	// Feature flags and their defaults, orgs can override them
+	FeatureFlags map[string]bool
+
 	// SMTP email settings
 	Smtp SmtpSettings
 
 	// QUOTA
 	Quota QuotaSettings
+
+	// Prometheus metrics endpoint
+	MetricsEndpointEnabled bool
+	MetricsListenAddr      string
 )
 
 type CommandLineArgs struct {
@@ -160,7 +224,18 @@ func parseAppUrlAndSubUrl(section *ini.Section) (string, string) {
 }
 
 func ToAbsUrl(relativeUrl string) string {
-	return AppUrl + relativeUrl
+	return AppUrl + strings.TrimPrefix(RoutePath("/"), "/") + relativeUrl
+}
+
+// RoutePath returns the path p is served under, use it to match requests.
+func RoutePath(p string) string {
+	return RootPath + p
+}
+
+// AppPath returns the path the browser reaches p under, behind root_url's
+// sub path. Use it for redirects and links.
+func AppPath(p string) string {
+	return AppSubUrl + RoutePath(p)
 }
 
 func applyEnvVariableOverrides() {
@@ -400,6 +475,9 @@ func NewConfigContext(args *CommandLineArgs) error {
 	RouterLogging = server.Key("router_logging").MustBool(false)
 	EnableGzip = server.Key("enable_gzip").MustBool(false)
 	EnforceDomain = server.Key("enforce_domain").MustBool(false)
+	EnforceHTTPS = server.Key("enforce_https").MustBool(false)
+	MaxRequestBodyBytes = server.Key("max_request_body_kb").MustInt64(1024) * 1024
+	MaxDashboardBodyBytes = server.Key("max_dashboard_body_kb").MustInt64(10240) * 1024
 	StaticRootPath = makeAbsolute(server.Key("static_root_path").String(), HomePath)
 
 	if err := validateStaticRootPath(); err != nil {
@@ -413,6 +491,13 @@ func NewConfigContext(args *CommandLineArgs) error {
 	CookieUserName = security.Key("cookie_username").String()
 	CookieRememberName = security.Key("cookie_remember_name").String()
 	DisableGravatar = security.Key("disable_gravatar").MustBool(true)
+	LoginMaxFailedAttempts = security.Key("login_max_failed_attempts").MustInt(5)
+	LoginLockoutSeconds = security.Key("login_lockout_seconds").MustInt(300)
+	LoginMaxLifetime = time.Duration(security.Key("login_maximum_lifetime_days").MustInt(30)) * 24 * time.Hour
+	PasswordHashCost = security.Key("password_hash_cost").MustInt(util.DefaultPasswordCost)
+	if PasswordHashCost < util.DefaultPasswordCost {
+		return fmt.Errorf("password_hash_cost can't be lower than %d", util.DefaultPasswordCost)
+	}
 
 	//  read data source proxy white list
 	DataProxyWhiteList = make(map[string]bool)
@@ -424,12 +509,19 @@ func NewConfigContext(args *CommandLineArgs) error {
 	AdminUser = security.Key("admin_user").String()
 	AdminPassword = security.Key("admin_password").String()
 
+	ExternalSnapshotEnabled = Cfg.Section("snapshots").Key("external_enabled").MustBool(true)
+	DashboardDeletedRetention = time.Duration(Cfg.Section("dashboards").Key("deleted_retention_days").MustInt(30)) * 24 * time.Hour
+	DataSourceDeletedRetention = time.Duration(Cfg.Section("datasources").Key("deleted_retention_days").MustInt(30)) * 24 * time.Hour
+	MaxDashboardImportBytes = Cfg.Section("dashboards").Key("import_max_zip_kb").MustInt64(102400) * 1024
+
 	users := Cfg.Section("users")
 	AllowUserSignUp = users.Key("allow_sign_up").MustBool(true)
 	AllowUserOrgCreate = users.Key("allow_org_create").MustBool(true)
 	AutoAssignOrg = users.Key("auto_assign_org").MustBool(true)
+	AutoAssignOrgId = users.Key("auto_assign_org_id").MustInt64(1)
 	AutoAssignOrgRole = users.Key("auto_assign_org_role").In("Editor", []string{"Editor", "Admin", "Read Only Editor", "Viewer"})
 	VerifyEmailEnabled = users.Key("verify_email_enabled").MustBool(false)
+	RequireEmailVerification = users.Key("require_email_verification").MustBool(false)
 
 	// anonymous access
 	AnonymousEnabled = Cfg.Section("auth.anonymous").Key("enabled").MustBool(false)
@@ -449,16 +541,41 @@ func NewConfigContext(args *CommandLineArgs) error {
 	// PhantomJS rendering
 	ImagesDir = filepath.Join(DataPath, "png")
 	PhantomDir = filepath.Join(HomePath, "vendor/phantomjs")
+	RenderAllowedHosts = Cfg.Section("rendering").Key("allowed_hosts").Strings(" ")
+	RenderCacheTtlSeconds = Cfg.Section("rendering").Key("cache_ttl_seconds").MustInt(60)
+	RenderMaxWidth = Cfg.Section("rendering").Key("max_width").MustInt(3000)
+	RenderMaxHeight = Cfg.Section("rendering").Key("max_height").MustInt(3000)
+	RenderMaxScale = Cfg.Section("rendering").Key("max_scale").MustFloat64(3)
+	RenderConcurrencyLimit = Cfg.Section("rendering").Key("concurrency_limit").MustInt(5)
+	RenderTimeoutSeconds = Cfg.Section("rendering").Key("timeout_seconds").MustInt(15)
 
 	analytics := Cfg.Section("analytics")
 	ReportingEnabled = analytics.Key("reporting_enabled").MustBool(true)
 	GoogleAnalyticsId = analytics.Key("google_analytics_ua_id").String()
 	GoogleTagManagerId = analytics.Key("google_tag_manager_id").String()
 
+	metricsSec := Cfg.Section("metrics")
+	MetricsEndpointEnabled = metricsSec.Key("enabled").MustBool(true)
+	MetricsListenAddr = metricsSec.Key("listen_addr").String()
+
 	ldapSec := Cfg.Section("auth.ldap")
 	LdapEnabled = ldapSec.Key("enabled").MustBool(false)
 	LdapConfigFile = ldapSec.Key("config_file").String()
 
+	provisioning := Cfg.Section("provisioning")
+	if path := provisioning.Key("datasources").String(); path != "" {
+		ProvisionDataSourcesPath = makeAbsolute(path, HomePath)
+	}
+	ProvisionPruneDataSources = provisioning.Key("prune_datasources").MustBool(false)
+	if path := provisioning.Key("dashboards").String(); path != "" {
+		ProvisionDashboardsPath = makeAbsolute(path, HomePath)
+	}
+
+	FeatureFlags = make(map[string]bool)
+	for _, key := range Cfg.Section("feature_flags").Keys() {
+		FeatureFlags[key.Name()] = key.MustBool(false)
+	}
+
 	readSessionConfig()
 	readSmtpSettings()
 	readQuotaSettings()
@@ -467,6 +584,10 @@ func NewConfigContext(args *CommandLineArgs) error {
 		log.Warn("require_email_validation is enabled but smpt is disabled")
 	}
 
+	if RequireEmailVerification && !Smtp.Enabled {
+		log.Warn("require_email_verification is enabled but smtp is disabled, users can't verify their email")
+	}
+
 	return nil
 }
 
@@ -492,6 +613,16 @@ func readSessionConfig() {
 	}
 }
 
+// UserSessionIdleLifetime is how long a login lasts without being used,
+// through its session or its remember me cookie.
+func UserSessionIdleLifetime() time.Duration {
+	lifetime := time.Duration(SessionOptions.Maxlifetime) * time.Second
+	if remember := time.Duration(LogInRememberDays) * 24 * time.Hour; remember > lifetime {
+		lifetime = remember
+	}
+	return lifetime
+}
+
 var logLevels = map[string]int{
 	"Trace":    0,
 	"Debug":    1,
@@ -507,6 +638,7 @@ func initLogging(args *CommandLineArgs) {
 	// Get and check log mode.
 	LogModes = strings.Split(Cfg.Section("log").Key("mode").MustString("console"), ",")
 	LogsPath = makeAbsolute(Cfg.Section("paths").Key("logs").String(), HomePath)
+	LogFormat = Cfg.Section("log").Key("format").In(log.FORMAT_TEXT, []string{log.FORMAT_TEXT, log.FORMAT_JSON})
 
 	LogConfigs = make([]util.DynMap, len(LogModes))
 	for i, mode := range LogModes {
@@ -531,6 +663,7 @@ func initLogging(args *CommandLineArgs) {
 			LogConfigs[i] = util.DynMap{
 				"level":      level,
 				"formatting": formatting,
+				"format":     LogFormat,
 			}
 		case "file":
 			logPath := sec.Key("file_name").MustString(filepath.Join(LogsPath, "grafana.log"))
@@ -543,6 +676,7 @@ func initLogging(args *CommandLineArgs) {
 				"maxsize":  1 << uint(sec.Key("max_size_shift").MustInt(28)),
 				"daily":    sec.Key("daily_rotate").MustBool(true),
 				"maxdays":  sec.Key("max_days").MustInt(7),
+				"format":   LogFormat,
 			}
 		case "conn":
 			LogConfigs[i] = util.DynMap{
@@ -89,5 +89,16 @@ func TestLoadingSettings(t *testing.T) {
 			So(DataPath, ShouldEqual, "/tmp/env_override")
 		})
 
+		Convey("Should build paths under the root path", func() {
+			defer func(url, subUrl, root string) {
+				AppUrl, AppSubUrl, RootPath = url, subUrl, root
+			}(AppUrl, AppSubUrl, RootPath)
+			AppUrl, AppSubUrl, RootPath = "http://example.com/sub/", "/sub", "/grafana"
+
+			So(RoutePath("/login"), ShouldEqual, "/grafana/login")
+			So(AppPath("/login"), ShouldEqual, "/sub/grafana/login")
+			So(ToAbsUrl("invite/abc"), ShouldEqual, "http://example.com/sub/grafana/invite/abc")
+		})
+
 	})
 }
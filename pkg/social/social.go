@@ -70,7 +70,7 @@ func NewOAuthService() {
 				AuthURL:  info.AuthUrl,
 				TokenURL: info.TokenUrl,
 			},
-			RedirectURL: strings.TrimSuffix(setting.AppUrl, "/") + SocialBaseUrl + name,
+			RedirectURL: strings.TrimSuffix(setting.AppUrl, "/") + setting.RoutePath(SocialBaseUrl+name),
 			Scopes:      info.Scopes,
 		}
 
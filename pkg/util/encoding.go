@@ -28,8 +28,21 @@ func GetRandomString(n int, alphabets ...byte) string {
 	return string(bytes)
 }
 
+// DefaultPasswordCost is the number of PBKDF2 iterations of password hashes
+// stored without a cost.
+const DefaultPasswordCost = 10000
+
 func EncodePassword(password string, salt string) string {
-	newPasswd := PBKDF2([]byte(password), []byte(salt), 10000, 50, sha256.New)
+	return EncodePasswordWithCost(password, salt, DefaultPasswordCost)
+}
+
+// EncodePasswordWithCost hashes with cost PBKDF2 iterations, 0 means
+// DefaultPasswordCost.
+func EncodePasswordWithCost(password string, salt string, cost int) string {
+	if cost == 0 {
+		cost = DefaultPasswordCost
+	}
+	newPasswd := PBKDF2([]byte(password), []byte(salt), cost, 50, sha256.New)
 	return fmt.Sprintf("%x", newPasswd)
 }
 
@@ -102,3 +115,12 @@ func DecodeBasicAuthHeader(header string) (string, string, error) {
 
 	return userAndPass[0], userAndPass[1], nil
 }
+
+// GenerateUUID returns a random (version 4) uuid string.
+func GenerateUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
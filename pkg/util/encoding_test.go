@@ -23,4 +23,10 @@ func TestEncoding(t *testing.T) {
 		So(password, ShouldEqual, "1234")
 	})
 
+	Convey("When encoding a password with a cost", t, func() {
+		So(EncodePasswordWithCost("pass", "salt", 0), ShouldEqual, EncodePassword("pass", "salt"))
+		So(EncodePasswordWithCost("pass", "salt", DefaultPasswordCost), ShouldEqual, EncodePassword("pass", "salt"))
+		So(EncodePasswordWithCost("pass", "salt", 20000), ShouldNotEqual, EncodePassword("pass", "salt"))
+	})
+
 }
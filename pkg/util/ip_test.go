@@ -0,0 +1,31 @@
+package util
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIPNets(t *testing.T) {
+
+	Convey("When parsing networks", t, func() {
+		nets, err := ParseCIDRs([]string{"10.0.0.0/8", " 192.168.1.7 ", "", "2001:db8::/32"})
+		So(err, ShouldBeNil)
+		So(len(nets), ShouldEqual, 3)
+
+		So(IPInNets(net.ParseIP("10.2.3.4"), nets), ShouldBeTrue)
+		So(IPInNets(net.ParseIP("192.168.1.7"), nets), ShouldBeTrue)
+		So(IPInNets(net.ParseIP("192.168.1.8"), nets), ShouldBeFalse)
+		So(IPInNets(net.ParseIP("2001:db8::1"), nets), ShouldBeTrue)
+		So(IPInNets(nil, nets), ShouldBeFalse)
+	})
+
+	Convey("When a network doesn't parse", t, func() {
+		_, err := ParseCIDRs([]string{"10.0.0.0/33"})
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseCIDRs([]string{"localhost"})
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -86,8 +86,8 @@ function (angular, _) {
       }
 
       backendSrv.get('/api/orgs', {query: ''}).then(function(result) {
-        $scope.orgsSearchCache = result;
-        callback(_.pluck(result, "name"));
+        $scope.orgsSearchCache = result.items;
+        callback(_.pluck(result.items, "name"));
       });
     };
 
@@ -13,8 +13,8 @@ function (angular) {
     };
 
     $scope.getOrgs = function() {
-      backendSrv.get('/api/orgs').then(function(orgs) {
-        $scope.orgs = orgs;
+      backendSrv.get('/api/orgs').then(function(result) {
+        $scope.orgs = result.items;
       });
     };
 
@@ -115,7 +115,9 @@ function (angular, _, config) {
     };
 
     this.search = function(query) {
-      return this.get('/api/search', query);
+      return this.get('/api/search', query).then(function(result) {
+        return result.items;
+      });
     };
 
     this.getDashboard = function(type, slug) {